@@ -3,7 +3,18 @@ package app
 import (
 	"chatapp/config"
 	"chatapp/handler"
+	"chatapp/service/attachment"
+	"chatapp/service/audit"
 	"chatapp/service/auth"
+	"chatapp/service/conversation"
+	"chatapp/service/keys"
+	"chatapp/service/message"
+	"chatapp/service/presence"
+	"chatapp/service/push"
+	"chatapp/service/ratelimit"
+	"chatapp/service/realtime"
+	"chatapp/service/transfer"
+	"chatapp/service/transparency"
 	"chatapp/service/user"
 
 	"context"
@@ -18,16 +29,53 @@ import (
 )
 
 type App struct {
-	logger      *slog.Logger
-	authService *auth.AuthService
-	userService *user.UserService
+	logger              *slog.Logger
+	authService         *auth.AuthService
+	userService         *user.UserService
+	keyService          *keys.KeyService
+	conversationService *conversation.ConversationService
+	transparencyService *transparency.TransparencyService
+	messageService      *message.MessageService
+	dispatcher          *realtime.Dispatcher
+	attachmentService   *attachment.AttachmentService
+	pushService         *push.PushService
+	presenceService     *presence.PresenceService
+	limiter             *ratelimit.Limiter
+	auditService        *audit.AuditService
+	transferService     *transfer.TransferService
 }
 
-func NewApp(logger *slog.Logger, authService *auth.AuthService, userService *user.UserService) *App {
+func NewApp(
+	logger *slog.Logger,
+	authService *auth.AuthService,
+	userService *user.UserService,
+	keyService *keys.KeyService,
+	conversationService *conversation.ConversationService,
+	transparencyService *transparency.TransparencyService,
+	messageService *message.MessageService,
+	dispatcher *realtime.Dispatcher,
+	attachmentService *attachment.AttachmentService,
+	pushService *push.PushService,
+	presenceService *presence.PresenceService,
+	limiter *ratelimit.Limiter,
+	auditService *audit.AuditService,
+	transferService *transfer.TransferService,
+) *App {
 	return &App{
-		logger:      logger,
-		authService: authService,
-		userService: userService,
+		logger:              logger,
+		authService:         authService,
+		userService:         userService,
+		keyService:          keyService,
+		conversationService: conversationService,
+		transparencyService: transparencyService,
+		messageService:      messageService,
+		dispatcher:          dispatcher,
+		attachmentService:   attachmentService,
+		pushService:         pushService,
+		presenceService:     presenceService,
+		limiter:             limiter,
+		auditService:        auditService,
+		transferService:     transferService,
 	}
 }
 
@@ -36,10 +84,22 @@ func (me *App) Run() error {
 	server := fiber.New(fiber.Config{
 		ErrorHandler: handler.ErrorHandler,
 		Prefork:      false,
+		// attachment uploads are streamed instead of being buffered up to the
+		// body limit.
+		StreamRequestBody: true,
 	})
 	server.Use(handler.WithLogging(me.logger))
 	me.loadAuthRoutes(server)
 	me.loadUserRoutes(server)
+	me.loadKeyRoutes(server)
+	me.loadConversationRoutes(server)
+	me.loadTransparencyRoutes(server)
+	me.loadMessageRoutes(server)
+	me.loadRealtimeRoutes(server)
+	me.loadAttachmentRoutes(server)
+	me.loadUploadRoutes(server)
+	me.loadPushRoutes(server)
+	me.loadTransferRoutes(server)
 
 	listenErrChan := make(chan error, 1)
 	go func() {
@@ -69,12 +129,213 @@ func (me *App) Run() error {
 }
 
 func (me *App) loadAuthRoutes(server *fiber.App) {
-	ah := handler.NewAuthHandler(me.authService, me.userService)
+	ah := handler.NewAuthHandler(me.authService, me.userService, me.auditService)
+	rl := handler.NewRateLimitHandler(me.limiter)
 
-	server.Post("/register", ah.HandleRegister)
-	server.Get("/verify-email", ah.HandleVerifyEmail)
-	server.Get("/login", ah.HandleLogin)
+	server.Post("/register", rl.WithRateLimit(ratelimit.RegisterPolicyByIP, handler.ByIP), ah.HandleRegister)
+	server.Post("/register/opaque", rl.WithRateLimit(ratelimit.RegisterPolicyByIP, handler.ByIP), ah.HandleBeginOpaqueRegistration)
+	server.Post("/register/opaque/finish", rl.WithRateLimit(ratelimit.RegisterPolicyByIP, handler.ByIP), ah.HandleFinishOpaqueRegistration)
+	server.Get("/verify-email", rl.WithRateLimit(ratelimit.VerifyEmailPolicyByIP, handler.ByIP), ah.HandleVerifyEmail)
+	server.Get("/login",
+		rl.WithRateLimit(ratelimit.LoginPolicyByIP, handler.ByIP),
+		rl.WithRateLimit(ratelimit.LoginPolicyByEmail, handler.ByEmail),
+		ah.HandleLogin,
+	)
+	server.Post("/login/opaque",
+		rl.WithRateLimit(ratelimit.LoginPolicyByIP, handler.ByIP),
+		rl.WithRateLimit(ratelimit.LoginPolicyByEmail, handler.ByEmail),
+		ah.HandleBeginOpaqueLogin,
+	)
+	server.Post("/login/opaque/finish", rl.WithRateLimit(ratelimit.LoginPolicyByIP, handler.ByIP), ah.HandleFinishOpaqueLogin)
+	server.Post("/login/link",
+		rl.WithRateLimit(ratelimit.LoginLinkPolicyByIP, handler.ByIP),
+		rl.WithRateLimit(ratelimit.LoginLinkPolicyByEmail, handler.ByEmail),
+		ah.HandleRequestLoginLink,
+	)
+	server.Get("/login/link", rl.WithRateLimit(ratelimit.LoginPolicyByIP, handler.ByIP), ah.HandleLoginWithLink)
+	server.Post("/login/mfa", rl.WithRateLimit(ratelimit.MFAPolicyByIP, handler.ByIP), ah.HandleLoginMFA)
+	server.Post("/login/mfa/passkey", rl.WithRateLimit(ratelimit.MFAPolicyByIP, handler.ByIP), ah.HandleBeginPasskeyMFA)
+	server.Post("/login/mfa/passkey/finish", rl.WithRateLimit(ratelimit.MFAPolicyByIP, handler.ByIP), ah.HandleFinishPasskeyMFA)
+	server.Post("/login/passkey", rl.WithRateLimit(ratelimit.LoginPolicyByIP, handler.ByIP), ah.HandleBeginPasskeyLogin)
+	server.Post("/login/passkey/finish", rl.WithRateLimit(ratelimit.LoginPolicyByIP, handler.ByIP), ah.HandleFinishPasskeyLogin)
+	server.Post("/logout", ah.WithSession, ah.HandleLogout)
+	server.Get("/login-alerts/deny", ah.HandleConfirmDenyLogin)
+	server.Post("/login-alerts/deny", rl.WithRateLimit(ratelimit.VerifyEmailPolicyByIP, handler.ByIP), ah.HandleDenyLogin)
+	server.Post("/forgot-password",
+		rl.WithRateLimit(ratelimit.PasswordResetPolicyByIP, handler.ByIP),
+		rl.WithRateLimit(ratelimit.PasswordResetPolicyByEmail, handler.ByEmail),
+		ah.HandleForgotPassword,
+	)
+	server.Post("/reset-password", rl.WithRateLimit(ratelimit.PasswordResetPolicyByIP, handler.ByIP), ah.HandleResetPassword)
+	// with the session only, accounts without a profile can be deleted too.
+	server.Get("/account-deletion", ah.WithSession, ah.HandleGetAccountDeletion)
+	server.Post("/account-deletion", ah.WithSession, ah.HandleRequestAccountDeletion)
+	server.Delete("/account-deletion", ah.WithSession, ah.HandleCancelAccountDeletion)
+	server.Get("/account-deletion/cancel", rl.WithRateLimit(ratelimit.VerifyEmailPolicyByIP, handler.ByIP), ah.HandleCancelAccountDeletionLink)
 }
 
-// TODO:
-func (me *App) loadUserRoutes(server *fiber.App) {}
+// authenticated returns the middlewares that require a valid session and load
+// the current user.
+func (me *App) authenticated() []fiber.Handler {
+	ah := handler.NewAuthHandler(me.authService, me.userService, me.auditService)
+	uh := handler.NewUserHandler(me.userService)
+
+	return []fiber.Handler{ah.WithSession, uh.WithUser}
+}
+
+// withDevice returns the authenticated middlewares plus the one loading the
+// device the request is made from.
+func (me *App) withDevice() []fiber.Handler {
+	kh := handler.NewKeyHandler(me.keyService, me.auditService)
+
+	return append(me.authenticated(), kh.WithDevice)
+}
+
+func (me *App) loadUserRoutes(server *fiber.App) {
+	uh := handler.NewUserHandler(me.userService)
+	adh := handler.NewAuditHandler(me.auditService)
+	ah := handler.NewAuthHandler(me.authService, me.userService, me.auditService)
+	kh := handler.NewKeyHandler(me.keyService, me.auditService)
+	rl := handler.NewRateLimitHandler(me.limiter)
+
+	users := server.Group("/me", me.authenticated()...)
+	users.Get("/", uh.HandleGetMe)
+	users.Put("/delivery-access-key", uh.HandleSetDeliveryAccessKey)
+	users.Delete("/delivery-access-key", uh.HandleDeleteDeliveryAccessKey)
+	users.Put("/read-receipts", uh.HandleSetReadReceipts)
+	users.Put("/presence-visibility", uh.HandleSetPresenceVisibility)
+	users.Get("/security-events", adh.HandleListSecurityEvents)
+	users.Get("/mfa", ah.HandleGetMFAStatus)
+	users.Post("/mfa/totp", ah.HandleBeginTOTPEnrollment)
+	users.Post("/mfa/totp/confirm", ah.HandleConfirmTOTP)
+	users.Post("/mfa/totp/disable", ah.HandleDisableTOTP)
+	users.Post("/mfa/recovery-codes", ah.HandleRegenerateRecoveryCodes)
+	users.Get("/passkeys", ah.HandleListPasskeys)
+	users.Post("/passkeys", ah.HandleBeginPasskeyRegistration)
+	users.Post("/passkeys/finish", ah.HandleFinishPasskeyRegistration)
+	users.Delete("/passkeys/:passkeyID", ah.HandleDeletePasskey)
+	users.Post("/opaque", rl.WithRateLimit(ratelimit.OpaqueUpgradePolicyByCredentials, handler.ByCredentialsID), ah.HandleBeginOpaqueUpgrade)
+	users.Post("/opaque/finish", ah.HandleFinishOpaqueUpgrade)
+	users.Get("/key-backup", kh.HandleGetKeyBackup)
+	users.Post("/key-backup", kh.HandleCreateKeyBackup)
+	users.Put("/key-backup", kh.HandleRotateKeyBackup)
+	users.Put("/key-backup/pin", kh.HandleChangeKeyBackupPIN)
+	users.Post("/key-backup/restore", kh.HandleRestoreKeyBackup)
+	users.Delete("/key-backup", kh.HandleDeleteKeyBackup)
+}
+
+func (me *App) loadKeyRoutes(server *fiber.App) {
+	kh := handler.NewKeyHandler(me.keyService, me.auditService)
+	ph := handler.NewPresenceHandler(me.presenceService)
+
+	devices := server.Group("/devices", me.authenticated()...)
+	devices.Post("/", kh.HandleRegisterDevice)
+	devices.Put("/:deviceID/identity-key", kh.HandleRotateIdentityKey)
+
+	users := server.Group("/users", me.authenticated()...)
+	users.Get("/:username/devices", kh.HandleListUserDevices)
+	users.Get("/:username/devices/:deviceID/key-history", kh.HandleListDeviceKeyHistory)
+	users.Get("/:username/presence", ph.HandleGetPresence)
+
+	contacts := server.Group("/contacts", me.authenticated()...)
+	contacts.Get("/:username/safety-number", kh.HandleGetSafetyNumberKeys)
+	contacts.Get("/:username/verification", kh.HandleGetContactVerification)
+	contacts.Put("/:username/verification", kh.HandleVerifyContact)
+	contacts.Delete("/:username/verification", kh.HandleUnverifyContact)
+}
+
+func (me *App) loadConversationRoutes(server *fiber.App) {
+	ch := handler.NewConversationHandler(me.conversationService)
+	mh := handler.NewMessageHandler(me.messageService)
+	kh := handler.NewKeyHandler(me.keyService, me.auditService)
+	ph := handler.NewPresenceHandler(me.presenceService)
+	rl := handler.NewRateLimitHandler(me.limiter)
+
+	conversations := server.Group("/conversations", me.authenticated()...)
+	conversations.Post("/", ch.HandleCreateConversation)
+	conversations.Get("/", ch.HandleListConversations)
+	conversations.Get("/:conversationID/events", ch.HandleListConversationEvents)
+	conversations.Put("/:conversationID/disappearing-timer", ch.HandleSetDisappearingTimer)
+	conversations.Post("/:conversationID/typing", ph.HandleSetTyping)
+	conversations.Post("/:conversationID/messages", kh.WithDevice, rl.WithRateLimit(ratelimit.SendPolicyByCredentials, handler.ByCredentialsID), mh.HandleSendMessage)
+	conversations.Get("/:conversationID/messages", kh.WithDevice, mh.HandleSyncMessages)
+	conversations.Get("/:conversationID/messages/:messageID/status", mh.HandleGetMessageStatus)
+}
+
+func (me *App) loadTransparencyRoutes(server *fiber.App) {
+	th := handler.NewTransparencyHandler(me.transparencyService)
+
+	// everything but the monitor is public so anyone can audit the log.
+	server.Get("/kt/public-key", th.HandleGetPublicKey)
+	server.Get("/kt/tree-head", th.HandleGetTreeHead)
+	server.Get("/kt/entries/:leafIndex/inclusion-proof", th.HandleGetInclusionProof)
+	server.Get("/kt/consistency-proof", th.HandleGetConsistencyProof)
+	server.Get("/kt/monitor", append(me.authenticated(), th.HandleGetMonitorReport)...)
+}
+
+func (me *App) loadMessageRoutes(server *fiber.App) {
+	mh := handler.NewMessageHandler(me.messageService)
+
+	mailbox := server.Group("/mailbox", me.withDevice()...)
+	mailbox.Get("/", mh.HandleListMailbox)
+	mailbox.Delete("/:envelopeID", mh.HandleAckEnvelope)
+
+	server.Get("/certificates/public-key", mh.HandleGetSenderCertificatePublicKey)
+	server.Get("/certificates/sender", append(me.withDevice(), mh.HandleGetSenderCertificate)...)
+
+	// sealed sender delivery carries no session, the delivery access key is
+	// checked instead, and isn't logged with the client IP.
+	server.Post("/sealed/users/:username/messages", handler.WithAnonymousLogging, mh.HandleSendSealedMessage)
+}
+
+func (me *App) loadRealtimeRoutes(server *fiber.App) {
+	rh := handler.NewRealtimeHandler(me.dispatcher, me.presenceService)
+
+	server.Get("/ws", append(me.withDevice(), rh.WithUpgrade, rh.HandleWebSocket())...)
+	server.Get("/events", append(me.withDevice(), rh.HandleEventStream)...)
+	server.Get("/events/poll", append(me.withDevice(), rh.HandlePollEvents)...)
+}
+
+func (me *App) loadAttachmentRoutes(server *fiber.App) {
+	ath := handler.NewAttachmentHandler(me.attachmentService)
+	rl := handler.NewRateLimitHandler(me.limiter)
+
+	attachments := server.Group("/attachments", me.authenticated()...)
+	attachments.Post("/", rl.WithRateLimit(ratelimit.UploadPolicyByCredentials, handler.ByCredentialsID), ath.HandleUploadAttachment)
+	attachments.Get("/:attachmentID", ath.HandleDownloadAttachment)
+	attachments.Delete("/:attachmentID", ath.HandleDeleteAttachment)
+}
+
+func (me *App) loadUploadRoutes(server *fiber.App) {
+	uph := handler.NewUploadHandler(me.attachmentService)
+	rl := handler.NewRateLimitHandler(me.limiter)
+
+	server.Options("/uploads", uph.WithTusResumable, uph.HandleOptions)
+
+	uploads := server.Group("/uploads", append(me.authenticated(), uph.WithTusResumable)...)
+	uploads.Post("/", rl.WithRateLimit(ratelimit.UploadPolicyByCredentials, handler.ByCredentialsID), uph.HandleCreateUpload)
+	uploads.Head("/:uploadID", uph.HandleGetUploadOffset)
+	uploads.Patch("/:uploadID", uph.HandleAppendUpload)
+	uploads.Delete("/:uploadID", uph.HandleTerminateUpload)
+}
+
+func (me *App) loadPushRoutes(server *fiber.App) {
+	ph := handler.NewPushHandler(me.pushService)
+
+	server.Get("/push/vapid-public-key", ph.HandleGetVAPIDPublicKey)
+	server.Put("/push/subscription", append(me.withDevice(), ph.HandleSubscribe)...)
+	server.Delete("/push/subscription", append(me.withDevice(), ph.HandleUnsubscribe)...)
+}
+
+func (me *App) loadTransferRoutes(server *fiber.App) {
+	trh := handler.NewTransferHandler(me.transferService)
+
+	transfers := server.Group("/transfers", me.withDevice()...)
+	transfers.Post("/", trh.HandleCreateTransfer)
+	transfers.Get("/", trh.HandleListTransfers)
+	transfers.Get("/:transferID", trh.HandleGetTransfer)
+	transfers.Delete("/:transferID", trh.HandleCancelTransfer)
+	transfers.Post("/:transferID/complete", trh.HandleCompleteTransfer)
+	transfers.Put("/:transferID/chunks/:index", trh.HandleUploadChunk)
+	transfers.Get("/:transferID/chunks/:index", trh.HandleDownloadChunk)
+}
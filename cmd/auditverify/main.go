@@ -0,0 +1,103 @@
+// Command auditverify walks the audit_events hash chain and reports the first
+// row that was edited, inserted or deleted out of band.
+//
+//	auditverify [-db PG_URL] [-anchor HASH]
+//
+// Deleting the newest rows leaves a valid, shorter chain. Keep the head hash
+// printed by a previous run and pass it as -anchor to detect that too.
+package main
+
+import (
+	"bytes"
+	"chatapp/repo"
+	"chatapp/service/audit/chain"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	_ "github.com/lib/pq"
+)
+
+const pageSize = 1000
+
+func main() {
+	dbURL := flag.String("db", os.Getenv("PG_URL"), "postgres connection url")
+	anchorFlag := flag.String("anchor", "", "hex encoded hash of an earlier head, which must still be in the chain")
+	flag.Parse()
+
+	if err := verify(*dbURL, *anchorFlag); err != nil {
+		fmt.Fprintln(os.Stderr, "FAIL:", err)
+		os.Exit(1)
+	}
+}
+
+func verify(dbURL, anchorHex string) error {
+	anchor, err := hex.DecodeString(anchorHex)
+	if err != nil {
+		return fmt.Errorf("invalid anchor: %w", err)
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+	queries := repo.New(db)
+
+	var (
+		ctx         = context.Background()
+		prevHash    = chain.Genesis
+		lastID      int64
+		count       int
+		anchorFound = len(anchor) == 0
+	)
+	for {
+		events, err := queries.ListAuditEventsAfterID(ctx, repo.ListAuditEventsAfterIDParams{
+			ID:    lastID,
+			Limit: pageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list audit events: %w", err)
+		}
+
+		for _, event := range events {
+			if !bytes.Equal(event.PrevHash, prevHash) {
+				return fmt.Errorf("event %d: previous hash mismatch, an event before it was changed or deleted", event.ID)
+			}
+			hash := chain.Hash(prevHash, chain.Event{
+				ID:             event.ID,
+				CredentialsID:  event.CredentialsID,
+				Type:           event.Type,
+				IP:             event.Ip,
+				UserAgent:      event.UserAgent,
+				Details:        event.Details,
+				CreatedAtMicro: event.CreatedAt.UnixMicro(),
+			})
+			if !bytes.Equal(event.Hash, hash) {
+				return fmt.Errorf("event %d: hash mismatch, the event was changed", event.ID)
+			}
+			if !anchorFound && bytes.Equal(hash, anchor) {
+				anchorFound = true
+			}
+
+			prevHash = hash
+			lastID = event.ID
+			count++
+		}
+
+		if len(events) < pageSize {
+			break
+		}
+	}
+
+	if !anchorFound {
+		return errors.New("anchor not found, the chain was truncated or rewritten")
+	}
+
+	fmt.Printf("OK %d events, head %d %x\n", count, lastID, prevHash)
+	return nil
+}
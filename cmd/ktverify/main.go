@@ -0,0 +1,217 @@
+// Command ktverify checks key transparency proofs offline. Every input is a
+// JSON document exactly as returned by the server's /kt endpoints, and the
+// server's public key as returned by /kt/public-key.
+//
+//	ktverify tree-head   -public-key KEY TREE_HEAD.json
+//	ktverify inclusion   -public-key KEY -tree-head TREE_HEAD.json PROOF.json
+//	ktverify consistency -public-key KEY -first OLD_HEAD.json -second NEW_HEAD.json PROOF.json
+//	ktverify monitor     -public-key KEY REPORT.json
+package main
+
+import (
+	"chatapp/service/transparency/kt"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "tree-head":
+		err = verifyTreeHead(os.Args[2:])
+	case "inclusion":
+		err = verifyInclusion(os.Args[2:])
+	case "consistency":
+		err = verifyConsistency(os.Args[2:])
+	case "monitor":
+		err = verifyMonitor(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "FAIL:", err)
+		os.Exit(1)
+	}
+	fmt.Println("OK")
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ktverify <tree-head|inclusion|consistency|monitor> [flags] FILE")
+	os.Exit(2)
+}
+
+func verifyTreeHead(args []string) error {
+	flags := flag.NewFlagSet("tree-head", flag.ExitOnError)
+	publicKeyFlag := flags.String("public-key", "", "base64 encoded log public key")
+	flags.Parse(args)
+
+	publicKey, err := parsePublicKey(*publicKeyFlag)
+	if err != nil {
+		return err
+	}
+
+	var head kt.SignedTreeHead
+	if err := readJSON(flags.Arg(0), &head); err != nil {
+		return err
+	}
+	if err := head.Verify(publicKey); err != nil {
+		return err
+	}
+
+	printTreeHead(head)
+	return nil
+}
+
+func verifyInclusion(args []string) error {
+	flags := flag.NewFlagSet("inclusion", flag.ExitOnError)
+	publicKeyFlag := flags.String("public-key", "", "base64 encoded log public key")
+	treeHeadFlag := flags.String("tree-head", "", "signed tree head file")
+	flags.Parse(args)
+
+	publicKey, err := parsePublicKey(*publicKeyFlag)
+	if err != nil {
+		return err
+	}
+
+	var head kt.SignedTreeHead
+	if err := readJSON(*treeHeadFlag, &head); err != nil {
+		return err
+	}
+	if err := head.Verify(publicKey); err != nil {
+		return err
+	}
+
+	var proof kt.InclusionProof
+	if err := readJSON(flags.Arg(0), &proof); err != nil {
+		return err
+	}
+	if err := proof.Verify(head.TreeHead); err != nil {
+		return err
+	}
+
+	printTreeHead(head)
+	printBinding(proof)
+	return nil
+}
+
+func verifyConsistency(args []string) error {
+	flags := flag.NewFlagSet("consistency", flag.ExitOnError)
+	publicKeyFlag := flags.String("public-key", "", "base64 encoded log public key")
+	firstFlag := flags.String("first", "", "older signed tree head file")
+	secondFlag := flags.String("second", "", "newer signed tree head file")
+	flags.Parse(args)
+
+	publicKey, err := parsePublicKey(*publicKeyFlag)
+	if err != nil {
+		return err
+	}
+
+	var first, second kt.SignedTreeHead
+	if err := readJSON(*firstFlag, &first); err != nil {
+		return err
+	}
+	if err := readJSON(*secondFlag, &second); err != nil {
+		return err
+	}
+	if err := first.Verify(publicKey); err != nil {
+		return fmt.Errorf("first tree head: %w", err)
+	}
+	if err := second.Verify(publicKey); err != nil {
+		return fmt.Errorf("second tree head: %w", err)
+	}
+
+	var proof kt.ConsistencyProof
+	if err := readJSON(flags.Arg(0), &proof); err != nil {
+		return err
+	}
+	if err := proof.Verify(first.TreeHead, second.TreeHead); err != nil {
+		return err
+	}
+
+	printTreeHead(first)
+	printTreeHead(second)
+	return nil
+}
+
+func verifyMonitor(args []string) error {
+	flags := flag.NewFlagSet("monitor", flag.ExitOnError)
+	publicKeyFlag := flags.String("public-key", "", "base64 encoded log public key")
+	flags.Parse(args)
+
+	publicKey, err := parsePublicKey(*publicKeyFlag)
+	if err != nil {
+		return err
+	}
+
+	var report kt.MonitorReport
+	if err := readJSON(flags.Arg(0), &report); err != nil {
+		return err
+	}
+	if err := report.Verify(publicKey); err != nil {
+		return err
+	}
+
+	printTreeHead(report.TreeHead)
+	fmt.Printf("%d keys published for %q:\n", len(report.Entries), report.Username)
+	for _, entry := range report.Entries {
+		printBinding(entry)
+	}
+	return nil
+}
+
+func parsePublicKey(value string) (ed25519.PublicKey, error) {
+	if value == "" {
+		return nil, errors.New("-public-key is required")
+	}
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+func readJSON(path string, v any) error {
+	if path == "" {
+		return errors.New("missing input file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func printTreeHead(head kt.SignedTreeHead) {
+	fmt.Printf("tree head: size=%d root=%s time=%s\n",
+		head.TreeSize,
+		hex.EncodeToString(head.RootHash),
+		time.UnixMilli(head.Timestamp).UTC().Format(time.RFC3339),
+	)
+}
+
+func printBinding(proof kt.InclusionProof) {
+	fmt.Printf("  leaf %d: username=%s device=%s key=%s\n",
+		proof.LeafIndex,
+		proof.Binding.Username,
+		proof.Binding.DeviceID,
+		base64.StdEncoding.EncodeToString(proof.Binding.IdentityKey),
+	)
+}
@@ -0,0 +1,27 @@
+// Command vapidkeygen generates a VAPID key pair for Web Push. The private key
+// goes in WEB_PUSH_VAPID_PRIVATE_KEY, the public key is what browsers subscribe
+// with, and is also served by GET /push/vapid-public-key.
+package main
+
+import (
+	"chatapp/service/push/webpush"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+func main() {
+	privateKey, err := webpush.GenerateVAPIDKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate vapid key: %v\n", err)
+		os.Exit(1)
+	}
+	key, err := webpush.NewVAPIDKey(privateKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load vapid key: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("WEB_PUSH_VAPID_PRIVATE_KEY=%s\n", base64.StdEncoding.EncodeToString(privateKey))
+	fmt.Printf("public key: %s\n", base64.RawURLEncoding.EncodeToString(key.PublicKey()))
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strconv"
@@ -13,11 +14,132 @@ var (
 	ListenAddr                              = getEnvString("LISTEN_ADDR")
 	AppBaseUrl                              = getEnvString("APP_BASE_URL")
 	PGUrl                                   = getEnvString("PG_URL")
+	ValkeyURL                               = getEnvString("VALKEY_URL", "") // optional, e.g. redis://localhost:6379/0
 	EmailFrom                               = getEnvString("EMAIL_FROM")
 	PapercutSmtpHost                        = getEnvString("PAPERCUT_SMTP_HOST")
 	EmailVerificationTokenExpiration        = time.Hour * 24
 	EmailVerificationTokenCleanupWorkerTick = time.Hour
+	PasswordResetTokenExpiration            = time.Hour
+	LoginLinkExpiration                     = time.Minute * 15
+	LoginAlertExpiration                    = time.Hour * 24 * 7
+	AccountDeletionGracePeriod              = time.Hour * 24 * time.Duration(getEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 14))
+	LoginHistorySize                        = 20
+	LoginUsualTimeTolerance                 = 3.0 // hours around previous logins' time of day
+	LoginUsualTimeMinHistory                = 5
+	ImpossibleTravelSpeedKmh                = 1000.0
+	ImpossibleTravelMinDistanceKm           = 500.0
+	GeoIPDatabasePath                       = getEnvString("GEOIP_DATABASE_PATH", "") // optional, see service/geoip
+	TOTPIssuer                              = getEnvString("TOTP_ISSUER", "Chat App")
+	TOTPSkew                                = 1 // time steps accepted either side of the current one
+	MFAChallengeExpiration                  = time.Minute * 5
+	MFAMaxFailures                          = getEnvInt("MFA_MAX_FAILURES", 5) // wrong codes before a lockout
+	MFAFailureWindow                        = time.Minute * 15
+	RecoveryCodeCount                       = 10
+	WebAuthnRPID                            = getEnvString("WEBAUTHN_RP_ID", "localhost") // the app's domain
+	WebAuthnRPOrigin                        = getEnvString("WEBAUTHN_RP_ORIGIN", AppBaseUrl)
+	WebAuthnRPDisplayName                   = getEnvString("WEBAUTHN_RP_DISPLAY_NAME", "Chat App")
+	WebAuthnCeremonyExpiration              = time.Minute * 5
+	MaxPasskeys                             = 10
+	OpaqueServerSecret                      = getEnvBase64("OPAQUE_SERVER_SECRET") // 32+ random bytes, changing it invalidates every OPAQUE account
+	OpaqueContext                           = getEnvString("OPAQUE_CONTEXT", "chatapp-opaque-v1")
+	OpaqueRegistrationExpiration            = time.Minute * 5
+	OpaqueLoginExpiration                   = time.Minute * 2
 	SessionExpiration                       time.Duration
+	SessionCacheTTL                         = time.Minute * 5
+	KeyTransparencySigningKey               = getEnvBase64("KT_SIGNING_KEY")          // ed25519 seed
+	SenderCertificateSigningKey             = getEnvBase64("SENDER_CERT_SIGNING_KEY") // ed25519 seed
+	SenderCertificateExpiration             = time.Hour * 24
+	KeyBackupVerifierKey                    = getEnvBase64("KEY_BACKUP_VERIFIER_KEY") // hmac key, verifiers don't help offline guessing without it
+	KeyBackupMaxGuesses                     = getEnvInt("KEY_BACKUP_MAX_GUESSES", 10)
+	MaxKeyBackupSize                        = 64 * 1024
+	MaxEnvelopeContentSize                  = 256 * 1024
+	MailboxPageSize                         = 100
+	MessageSyncPageSize                     = 100
+	MaxUndeliveredEnvelopeRetention         = time.Hour * 24 * time.Duration(getEnvInt("MAX_UNDELIVERED_ENVELOPE_RETENTION_DAYS", 30))
+	MaxDisappearingTimer                    = time.Hour * 24 * 28
+	MessagePurgeWorkerTick                  = time.Minute
+	MessagePurgeBatchSize                   = 1000
+	MessageEditWindow                       = time.Minute * time.Duration(getEnvInt("MESSAGE_EDIT_WINDOW_MINUTES", 60*24))
+	BlobStore                               = getEnvString("BLOB_STORE", "filesystem") // filesystem or s3
+	BlobStoreDir                            = getEnvString("BLOB_STORE_DIR", "data/blobs")
+	S3Endpoint                              = getEnvString("S3_ENDPOINT", "")
+	S3Region                                = getEnvString("S3_REGION", "us-east-1")
+	S3Bucket                                = getEnvString("S3_BUCKET", "")
+	S3AccessKeyID                           = getEnvString("S3_ACCESS_KEY_ID", "")
+	S3SecretAccessKey                       = getEnvString("S3_SECRET_ACCESS_KEY", "")
+	MaxAttachmentSize                       = int64(100 * 1024 * 1024)
+	AttachmentQuota                         = int64(getEnvInt("ATTACHMENT_QUOTA_MB", 1024)) * 1024 * 1024
+	AttachmentTTL                           = time.Hour * 24
+	MaxMessageAttachments                   = 32
+	ResumableUploadDir                      = getEnvString("RESUMABLE_UPLOAD_DIR", "data/uploads")
+	ResumableUploadExpiration               = time.Hour * 24
+	HistoryTransferExpiration               = time.Hour * 24
+	MaxHistoryTransferSize                  = int64(getEnvInt("MAX_HISTORY_TRANSFER_SIZE_MB", 2048)) * 1024 * 1024
+	MaxHistoryTransferChunkSize             = int64(16 * 1024 * 1024)
+	MaxOpenHistoryTransfers                 = getEnvInt("MAX_OPEN_HISTORY_TRANSFERS", 3)
+	WebPushVAPIDPrivateKey                  = getEnvBase64("WEB_PUSH_VAPID_PRIVATE_KEY") // raw P-256 private key
+	WebPushSubject                          = getEnvString("WEB_PUSH_SUBJECT")           // mailto: or https: contact of the operator
+	WebPushVAPIDExpiration                  = time.Hour * 12
+	WebPushTTL                              = time.Hour * 24
+	PushRequestTimeout                      = time.Second * 10
+	PushAllowPrivateEndpoints               = getEnvInt("PUSH_ALLOW_PRIVATE_ENDPOINTS", 0) == 1 // for local push services only
+	PushCoalesceWindow                      = time.Second
+	PushMinInterval                         = time.Second * 10
+	PushMaxAttempts                         = 5
+	PushRetryBackoff                        = time.Second * 2
+	PushMaxBackoff                          = time.Minute * 5
+	PresenceThrottleInterval                = time.Second * 10
+	PresenceThrottleBurst                   = 3
+	TypingThrottleInterval                  = time.Second * 2
+	TypingThrottleBurst                     = 3
+	TypingIndicatorTimeout                  = time.Second * 6
+	RealtimeHistorySize                     = 256
+	RealtimeHistoryTTL                      = time.Minute * 5
+	PresenceOfflineGrace                    = time.Second * 5
+	LongPollTimeout                         = time.Second * 25
+	SSEHeartbeatInterval                    = time.Second * 15
+	ClusterBus                              = getEnvString("CLUSTER_BUS", "local") // local, postgres or valkey
+	ClusterHeartbeatInterval                = time.Second * 10
+	ClusterBusPollInterval                  = time.Second * 30
+	ClusterBusBatchSize                     = 100
+	ClusterEventRetention                   = time.Minute
+	RateLimitStore                          = getEnvString("RATE_LIMIT_STORE", "memory") // memory, postgres or valkey
+	RateLimitCleanupWorkerTick              = time.Minute * 10
+	LoginRateLimitPerIP                     = getEnvInt("LOGIN_RATE_LIMIT_PER_IP", 30)
+	LoginRateLimitPerEmail                  = getEnvInt("LOGIN_RATE_LIMIT_PER_EMAIL", 10)
+	LoginRateLimitWindow                    = time.Minute * 15
+	RegisterRateLimitPerIP                  = getEnvInt("REGISTER_RATE_LIMIT_PER_IP", 5)
+	RegisterRateLimitWindow                 = time.Hour
+	VerifyEmailRateLimitPerIP               = getEnvInt("VERIFY_EMAIL_RATE_LIMIT_PER_IP", 20)
+	VerifyEmailRateLimitWindow              = time.Hour
+	PasswordResetRateLimitPerIP             = getEnvInt("PASSWORD_RESET_RATE_LIMIT_PER_IP", 10)
+	PasswordResetRateLimitPerEmail          = getEnvInt("PASSWORD_RESET_RATE_LIMIT_PER_EMAIL", 3)
+	PasswordResetRateLimitWindow            = time.Hour
+	LoginLinkRateLimitPerIP                 = getEnvInt("LOGIN_LINK_RATE_LIMIT_PER_IP", 10)
+	LoginLinkRateLimitPerEmail              = getEnvInt("LOGIN_LINK_RATE_LIMIT_PER_EMAIL", 5)
+	LoginLinkRateLimitWindow                = time.Hour
+	MFARateLimitPerIP                       = getEnvInt("MFA_RATE_LIMIT_PER_IP", 30)
+	MFARateLimitWindow                      = time.Minute * 15
+	SendRateLimitPerAccount                 = getEnvInt("SEND_RATE_LIMIT_PER_ACCOUNT", 600)
+	SendRateLimitWindow                     = time.Minute
+	UploadRateLimitPerAccount               = getEnvInt("UPLOAD_RATE_LIMIT_PER_ACCOUNT", 60)
+	UploadRateLimitWindow                   = time.Hour
+	OpaqueUpgradeRateLimitPerAccount        = getEnvInt("OPAQUE_UPGRADE_RATE_LIMIT_PER_ACCOUNT", 5)
+	OpaqueUpgradeRateLimitWindow            = time.Hour
+	LoginFailureDelayAfter                  = getEnvInt("LOGIN_FAILURE_DELAY_AFTER", 3) // failed logins before delays start
+	LoginFailureDelay                       = time.Second                               // doubles with every further failure
+	LoginFailureMaxDelay                    = time.Second * 10
+	LoginLockoutThreshold                   = getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 10) // failed logins before a lockout
+	LoginFailureWindow                      = time.Hour
+	AuditEventsPageSize                     = 100
+	AuditMaxUserAgentLength                 = 512
+	LoginLockoutDuration                    = time.Minute * time.Duration(getEnvInt("LOGIN_LOCKOUT_MINUTES", 15))
+	AttachmentGCWorkerTick                  = time.Hour
+	AttachmentGCBatchSize                   = 100
+	HistoryTransferGCWorkerTick             = time.Minute * 10
+	HistoryTransferGCBatchSize              = 100
+	AccountDeletionWorkerTick               = time.Minute * 10
+	AccountDeletionBatchSize                = 10
 )
 
 func getEnvString(key string, defaultValue ...string) string {
@@ -43,3 +165,17 @@ func getEnvInt(key string, defaultValue ...int) int {
 	}
 	return defaultValue[0]
 }
+
+func getEnvBase64(key string, defaultValue ...[]byte) []byte {
+	if value, ok := os.LookupEnv(key); ok {
+		bytesValue, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			panic(fmt.Sprintf("invalid base64 value for env var: %s", key))
+		}
+		return bytesValue
+	}
+	if len(defaultValue) == 0 {
+		panic(fmt.Sprintf("env var: %s not found", key))
+	}
+	return defaultValue[0]
+}
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+create table devices (
+    id uuid default gen_random_uuid(),
+    user_id uuid not null,
+    name varchar(50) not null,
+    identity_key bytea not null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (user_id) references users (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table devices;
+-- +goose StatementEnd
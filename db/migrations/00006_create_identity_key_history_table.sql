@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+create table identity_key_history (
+    id bigserial,
+    device_id uuid not null,
+    identity_key bytea not null,
+    created_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (device_id) references devices (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table identity_key_history;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+create table conversations (
+    id uuid default gen_random_uuid(),
+    created_at timestamptz not null default now(),
+
+    primary key (id)
+);
+
+create table conversation_participants (
+    conversation_id uuid not null,
+    user_id uuid not null,
+    created_at timestamptz not null default now(),
+
+    primary key (conversation_id, user_id),
+    foreign key (conversation_id) references conversations (id) on delete cascade,
+    foreign key (user_id) references users (id) on delete cascade
+);
+
+create index conversation_participants_user_id_idx on conversation_participants (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table conversation_participants;
+drop table conversations;
+-- +goose StatementEnd
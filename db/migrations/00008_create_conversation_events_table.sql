@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+create table conversation_events (
+    id uuid default gen_random_uuid(),
+    conversation_id uuid not null,
+    type varchar(50) not null,
+    -- the user the event is about (e.g. the user whose identity key changed).
+    subject_user_id uuid not null,
+    subject_device_id uuid,
+    -- when set, only this participant can see the event.
+    audience_user_id uuid,
+    created_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (conversation_id) references conversations (id) on delete cascade,
+    foreign key (subject_user_id) references users (id) on delete cascade,
+    foreign key (subject_device_id) references devices (id) on delete set null,
+    foreign key (audience_user_id) references users (id) on delete cascade
+);
+
+create index conversation_events_conversation_id_idx on conversation_events (conversation_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table conversation_events;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+create table contact_verifications (
+    user_id uuid not null,
+    contact_user_id uuid not null,
+    verified_at timestamptz not null default now(),
+    -- set when one of the contact's identity keys changes after verification.
+    key_changed_at timestamptz,
+
+    primary key (user_id, contact_user_id),
+    foreign key (user_id) references users (id) on delete cascade,
+    foreign key (contact_user_id) references users (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table contact_verifications;
+-- +goose StatementEnd
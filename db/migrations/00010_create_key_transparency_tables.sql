@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+-- single row holding the size of the log, used to hand out dense leaf indexes.
+create table kt_log (
+    id int,
+    tree_size bigint not null,
+
+    primary key (id),
+    check (id = 1)
+);
+
+insert into kt_log (id, tree_size) values (1, 0);
+
+-- append-only: rows are never updated or deleted, and have no foreign keys so
+-- the log outlives the devices and users it describes.
+create table kt_entries (
+    leaf_index bigint,
+    username varchar(50) not null,
+    device_id uuid not null,
+    identity_key bytea not null,
+    leaf_hash bytea not null,
+    created_at timestamptz not null default now(),
+
+    primary key (leaf_index)
+);
+
+create index kt_entries_username_idx on kt_entries (username);
+
+create table kt_tree_heads (
+    tree_size bigint,
+    root_hash bytea not null,
+    timestamp bigint not null,
+    signature bytea not null,
+    created_at timestamptz not null default now(),
+
+    primary key (tree_size)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table kt_tree_heads;
+drop table kt_entries;
+drop table kt_log;
+-- +goose StatementEnd
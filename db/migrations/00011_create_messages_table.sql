@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+create table messages (
+    id uuid default gen_random_uuid(),
+    conversation_id uuid not null,
+    sender_user_id uuid not null,
+    sender_device_id uuid,
+    created_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (conversation_id) references conversations (id) on delete cascade,
+    foreign key (sender_user_id) references users (id) on delete cascade,
+    foreign key (sender_device_id) references devices (id) on delete set null
+);
+
+create index messages_conversation_id_idx on messages (conversation_id);
+
+-- an envelope is a message encrypted for a single recipient device, waiting in
+-- that device's mailbox.
+create table envelopes (
+    id uuid default gen_random_uuid(),
+    -- null for sealed sender envelopes, where the server doesn't know who
+    -- sent the message or which conversation it belongs to.
+    message_id uuid,
+    recipient_device_id uuid not null,
+    content bytea not null,
+    created_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (message_id) references messages (id) on delete cascade,
+    foreign key (recipient_device_id) references devices (id) on delete cascade
+);
+
+create index envelopes_recipient_device_id_idx on envelopes (recipient_device_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table envelopes;
+drop table messages;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- key senders must present to deliver sealed sender messages to the user.
+-- sealed sender delivery is disabled while it's null.
+alter table users add column delivery_access_key bytea;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table users drop column delivery_access_key;
+-- +goose StatementEnd
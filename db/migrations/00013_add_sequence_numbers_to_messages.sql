@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table conversations add column last_seq bigint not null default 0;
+
+alter table messages add column seq bigint;
+alter table messages add column client_message_id uuid;
+
+update messages m set seq = numbered.seq, client_message_id = m.id
+from (
+    select id, row_number() over (partition by conversation_id order by created_at, id) as seq
+    from messages
+) numbered
+where numbered.id = m.id;
+
+update conversations c set last_seq = coalesce((select max(seq) from messages where conversation_id = c.id), 0);
+
+alter table messages alter column seq set not null;
+alter table messages alter column client_message_id set not null;
+alter table messages add constraint messages_conversation_id_seq_key unique (conversation_id, seq);
+alter table messages add constraint messages_sender_user_id_client_message_id_key unique (sender_user_id, client_message_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table messages drop column client_message_id;
+alter table messages drop column seq;
+alter table conversations drop column last_seq;
+-- +goose StatementEnd
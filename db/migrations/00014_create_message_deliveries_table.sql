@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- control messages (e.g. read receipts) are stored like regular messages, the
+-- kind and the referenced message are the only cleartext metadata about them.
+alter table messages add column kind varchar(20) not null default 'message';
+alter table messages add column reference_message_id uuid;
+alter table messages add foreign key (reference_message_id) references messages (id) on delete set null;
+
+alter table users add column read_receipts_enabled bool not null default true;
+
+-- delivery state of a message for each of its recipient devices, kept after the
+-- envelope has left the mailbox.
+create table message_deliveries (
+    message_id uuid not null,
+    recipient_device_id uuid not null,
+    recipient_user_id uuid not null,
+    delivered_at timestamptz,
+    read_at timestamptz,
+
+    primary key (message_id, recipient_device_id),
+    foreign key (message_id) references messages (id) on delete cascade,
+    foreign key (recipient_device_id) references devices (id) on delete cascade,
+    foreign key (recipient_user_id) references users (id) on delete cascade
+);
+
+create index message_deliveries_recipient_user_id_idx on message_deliveries (recipient_user_id) where read_at is null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table message_deliveries;
+alter table users drop column read_receipts_enabled;
+alter table messages drop column reference_message_id;
+alter table messages drop column kind;
+-- +goose StatementEnd
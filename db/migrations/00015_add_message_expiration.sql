@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- disappearing message timer in seconds, 0 when disabled.
+alter table conversations add column disappearing_timer int not null default 0;
+-- the new timer, for disappearing_timer_changed events.
+alter table conversation_events add column disappearing_timer int;
+
+alter table messages add column expires_at timestamptz;
+alter table envelopes add column expires_at timestamptz;
+
+create index messages_expires_at_idx on messages (expires_at) where expires_at is not null;
+create index envelopes_expires_at_idx on envelopes (expires_at) where expires_at is not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index envelopes_expires_at_idx;
+drop index messages_expires_at_idx;
+alter table envelopes drop column expires_at;
+alter table messages drop column expires_at;
+alter table conversation_events drop column disappearing_timer;
+alter table conversations drop column disappearing_timer;
+-- +goose StatementEnd
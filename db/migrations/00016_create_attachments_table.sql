@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- client-encrypted blobs, the server only knows their size and owner.
+create table attachments (
+    id uuid not null,
+    owner_user_id uuid not null,
+    size bigint not null,
+    -- null while the blob is being written to the blob store.
+    uploaded_at timestamptz,
+    created_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (owner_user_id) references users (id) on delete cascade
+);
+
+create index attachments_owner_user_id_idx on attachments (owner_user_id);
+create index attachments_created_at_idx on attachments (created_at);
+
+create table message_attachments (
+    message_id uuid not null,
+    attachment_id uuid not null,
+
+    primary key (message_id, attachment_id),
+    foreign key (message_id) references messages (id) on delete cascade,
+    foreign key (attachment_id) references attachments (id) on delete cascade
+);
+
+create index message_attachments_attachment_id_idx on message_attachments (attachment_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table message_attachments;
+drop table attachments;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- bytes received so far by a resumable (tus) upload, its partial data is kept in
+-- a staging file until it's complete.
+alter table attachments add column upload_offset bigint not null default 0;
+-- only set for resumable uploads.
+alter table attachments add column upload_expires_at timestamptz;
+
+update attachments set upload_offset = size where uploaded_at is not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table attachments drop column upload_expires_at;
+alter table attachments drop column upload_offset;
+-- +goose StatementEnd
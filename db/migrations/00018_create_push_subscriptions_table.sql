@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Web Push subscription of a device, as created by the browser's PushManager.
+create table push_subscriptions (
+    device_id uuid not null,
+    endpoint text not null,
+    -- the subscription's P-256 public key and auth secret (RFC 8291).
+    p256dh bytea not null,
+    auth bytea not null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+
+    primary key (device_id),
+    foreign key (device_id) references devices (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table push_subscriptions;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table push_subscriptions add column provider varchar(20) not null default 'webpush';
+-- only web push subscriptions have keys.
+alter table push_subscriptions alter column p256dh drop not null;
+alter table push_subscriptions alter column auth drop not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from push_subscriptions where provider <> 'webpush';
+alter table push_subscriptions alter column auth set not null;
+alter table push_subscriptions alter column p256dh set not null;
+alter table push_subscriptions drop column provider;
+-- +goose StatementEnd
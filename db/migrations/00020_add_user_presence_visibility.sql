@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- who can see when the user is online and last seen: everyone, contacts or
+-- nobody. Presence itself is never stored.
+alter table users add column presence_visibility varchar(10) not null default 'nobody';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table users drop column presence_visibility;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Events published on the cluster bus, instances are notified of new ones
+-- through LISTEN/NOTIFY and read them from here, since notification payloads
+-- are limited to 8000 bytes. Rows are only kept for a short while.
+create table cluster_events (
+    id bigserial not null,
+    payload bytea not null,
+    created_at timestamptz not null default now(),
+
+    primary key (id)
+);
+
+create index cluster_events_created_at_idx on cluster_events (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table cluster_events;
+-- +goose StatementEnd
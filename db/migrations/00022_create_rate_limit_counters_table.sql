@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Fixed window hit counters of the postgres rate limit store.
+create table rate_limit_counters (
+    key varchar not null,
+    hits bigint not null,
+    reset_at timestamptz not null,
+
+    primary key (key)
+);
+
+create index rate_limit_counters_reset_at_idx on rate_limit_counters (reset_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table rate_limit_counters;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Security relevant events. Every row is chained to the previous one through
+-- its hash (see service/audit/chain), so edits and deletions can be detected
+-- by cmd/auditverify.
+create table audit_events (
+    id bigserial not null,
+    -- null for events not tied to known credentials, e.g. a failed login
+    -- with an unknown email.
+    credentials_id uuid,
+    type varchar(50) not null,
+    ip varchar(45) not null,
+    user_agent text not null,
+    -- the JSON text exactly as hashed, jsonb would normalize it.
+    details text not null,
+    created_at timestamptz not null,
+    prev_hash bytea not null,
+    hash bytea not null,
+
+    primary key (id)
+);
+
+create index audit_events_credentials_id_idx on audit_events (credentials_id, id);
+
+create function audit_events_append_only() returns trigger as $$
+begin
+    raise exception 'audit_events is append-only';
+end;
+$$ language plpgsql;
+
+create trigger audit_events_append_only
+before update or delete on audit_events
+for each row execute function audit_events_append_only();
+
+create trigger audit_events_no_truncate
+before truncate on audit_events
+for each statement execute function audit_events_append_only();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table audit_events;
+drop function audit_events_append_only;
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- set when a login was reported as not made by the owner, logging in is
+-- refused until the password is reset.
+alter table credentials add column password_reset_required bool not null default false;
+
+-- Fingerprints of past logins, to tell whether a new one looks like the
+-- owner's. Only the latest few are kept per credentials.
+create table login_history (
+    id uuid default gen_random_uuid(),
+    credentials_id uuid not null,
+    session_id uuid not null,
+    -- the /24 (IPv4) or /48 (IPv6) network the login came from.
+    ip_prefix varchar(50) not null,
+    user_agent_family varchar(100) not null,
+    -- from the local IP-to-region database, null when unknown.
+    region varchar(100),
+    latitude double precision,
+    longitude double precision,
+    created_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+
+create index login_history_credentials_id_idx on login_history (credentials_id, created_at);
+
+-- "this wasn't me" links emailed on suspicious logins, the id is the token.
+create table login_alerts (
+    id uuid,
+    credentials_id uuid not null,
+    session_id uuid not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (id),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+
+create table password_reset_tokens (
+    id uuid,
+    credentials_id uuid not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (id),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table password_reset_tokens;
+drop table login_alerts;
+drop table login_history;
+alter table credentials drop column password_reset_required;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+-- totp_secret is set on enrollment and only used once totp_enabled is set by
+-- confirming a code. totp_last_counter is the time step of the last accepted
+-- code, so a code can't be used twice.
+alter table credentials add column totp_secret bytea;
+alter table credentials add column totp_enabled bool not null default false;
+alter table credentials add column totp_last_counter bigint not null default 0;
+
+-- single-use codes for when the authenticator is lost, only their sha256 is
+-- kept.
+create table recovery_codes (
+    credentials_id uuid not null,
+    code_hash bytea not null,
+    created_at timestamptz not null default now(),
+
+    primary key (credentials_id, code_hash),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+
+-- logins waiting for their second factor, the id is handed to the client
+-- instead of a session.
+create table mfa_challenges (
+    id uuid,
+    credentials_id uuid not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (id),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table mfa_challenges;
+drop table recovery_codes;
+alter table credentials drop column totp_last_counter;
+alter table credentials drop column totp_enabled;
+alter table credentials drop column totp_secret;
+-- +goose StatementEnd
@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+-- WebAuthn credentials, the id is the credential ID chosen by the
+-- authenticator.
+create table passkeys (
+    id bytea,
+    credentials_id uuid not null,
+    name varchar(100) not null,
+    public_key bytea not null,
+    attestation_type varchar(50) not null,
+    transports text[] not null default '{}',
+    aaguid bytea not null,
+    -- the authenticator's signature counter, a counter that doesn't grow
+    -- means the key was cloned.
+    sign_count bigint not null default 0,
+    backup_eligible bool not null,
+    backup_state bool not null,
+    created_at timestamptz not null default now(),
+    last_used_at timestamptz,
+
+    primary key (id),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+
+create index passkeys_credentials_id_idx on passkeys (credentials_id);
+
+-- registrations and passwordless logins in progress, session_data is the
+-- relying party's state between the two steps of the ceremony. Passwordless
+-- logins don't know the credentials until they finish.
+create table webauthn_ceremonies (
+    id uuid,
+    credentials_id uuid,
+    purpose varchar(20) not null,
+    session_data bytea not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (id),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+
+-- the ceremony of a passkey used as second factor.
+alter table mfa_challenges add column webauthn_session bytea;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table mfa_challenges drop column webauthn_session;
+drop table webauthn_ceremonies;
+drop table passkeys;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the OPAQUE registration record: the client's public key, masking key and
+-- envelope. Accounts with a record have no password hash.
+alter table credentials add column opaque_record bytea;
+
+-- registrations of new accounts in progress, the id becomes the credentials
+-- id since the server's OPRF key is derived from it.
+create table opaque_registrations (
+    id uuid,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (id)
+);
+
+-- logins between KE2 and KE3. credentials_id is null for unknown emails,
+-- which still get an answer so that they can't be told apart.
+create table opaque_logins (
+    id uuid,
+    credentials_id uuid,
+    email varchar(255) not null,
+    expected_client_mac bytea not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (id),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table opaque_logins;
+drop table opaque_registrations;
+alter table credentials drop column opaque_record;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- login links are email tokens too. browser_hash is the sha256 of the secret
+-- kept in a cookie of the browser that asked for the link, the link only
+-- works there.
+alter table email_verification_tokens
+    add column purpose varchar(20) not null default 'verify-email',
+    add column browser_hash bytea;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from email_verification_tokens where purpose <> 'verify-email';
+alter table email_verification_tokens
+    drop column browser_hash,
+    drop column purpose;
+-- +goose StatementEnd
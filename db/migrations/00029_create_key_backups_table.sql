@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a user's identity keys encrypted on the client with a key derived from a
+-- PIN. The server keeps the salt for that derivation and a verifier of a
+-- second key derived from the PIN, never the PIN or the encryption key.
+create table key_backups (
+    user_id uuid,
+    salt bytea not null,
+    verifier bytea not null,
+    ciphertext bytea not null,
+    -- guesses since the last right one, the backup is destroyed at the limit.
+    failed_guesses int not null default 0,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+
+    primary key (user_id),
+    foreign key (user_id) references users (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table key_backups;
+-- +goose StatementEnd
@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- message history sent from one of a user's devices to another. The archive
+-- is encrypted by the source device, encrypted_key is the archive key
+-- encrypted to the target device's identity key.
+create table history_transfers (
+    id uuid,
+    user_id uuid not null,
+    source_device_id uuid not null,
+    target_device_id uuid not null,
+    encrypted_key bytea not null,
+    chunk_count int not null,
+    total_size bigint not null,
+    -- uploading, ready, completed or canceled.
+    status varchar(20) not null default 'uploading',
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (id),
+    foreign key (user_id) references users (id) on delete cascade,
+    foreign key (source_device_id) references devices (id) on delete cascade,
+    foreign key (target_device_id) references devices (id) on delete cascade
+);
+
+create index history_transfers_source_device_id_idx on history_transfers (source_device_id);
+create index history_transfers_target_device_id_idx on history_transfers (target_device_id);
+create index history_transfers_expires_at_idx on history_transfers (expires_at);
+
+-- the archive's chunks, each one a blob.
+create table history_transfer_chunks (
+    transfer_id uuid not null,
+    index int not null,
+    blob_id uuid not null,
+    size bigint not null,
+    uploaded_at timestamptz not null default now(),
+    downloaded_at timestamptz,
+
+    primary key (transfer_id, index),
+    foreign key (transfer_id) references history_transfers (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table history_transfer_chunks;
+drop table history_transfers;
+-- +goose StatementEnd
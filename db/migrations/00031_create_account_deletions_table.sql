@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Accounts scheduled for deletion, the id is the token of the cancel link
+-- emailed to the owner. Once scheduled_at passed, the account is purged.
+create table account_deletions (
+    id uuid,
+    credentials_id uuid not null unique,
+    created_at timestamptz not null default now(),
+    scheduled_at timestamptz not null,
+
+    primary key (id),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+
+create index account_deletions_scheduled_at_idx on account_deletions (scheduled_at);
+
+-- the account_deleted events outlive the user they're about, so its peers
+-- still see why the conversation went quiet.
+alter table conversation_events alter column subject_user_id drop not null;
+alter table conversation_events drop constraint conversation_events_subject_user_id_fkey;
+alter table conversation_events add foreign key (subject_user_id) references users (id) on delete set null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from conversation_events where subject_user_id is null;
+alter table conversation_events drop constraint conversation_events_subject_user_id_fkey;
+alter table conversation_events add foreign key (subject_user_id) references users (id) on delete cascade;
+alter table conversation_events alter column subject_user_id set not null;
+drop table account_deletions;
+-- +goose StatementEnd
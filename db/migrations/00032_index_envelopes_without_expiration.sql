@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- envelopes from before 00015 have no expires_at, the purge finds them by age.
+create index envelopes_created_at_no_expiration_idx on envelopes (created_at) where expires_at is null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index envelopes_created_at_no_expiration_idx;
+-- +goose StatementEnd
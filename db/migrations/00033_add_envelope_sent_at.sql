@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- when the envelope's content first left the server for its device, over
+-- realtime or in a mailbox listing. The device may have the content from then
+-- on, even if it never acks the envelope.
+alter table envelopes add column sent_at timestamptz;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table envelopes drop column sent_at;
+-- +goose StatementEnd
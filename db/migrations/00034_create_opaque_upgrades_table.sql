@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- upgrades of password accounts to OPAQUE between the password check and the
+-- record, each can be finished once.
+create table opaque_upgrades (
+    id uuid,
+    credentials_id uuid not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (id),
+    foreign key (credentials_id) references credentials (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table opaque_upgrades;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the 1-1 conversation of each pair of users, user_low being the smaller id.
+-- The primary key keeps concurrent starts from creating two conversations.
+create table direct_conversations (
+    user_low uuid not null,
+    user_high uuid not null,
+    conversation_id uuid not null,
+
+    primary key (user_low, user_high),
+    unique (conversation_id),
+    check (user_low < user_high),
+    foreign key (user_low) references users (id) on delete cascade,
+    foreign key (user_high) references users (id) on delete cascade,
+    foreign key (conversation_id) references conversations (id) on delete cascade
+);
+
+-- pairs that already ended up with duplicates keep their oldest conversation.
+insert into direct_conversations (user_low, user_high, conversation_id)
+select distinct on (least(self.user_id, peer.user_id), greatest(self.user_id, peer.user_id))
+    least(self.user_id, peer.user_id), greatest(self.user_id, peer.user_id), c.id
+from conversations c
+join conversation_participants self on self.conversation_id = c.id
+join conversation_participants peer on peer.conversation_id = c.id and peer.user_id > self.user_id
+order by least(self.user_id, peer.user_id), greatest(self.user_id, peer.user_id), c.created_at, c.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table direct_conversations;
+-- +goose StatementEnd
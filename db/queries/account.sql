@@ -0,0 +1,39 @@
+-- name: InsertAccountDeletion :execrows
+insert into account_deletions (id, credentials_id, scheduled_at)
+values ($1, $2, $3)
+on conflict (credentials_id) do nothing;
+
+-- name: GetAccountDeletionByCredentialsID :one
+select * from account_deletions where credentials_id = $1;
+
+-- name: CancelAccountDeletion :one
+-- once scheduled_at passed, the purge may have started already.
+delete from account_deletions where id = $1 and scheduled_at > now()
+returning *;
+
+-- name: CancelCredentialsAccountDeletion :one
+delete from account_deletions where credentials_id = $1 and scheduled_at > now()
+returning *;
+
+-- name: ListDueAccountDeletions :many
+select * from account_deletions
+where scheduled_at <= now()
+order by scheduled_at
+limit sqlc.arg(max_count);
+
+-- name: InsertAccountDeletedEvents :exec
+-- the subject is set null with the user, the events stay for its peers. A
+-- purge that is retried doesn't add them twice.
+insert into conversation_events (conversation_id, type, subject_user_id)
+select cp.conversation_id, 'account_deleted', sqlc.arg(user_id)::uuid
+from conversation_participants cp
+where cp.user_id = sqlc.arg(user_id)
+    and not exists (
+        select 1 from conversation_events e
+        where e.conversation_id = cp.conversation_id and e.type = 'account_deleted' and e.subject_user_id = sqlc.arg(user_id)
+    );
+
+-- name: DeleteCredentials :exec
+-- everything else of the account goes with it through on delete cascade, but
+-- blobs have to be deleted before.
+delete from credentials where id = $1;
@@ -0,0 +1,71 @@
+-- name: LockUser :exec
+-- serializes the quota checks of concurrent uploads by the same user.
+select id from users where id = $1 for update;
+
+-- name: GetUserAttachmentUsage :one
+select coalesce(sum(size), 0)::bigint from attachments where owner_user_id = $1;
+
+-- name: InsertAttachment :one
+insert into attachments (id, owner_user_id, size, upload_expires_at)
+values ($1, $2, $3, $4)
+returning *;
+
+-- name: MarkAttachmentUploaded :exec
+update attachments set uploaded_at = now(), upload_offset = size where id = $1;
+
+-- name: UpdateAttachmentUploadOffset :execrows
+update attachments set upload_offset = sqlc.arg(new_offset)
+where id = sqlc.arg(id) and upload_offset = sqlc.arg(old_offset) and uploaded_at is null;
+
+-- name: GetAttachmentByID :one
+select * from attachments where id = $1;
+
+-- name: CheckAttachmentAccess :one
+-- the owner and the participants of conversations with messages referencing
+-- the attachment can download it.
+select exists (
+    select 1 from attachments a
+    where a.id = sqlc.arg(attachment_id) and a.uploaded_at is not null and (
+        a.owner_user_id = sqlc.arg(user_id) or exists (
+            select 1 from message_attachments ma
+            join messages m on m.id = ma.message_id
+            join conversation_participants cp on cp.conversation_id = m.conversation_id
+            where ma.attachment_id = a.id and cp.user_id = sqlc.arg(user_id)
+        )
+    )
+);
+
+-- name: InsertMessageAttachment :execrows
+-- only uploaded attachments of the sender can be attached.
+insert into message_attachments (message_id, attachment_id)
+select sqlc.arg(message_id), a.id from attachments a
+where a.id = sqlc.arg(attachment_id) and a.owner_user_id = sqlc.arg(owner_user_id) and a.uploaded_at is not null;
+
+-- name: ListMessageAttachmentIDs :many
+select attachment_id from message_attachments where message_id = $1 order by attachment_id;
+
+-- name: ListCollectableAttachments :many
+-- attachments past their TTL that no message waiting in a mailbox references.
+-- Resumable uploads in progress are only collected once they expire.
+select a.* from attachments a
+where a.created_at < sqlc.arg(created_before)
+    and (a.uploaded_at is not null or a.upload_expires_at is null)
+    and not exists (
+        select 1 from message_attachments ma
+        join envelopes e on e.message_id = ma.message_id
+        where ma.attachment_id = a.id
+    )
+order by a.created_at
+limit sqlc.arg(max_count);
+
+-- name: ListExpiredUploads :many
+select * from attachments
+where uploaded_at is null and upload_expires_at < now()
+order by upload_expires_at
+limit $1;
+
+-- name: DeleteAttachment :exec
+delete from attachments where id = $1;
+
+-- name: ListUserAttachmentIDs :many
+select id from attachments where owner_user_id = $1;
@@ -0,0 +1,32 @@
+-- name: LockAuditEvents :exec
+-- serializes appends until the end of the transaction, the chain needs the
+-- previous row's hash.
+select pg_advisory_xact_lock(hashtext('audit_events'));
+
+-- name: GetLatestAuditEventHash :one
+select hash
+from audit_events
+order by id desc
+limit 1;
+
+-- name: NextAuditEventID :one
+select nextval('audit_events_id_seq')::bigint;
+
+-- name: InsertAuditEvent :exec
+insert into audit_events (id, credentials_id, type, ip, user_agent, details, created_at, prev_hash, hash)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: ListAuditEventsAfterID :many
+select *
+from audit_events
+where id > $1
+order by id
+limit $2;
+
+-- name: ListCredentialsAuditEvents :many
+select *
+from audit_events
+where credentials_id = sqlc.arg(credentials_id)::uuid
+    and id < sqlc.arg(before_id)::bigint
+order by id desc
+limit sqlc.arg(page_size);
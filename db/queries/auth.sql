@@ -13,7 +13,17 @@ values ($1, $2, $3);
 delete from email_verification_tokens where expires_at <= now();
 
 -- name: GetEmailVerificationTokenByID :one
-select * from email_verification_tokens where id = $1;
+select * from email_verification_tokens where id = $1 and purpose = 'verify-email';
+
+-- name: InsertLoginLinkToken :exec
+insert into email_verification_tokens (id, email, purpose, browser_hash, expires_at)
+values ($1, $2, 'login', $3, $4);
+
+-- name: DeleteLoginLinkToken :one
+-- a link opened in another browser, or by a mail scanner, stays usable.
+delete from email_verification_tokens
+where id = $1 and purpose = 'login' and browser_hash = $2
+returning *;
 
 -- name: MarkEmailAsVerified :exec
 update credentials set email_is_verified = true where email = $1;
@@ -28,3 +38,67 @@ returning *;
 
 -- name: GetSessionByID :one
 select * from sessions where id = $1;
+
+-- name: DeleteSession :exec
+delete from sessions where id = $1;
+
+-- name: DeleteCredentialsSessions :many
+delete from sessions where credentials_id = $1
+returning id;
+
+-- name: GetCredentialsByID :one
+select * from credentials where id = $1;
+
+-- name: SetPasswordResetRequired :exec
+update credentials set password_reset_required = true where id = $1;
+
+-- name: UpdateCredentialsPassword :exec
+update credentials
+set password_hash = $2, password_reset_required = false, opaque_record = null
+where id = $1;
+
+-- name: InsertLoginHistory :exec
+insert into login_history (credentials_id, session_id, ip_prefix, user_agent_family, region, latitude, longitude)
+values ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListRecentLoginHistory :many
+select * from login_history
+where credentials_id = $1
+order by created_at desc
+limit $2;
+
+-- name: DeleteOldLoginHistory :exec
+-- keeps the latest logins.
+delete from login_history
+where login_history.credentials_id = $1
+    and login_history.id not in (
+        select recent.id from login_history recent
+        where recent.credentials_id = $1
+        order by recent.created_at desc
+        limit sqlc.arg(keep)
+    );
+
+-- name: InsertLoginAlert :exec
+insert into login_alerts (id, credentials_id, session_id, expires_at)
+values ($1, $2, $3, $4);
+
+-- name: DeleteLoginAlert :one
+delete from login_alerts where id = $1
+returning *;
+
+-- name: DeleteStaleLoginAlerts :exec
+delete from login_alerts where expires_at <= now();
+
+-- name: InsertPasswordResetToken :exec
+insert into password_reset_tokens (id, credentials_id, expires_at)
+values ($1, $2, $3);
+
+-- name: DeletePasswordResetToken :one
+delete from password_reset_tokens where id = $1
+returning *;
+
+-- name: DeleteCredentialsPasswordResetTokens :exec
+delete from password_reset_tokens where credentials_id = $1;
+
+-- name: DeleteStalePasswordResetTokens :exec
+delete from password_reset_tokens where expires_at <= now();
@@ -0,0 +1,27 @@
+-- name: LockClusterEvents :exec
+-- serializes publishers until the end of the transaction, so events commit
+-- in id order and listeners never skip one.
+select pg_advisory_xact_lock(hashtext('cluster_events'));
+
+-- name: InsertClusterEvent :one
+insert into cluster_events (payload)
+values ($1)
+returning id;
+
+-- name: NotifyClusterEvent :exec
+select pg_notify('cluster_events', (sqlc.arg(id)::bigint)::text);
+
+-- name: GetLatestClusterEventID :one
+select coalesce(max(id), 0)::bigint
+from cluster_events;
+
+-- name: ListClusterEventsAfterID :many
+select id, payload
+from cluster_events
+where id > $1
+order by id
+limit $2;
+
+-- name: DeleteClusterEventsBefore :execrows
+delete from cluster_events
+where created_at < $1;
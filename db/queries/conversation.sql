@@ -0,0 +1,60 @@
+-- name: InsertConversation :exec
+insert into conversations (id)
+values ($1);
+
+-- name: InsertConversationParticipant :exec
+insert into conversation_participants (conversation_id, user_id)
+values ($1, $2);
+
+-- name: InsertDirectConversation :exec
+insert into direct_conversations (user_low, user_high, conversation_id)
+values (least(sqlc.arg(user_id)::uuid, sqlc.arg(peer_user_id)::uuid), greatest(sqlc.arg(user_id)::uuid, sqlc.arg(peer_user_id)::uuid), sqlc.arg(conversation_id));
+
+-- name: GetDirectConversationID :one
+select conversation_id
+from direct_conversations
+where user_low = least(sqlc.arg(user_id)::uuid, sqlc.arg(peer_user_id)::uuid)
+and user_high = greatest(sqlc.arg(user_id)::uuid, sqlc.arg(peer_user_id)::uuid);
+
+-- name: CheckConversationParticipant :one
+select exists (select 1 from conversation_participants where conversation_id = $1 and user_id = $2);
+
+-- name: ListConversationsByUserID :many
+select c.id, c.disappearing_timer, c.created_at, u.id as peer_user_id, u.username as peer_username, u.name as peer_name
+from conversations c
+join conversation_participants self on self.conversation_id = c.id
+-- the peer is gone once its account was deleted.
+left join conversation_participants peer on peer.conversation_id = c.id and peer.user_id <> self.user_id
+left join users u on u.id = peer.user_id
+where self.user_id = $1
+order by c.created_at desc;
+
+-- name: ListConversationEvents :many
+select * from conversation_events
+where conversation_id = $1 and (audience_user_id is null or audience_user_id = sqlc.arg(user_id)::uuid)
+order by created_at;
+
+-- name: UpdateConversationDisappearingTimer :exec
+update conversations set disappearing_timer = $2 where id = $1;
+
+-- name: InsertConversationEvent :exec
+insert into conversation_events (conversation_id, type, subject_user_id, disappearing_timer)
+values ($1, $2, $3, $4);
+
+-- name: ListContactUserIDs :many
+-- contacts are the users sharing a conversation with the user.
+select distinct peer.user_id
+from conversation_participants self
+join conversation_participants peer on peer.conversation_id = self.conversation_id and peer.user_id <> self.user_id
+where self.user_id = $1;
+
+-- name: CheckContact :one
+select exists (
+    select 1
+    from conversation_participants self
+    join conversation_participants peer on peer.conversation_id = self.conversation_id
+    where self.user_id = sqlc.arg(user_id) and peer.user_id = sqlc.arg(contact_user_id)
+);
+
+-- name: ListConversationParticipantUserIDs :many
+select user_id from conversation_participants where conversation_id = $1;
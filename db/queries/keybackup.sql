@@ -0,0 +1,31 @@
+-- name: InsertKeyBackup :execrows
+insert into key_backups (user_id, salt, verifier, ciphertext)
+values ($1, $2, $3, $4)
+on conflict (user_id) do nothing;
+
+-- name: GetKeyBackup :one
+select * from key_backups where user_id = $1;
+
+-- name: UseKeyBackupGuess :one
+-- counts the guess before it is checked, so that concurrent guesses can't go
+-- over the limit.
+update key_backups
+set failed_guesses = failed_guesses + 1
+where user_id = $1 and failed_guesses < sqlc.arg(max_guesses)::int
+returning *;
+
+-- name: ResetKeyBackupGuesses :exec
+update key_backups set failed_guesses = 0 where user_id = $1;
+
+-- name: UpdateKeyBackupCiphertext :exec
+update key_backups
+set ciphertext = $2, failed_guesses = 0, updated_at = now()
+where user_id = $1;
+
+-- name: UpdateKeyBackupPIN :exec
+update key_backups
+set salt = $2, verifier = $3, ciphertext = $4, failed_guesses = 0, updated_at = now()
+where user_id = $1;
+
+-- name: DeleteKeyBackup :execrows
+delete from key_backups where user_id = $1;
@@ -0,0 +1,49 @@
+-- name: InsertDevice :one
+insert into devices (id, user_id, name, identity_key)
+values ($1, $2, $3, $4)
+returning *;
+
+-- name: GetDeviceByID :one
+select * from devices where id = $1;
+
+-- name: ListDevicesByUserID :many
+select * from devices where user_id = $1 order by created_at;
+
+-- name: UpdateDeviceIdentityKey :exec
+update devices set identity_key = $2, updated_at = now() where id = $1;
+
+-- name: InsertIdentityKeyHistory :exec
+insert into identity_key_history (device_id, identity_key)
+values ($1, $2);
+
+-- name: ListIdentityKeyHistoryByDeviceID :many
+select * from identity_key_history where device_id = $1 order by id;
+
+-- name: InsertIdentityKeyChangedEvents :exec
+insert into conversation_events (conversation_id, type, subject_user_id, subject_device_id)
+select conversation_id, 'identity_key_changed', sqlc.arg(user_id)::uuid, sqlc.arg(device_id)::uuid
+from conversation_participants
+where user_id = sqlc.arg(user_id);
+
+-- name: InsertVerifiedIdentityKeyChangedEvents :exec
+insert into conversation_events (conversation_id, type, subject_user_id, subject_device_id, audience_user_id)
+select verifier.conversation_id, 'verified_identity_key_changed', sqlc.arg(user_id)::uuid, sqlc.arg(device_id)::uuid, cv.user_id
+from contact_verifications cv
+join conversation_participants verifier on verifier.user_id = cv.user_id
+join conversation_participants contact on contact.conversation_id = verifier.conversation_id and contact.user_id = cv.contact_user_id
+where cv.contact_user_id = sqlc.arg(user_id) and cv.key_changed_at is null;
+
+-- name: MarkContactVerificationsKeyChanged :exec
+update contact_verifications set key_changed_at = now()
+where contact_user_id = $1 and key_changed_at is null;
+
+-- name: UpsertContactVerification :exec
+insert into contact_verifications (user_id, contact_user_id)
+values ($1, $2)
+on conflict (user_id, contact_user_id) do update set verified_at = now(), key_changed_at = null;
+
+-- name: DeleteContactVerification :exec
+delete from contact_verifications where user_id = $1 and contact_user_id = $2;
+
+-- name: GetContactVerification :one
+select * from contact_verifications where user_id = $1 and contact_user_id = $2;
@@ -0,0 +1,111 @@
+-- name: InsertMessage :one
+-- the conversation row lock serializes concurrent sends, so sequence numbers
+-- are handed out without gaps or duplicates. The expiration follows the
+-- disappearing timer the conversation has when the message is sent.
+with next as (
+    update conversations set last_seq = last_seq + 1
+    where id = sqlc.arg(conversation_id)
+    returning last_seq, disappearing_timer
+)
+insert into messages (id, conversation_id, sender_user_id, sender_device_id, client_message_id, kind, reference_message_id, seq, expires_at)
+select sqlc.arg(id), sqlc.arg(conversation_id), sqlc.arg(sender_user_id), sqlc.arg(sender_device_id), sqlc.arg(client_message_id), sqlc.arg(kind), sqlc.arg(reference_message_id), next.last_seq,
+    case when next.disappearing_timer > 0 then now() + make_interval(secs => next.disappearing_timer) end
+from next
+returning *;
+
+-- name: GetMessageByID :one
+select * from messages where id = $1;
+
+-- name: GetMessageBySenderClientMessageID :one
+select * from messages where sender_user_id = $1 and client_message_id = $2;
+
+-- name: GetConversationLastSeq :one
+select last_seq from conversations where id = $1;
+
+-- name: ListConversationMessagesAfterSeq :many
+select m.id, m.client_message_id, m.seq, m.kind, m.reference_message_id, m.sender_user_id, m.sender_device_id, m.created_at, m.expires_at, e.id as envelope_id, e.content
+from messages m
+left join envelopes e on e.message_id = m.id and e.recipient_device_id = sqlc.arg(device_id) and (e.expires_at is null or e.expires_at > now())
+where m.conversation_id = sqlc.arg(conversation_id) and m.seq > sqlc.arg(after_seq)
+    and (m.expires_at is null or m.expires_at > now())
+order by m.seq
+limit sqlc.arg(max_count);
+
+-- name: InsertEnvelope :one
+insert into envelopes (id, message_id, recipient_device_id, content, expires_at)
+values ($1, $2, $3, $4, sqlc.arg(expires_at)::timestamptz)
+returning *;
+
+-- name: ListConversationDevices :many
+select d.id, d.user_id
+from devices d
+join conversation_participants cp on cp.user_id = d.user_id
+where cp.conversation_id = $1;
+
+-- name: ListUserDeviceIDs :many
+select id from devices where user_id = $1;
+
+-- name: InsertMessageDelivery :exec
+insert into message_deliveries (message_id, recipient_device_id, recipient_user_id)
+values ($1, $2, $3);
+
+-- name: MarkMessageDelivered :one
+update message_deliveries md set delivered_at = now()
+from messages m
+where m.id = md.message_id and md.message_id = $1 and md.recipient_device_id = $2 and md.delivered_at is null
+returning md.message_id, m.conversation_id, m.sender_user_id, md.recipient_user_id, md.recipient_device_id, md.delivered_at;
+
+-- name: MarkMessagesRead :many
+-- marks every message of the conversation up to the given sequence number as
+-- read by the reader on all of their devices.
+update message_deliveries md set read_at = now()
+from messages m
+join users sender on sender.id = m.sender_user_id
+where m.id = md.message_id
+    and m.conversation_id = sqlc.arg(conversation_id)
+    and m.seq <= sqlc.arg(up_to_seq)
+    and md.recipient_user_id = sqlc.arg(reader_user_id)
+    and md.read_at is null
+returning md.message_id, m.sender_user_id, sender.read_receipts_enabled as sender_read_receipts_enabled, md.recipient_device_id, md.read_at;
+
+-- name: ListMessageDeliveries :many
+select * from message_deliveries where message_id = $1 order by recipient_user_id, recipient_device_id;
+
+-- name: ListMailboxEnvelopes :many
+select e.id, e.message_id, e.content, e.created_at, e.expires_at, m.conversation_id, m.seq, m.client_message_id, m.kind, m.reference_message_id, m.sender_user_id, m.sender_device_id
+from envelopes e
+left join messages m on m.id = e.message_id
+where e.recipient_device_id = $1 and (e.expires_at is null or e.expires_at > now())
+order by e.created_at
+limit $2;
+
+-- name: DeleteEnvelope :one
+delete from envelopes where id = $1 and recipient_device_id = $2
+returning message_id;
+
+-- name: DeleteExpiredMessages :execrows
+-- their envelopes and delivery state go with them.
+delete from messages
+where id in (select id from messages where expires_at <= now() limit $1);
+
+-- name: DeleteExpiredEnvelopes :execrows
+-- envelopes from before expires_at existed have none, they are expired once
+-- older than the retention.
+delete from envelopes
+where id in (
+    select e.id from envelopes e
+    where e.expires_at <= now() or (e.expires_at is null and e.created_at <= sqlc.arg(created_before))
+    limit sqlc.arg(max_count)
+);
+
+-- name: DeleteMessageEnvelopes :many
+-- purges the envelopes of a message that are still waiting in mailboxes.
+delete from envelopes where message_id = sqlc.arg(message_id)::uuid
+returning recipient_device_id, sent_at;
+
+-- name: MarkEnvelopesSent :exec
+update envelopes set sent_at = now()
+where id = any(sqlc.arg(ids)::uuid[]) and sent_at is null;
+
+-- name: CheckMessageDeleted :one
+select exists (select 1 from messages where reference_message_id = sqlc.arg(message_id)::uuid and kind = 'delete');
@@ -0,0 +1,46 @@
+-- name: SetPendingTOTPSecret :execrows
+update credentials
+set totp_secret = $2, totp_last_counter = 0
+where id = $1 and not totp_enabled;
+
+-- name: EnableTOTP :execrows
+update credentials
+set totp_enabled = true
+where id = $1 and not totp_enabled and totp_secret is not null;
+
+-- name: DisableTOTP :exec
+update credentials
+set totp_secret = null, totp_enabled = false, totp_last_counter = 0
+where id = $1;
+
+-- name: UseTOTPCounter :execrows
+-- fails for counters at or before the last accepted one.
+update credentials
+set totp_last_counter = $2
+where id = $1 and totp_last_counter < $2;
+
+-- name: InsertRecoveryCodes :exec
+insert into recovery_codes (credentials_id, code_hash)
+select $1, unnest(sqlc.arg(code_hashes)::bytea[]);
+
+-- name: DeleteRecoveryCodes :exec
+delete from recovery_codes where credentials_id = $1;
+
+-- name: UseRecoveryCode :execrows
+delete from recovery_codes where credentials_id = $1 and code_hash = $2;
+
+-- name: CountRecoveryCodes :one
+select count(*) from recovery_codes where credentials_id = $1;
+
+-- name: InsertMFAChallenge :exec
+insert into mfa_challenges (id, credentials_id, expires_at)
+values ($1, $2, $3);
+
+-- name: GetMFAChallenge :one
+select * from mfa_challenges where id = $1;
+
+-- name: DeleteMFAChallenge :execrows
+delete from mfa_challenges where id = $1;
+
+-- name: DeleteStaleMFAChallenges :exec
+delete from mfa_challenges where expires_at <= now();
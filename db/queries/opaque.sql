@@ -0,0 +1,43 @@
+-- name: InsertOpaqueCredentials :exec
+-- password_hash is empty, no password matches it.
+insert into credentials (id, email, password_hash, opaque_record)
+values ($1, $2, '', $3);
+
+-- name: SetOpaqueRecord :exec
+-- a pending password reset still applies, it replaces the record.
+update credentials
+set opaque_record = $2, password_hash = ''
+where id = $1;
+
+-- name: InsertOpaqueRegistration :exec
+insert into opaque_registrations (id, expires_at)
+values ($1, $2);
+
+-- name: DeleteOpaqueRegistration :one
+delete from opaque_registrations where id = $1
+returning *;
+
+-- name: DeleteStaleOpaqueRegistrations :exec
+delete from opaque_registrations where expires_at <= now();
+
+-- name: InsertOpaqueUpgrade :exec
+insert into opaque_upgrades (id, credentials_id, expires_at)
+values ($1, $2, $3);
+
+-- name: DeleteOpaqueUpgrade :one
+delete from opaque_upgrades where id = $1 and credentials_id = $2
+returning *;
+
+-- name: DeleteStaleOpaqueUpgrades :exec
+delete from opaque_upgrades where expires_at <= now();
+
+-- name: InsertOpaqueLogin :exec
+insert into opaque_logins (id, credentials_id, email, expected_client_mac, expires_at)
+values ($1, $2, $3, $4, $5);
+
+-- name: DeleteOpaqueLogin :one
+delete from opaque_logins where id = $1
+returning *;
+
+-- name: DeleteStaleOpaqueLogins :exec
+delete from opaque_logins where expires_at <= now();
@@ -0,0 +1,34 @@
+-- name: InsertPasskey :one
+insert into passkeys (id, credentials_id, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+returning *;
+
+-- name: ListCredentialsPasskeys :many
+select * from passkeys
+where credentials_id = $1
+order by created_at;
+
+-- name: CountCredentialsPasskeys :one
+select count(*) from passkeys where credentials_id = $1;
+
+-- name: UpdatePasskeyUse :exec
+update passkeys
+set sign_count = $2, backup_state = $3, last_used_at = now()
+where id = $1;
+
+-- name: DeletePasskey :execrows
+delete from passkeys where id = $1 and credentials_id = $2;
+
+-- name: InsertWebAuthnCeremony :exec
+insert into webauthn_ceremonies (id, credentials_id, purpose, session_data, expires_at)
+values ($1, $2, $3, $4, $5);
+
+-- name: DeleteWebAuthnCeremony :one
+delete from webauthn_ceremonies where id = $1
+returning *;
+
+-- name: DeleteStaleWebAuthnCeremonies :exec
+delete from webauthn_ceremonies where expires_at <= now();
+
+-- name: SetMFAChallengeWebAuthnSession :execrows
+update mfa_challenges set webauthn_session = $2 where id = $1;
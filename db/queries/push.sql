@@ -0,0 +1,14 @@
+-- name: UpsertPushSubscription :exec
+insert into push_subscriptions (device_id, provider, endpoint, p256dh, auth)
+values ($1, $2, $3, $4, $5)
+on conflict (device_id) do update set provider = excluded.provider, endpoint = excluded.endpoint, p256dh = excluded.p256dh, auth = excluded.auth, updated_at = now();
+
+-- name: GetPushSubscriptionByDeviceID :one
+select * from push_subscriptions where device_id = $1;
+
+-- name: DeletePushSubscription :exec
+delete from push_subscriptions where device_id = $1;
+
+-- name: DeletePushSubscriptionByEndpoint :exec
+-- only removes the subscription if it wasn't replaced in the meantime.
+delete from push_subscriptions where device_id = $1 and endpoint = $2;
@@ -0,0 +1,27 @@
+-- name: HitRateLimitCounter :one
+-- a counter past its window starts over.
+insert into rate_limit_counters (key, hits, reset_at)
+values (sqlc.arg(key), 1, sqlc.arg(reset_at)::timestamptz)
+on conflict (key) do update
+set hits = case
+        when rate_limit_counters.reset_at <= sqlc.arg(now)::timestamptz then 1
+        else rate_limit_counters.hits + 1
+    end,
+    reset_at = case
+        when rate_limit_counters.reset_at <= sqlc.arg(now)::timestamptz then excluded.reset_at
+        else rate_limit_counters.reset_at
+    end
+returning hits, reset_at;
+
+-- name: GetRateLimitCounter :one
+select hits, reset_at
+from rate_limit_counters
+where key = $1 and reset_at > now();
+
+-- name: DeleteRateLimitCounter :exec
+delete from rate_limit_counters
+where key = $1;
+
+-- name: DeleteExpiredRateLimitCounters :execrows
+delete from rate_limit_counters
+where reset_at <= now();
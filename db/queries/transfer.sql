@@ -0,0 +1,66 @@
+-- name: InsertHistoryTransfer :one
+insert into history_transfers (id, user_id, source_device_id, target_device_id, encrypted_key, chunk_count, total_size, expires_at)
+values ($1, $2, $3, $4, $5, $6, $7, $8)
+returning *;
+
+-- name: CountOpenHistoryTransfers :one
+select count(*) from history_transfers
+where user_id = $1 and status in ('uploading', 'ready') and expires_at > now();
+
+-- name: GetHistoryTransfer :one
+select * from history_transfers where id = $1;
+
+-- name: ListDeviceHistoryTransfers :many
+select * from history_transfers
+where (source_device_id = sqlc.arg(device_id) or target_device_id = sqlc.arg(device_id))
+    and expires_at > now()
+order by created_at desc;
+
+-- name: UpdateHistoryTransferStatus :execrows
+-- only moves on from the given status, so that a canceled transfer stays so.
+update history_transfers
+set status = sqlc.arg(status), updated_at = now()
+where id = sqlc.arg(id) and status = sqlc.arg(from_status);
+
+-- name: CancelHistoryTransfer :execrows
+update history_transfers
+set status = 'canceled', updated_at = now()
+where id = $1 and status in ('uploading', 'ready');
+
+-- name: GetHistoryTransferProgress :one
+select
+    count(*)::int as uploaded_chunks,
+    count(downloaded_at)::int as downloaded_chunks,
+    coalesce(sum(size), 0)::bigint as uploaded_size
+from history_transfer_chunks
+where transfer_id = $1;
+
+-- name: InsertHistoryTransferChunk :execrows
+insert into history_transfer_chunks (transfer_id, index, blob_id, size)
+values ($1, $2, $3, $4)
+on conflict (transfer_id, index) do nothing;
+
+-- name: GetHistoryTransferChunk :one
+select * from history_transfer_chunks where transfer_id = $1 and index = $2;
+
+-- name: MarkHistoryTransferChunkDownloaded :exec
+update history_transfer_chunks
+set downloaded_at = coalesce(downloaded_at, now())
+where transfer_id = $1 and index = $2;
+
+-- name: ListHistoryTransferChunkBlobs :many
+select blob_id from history_transfer_chunks where transfer_id = $1;
+
+-- name: DeleteHistoryTransferChunks :exec
+delete from history_transfer_chunks where transfer_id = $1;
+
+-- name: ListExpiredHistoryTransfers :many
+select id from history_transfers
+where expires_at <= now()
+limit sqlc.arg(max_count);
+
+-- name: DeleteHistoryTransfer :exec
+delete from history_transfers where id = $1;
+
+-- name: ListUserHistoryTransfers :many
+select id from history_transfers where user_id = $1;
@@ -0,0 +1,34 @@
+-- name: AppendKeyTransparencyEntry :one
+with next as (
+    update kt_log set tree_size = tree_size + 1
+    where id = 1
+    returning tree_size - 1 as leaf_index
+)
+insert into kt_entries (leaf_index, username, device_id, identity_key, leaf_hash)
+select next.leaf_index, sqlc.arg(username), sqlc.arg(device_id), sqlc.arg(identity_key), sqlc.arg(leaf_hash)
+from next
+returning leaf_index;
+
+-- name: GetKeyTransparencyTreeSize :one
+select tree_size from kt_log where id = 1;
+
+-- name: ListKeyTransparencyLeafHashes :many
+select leaf_hash from kt_entries
+where leaf_index >= sqlc.arg(from_index) and leaf_index < sqlc.arg(to_index)
+order by leaf_index;
+
+-- name: GetKeyTransparencyEntry :one
+select * from kt_entries where leaf_index = $1;
+
+-- name: ListKeyTransparencyEntriesByUsername :many
+select * from kt_entries
+where username = $1 and leaf_index < sqlc.arg(tree_size)
+order by leaf_index;
+
+-- name: GetKeyTransparencyTreeHead :one
+select * from kt_tree_heads where tree_size = $1;
+
+-- name: InsertKeyTransparencyTreeHead :exec
+insert into kt_tree_heads (tree_size, root_hash, timestamp, signature)
+values ($1, $2, $3, $4)
+on conflict (tree_size) do nothing;
@@ -4,3 +4,21 @@ select exists (select 1 from users where username = $1 for update);
 -- name: InsertUser :exec
 insert into users (id, name, username, credentials_id)
 values ($1, $2, $3, $4);
+
+-- name: GetUserByCredentialsID :one
+select * from users where credentials_id = $1;
+
+-- name: GetUserByUsername :one
+select * from users where username = $1;
+
+-- name: GetUserByID :one
+select * from users where id = $1;
+
+-- name: UpdateUserDeliveryAccessKey :exec
+update users set delivery_access_key = $2 where id = $1;
+
+-- name: UpdateUserReadReceiptsEnabled :exec
+update users set read_receipts_enabled = $2 where id = $1;
+
+-- name: UpdateUserPresenceVisibility :exec
+update users set presence_visibility = $2 where id = $1;
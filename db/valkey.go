@@ -0,0 +1,34 @@
+package db
+
+import (
+	"chatapp/config"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Valkey is nil unless VALKEY_URL is set, everything it backs has an
+// in-process or Postgres fallback.
+var Valkey *redis.Client
+
+func init() {
+	if config.ValkeyURL == "" {
+		return
+	}
+
+	options, err := redis.ParseURL(config.ValkeyURL)
+	if err != nil {
+		panic(fmt.Errorf("error parsing valkey url: %w", err))
+	}
+	client := redis.NewClient(options)
+
+	pingCtx, pingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer pingCancel()
+	if err := client.Ping(pingCtx).Err(); err != nil {
+		panic(fmt.Errorf("error pinging valkey: %w", err))
+	}
+
+	Valkey = client
+}
@@ -0,0 +1,438 @@
+// Package valkeyfake is an in-memory server speaking enough of RESP2 for the
+// valkey integrations to run against it without a real valkey: strings with
+// expiration, counters, transactions, pub/sub and pre-registered scripts.
+package valkeyfake
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// status is a simple string reply, as opposed to a bulk string.
+type status string
+
+// ScriptFunc stands in for a Lua script. call runs a command within the
+// script, it must not be used once the function returned.
+type ScriptFunc func(call func(args ...string) any, keys, args []string) any
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (me *entry) expired(now time.Time) bool {
+	return !me.expiresAt.IsZero() && !now.Before(me.expiresAt)
+}
+
+type Server struct {
+	listener net.Listener
+
+	mu          sync.Mutex
+	data        map[string]*entry
+	scripts     map[string]ScriptFunc
+	subscribers map[string]map[*conn]struct{}
+	conns       map[*conn]struct{}
+}
+
+// NewServer starts a server on a random local port.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	server := &Server{
+		listener:    listener,
+		data:        map[string]*entry{},
+		scripts:     map[string]ScriptFunc{},
+		subscribers: map[string]map[*conn]struct{}{},
+		conns:       map[*conn]struct{}{},
+	}
+	go server.serve()
+	return server, nil
+}
+
+// Addr is the host:port the server listens on.
+func (me *Server) Addr() string {
+	return me.listener.Addr().String()
+}
+
+// URL is what VALKEY_URL would be set to.
+func (me *Server) URL() string {
+	return "redis://" + me.Addr() + "/0"
+}
+
+func (me *Server) Close() error {
+	err := me.listener.Close()
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	for c := range me.conns {
+		c.netConn.Close()
+	}
+	return err
+}
+
+// RegisterScript makes EVAL and EVALSHA of the source run fn.
+func (me *Server) RegisterScript(source string, fn ScriptFunc) {
+	sum := sha1.Sum([]byte(source))
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	me.scripts[source] = fn
+	me.scripts[hex.EncodeToString(sum[:])] = fn
+}
+
+func (me *Server) serve() {
+	for {
+		netConn, err := me.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{server: me, netConn: netConn, writer: bufio.NewWriter(netConn), channels: map[string]struct{}{}}
+		me.mu.Lock()
+		me.conns[c] = struct{}{}
+		me.mu.Unlock()
+		go c.serve()
+	}
+}
+
+type conn struct {
+	server  *Server
+	netConn net.Conn
+
+	writeMu sync.Mutex
+	writer  *bufio.Writer
+
+	// only used by the connection's own goroutine, or with server.mu held.
+	channels map[string]struct{}
+	queued   [][]string
+	inMulti  bool
+}
+
+func (me *conn) serve() {
+	defer func() {
+		me.server.mu.Lock()
+		for channel := range me.channels {
+			delete(me.server.subscribers[channel], me)
+		}
+		delete(me.server.conns, me)
+		me.server.mu.Unlock()
+		me.netConn.Close()
+	}()
+
+	reader := bufio.NewReader(me.netConn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		me.write(me.handle(args))
+	}
+}
+
+func (me *conn) write(reply any) {
+	me.writeMu.Lock()
+	defer me.writeMu.Unlock()
+	writeReply(me.writer, reply)
+	me.writer.Flush()
+}
+
+func (me *conn) handle(args []string) any {
+	name := strings.ToLower(args[0])
+
+	if me.inMulti {
+		switch name {
+		case "exec":
+			me.inMulti = false
+			me.server.mu.Lock()
+			defer me.server.mu.Unlock()
+			replies := make([]any, 0, len(me.queued))
+			for _, queued := range me.queued {
+				replies = append(replies, me.server.call(queued))
+			}
+			me.queued = nil
+			return replies
+		case "discard":
+			me.inMulti = false
+			me.queued = nil
+			return status("OK")
+		}
+		me.queued = append(me.queued, args)
+		return status("QUEUED")
+	}
+
+	switch name {
+	case "multi":
+		me.inMulti = true
+		return status("OK")
+	case "subscribe":
+		me.server.mu.Lock()
+		defer me.server.mu.Unlock()
+		for i, channel := range args[1:] {
+			if me.server.subscribers[channel] == nil {
+				me.server.subscribers[channel] = map[*conn]struct{}{}
+			}
+			me.server.subscribers[channel][me] = struct{}{}
+			me.channels[channel] = struct{}{}
+			// every channel but the last gets its own confirmation.
+			if i < len(args)-2 {
+				me.write([]any{"subscribe", channel, int64(len(me.channels))})
+			}
+		}
+		return []any{"subscribe", args[len(args)-1], int64(len(me.channels))}
+	case "unsubscribe":
+		me.server.mu.Lock()
+		defer me.server.mu.Unlock()
+		channels := args[1:]
+		if len(channels) == 0 {
+			for channel := range me.channels {
+				channels = append(channels, channel)
+			}
+		}
+		var reply any = []any{"unsubscribe", nil, int64(0)}
+		for i, channel := range channels {
+			delete(me.server.subscribers[channel], me)
+			delete(me.channels, channel)
+			reply = []any{"unsubscribe", channel, int64(len(me.channels))}
+			if i < len(channels)-1 {
+				me.write(reply)
+			}
+		}
+		return reply
+	case "ping":
+		if len(me.channels) > 0 {
+			message := ""
+			if len(args) > 1 {
+				message = args[1]
+			}
+			return []any{"pong", message}
+		}
+	}
+
+	me.server.mu.Lock()
+	defer me.server.mu.Unlock()
+	return me.server.call(args)
+}
+
+// call runs a command, it must be called with mu held.
+func (me *Server) call(args []string) any {
+	now := time.Now()
+	get := func(key string) *entry {
+		e, ok := me.data[key]
+		if !ok {
+			return nil
+		}
+		if e.expired(now) {
+			delete(me.data, key)
+			return nil
+		}
+		return e
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "ping":
+		if len(args) > 1 {
+			return args[1]
+		}
+		return status("PONG")
+	case "client", "select":
+		return status("OK")
+	case "get":
+		if len(args) != 2 {
+			return errWrongArgs(args[0])
+		}
+		if e := get(args[1]); e != nil {
+			return e.value
+		}
+		return nil
+	case "set":
+		if len(args) < 3 {
+			return errWrongArgs(args[0])
+		}
+		e := &entry{value: args[2]}
+		for i := 3; i < len(args); i++ {
+			switch strings.ToLower(args[i]) {
+			case "ex", "px":
+				if i+1 >= len(args) {
+					return errors.New("ERR syntax error")
+				}
+				n, err := strconv.ParseInt(args[i+1], 10, 64)
+				if err != nil || n <= 0 {
+					return errors.New("ERR invalid expire time in 'set' command")
+				}
+				unit := time.Second
+				if strings.ToLower(args[i]) == "px" {
+					unit = time.Millisecond
+				}
+				e.expiresAt = now.Add(time.Duration(n) * unit)
+				i++
+			default:
+				return errors.New("ERR syntax error")
+			}
+		}
+		me.data[args[1]] = e
+		return status("OK")
+	case "del":
+		var deleted int64
+		for _, key := range args[1:] {
+			if get(key) != nil {
+				delete(me.data, key)
+				deleted++
+			}
+		}
+		return deleted
+	case "incr":
+		if len(args) != 2 {
+			return errWrongArgs(args[0])
+		}
+		e := get(args[1])
+		if e == nil {
+			e = &entry{value: "0"}
+			me.data[args[1]] = e
+		}
+		n, err := strconv.ParseInt(e.value, 10, 64)
+		if err != nil {
+			return errors.New("ERR value is not an integer or out of range")
+		}
+		n++
+		e.value = strconv.FormatInt(n, 10)
+		return n
+	case "pexpire":
+		if len(args) < 3 {
+			return errWrongArgs(args[0])
+		}
+		ms, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errors.New("ERR value is not an integer or out of range")
+		}
+		e := get(args[1])
+		if e == nil {
+			return int64(0)
+		}
+		if len(args) > 3 && strings.ToLower(args[3]) == "nx" && !e.expiresAt.IsZero() {
+			return int64(0)
+		}
+		e.expiresAt = now.Add(time.Duration(ms) * time.Millisecond)
+		return int64(1)
+	case "pttl":
+		if len(args) != 2 {
+			return errWrongArgs(args[0])
+		}
+		e := get(args[1])
+		switch {
+		case e == nil:
+			return int64(-2)
+		case e.expiresAt.IsZero():
+			return int64(-1)
+		}
+		return e.expiresAt.Sub(now).Milliseconds()
+	case "publish":
+		if len(args) != 3 {
+			return errWrongArgs(args[0])
+		}
+		message := []any{"message", args[1], args[2]}
+		for c := range me.subscribers[args[1]] {
+			c.write(message)
+		}
+		return int64(len(me.subscribers[args[1]]))
+	case "eval", "evalsha":
+		if len(args) < 3 {
+			return errWrongArgs(args[0])
+		}
+		fn, ok := me.scripts[args[1]]
+		if !ok {
+			return errors.New("NOSCRIPT No matching script. Please use EVAL.")
+		}
+		numKeys, err := strconv.Atoi(args[2])
+		if err != nil || numKeys < 0 || 3+numKeys > len(args) {
+			return errors.New("ERR Number of keys can't be greater than number of args")
+		}
+		return fn(func(args ...string) any { return me.call(args) }, args[3:3+numKeys], args[3+numKeys:])
+	}
+
+	return fmt.Errorf("ERR unknown command '%s'", args[0])
+}
+
+func errWrongArgs(command string) error {
+	return fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(command))
+}
+
+// readCommand reads an array of bulk strings.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		// inline commands, as typed in a terminal.
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid array length: %w", err)
+	}
+	args := make([]string, 0, count)
+	for range count {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected bulk string, got %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid bulk string length: %q", line)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeReply(w *bufio.Writer, reply any) {
+	switch reply := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case status:
+		fmt.Fprintf(w, "+%s\r\n", reply)
+	case error:
+		fmt.Fprintf(w, "-%s\r\n", reply)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", reply)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(reply), reply)
+	case []any:
+		fmt.Fprintf(w, "*%d\r\n", len(reply))
+		for _, item := range reply {
+			writeReply(w, item)
+		}
+	default:
+		fmt.Fprintf(w, "-ERR unsupported reply %T\r\n", reply)
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"bytes"
+	"chatapp/config"
+	"chatapp/service"
+	"chatapp/service/attachment"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AttachmentHandler struct {
+	attachmentService *attachment.AttachmentService
+}
+
+func NewAttachmentHandler(attachmentService *attachment.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+	}
+}
+
+// requestBody returns the request body as a stream when fiber streams it, so
+// large uploads aren't buffered in memory.
+func requestBody(c *fiber.Ctx) io.Reader {
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		return stream
+	}
+	return bytes.NewReader(c.Body())
+}
+
+// HandleUploadAttachment takes the encrypted blob as the raw request body.
+func (me *AttachmentHandler) HandleUploadAttachment(c *fiber.Ctx) error {
+	size := int64(c.Request().Header.ContentLength())
+	if size < 0 {
+		return fiber.ErrLengthRequired
+	}
+	if size > config.MaxAttachmentSize {
+		return fiber.ErrRequestEntityTooLarge
+	}
+
+	uploaded, err := me.attachmentService.Upload(getCurrentUserID(c), size, requestBody(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrQuotaExceeded):
+			return fiber.NewError(fiber.StatusInsufficientStorage, "attachment quota exceeded")
+		}
+		return fmt.Errorf("failed to upload attachment: %w", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"id":   uploaded.ID,
+		"size": uploaded.Size,
+	})
+}
+
+// HandleDownloadAttachment sends the blob, or the single byte range asked for
+// in the Range header.
+func (me *AttachmentHandler) HandleDownloadAttachment(c *fiber.Ctx) error {
+	attachmentID, err := uuid.Parse(c.Params("attachmentID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	downloaded, err := me.attachmentService.GetAttachment(getCurrentUserID(c), attachmentID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	status := fiber.StatusOK
+	offset, length := int64(0), downloaded.Size
+	if c.Get(fiber.HeaderRange) != "" {
+		byteRange, err := c.Range(int(downloaded.Size))
+		if err != nil || byteRange.Type != "bytes" {
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", downloaded.Size))
+			return fiber.ErrRequestedRangeNotSatisfiable
+		}
+		// multiple ranges aren't supported, the whole blob is sent instead.
+		if len(byteRange.Ranges) == 1 {
+			status = fiber.StatusPartialContent
+			offset = int64(byteRange.Ranges[0].Start)
+			length = int64(byteRange.Ranges[0].End-byteRange.Ranges[0].Start) + 1
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, downloaded.Size))
+		}
+	}
+
+	body, err := me.attachmentService.Open(downloaded, offset, length)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to open attachment: %w", err)
+	}
+
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+	// fiber closes the body once it's sent.
+	return c.Status(status).SendStream(body, int(length))
+}
+
+func (me *AttachmentHandler) HandleDeleteAttachment(c *fiber.Ctx) error {
+	attachmentID, err := uuid.Parse(c.Params("attachmentID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	if err := me.attachmentService.Delete(getCurrentUserID(c), attachmentID); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"chatapp/service/audit"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandler struct {
+	auditService *audit.AuditService
+}
+
+func NewAuditHandler(auditService *audit.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// getAuditClient returns who the request comes from, for audit events.
+func getAuditClient(c *fiber.Ctx) audit.Client {
+	return audit.Client{
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
+
+func (me *AuditHandler) HandleListSecurityEvents(c *fiber.Ctx) error {
+	beforeID, err := parseUintQuery(c, "before-id")
+	if err != nil || beforeID > 1<<63-1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"before-id": "must be a non-negative integer",
+		})
+	}
+
+	events, err := me.auditService.ListEvents(getCurrentUserCredentialsID(c), int64(beforeID))
+	if err != nil {
+		return fmt.Errorf("failed to list security events: %w", err)
+	}
+
+	return c.JSON(events)
+}
@@ -1,26 +1,32 @@
 package handler
 
 import (
+	"chatapp/config"
+	"chatapp/repo"
 	"chatapp/service"
+	"chatapp/service/audit"
 	"chatapp/service/auth"
 	"chatapp/service/user"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
-	authService *auth.AuthService
-	userService *user.UserService
+	authService  *auth.AuthService
+	userService  *user.UserService
+	auditService *audit.AuditService
 }
 
-func NewAuthHandler(authService *auth.AuthService, userService *user.UserService) *AuthHandler {
+func NewAuthHandler(authService *auth.AuthService, userService *user.UserService, auditService *audit.AuditService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		userService: userService,
+		authService:  authService,
+		userService:  userService,
+		auditService: auditService,
 	}
 }
 
@@ -97,17 +103,52 @@ func (me *AuthHandler) HandleLogin(c *fiber.Ctx) error {
 		password = c.FormValue("password")
 	)
 
-	session, err := me.authService.Login(email, password)
+	session, err := me.authService.Login(email, password, getAuditClient(c))
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrUnauthorized):
-			return fiber.ErrUnauthorized
-		case errors.Is(err, service.ErrEmailNotVerified):
-			return c.Status(fiber.StatusForbidden).SendString("email is not verified")
-		}
-		return fmt.Errorf("failed to login: %w", err)
+		return me.loginError(c, email, err)
 	}
 
+	me.loggedIn(c, session)
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// loginError answers the errors of a login once the password was sent.
+func (me *AuthHandler) loginError(c *fiber.Ctx, email string, err error) error {
+	var (
+		locked      *auth.LockedError
+		mfaRequired *auth.MFARequiredError
+	)
+	switch {
+	case errors.As(err, &mfaRequired):
+		// the password was right, the session comes from /login/mfa.
+		return c.Status(fiber.StatusAccepted).JSON(MFAChallengeResponse{
+			ChallengeID: mfaRequired.ChallengeID,
+			ExpiresAt:   mfaRequired.ExpiresAt,
+			Methods:     mfaRequired.Methods,
+		})
+	case errors.As(err, &locked):
+		me.auditService.RecordLoginFailure(email, true, getAuditClient(c))
+		return tooManyRequests(c, locked.RetryAfter)
+	case errors.Is(err, service.ErrUnauthorized):
+		me.auditService.RecordLoginFailure(email, false, getAuditClient(c))
+		return fiber.ErrUnauthorized
+	case errors.Is(err, service.ErrEmailNotVerified):
+		return c.Status(fiber.StatusForbidden).SendString("email is not verified")
+	case errors.Is(err, service.ErrPasswordResetNeeded):
+		return c.Status(fiber.StatusForbidden).SendString("password reset required")
+	}
+	return fmt.Errorf("failed to login: %w", err)
+}
+
+// loggedIn records the login and hands the session to the client.
+func (me *AuthHandler) loggedIn(c *fiber.Ctx, session repo.Session) {
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: session.CredentialsID, Valid: true},
+		Type:          audit.TypeLoginSucceeded,
+		Client:        getAuditClient(c),
+		Details:       map[string]any{"sessionId": session.ID},
+	})
+
 	c.Cookie(&fiber.Cookie{
 		Name:     "session-id",
 		Value:    session.ID.String(),
@@ -122,7 +163,158 @@ func (me *AuthHandler) HandleLogin(c *fiber.Ctx) error {
 		Name:  "csrf-token",
 		Value: session.CsrfToken,
 	})
+}
+
+func (me *AuthHandler) HandleLogout(c *fiber.Ctx) error {
+	sessionID := getCurrentSessionID(c)
+	if err := me.authService.RevokeSession(sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: getCurrentUserCredentialsID(c), Valid: true},
+		Type:          audit.TypeSessionRevoked,
+		Client:        getAuditClient(c),
+		Details:       map[string]any{"sessionId": sessionID},
+	})
+
+	c.ClearCookie("session-id", "session-token", "csrf-token")
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// denyLoginPage asks to confirm a "this wasn't me" link. Mail scanners that
+// open links mustn't deny the login, only submitting the form does.
+const denyLoginPage = `<!doctype html>
+<html>
+<head><meta charset="utf-8"><meta name="referrer" content="no-referrer"><title>Chat App New Login</title></head>
+<body>
+<p>sign out the new login to your account and reset your password?</p>
+<form method="post" action="/login-alerts/deny">
+<input type="hidden" name="token" value="%s">
+<button type="submit">this wasn't me</button>
+</form>
+</body>
+</html>
+`
+
+// HandleConfirmDenyLogin is the "this wasn't me" link of new login alerts, it
+// only shows the form that posts to HandleDenyLogin.
+func (me *AuthHandler) HandleConfirmDenyLogin(c *fiber.Ctx) error {
+	alertID, err := uuid.Parse(c.Query("token"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid token")
+	}
+
+	c.Type("html", "utf-8")
+	return c.SendString(fmt.Sprintf(denyLoginPage, alertID))
+}
+
+// HandleDenyLogin takes the token of a login alert from the form of
+// HandleConfirmDenyLogin.
+func (me *AuthHandler) HandleDenyLogin(c *fiber.Ctx) error {
+	alertID, err := uuid.Parse(c.FormValue("token"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid token")
+	}
+
+	if ok, err := me.authService.DenyLogin(alertID, getAuditClient(c)); err != nil {
+		return fmt.Errorf("failed to deny login: %w", err)
+	} else if !ok {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid or expired token")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *AuthHandler) HandleForgotPassword(c *fiber.Ctx) error {
+	email := strings.TrimSpace(c.FormValue("email"))
+
+	if err := me.authService.RequestPasswordReset(email); err != nil {
+		return fmt.Errorf("failed to request password reset: %w", err)
+	}
+
+	// the same answer whether the email exists or not.
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+func (me *AuthHandler) HandleResetPassword(c *fiber.Ctx) error {
+	var (
+		tokenStr       = c.FormValue("token")
+		password       = c.FormValue("password")
+		verifyPassword = c.FormValue("verify-password")
+	)
+
+	tokenID, err := uuid.Parse(tokenStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid token")
+	}
+
+	if err := me.authService.ResetPassword(auth.ResetPasswordParams{
+		TokenID:        tokenID,
+		Password:       password,
+		VerifyPassword: verifyPassword,
+		Client:         getAuditClient(c),
+	}); err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrUnauthorized):
+			return c.Status(fiber.StatusBadRequest).SendString("invalid or expired token")
+		}
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// HandleRequestLoginLink emails a login link that only works in this browser,
+// the cookie holds the secret binding them.
+func (me *AuthHandler) HandleRequestLoginLink(c *fiber.Ctx) error {
+	email := strings.TrimSpace(c.FormValue("email"))
+
+	browserSecret := me.authService.RequestLoginLink(email)
+	c.Cookie(&fiber.Cookie{
+		Name:     "login-link",
+		Value:    browserSecret,
+		Path:     "/login/link",
+		Expires:  time.Now().Add(config.LoginLinkExpiration),
+		HTTPOnly: true,
+		// lax, the link is opened from an email.
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	// the same answer whether the email exists or not.
+	return c.SendStatus(fiber.StatusAccepted)
+}
 
+// HandleLoginWithLink answers like HandleLogin.
+func (me *AuthHandler) HandleLoginWithLink(c *fiber.Ctx) error {
+	tokenID, err := uuid.Parse(c.Query("token"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid token")
+	}
+
+	session, err := me.authService.LoginWithLink(auth.LoginWithLinkParams{
+		TokenID:       tokenID,
+		BrowserSecret: c.Cookies("login-link"),
+		Client:        getAuditClient(c),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			return c.Status(fiber.StatusBadRequest).SendString("invalid or expired link, or opened in another browser")
+		}
+		return me.loginError(c, "", err)
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:    "login-link",
+		Path:    "/login/link",
+		Expires: time.Now().Add(-time.Hour),
+	})
+	me.loggedIn(c, session)
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -151,9 +343,14 @@ func (me *AuthHandler) WithSession(c *fiber.Ctx) error {
 	}
 
 	c.Locals("auth.credentialsID", credentialsID)
+	c.Locals("auth.sessionID", sessionID)
 	return c.Next()
 }
 
+func getCurrentSessionID(c *fiber.Ctx) uuid.UUID {
+	return c.Locals("auth.sessionID").(uuid.UUID)
+}
+
 func getCurrentUserCredentialsID(c *fiber.Ctx) uuid.UUID {
 	return c.Locals("auth.credentialsID").(uuid.UUID)
 }
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/conversation"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ConversationHandler struct {
+	conversationService *conversation.ConversationService
+}
+
+func NewConversationHandler(conversationService *conversation.ConversationService) *ConversationHandler {
+	return &ConversationHandler{
+		conversationService: conversationService,
+	}
+}
+
+func (me *ConversationHandler) HandleCreateConversation(c *fiber.Ctx) error {
+	username := strings.TrimSpace(c.FormValue("username"))
+
+	conversationID, created, err := me.conversationService.CreateDirectConversation(getCurrentUserID(c), username)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"username": "user not found",
+			})
+		}
+		return fmt.Errorf("failed to create conversation: %w", err)
+	}
+
+	status := fiber.StatusOK
+	if created {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"id": conversationID,
+	})
+}
+
+type conversationResponse struct {
+	ID uuid.UUID `json:"id"`
+	// the peer fields are null once the peer deleted its account.
+	PeerUserID   *uuid.UUID `json:"peerUserId"`
+	PeerUsername *string    `json:"peerUsername"`
+	PeerName     *string    `json:"peerName"`
+	// DisappearingTimer is in seconds, 0 when disabled.
+	DisappearingTimer int32     `json:"disappearingTimer"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+func (me *ConversationHandler) HandleListConversations(c *fiber.Ctx) error {
+	conversations, err := me.conversationService.ListConversations(getCurrentUserID(c))
+	if err != nil {
+		return fmt.Errorf("failed to list conversations: %w", err)
+	}
+
+	result := make([]conversationResponse, 0, len(conversations))
+	for _, conv := range conversations {
+		response := conversationResponse{
+			ID:                conv.ID,
+			DisappearingTimer: conv.DisappearingTimer,
+			CreatedAt:         conv.CreatedAt,
+		}
+		if conv.PeerUserID.Valid {
+			response.PeerUserID = &conv.PeerUserID.UUID
+			response.PeerUsername = &conv.PeerUsername.String
+			response.PeerName = &conv.PeerName.String
+		}
+		result = append(result, response)
+	}
+
+	return c.JSON(result)
+}
+
+type conversationEventResponse struct {
+	ID                uuid.UUID  `json:"id"`
+	Type              string     `json:"type"`
+	SubjectUserID     *uuid.UUID `json:"subjectUserId"`
+	SubjectDeviceID   *uuid.UUID `json:"subjectDeviceId"`
+	DisappearingTimer *int32     `json:"disappearingTimer,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+func (me *ConversationHandler) HandleListConversationEvents(c *fiber.Ctx) error {
+	conversationID, err := uuid.Parse(c.Params("conversationID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	events, err := me.conversationService.ListEvents(getCurrentUserID(c), conversationID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to list conversation events: %w", err)
+	}
+
+	result := make([]conversationEventResponse, 0, len(events))
+	for _, event := range events {
+		response := conversationEventResponse{
+			ID:        event.ID,
+			Type:      event.Type,
+			CreatedAt: event.CreatedAt,
+		}
+		if event.SubjectUserID.Valid {
+			response.SubjectUserID = &event.SubjectUserID.UUID
+		}
+		if event.SubjectDeviceID.Valid {
+			response.SubjectDeviceID = &event.SubjectDeviceID.UUID
+		}
+		if event.DisappearingTimer.Valid {
+			response.DisappearingTimer = &event.DisappearingTimer.Int32
+		}
+		result = append(result, response)
+	}
+
+	return c.JSON(result)
+}
+
+func (me *ConversationHandler) HandleSetDisappearingTimer(c *fiber.Ctx) error {
+	conversationID, err := uuid.Parse(c.Params("conversationID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	seconds, err := strconv.Atoi(c.FormValue("seconds"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"seconds": "must be an integer",
+		})
+	}
+
+	if err := me.conversationService.SetDisappearingTimer(getCurrentUserID(c), conversationID, seconds); err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to set disappearing timer: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
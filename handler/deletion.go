@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/auth"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AccountDeletionResponse struct {
+	RequestedAt time.Time `json:"requestedAt"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+}
+
+func newAccountDeletionResponse(deletion repo.AccountDeletion) AccountDeletionResponse {
+	return AccountDeletionResponse{
+		RequestedAt: deletion.CreatedAt,
+		ScheduledAt: deletion.ScheduledAt,
+	}
+}
+
+// HandleRequestAccountDeletion takes the re-authentication of getReauthParams.
+func (me *AuthHandler) HandleRequestAccountDeletion(c *fiber.Ctx) error {
+	reauth, ok, err := getReauthParams(c)
+	if !ok {
+		return err
+	}
+
+	deletion, err := me.authService.RequestAccountDeletion(auth.RequestAccountDeletionParams{
+		CredentialsID: getCurrentUserCredentialsID(c),
+		Reauth:        reauth,
+		Client:        getAuditClient(c),
+	})
+	if err != nil {
+		if handled, err := handleReauthError(c, err); handled {
+			return err
+		}
+		if errors.Is(err, service.ErrConflict) {
+			return c.Status(fiber.StatusConflict).SendString("account deletion already scheduled")
+		}
+		return fmt.Errorf("failed to request account deletion: %w", err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(newAccountDeletionResponse(deletion))
+}
+
+func (me *AuthHandler) HandleGetAccountDeletion(c *fiber.Ctx) error {
+	deletion, err := me.authService.GetAccountDeletion(getCurrentUserCredentialsID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to get account deletion: %w", err)
+	}
+
+	return c.JSON(newAccountDeletionResponse(deletion))
+}
+
+func (me *AuthHandler) HandleCancelAccountDeletion(c *fiber.Ctx) error {
+	if err := me.authService.CancelCredentialsAccountDeletion(getCurrentUserCredentialsID(c), getAuditClient(c)); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// HandleCancelAccountDeletionLink is the cancel link emailed when the deletion
+// was requested.
+func (me *AuthHandler) HandleCancelAccountDeletionLink(c *fiber.Ctx) error {
+	tokenID, err := uuid.Parse(c.Query("token"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid token")
+	}
+
+	if ok, err := me.authService.CancelAccountDeletion(tokenID, getAuditClient(c)); err != nil {
+		return fmt.Errorf("failed to cancel account deletion: %w", err)
+	} else if !ok {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid or expired token")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/audit"
+	"chatapp/service/keys"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type KeyBackupResponse struct {
+	Salt             []byte    `json:"salt"`
+	RemainingGuesses int       `json:"remainingGuesses"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+type KeyBackupCiphertextResponse struct {
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// base64FormValue decodes the form value, answering the client itself if it
+// isn't valid.
+func base64FormValue(c *fiber.Ctx, key string) ([]byte, bool, error) {
+	value, err := base64.StdEncoding.DecodeString(c.FormValue(key))
+	if err != nil {
+		return nil, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			key: "must be base64 encoded",
+		})
+	}
+	return value, true, nil
+}
+
+// keyBackupError answers the errors every access key check can return.
+func (me *KeyHandler) keyBackupError(c *fiber.Ctx, err error, action string) error {
+	var wrongPIN *keys.WrongPINError
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(errs)
+		}
+		return fmt.Errorf("failed to exctract validation errors")
+	case errors.As(err, &wrongPIN):
+		details := map[string]any{"remainingGuesses": wrongPIN.RemainingGuesses}
+		me.auditService.Record(audit.RecordParams{
+			CredentialsID: uuid.NullUUID{UUID: getCurrentUserCredentialsID(c), Valid: true},
+			Type:          audit.TypeKeyBackupFailed,
+			Client:        getAuditClient(c),
+			Details:       details,
+		})
+		if wrongPIN.RemainingGuesses == 0 {
+			me.auditService.Record(audit.RecordParams{
+				CredentialsID: uuid.NullUUID{UUID: getCurrentUserCredentialsID(c), Valid: true},
+				Type:          audit.TypeKeyBackupDeleted,
+				Client:        getAuditClient(c),
+				Details:       map[string]any{"reason": "guess_limit"},
+			})
+		}
+		return c.Status(fiber.StatusForbidden).JSON(details)
+	case errors.Is(err, service.ErrNotFound):
+		return fiber.ErrNotFound
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+func (me *KeyHandler) HandleGetKeyBackup(c *fiber.Ctx) error {
+	backup, err := me.keyService.GetKeyBackup(getCurrentUserID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to get key backup: %w", err)
+	}
+
+	return c.JSON(KeyBackupResponse{
+		Salt:             backup.Salt,
+		RemainingGuesses: backup.RemainingGuesses,
+		CreatedAt:        backup.CreatedAt,
+		UpdatedAt:        backup.UpdatedAt,
+	})
+}
+
+func (me *KeyHandler) HandleCreateKeyBackup(c *fiber.Ctx) error {
+	salt, ok, err := base64FormValue(c, "salt")
+	if !ok {
+		return err
+	}
+	accessKey, ok, err := base64FormValue(c, "access-key")
+	if !ok {
+		return err
+	}
+	ciphertext, ok, err := base64FormValue(c, "ciphertext")
+	if !ok {
+		return err
+	}
+
+	if err := me.keyService.CreateKeyBackup(keys.CreateKeyBackupParams{
+		UserID:     getCurrentUserID(c),
+		Salt:       salt,
+		AccessKey:  accessKey,
+		Ciphertext: ciphertext,
+	}); err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrConflict):
+			return c.Status(fiber.StatusConflict).SendString("key backup already exists")
+		}
+		return fmt.Errorf("failed to create key backup: %w", err)
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: getCurrentUserCredentialsID(c), Valid: true},
+		Type:          audit.TypeKeyBackupCreated,
+		Client:        getAuditClient(c),
+	})
+
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+// HandleRestoreKeyBackup is a POST so that the access key isn't in a URL.
+func (me *KeyHandler) HandleRestoreKeyBackup(c *fiber.Ctx) error {
+	accessKey, ok, err := base64FormValue(c, "access-key")
+	if !ok {
+		return err
+	}
+
+	ciphertext, err := me.keyService.RestoreKeyBackup(getCurrentUserID(c), accessKey)
+	if err != nil {
+		return me.keyBackupError(c, err, "restore key backup")
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: getCurrentUserCredentialsID(c), Valid: true},
+		Type:          audit.TypeKeyBackupRestore,
+		Client:        getAuditClient(c),
+	})
+
+	return c.JSON(KeyBackupCiphertextResponse{Ciphertext: ciphertext})
+}
+
+func (me *KeyHandler) HandleRotateKeyBackup(c *fiber.Ctx) error {
+	accessKey, ok, err := base64FormValue(c, "access-key")
+	if !ok {
+		return err
+	}
+	ciphertext, ok, err := base64FormValue(c, "ciphertext")
+	if !ok {
+		return err
+	}
+
+	if err := me.keyService.RotateKeyBackup(keys.RotateKeyBackupParams{
+		UserID:     getCurrentUserID(c),
+		AccessKey:  accessKey,
+		Ciphertext: ciphertext,
+	}); err != nil {
+		return me.keyBackupError(c, err, "rotate key backup")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *KeyHandler) HandleChangeKeyBackupPIN(c *fiber.Ctx) error {
+	accessKey, ok, err := base64FormValue(c, "access-key")
+	if !ok {
+		return err
+	}
+	newSalt, ok, err := base64FormValue(c, "new-salt")
+	if !ok {
+		return err
+	}
+	newAccessKey, ok, err := base64FormValue(c, "new-access-key")
+	if !ok {
+		return err
+	}
+	ciphertext, ok, err := base64FormValue(c, "ciphertext")
+	if !ok {
+		return err
+	}
+
+	if err := me.keyService.ChangeKeyBackupPIN(keys.ChangeKeyBackupPINParams{
+		UserID:       getCurrentUserID(c),
+		AccessKey:    accessKey,
+		NewSalt:      newSalt,
+		NewAccessKey: newAccessKey,
+		Ciphertext:   ciphertext,
+	}); err != nil {
+		return me.keyBackupError(c, err, "change key backup pin")
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: getCurrentUserCredentialsID(c), Valid: true},
+		Type:          audit.TypeKeyBackupPIN,
+		Client:        getAuditClient(c),
+	})
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// HandleDeleteKeyBackup takes the access key in the body, like a rotation.
+func (me *KeyHandler) HandleDeleteKeyBackup(c *fiber.Ctx) error {
+	accessKey, ok, err := base64FormValue(c, "access-key")
+	if !ok {
+		return err
+	}
+
+	if err := me.keyService.DeleteKeyBackup(getCurrentUserID(c), accessKey); err != nil {
+		return me.keyBackupError(c, err, "delete key backup")
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: getCurrentUserCredentialsID(c), Valid: true},
+		Type:          audit.TypeKeyBackupDeleted,
+		Client:        getAuditClient(c),
+		Details:       map[string]any{"reason": "deleted"},
+	})
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/audit"
+	"chatapp/service/keys"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type KeyHandler struct {
+	keyService   *keys.KeyService
+	auditService *audit.AuditService
+}
+
+func NewKeyHandler(keyService *keys.KeyService, auditService *audit.AuditService) *KeyHandler {
+	return &KeyHandler{
+		keyService:   keyService,
+		auditService: auditService,
+	}
+}
+
+// WithDevice loads the device the request is made from, given in the
+// X-Device-ID header. It must run after UserHandler.WithUser.
+func (me *KeyHandler) WithDevice(c *fiber.Ctx) error {
+	deviceID, err := uuid.Parse(c.Get("X-Device-ID"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "missing or invalid X-Device-ID header")
+	}
+
+	device, err := me.keyService.GetUserDevice(getCurrentUserID(c), deviceID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "unknown device")
+		}
+		return fmt.Errorf("failed to get current device: %w", err)
+	}
+
+	c.Locals("keys.deviceID", device.ID)
+	return c.Next()
+}
+
+func getCurrentDeviceID(c *fiber.Ctx) uuid.UUID {
+	return c.Locals("keys.deviceID").(uuid.UUID)
+}
+
+type deviceResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	IdentityKey []byte    `json:"identityKey"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func newDeviceResponse(device repo.Device) deviceResponse {
+	return deviceResponse{
+		ID:          device.ID,
+		Name:        device.Name,
+		IdentityKey: device.IdentityKey,
+		CreatedAt:   device.CreatedAt,
+		UpdatedAt:   device.UpdatedAt,
+	}
+}
+
+func newDeviceResponses(devices []repo.Device) []deviceResponse {
+	result := make([]deviceResponse, 0, len(devices))
+	for _, device := range devices {
+		result = append(result, newDeviceResponse(device))
+	}
+	return result
+}
+
+func (me *KeyHandler) HandleRegisterDevice(c *fiber.Ctx) error {
+	var (
+		name           = strings.TrimSpace(c.FormValue("name"))
+		identityKeyStr = c.FormValue("identity-key")
+	)
+
+	identityKey, err := base64.StdEncoding.DecodeString(identityKeyStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"identity-key": "must be base64 encoded",
+		})
+	}
+
+	device, err := me.keyService.RegisterDevice(keys.RegisterDeviceParams{
+		UserID:      getCurrentUserID(c),
+		Name:        name,
+		IdentityKey: identityKey,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		}
+		return fmt.Errorf("failed to register device: %w", err)
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: getCurrentUserCredentialsID(c), Valid: true},
+		Type:          audit.TypeDeviceLinked,
+		Client:        getAuditClient(c),
+		Details:       map[string]any{"deviceId": device.ID, "name": device.Name},
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(newDeviceResponse(device))
+}
+
+func (me *KeyHandler) HandleRotateIdentityKey(c *fiber.Ctx) error {
+	deviceID, err := uuid.Parse(c.Params("deviceID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	identityKey, err := base64.StdEncoding.DecodeString(c.FormValue("identity-key"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"identity-key": "must be base64 encoded",
+		})
+	}
+
+	if err := me.keyService.RotateIdentityKey(keys.RotateIdentityKeyParams{
+		UserID:      getCurrentUserID(c),
+		DeviceID:    deviceID,
+		IdentityKey: identityKey,
+	}); err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to rotate identity key: %w", err)
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: getCurrentUserCredentialsID(c), Valid: true},
+		Type:          audit.TypeIdentityKeyReset,
+		Client:        getAuditClient(c),
+		Details:       map[string]any{"deviceId": deviceID},
+	})
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *KeyHandler) HandleListUserDevices(c *fiber.Ctx) error {
+	devices, err := me.keyService.ListUserDevices(c.Params("username"))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to list user devices: %w", err)
+	}
+
+	return c.JSON(newDeviceResponses(devices))
+}
+
+type identityKeyHistoryResponse struct {
+	IdentityKey []byte    `json:"identityKey"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (me *KeyHandler) HandleListDeviceKeyHistory(c *fiber.Ctx) error {
+	deviceID, err := uuid.Parse(c.Params("deviceID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	history, err := me.keyService.ListDeviceKeyHistory(c.Params("username"), deviceID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to list device key history: %w", err)
+	}
+
+	result := make([]identityKeyHistoryResponse, 0, len(history))
+	for _, entry := range history {
+		result = append(result, identityKeyHistoryResponse{
+			IdentityKey: entry.IdentityKey,
+			CreatedAt:   entry.CreatedAt,
+		})
+	}
+
+	return c.JSON(result)
+}
+
+type partyKeysResponse struct {
+	UserID   uuid.UUID        `json:"userId"`
+	Username string           `json:"username"`
+	Devices  []deviceResponse `json:"devices"`
+}
+
+func (me *KeyHandler) HandleGetSafetyNumberKeys(c *fiber.Ctx) error {
+	safetyNumberKeys, err := me.keyService.GetSafetyNumberKeys(getCurrentUserID(c), c.Params("username"))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to get safety number keys: %w", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"local": partyKeysResponse{
+			UserID:   safetyNumberKeys.Local.UserID,
+			Username: safetyNumberKeys.Local.Username,
+			Devices:  newDeviceResponses(safetyNumberKeys.Local.Devices),
+		},
+		"remote": partyKeysResponse{
+			UserID:   safetyNumberKeys.Remote.UserID,
+			Username: safetyNumberKeys.Remote.Username,
+			Devices:  newDeviceResponses(safetyNumberKeys.Remote.Devices),
+		},
+	})
+}
+
+func (me *KeyHandler) HandleVerifyContact(c *fiber.Ctx) error {
+	if err := me.keyService.VerifyContact(getCurrentUserID(c), c.Params("username")); err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to verify contact: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *KeyHandler) HandleUnverifyContact(c *fiber.Ctx) error {
+	if err := me.keyService.UnverifyContact(getCurrentUserID(c), c.Params("username")); err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to unverify contact: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *KeyHandler) HandleGetContactVerification(c *fiber.Ctx) error {
+	verification, err := me.keyService.GetContactVerification(getCurrentUserID(c), c.Params("username"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to get contact verification: %w", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":       verification.Status,
+		"verifiedAt":   verification.VerifiedAt,
+		"keyChangedAt": verification.KeyChangedAt,
+	})
+}
@@ -14,6 +14,18 @@ func WithLogging(logger *slog.Logger) func(c *fiber.Ctx) error {
 		duration := time.Since(start)
 		status := c.Response().StatusCode()
 		method := c.Method()
+
+		if anonymous, _ := c.Locals("logging.anonymous").(bool); anonymous {
+			logger.Info("request",
+				"took", duration,
+				"method", method,
+				"route", c.Route().Path,
+				"status", status,
+				"error", err,
+			)
+			return err
+		}
+
 		path := c.Path()
 		ip := c.IP()
 
@@ -29,3 +41,11 @@ func WithLogging(logger *slog.Logger) func(c *fiber.Ctx) error {
 		return err
 	}
 }
+
+// WithAnonymousLogging keeps WithLogging from recording who made the request:
+// the client IP and the concrete path (which may name a user) are left out,
+// only the route pattern is logged.
+func WithAnonymousLogging(c *fiber.Ctx) error {
+	c.Locals("logging.anonymous", true)
+	return c.Next()
+}
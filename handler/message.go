@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/message"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type MessageHandler struct {
+	messageService *message.MessageService
+}
+
+func NewMessageHandler(messageService *message.MessageService) *MessageHandler {
+	return &MessageHandler{
+		messageService: messageService,
+	}
+}
+
+type envelopeRequest struct {
+	DeviceID uuid.UUID `json:"deviceId"`
+	Content  []byte    `json:"content"`
+}
+
+type sendMessageRequest struct {
+	ClientMessageID    uuid.UUID         `json:"clientMessageId"`
+	Kind               string            `json:"kind"`
+	ReferenceMessageID *uuid.UUID        `json:"referenceMessageId"`
+	AttachmentIDs      []uuid.UUID       `json:"attachmentIds"`
+	Envelopes          []envelopeRequest `json:"envelopes"`
+}
+
+func (me *sendMessageRequest) envelopeParams() []message.EnvelopeParams {
+	envelopes := make([]message.EnvelopeParams, 0, len(me.Envelopes))
+	for _, envelope := range me.Envelopes {
+		envelopes = append(envelopes, message.EnvelopeParams{
+			DeviceID: envelope.DeviceID,
+			Content:  envelope.Content,
+		})
+	}
+	return envelopes
+}
+
+// handleSendError maps the errors shared by the send endpoints.
+func handleSendError(c *fiber.Ctx, err error) error {
+	var mismatch *message.MismatchedDevicesError
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(errs)
+		}
+		return fmt.Errorf("failed to exctract validation errors")
+	case errors.As(err, &mismatch):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"missing": mismatch.Missing,
+			"extra":   mismatch.Extra,
+		})
+	case errors.Is(err, service.ErrNotFound):
+		return fiber.ErrNotFound
+	case errors.Is(err, service.ErrUnauthorized):
+		return fiber.ErrUnauthorized
+	case errors.Is(err, service.ErrReadReceiptsDisabled):
+		return fiber.NewError(fiber.StatusForbidden, "read receipts are disabled")
+	case errors.Is(err, service.ErrForbidden):
+		return fiber.ErrForbidden
+	case errors.Is(err, service.ErrEditWindowExpired):
+		return fiber.NewError(fiber.StatusForbidden, "message can no longer be edited or deleted")
+	}
+	return fmt.Errorf("failed to send message: %w", err)
+}
+
+func (me *MessageHandler) HandleSendMessage(c *fiber.Ctx) error {
+	conversationID, err := uuid.Parse(c.Params("conversationID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	var req sendMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	params := message.SendMessageParams{
+		UserID:          getCurrentUserID(c),
+		DeviceID:        getCurrentDeviceID(c),
+		ConversationID:  conversationID,
+		ClientMessageID: req.ClientMessageID,
+		Kind:            req.Kind,
+		AttachmentIDs:   req.AttachmentIDs,
+		Envelopes:       req.envelopeParams(),
+	}
+	if req.ReferenceMessageID != nil {
+		params.ReferenceMessageID = uuid.NullUUID{UUID: *req.ReferenceMessageID, Valid: true}
+	}
+
+	sent, created, err := me.messageService.SendMessage(params)
+	if err != nil {
+		return handleSendError(c, err)
+	}
+
+	status := fiber.StatusOK
+	if created {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"id":              sent.ID,
+		"clientMessageId": sent.ClientMessageID,
+		"seq":             sent.Seq,
+		"createdAt":       sent.CreatedAt,
+	})
+}
+
+func (me *MessageHandler) HandleSendSealedMessage(c *fiber.Ctx) error {
+	accessKey, err := base64.StdEncoding.DecodeString(c.Get("Delivery-Access-Key"))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+
+	var req sendMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	if err := me.messageService.SendSealedMessage(message.SendSealedMessageParams{
+		RecipientUsername: c.Params("username"),
+		DeliveryAccessKey: accessKey,
+		Envelopes:         req.envelopeParams(),
+	}); err != nil {
+		return handleSendError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+func (me *MessageHandler) HandleListMailbox(c *fiber.Ctx) error {
+	envelopes, err := me.messageService.ListMailbox(getCurrentDeviceID(c))
+	if err != nil {
+		return fmt.Errorf("failed to list mailbox: %w", err)
+	}
+
+	return c.JSON(envelopes)
+}
+
+type syncMessageResponse struct {
+	ID                 uuid.UUID  `json:"id"`
+	ClientMessageID    uuid.UUID  `json:"clientMessageId"`
+	Seq                int64      `json:"seq"`
+	Kind               string     `json:"kind"`
+	ReferenceMessageID *uuid.UUID `json:"referenceMessageId"`
+	SenderUserID       uuid.UUID  `json:"senderUserId"`
+	SenderDeviceID     *uuid.UUID `json:"senderDeviceId"`
+	EnvelopeID         *uuid.UUID `json:"envelopeId"`
+	Content            []byte     `json:"content"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	ExpiresAt          *time.Time `json:"expiresAt"`
+}
+
+func (me *MessageHandler) HandleSyncMessages(c *fiber.Ctx) error {
+	conversationID, err := uuid.Parse(c.Params("conversationID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	afterSeq, err := parseUintQuery(c, "after-seq")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"after-seq": "must be a non-negative integer",
+		})
+	}
+
+	result, err := me.messageService.SyncMessages(message.SyncMessagesParams{
+		UserID:         getCurrentUserID(c),
+		DeviceID:       getCurrentDeviceID(c),
+		ConversationID: conversationID,
+		AfterSeq:       int64(afterSeq),
+		Limit:          c.QueryInt("limit"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to sync messages: %w", err)
+	}
+
+	messages := make([]syncMessageResponse, 0, len(result.Messages))
+	for _, msg := range result.Messages {
+		response := syncMessageResponse{
+			ID:                 msg.ID,
+			ClientMessageID:    msg.ClientMessageID,
+			Seq:                msg.Seq,
+			Kind:               msg.Kind,
+			ReferenceMessageID: nullUUIDPtr(msg.ReferenceMessageID),
+			SenderUserID:       msg.SenderUserID,
+			SenderDeviceID:     nullUUIDPtr(msg.SenderDeviceID),
+			EnvelopeID:         nullUUIDPtr(msg.EnvelopeID),
+			Content:            msg.Content,
+			CreatedAt:          msg.CreatedAt,
+		}
+		if msg.ExpiresAt.Valid {
+			response.ExpiresAt = &msg.ExpiresAt.Time
+		}
+		messages = append(messages, response)
+	}
+
+	return c.JSON(fiber.Map{
+		"messages": messages,
+		"lastSeq":  result.LastSeq,
+	})
+}
+
+func (me *MessageHandler) HandleAckEnvelope(c *fiber.Ctx) error {
+	envelopeID, err := uuid.Parse(c.Params("envelopeID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	if err := me.messageService.AckEnvelope(getCurrentDeviceID(c), envelopeID); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to ack envelope: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+type deliveryResponse struct {
+	RecipientUserID   uuid.UUID  `json:"recipientUserId"`
+	RecipientDeviceID uuid.UUID  `json:"recipientDeviceId"`
+	DeliveredAt       *time.Time `json:"deliveredAt"`
+	ReadAt            *time.Time `json:"readAt"`
+}
+
+func (me *MessageHandler) HandleGetMessageStatus(c *fiber.Ctx) error {
+	conversationID, err := uuid.Parse(c.Params("conversationID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	messageID, err := uuid.Parse(c.Params("messageID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	deliveries, err := me.messageService.GetMessageStatus(getCurrentUserID(c), conversationID, messageID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to get message status: %w", err)
+	}
+
+	result := make([]deliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		response := deliveryResponse{
+			RecipientUserID:   delivery.RecipientUserID,
+			RecipientDeviceID: delivery.RecipientDeviceID,
+		}
+		if delivery.DeliveredAt.Valid {
+			response.DeliveredAt = &delivery.DeliveredAt.Time
+		}
+		if delivery.ReadAt.Valid {
+			response.ReadAt = &delivery.ReadAt.Time
+		}
+		result = append(result, response)
+	}
+
+	return c.JSON(result)
+}
+
+func (me *MessageHandler) HandleGetSenderCertificate(c *fiber.Ctx) error {
+	certificate, err := me.messageService.IssueSenderCertificate(getCurrentUserID(c), getCurrentDeviceID(c))
+	if err != nil {
+		return fmt.Errorf("failed to issue sender certificate: %w", err)
+	}
+
+	return c.JSON(certificate)
+}
+
+func (me *MessageHandler) HandleGetSenderCertificatePublicKey(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"publicKey": []byte(me.messageService.SenderCertificatePublicKey()),
+	})
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/auth"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type MFAChallengeResponse struct {
+	ChallengeID uuid.UUID `json:"challengeId"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Methods     []string  `json:"methods"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth:// provisioning URI to show as a QR code.
+	URI string `json:"uri"`
+}
+
+type RecoveryCodesResponse struct {
+	// RecoveryCodes are only set when new ones were created.
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+type MFAStatusResponse struct {
+	TOTPEnabled   bool  `json:"totpEnabled"`
+	Passkeys      int64 `json:"passkeys"`
+	RecoveryCodes int64 `json:"recoveryCodes"`
+}
+
+// mfaCodeError answers for wrong, or too many wrong, MFA codes and for
+// enrollments in the wrong state, anything else fails the request.
+func mfaCodeError(c *fiber.Ctx, err error, action string) error {
+	var locked *auth.LockedError
+	switch {
+	case errors.As(err, &locked):
+		return tooManyRequests(c, locked.RetryAfter)
+	case errors.Is(err, service.ErrUnauthorized):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"code": "invalid code",
+		})
+	case errors.Is(err, service.ErrConflict):
+		return fiber.ErrConflict
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+func (me *AuthHandler) HandleLoginMFA(c *fiber.Ctx) error {
+	var (
+		challengeStr = c.FormValue("challenge")
+		code         = c.FormValue("code")
+	)
+
+	challengeID, err := uuid.Parse(challengeStr)
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+
+	session, err := me.authService.CompleteMFALogin(auth.CompleteMFALoginParams{
+		ChallengeID: challengeID,
+		Code:        code,
+		Client:      getAuditClient(c),
+	})
+	if err != nil {
+		var locked *auth.LockedError
+		switch {
+		case errors.As(err, &locked):
+			return tooManyRequests(c, locked.RetryAfter)
+		case errors.Is(err, service.ErrUnauthorized):
+			return fiber.ErrUnauthorized
+		}
+		return fmt.Errorf("failed to complete mfa login: %w", err)
+	}
+
+	me.loggedIn(c, session)
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *AuthHandler) HandleGetMFAStatus(c *fiber.Ctx) error {
+	status, err := me.authService.GetMFAStatus(getCurrentUserCredentialsID(c))
+	if err != nil {
+		return fmt.Errorf("failed to get mfa status: %w", err)
+	}
+
+	return c.JSON(MFAStatusResponse{
+		TOTPEnabled:   status.TOTPEnabled,
+		Passkeys:      status.Passkeys,
+		RecoveryCodes: status.RecoveryCodes,
+	})
+}
+
+// HandleBeginTOTPEnrollment takes the re-authentication of getReauthParams.
+func (me *AuthHandler) HandleBeginTOTPEnrollment(c *fiber.Ctx) error {
+	reauth, ok, err := getReauthParams(c)
+	if !ok {
+		return err
+	}
+
+	enrollment, err := me.authService.BeginTOTPEnrollment(auth.BeginTOTPEnrollmentParams{
+		CredentialsID: getCurrentUserCredentialsID(c),
+		Reauth:        reauth,
+		Client:        getAuditClient(c),
+	})
+	if err != nil {
+		if handled, err := handleReauthError(c, err); handled {
+			return err
+		}
+		if errors.Is(err, service.ErrConflict) {
+			return c.Status(fiber.StatusConflict).SendString("totp is already enabled")
+		}
+		return fmt.Errorf("failed to begin totp enrollment: %w", err)
+	}
+
+	return c.JSON(TOTPEnrollmentResponse{
+		Secret: enrollment.Secret,
+		URI:    enrollment.URI,
+	})
+}
+
+// HandleConfirmTOTP takes the code of the new secret, and the
+// re-authentication of getReauthParams. TOTP isn't enabled yet, so the code
+// isn't checked as a second factor of the re-authentication.
+func (me *AuthHandler) HandleConfirmTOTP(c *fiber.Ctx) error {
+	reauth, ok, err := getReauthParams(c)
+	if !ok {
+		return err
+	}
+
+	codes, err := me.authService.ConfirmTOTP(auth.ConfirmTOTPParams{
+		CredentialsID: getCurrentUserCredentialsID(c),
+		Code:          c.FormValue("code"),
+		Reauth:        reauth,
+		Client:        getAuditClient(c),
+	})
+	if err != nil {
+		if handled, err := handleReauthError(c, err); handled {
+			return err
+		}
+		if errors.Is(err, service.ErrConflict) {
+			return fiber.ErrConflict
+		}
+		return fmt.Errorf("failed to confirm totp: %w", err)
+	}
+
+	return c.JSON(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (me *AuthHandler) HandleDisableTOTP(c *fiber.Ctx) error {
+	err := me.authService.DisableTOTP(getCurrentUserCredentialsID(c), c.FormValue("code"), getAuditClient(c))
+	if err != nil {
+		return mfaCodeError(c, err, "disable totp")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *AuthHandler) HandleRegenerateRecoveryCodes(c *fiber.Ctx) error {
+	codes, err := me.authService.RegenerateRecoveryCodes(getCurrentUserCredentialsID(c), c.FormValue("code"), getAuditClient(c))
+	if err != nil {
+		return mfaCodeError(c, err, "regenerate recovery codes")
+	}
+
+	return c.JSON(RecoveryCodesResponse{RecoveryCodes: codes})
+}
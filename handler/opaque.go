@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/auth"
+	"chatapp/service/user"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// OPAQUE messages are base64url encoded form values. The export key a client
+// gets from registering and logging in never leaves it, clients derive the
+// key encrypting their key backups from it.
+
+type OpaqueRegistrationResponse struct {
+	RegistrationID uuid.UUID `json:"registrationId,omitempty"`
+	Response       string    `json:"response"`
+}
+
+type OpaqueLoginResponse struct {
+	LoginID uuid.UUID `json:"loginId"`
+	KE2     string    `json:"ke2"`
+}
+
+// opaqueFormValue decodes the form value, answering the client itself if it
+// isn't valid.
+func opaqueFormValue(c *fiber.Ctx, key string) ([]byte, bool, error) {
+	value, err := base64.RawURLEncoding.DecodeString(c.FormValue(key))
+	if err != nil || len(value) == 0 {
+		return nil, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			key: "must be base64url",
+		})
+	}
+	return value, true, nil
+}
+
+func (me *AuthHandler) HandleBeginOpaqueRegistration(c *fiber.Ctx) error {
+	request, ok, err := opaqueFormValue(c, "registration-request")
+	if !ok {
+		return err
+	}
+
+	registration, err := me.authService.BeginOpaqueRegistration(request)
+	if err != nil {
+		if errors.Is(err, service.ErrOpaque) {
+			return c.Status(fiber.StatusBadRequest).SendString("invalid registration request")
+		}
+		return fmt.Errorf("failed to begin opaque registration: %w", err)
+	}
+
+	return c.JSON(OpaqueRegistrationResponse{
+		RegistrationID: registration.ID,
+		Response:       base64.RawURLEncoding.EncodeToString(registration.Response),
+	})
+}
+
+// HandleFinishOpaqueRegistration is HandleRegister with a registration record
+// instead of a password.
+func (me *AuthHandler) HandleFinishOpaqueRegistration(c *fiber.Ctx) error {
+	var (
+		name     = strings.TrimSpace(c.FormValue("name"))
+		username = strings.TrimSpace(c.FormValue("username"))
+		email    = strings.TrimSpace(c.FormValue("email"))
+	)
+
+	registrationID, err := uuid.Parse(c.FormValue("registration-id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"registration-id": "must be a valid uuid",
+		})
+	}
+	record, ok, err := opaqueFormValue(c, "record")
+	if !ok {
+		return err
+	}
+
+	credentialsID, err := me.authService.FinishOpaqueRegistration(auth.FinishOpaqueRegistrationParams{
+		RegistrationID: registrationID,
+		Email:          email,
+		Record:         record,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrOpaque):
+			return c.Status(fiber.StatusBadRequest).SendString("invalid record")
+		case errors.Is(err, service.ErrUnauthorized):
+			return c.Status(fiber.StatusBadRequest).SendString("invalid or expired registration")
+		case errors.Is(err, service.ErrEmailConflict):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"email": "email already exists",
+			})
+		}
+		return fmt.Errorf("failed to register user: %w", err)
+	}
+
+	if err := me.userService.CreateUser(user.CreateUserParams{
+		Name:          name,
+		Username:      username,
+		CredentialsID: credentialsID,
+	}); err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrUsernameConflict):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"username": "username already exists",
+			})
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+// HandleBeginOpaqueUpgrade moves an account that logs in with a password to
+// OPAQUE, the current password is sent one last time.
+func (me *AuthHandler) HandleBeginOpaqueUpgrade(c *fiber.Ctx) error {
+	request, ok, err := opaqueFormValue(c, "registration-request")
+	if !ok {
+		return err
+	}
+
+	upgrade, err := me.authService.BeginOpaqueUpgrade(getCurrentUserCredentialsID(c), c.FormValue("password"), request)
+	if err != nil {
+		var locked *auth.LockedError
+		switch {
+		case errors.As(err, &locked):
+			return tooManyRequests(c, locked.RetryAfter)
+		case errors.Is(err, service.ErrOpaque):
+			return c.Status(fiber.StatusBadRequest).SendString("invalid registration request")
+		case errors.Is(err, service.ErrUnauthorized):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"password": "wrong password",
+			})
+		case errors.Is(err, service.ErrConflict):
+			return c.Status(fiber.StatusConflict).SendString("already using opaque")
+		}
+		return fmt.Errorf("failed to begin opaque upgrade: %w", err)
+	}
+
+	return c.JSON(OpaqueRegistrationResponse{
+		RegistrationID: upgrade.ID,
+		Response:       base64.RawURLEncoding.EncodeToString(upgrade.Response),
+	})
+}
+
+// HandleFinishOpaqueUpgrade takes the registration-id returned by
+// HandleBeginOpaqueUpgrade.
+func (me *AuthHandler) HandleFinishOpaqueUpgrade(c *fiber.Ctx) error {
+	upgradeID, err := uuid.Parse(c.FormValue("registration-id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"registration-id": "must be a valid uuid",
+		})
+	}
+	record, ok, err := opaqueFormValue(c, "record")
+	if !ok {
+		return err
+	}
+
+	if err := me.authService.FinishOpaqueUpgrade(auth.FinishOpaqueUpgradeParams{
+		CredentialsID: getCurrentUserCredentialsID(c),
+		UpgradeID:     upgradeID,
+		Record:        record,
+		Client:        getAuditClient(c),
+	}); err != nil {
+		switch {
+		case errors.Is(err, service.ErrOpaque):
+			return c.Status(fiber.StatusBadRequest).SendString("invalid record")
+		case errors.Is(err, service.ErrUnauthorized):
+			return c.Status(fiber.StatusBadRequest).SendString("invalid or expired upgrade")
+		case errors.Is(err, service.ErrConflict):
+			return c.Status(fiber.StatusConflict).SendString("already using opaque")
+		}
+		return fmt.Errorf("failed to finish opaque upgrade: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *AuthHandler) HandleBeginOpaqueLogin(c *fiber.Ctx) error {
+	email := strings.TrimSpace(c.FormValue("email"))
+	ke1, ok, err := opaqueFormValue(c, "ke1")
+	if !ok {
+		return err
+	}
+
+	login, err := me.authService.BeginOpaqueLogin(email, ke1)
+	if err != nil {
+		var locked *auth.LockedError
+		switch {
+		case errors.As(err, &locked):
+			me.auditService.RecordLoginFailure(email, true, getAuditClient(c))
+			return tooManyRequests(c, locked.RetryAfter)
+		case errors.Is(err, service.ErrOpaque):
+			return c.Status(fiber.StatusBadRequest).SendString("invalid ke1")
+		}
+		return fmt.Errorf("failed to begin opaque login: %w", err)
+	}
+
+	return c.JSON(OpaqueLoginResponse{
+		LoginID: login.ID,
+		KE2:     base64.RawURLEncoding.EncodeToString(login.KE2),
+	})
+}
+
+// HandleFinishOpaqueLogin answers like HandleLogin.
+func (me *AuthHandler) HandleFinishOpaqueLogin(c *fiber.Ctx) error {
+	email := strings.TrimSpace(c.FormValue("email"))
+	loginID, err := uuid.Parse(c.FormValue("login-id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"login-id": "must be a valid uuid",
+		})
+	}
+	ke3, ok, err := opaqueFormValue(c, "ke3")
+	if !ok {
+		return err
+	}
+
+	session, err := me.authService.FinishOpaqueLogin(auth.FinishOpaqueLoginParams{
+		LoginID: loginID,
+		Email:   email,
+		KE3:     ke3,
+		Client:  getAuditClient(c),
+	})
+	if err != nil {
+		return me.loginError(c, email, err)
+	}
+
+	me.loggedIn(c, session)
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/auth"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PasskeyCeremonyResponse struct {
+	CeremonyID uuid.UUID `json:"ceremonyId"`
+	// Options go to navigator.credentials.create or get.
+	Options any `json:"options"`
+}
+
+type PasskeyResponse struct {
+	// ID is the base64url encoded credential ID.
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	Synced     bool       `json:"synced"`
+}
+
+type PasskeyRegistrationResponse struct {
+	Passkey PasskeyResponse `json:"passkey"`
+	// RecoveryCodes are only set for the account's first second factor.
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+func newPasskeyResponse(passkey auth.Passkey) PasskeyResponse {
+	return PasskeyResponse{
+		ID:         base64.RawURLEncoding.EncodeToString(passkey.ID),
+		Name:       passkey.Name,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt,
+		Synced:     passkey.Synced,
+	}
+}
+
+// HandleBeginPasskeyRegistration takes the re-authentication of
+// getReauthParams.
+func (me *AuthHandler) HandleBeginPasskeyRegistration(c *fiber.Ctx) error {
+	reauth, ok, err := getReauthParams(c)
+	if !ok {
+		return err
+	}
+
+	ceremony, err := me.authService.BeginPasskeyRegistration(auth.BeginPasskeyRegistrationParams{
+		CredentialsID: getCurrentUserCredentialsID(c),
+		Reauth:        reauth,
+		Client:        getAuditClient(c),
+	})
+	if err != nil {
+		if handled, err := handleReauthError(c, err); handled {
+			return err
+		}
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			return c.Status(fiber.StatusConflict).SendString("too many passkeys")
+		}
+		return fmt.Errorf("failed to begin passkey registration: %w", err)
+	}
+
+	return c.JSON(PasskeyCeremonyResponse{
+		CeremonyID: ceremony.ID,
+		Options:    ceremony.Options,
+	})
+}
+
+// HandleFinishPasskeyRegistration takes the PublicKeyCredential as the JSON
+// body, and the ceremony ID and passkey name as query parameters.
+func (me *AuthHandler) HandleFinishPasskeyRegistration(c *fiber.Ctx) error {
+	ceremonyID, err := uuid.Parse(c.Query("ceremony-id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"ceremony-id": "must be a valid uuid",
+		})
+	}
+
+	passkey, codes, err := me.authService.FinishPasskeyRegistration(auth.FinishPasskeyRegistrationParams{
+		CredentialsID: getCurrentUserCredentialsID(c),
+		CeremonyID:    ceremonyID,
+		Name:          c.Query("name"),
+		Response:      c.Body(),
+		Client:        getAuditClient(c),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrUnauthorized):
+			return c.Status(fiber.StatusBadRequest).SendString("invalid or expired ceremony")
+		case errors.Is(err, service.ErrWebAuthn):
+			return c.Status(fiber.StatusBadRequest).SendString("invalid passkey")
+		case errors.Is(err, service.ErrConflict):
+			return c.Status(fiber.StatusConflict).SendString("passkey already registered")
+		}
+		return fmt.Errorf("failed to finish passkey registration: %w", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(PasskeyRegistrationResponse{
+		Passkey:       newPasskeyResponse(passkey),
+		RecoveryCodes: codes,
+	})
+}
+
+func (me *AuthHandler) HandleListPasskeys(c *fiber.Ctx) error {
+	passkeys, err := me.authService.ListPasskeys(getCurrentUserCredentialsID(c))
+	if err != nil {
+		return fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	res := make([]PasskeyResponse, len(passkeys))
+	for i, passkey := range passkeys {
+		res[i] = newPasskeyResponse(passkey)
+	}
+	return c.JSON(res)
+}
+
+// HandleDeletePasskey takes the re-authentication of getReauthParams.
+func (me *AuthHandler) HandleDeletePasskey(c *fiber.Ctx) error {
+	passkeyID, err := base64.RawURLEncoding.DecodeString(c.Params("passkeyID"))
+	if err != nil || len(passkeyID) == 0 {
+		return fiber.ErrNotFound
+	}
+	reauth, ok, err := getReauthParams(c)
+	if !ok {
+		return err
+	}
+
+	if err := me.authService.DeletePasskey(auth.DeletePasskeyParams{
+		CredentialsID: getCurrentUserCredentialsID(c),
+		PasskeyID:     passkeyID,
+		Reauth:        reauth,
+		Client:        getAuditClient(c),
+	}); err != nil {
+		if handled, err := handleReauthError(c, err); handled {
+			return err
+		}
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *AuthHandler) HandleBeginPasskeyMFA(c *fiber.Ctx) error {
+	challengeID, err := uuid.Parse(c.Query("challenge"))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+
+	options, err := me.authService.BeginPasskeyMFA(challengeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnauthorized):
+			return fiber.ErrUnauthorized
+		case errors.Is(err, service.ErrConflict):
+			return c.Status(fiber.StatusConflict).SendString("no passkeys")
+		}
+		return fmt.Errorf("failed to begin passkey mfa: %w", err)
+	}
+
+	return c.JSON(options)
+}
+
+// HandleFinishPasskeyMFA takes the PublicKeyCredential as the JSON body and
+// the MFA challenge as a query parameter.
+func (me *AuthHandler) HandleFinishPasskeyMFA(c *fiber.Ctx) error {
+	challengeID, err := uuid.Parse(c.Query("challenge"))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+
+	session, err := me.authService.FinishPasskeyMFALogin(auth.FinishPasskeyMFALoginParams{
+		ChallengeID: challengeID,
+		Response:    c.Body(),
+		Client:      getAuditClient(c),
+	})
+	if err != nil {
+		var locked *auth.LockedError
+		switch {
+		case errors.As(err, &locked):
+			return tooManyRequests(c, locked.RetryAfter)
+		case errors.Is(err, service.ErrUnauthorized):
+			return fiber.ErrUnauthorized
+		}
+		return fmt.Errorf("failed to finish passkey mfa: %w", err)
+	}
+
+	me.loggedIn(c, session)
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *AuthHandler) HandleBeginPasskeyLogin(c *fiber.Ctx) error {
+	ceremony, err := me.authService.BeginPasskeyLogin()
+	if err != nil {
+		return fmt.Errorf("failed to begin passkey login: %w", err)
+	}
+
+	return c.JSON(PasskeyCeremonyResponse{
+		CeremonyID: ceremony.ID,
+		Options:    ceremony.Options,
+	})
+}
+
+// HandleFinishPasskeyLogin takes the PublicKeyCredential as the JSON body and
+// the ceremony ID as a query parameter, and signs in like HandleLogin.
+func (me *AuthHandler) HandleFinishPasskeyLogin(c *fiber.Ctx) error {
+	ceremonyID, err := uuid.Parse(c.Query("ceremony-id"))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+
+	session, err := me.authService.FinishPasskeyLogin(auth.FinishPasskeyLoginParams{
+		CeremonyID: ceremonyID,
+		Response:   c.Body(),
+		Client:     getAuditClient(c),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnauthorized):
+			return fiber.ErrUnauthorized
+		case errors.Is(err, service.ErrEmailNotVerified):
+			return c.Status(fiber.StatusForbidden).SendString("email is not verified")
+		case errors.Is(err, service.ErrPasswordResetNeeded):
+			return c.Status(fiber.StatusForbidden).SendString("password reset required")
+		}
+		return fmt.Errorf("failed to finish passkey login: %w", err)
+	}
+
+	me.loggedIn(c, session)
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/presence"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PresenceHandler struct {
+	presenceService *presence.PresenceService
+}
+
+func NewPresenceHandler(presenceService *presence.PresenceService) *PresenceHandler {
+	return &PresenceHandler{
+		presenceService: presenceService,
+	}
+}
+
+func (me *PresenceHandler) HandleGetPresence(c *fiber.Ctx) error {
+	result, err := me.presenceService.GetPresence(getCurrentUserID(c), c.Params("username"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		case errors.Is(err, service.ErrForbidden):
+			return fiber.ErrForbidden
+		}
+		return fmt.Errorf("failed to get presence: %w", err)
+	}
+
+	return c.JSON(result)
+}
+
+// HandleSetTyping is the HTTP counterpart of the typing message clients can
+// send over the websocket.
+func (me *PresenceHandler) HandleSetTyping(c *fiber.Ctx) error {
+	conversationID, err := uuid.Parse(c.Params("conversationID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	typing, err := strconv.ParseBool(c.FormValue("typing"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"typing": "must be a boolean",
+		})
+	}
+
+	if err := me.presenceService.SetTyping(getCurrentUserID(c), conversationID, typing); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		case errors.Is(err, service.ErrThrottled):
+			return fiber.ErrTooManyRequests
+		}
+		return fmt.Errorf("failed to set typing: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/push"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PushHandler struct {
+	pushService *push.PushService
+}
+
+func NewPushHandler(pushService *push.PushService) *PushHandler {
+	return &PushHandler{
+		pushService: pushService,
+	}
+}
+
+func (me *PushHandler) HandleGetVAPIDPublicKey(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"publicKey": base64.RawURLEncoding.EncodeToString(me.pushService.VAPIDPublicKey()),
+	})
+}
+
+// pushSubscriptionRequest is the JSON form of the browser's PushSubscription,
+// with base64url encoded keys, plus the provider which defaults to webpush.
+// UnifiedPush subscriptions only have an endpoint.
+type pushSubscriptionRequest struct {
+	Provider string `json:"provider"`
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+func (me *PushHandler) HandleSubscribe(c *fiber.Ctx) error {
+	var req pushSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	p256dh, err := base64.RawURLEncoding.DecodeString(req.Keys.P256dh)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"p256dh": "must be base64url encoded",
+		})
+	}
+	auth, err := base64.RawURLEncoding.DecodeString(req.Keys.Auth)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"auth": "must be base64url encoded",
+		})
+	}
+
+	if req.Provider == "" {
+		req.Provider = push.ProviderWebPush
+	}
+
+	if err := me.pushService.Subscribe(push.SubscribeParams{
+		DeviceID: getCurrentDeviceID(c),
+		Provider: req.Provider,
+		Endpoint: req.Endpoint,
+		P256dh:   p256dh,
+		Auth:     auth,
+	}); err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		}
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *PushHandler) HandleUnsubscribe(c *fiber.Ctx) error {
+	if err := me.pushService.Unsubscribe(getCurrentDeviceID(c)); err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"chatapp/service/ratelimit"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateKey picks what a policy counts hits by, an empty key skips the policy.
+type RateKey func(c *fiber.Ctx) string
+
+// ByIP counts hits per client IP.
+func ByIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// ByEmail counts hits per email form value.
+func ByEmail(c *fiber.Ctx) string {
+	email := strings.ToLower(strings.TrimSpace(c.FormValue("email")))
+	if email == "" {
+		return ""
+	}
+	return "email:" + email
+}
+
+// ByCredentialsID counts hits per account, it must come after WithSession.
+func ByCredentialsID(c *fiber.Ctx) string {
+	return "credentials:" + getCurrentUserCredentialsID(c).String()
+}
+
+type RateLimitHandler struct {
+	limiter *ratelimit.Limiter
+}
+
+func NewRateLimitHandler(limiter *ratelimit.Limiter) *RateLimitHandler {
+	return &RateLimitHandler{
+		limiter: limiter,
+	}
+}
+
+// tooManyRequests responds 429 with the seconds to wait in Retry-After.
+func tooManyRequests(c *fiber.Ctx, retryAfter time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(max(retryAfter, time.Second).Seconds()))))
+	return fiber.ErrTooManyRequests
+}
+
+// WithRateLimit counts a hit on the request's key under the policy, and
+// rejects the request once the limit is exceeded.
+func (me *RateLimitHandler) WithRateLimit(policy ratelimit.Policy, key RateKey) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		value := key(c)
+		if value == "" {
+			return c.Next()
+		}
+
+		ok, retryAfter, err := me.limiter.Allow(c.Context(), policy, value)
+		if err != nil {
+			return fmt.Errorf("failed to check rate limit: %w", err)
+		}
+		if !ok {
+			return tooManyRequests(c, retryAfter)
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"bufio"
+	"chatapp/config"
+	"chatapp/service/presence"
+	"chatapp/service/realtime"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// realtimeMaxMessageSize bounds what clients can send, typing indicators
+	// are tiny.
+	realtimeMaxMessageSize = 1024
+	realtimeWriteTimeout   = 10 * time.Second
+	realtimePongTimeout    = 60 * time.Second
+	realtimePingInterval   = realtimePongTimeout * 9 / 10
+)
+
+type RealtimeHandler struct {
+	dispatcher      *realtime.Dispatcher
+	presenceService *presence.PresenceService
+}
+
+func NewRealtimeHandler(dispatcher *realtime.Dispatcher, presenceService *presence.PresenceService) *RealtimeHandler {
+	return &RealtimeHandler{
+		dispatcher:      dispatcher,
+		presenceService: presenceService,
+	}
+}
+
+// clientMessage is what clients can send over the websocket.
+type clientMessage struct {
+	Type string `json:"type"`
+	// ConversationID and Typing are set for typing messages.
+	ConversationID uuid.UUID `json:"conversationId"`
+	Typing         bool      `json:"typing"`
+}
+
+// handleClientMessage acts on a message sent by the client. Invalid and
+// throttled messages are dropped, they are only hints for the peers.
+func (me *RealtimeHandler) handleClientMessage(userID uuid.UUID, data []byte) {
+	var msg clientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+
+	switch msg.Type {
+	case presence.EventTypeTyping:
+		me.presenceService.SetTyping(userID, msg.ConversationID, msg.Typing)
+	}
+}
+
+// getLastEventID returns the ID of the last event the client got, from the
+// Last-Event-ID header sent by SSE clients or the last-event-id query.
+func getLastEventID(c *fiber.Ctx) (uint64, error) {
+	if value := c.Get("Last-Event-ID"); value != "" {
+		return strconv.ParseUint(value, 10, 64)
+	}
+	return parseUintQuery(c, "last-event-id")
+}
+
+func badLastEventID(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"last-event-id": "must be a non-negative integer",
+	})
+}
+
+// WithUpgrade rejects requests that aren't websocket upgrades.
+func (me *RealtimeHandler) WithUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	return c.Next()
+}
+
+// HandleWebSocket pushes the events of the current device over a websocket,
+// and takes typing indicators from the client.
+func (me *RealtimeHandler) HandleWebSocket() fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		userID := conn.Locals("user.userID").(uuid.UUID)
+		lastEventID, _ := strconv.ParseUint(conn.Query("last-event-id"), 10, 64)
+		sub := me.dispatcher.Subscribe(userID, conn.Locals("keys.deviceID").(uuid.UUID), lastEventID)
+		me.presenceService.Connected(userID)
+		defer func() {
+			sub.Close()
+			me.presenceService.Disconnected(userID)
+		}()
+
+		// the reader keeps the read deadline going on pongs, handles client
+		// messages and notices when the client goes away.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			conn.SetReadLimit(realtimeMaxMessageSize)
+			conn.SetReadDeadline(time.Now().Add(realtimePongTimeout))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(realtimePongTimeout))
+			})
+			for {
+				messageType, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				if messageType == websocket.TextMessage {
+					me.handleClientMessage(userID, data)
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(realtimePingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case event, ok := <-sub.Events:
+				if !ok {
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(realtimeWriteTimeout))
+					return
+				}
+				conn.SetWriteDeadline(time.Now().Add(realtimeWriteTimeout))
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(realtimeWriteTimeout)); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+// writeEvent writes the event in the text/event-stream format.
+func writeEvent(w *bufio.Writer, event realtime.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// HandleEventStream pushes the events of the current device as server-sent
+// events, resuming after the Last-Event-ID header.
+func (me *RealtimeHandler) HandleEventStream(c *fiber.Ctx) error {
+	lastEventID, err := getLastEventID(c)
+	if err != nil {
+		return badLastEventID(c)
+	}
+
+	// the context is recycled once the handler returns, so nothing of it can
+	// be used by the stream writer.
+	userID := getCurrentUserID(c)
+	sub := me.dispatcher.Subscribe(userID, getCurrentDeviceID(c), lastEventID)
+	me.presenceService.Connected(userID)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer func() {
+			sub.Close()
+			me.presenceService.Disconnected(userID)
+		}()
+
+		ticker := time.NewTicker(config.SSEHeartbeatInterval)
+		defer ticker.Stop()
+
+		// a failing write is the only way to notice the client went away.
+		for {
+			select {
+			case event, ok := <-sub.Events:
+				if !ok {
+					return
+				}
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if _, err := w.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// HandlePollEvents waits for the events of the current device published after
+// last-event-id and returns them, or an empty list after the timeout.
+func (me *RealtimeHandler) HandlePollEvents(c *fiber.Ctx) error {
+	lastEventID, err := getLastEventID(c)
+	if err != nil {
+		return badLastEventID(c)
+	}
+
+	timeout := config.LongPollTimeout
+	if seconds := c.QueryInt("timeout"); seconds > 0 && time.Duration(seconds)*time.Second < timeout {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	userID := getCurrentUserID(c)
+	sub := me.dispatcher.Subscribe(userID, getCurrentDeviceID(c), lastEventID)
+	me.presenceService.Connected(userID)
+	defer func() {
+		sub.Close()
+		me.presenceService.Disconnected(userID)
+	}()
+
+	events := []realtime.Event{}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case event, ok := <-sub.Events:
+		if ok {
+			events = append(events, event)
+		}
+	case <-timer.C:
+	}
+
+	// whatever else is already there goes along in the same response.
+drain:
+	for len(events) > 0 {
+		select {
+		case event, ok := <-sub.Events:
+			if !ok {
+				break drain
+			}
+			events = append(events, event)
+		default:
+			break drain
+		}
+	}
+
+	lastEventID = max(lastEventID, sub.LastEventID)
+	if len(events) > 0 {
+		lastEventID = events[len(events)-1].ID
+	}
+	return c.JSON(fiber.Map{
+		"events":      events,
+		"lastEventId": lastEventID,
+	})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/auth"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// getReauthParams reads the password, or for OPAQUE accounts the login-id and
+// ke3 of a login started at /login/opaque, and a TOTP or recovery code if TOTP
+// is enabled.
+func getReauthParams(c *fiber.Ctx) (auth.ReauthParams, bool, error) {
+	params := auth.ReauthParams{
+		Password: c.FormValue("password"),
+		Code:     c.FormValue("code"),
+	}
+	if loginID := c.FormValue("login-id"); loginID != "" {
+		var err error
+		if params.OpaqueLoginID, err = uuid.Parse(loginID); err != nil {
+			return params, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"login-id": "must be a valid uuid",
+			})
+		}
+		ke3, ok, err := opaqueFormValue(c, "ke3")
+		if !ok {
+			return params, false, err
+		}
+		params.KE3 = ke3
+	}
+	return params, true, nil
+}
+
+// handleReauthError responds to the errors of a failed re-authentication, and
+// reports whether err was one.
+func handleReauthError(c *fiber.Ctx, err error) (bool, error) {
+	var locked *auth.LockedError
+	switch {
+	case errors.As(err, &locked):
+		return true, tooManyRequests(c, locked.RetryAfter)
+	case errors.Is(err, service.ErrMFARequired):
+		return true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"code": "invalid code",
+		})
+	case errors.Is(err, service.ErrUnauthorized):
+		return true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"password": "wrong password",
+		})
+	}
+	return false, nil
+}
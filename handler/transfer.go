@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"chatapp/config"
+	"chatapp/service"
+	"chatapp/service/transfer"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type TransferHandler struct {
+	transferService *transfer.TransferService
+}
+
+func NewTransferHandler(transferService *transfer.TransferService) *TransferHandler {
+	return &TransferHandler{
+		transferService: transferService,
+	}
+}
+
+// HandleCreateTransfer starts sending history from the current device. The
+// archive key is encrypted to the target device's identity key by the client.
+func (me *TransferHandler) HandleCreateTransfer(c *fiber.Ctx) error {
+	targetDeviceID, err := uuid.Parse(c.FormValue("target-device-id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"target-device-id": "must be a valid uuid",
+		})
+	}
+	encryptedKey, err := base64.StdEncoding.DecodeString(c.FormValue("encrypted-key"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"encrypted-key": "must be base64 encoded",
+		})
+	}
+	chunkCount, err := strconv.Atoi(c.FormValue("chunk-count"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"chunk-count": "must be an integer",
+		})
+	}
+	totalSize, err := strconv.ParseInt(c.FormValue("total-size"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"total-size": "must be an integer",
+		})
+	}
+
+	created, err := me.transferService.CreateTransfer(transfer.CreateTransferParams{
+		UserID:         getCurrentUserID(c),
+		SourceDeviceID: getCurrentDeviceID(c),
+		TargetDeviceID: targetDeviceID,
+		EncryptedKey:   encryptedKey,
+		ChunkCount:     chunkCount,
+		TotalSize:      totalSize,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrQuotaExceeded):
+			return c.Status(fiber.StatusConflict).SendString("too many open history transfers")
+		}
+		return fmt.Errorf("failed to create history transfer: %w", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func (me *TransferHandler) HandleListTransfers(c *fiber.Ctx) error {
+	transfers, err := me.transferService.ListTransfers(getCurrentDeviceID(c))
+	if err != nil {
+		return fmt.Errorf("failed to list history transfers: %w", err)
+	}
+
+	return c.JSON(transfers)
+}
+
+func (me *TransferHandler) HandleGetTransfer(c *fiber.Ctx) error {
+	transferID, err := uuid.Parse(c.Params("transferID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	found, err := me.transferService.GetTransfer(getCurrentDeviceID(c), transferID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to get history transfer: %w", err)
+	}
+
+	return c.JSON(found)
+}
+
+// HandleUploadChunk takes the encrypted chunk as the raw request body.
+func (me *TransferHandler) HandleUploadChunk(c *fiber.Ctx) error {
+	transferID, err := uuid.Parse(c.Params("transferID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	size := int64(c.Request().Header.ContentLength())
+	if size < 0 {
+		return fiber.ErrLengthRequired
+	}
+	if size > config.MaxHistoryTransferChunkSize {
+		return fiber.ErrRequestEntityTooLarge
+	}
+
+	if err := me.transferService.UploadChunk(transfer.UploadChunkParams{
+		DeviceID:   getCurrentDeviceID(c),
+		TransferID: transferID,
+		Index:      index,
+		Size:       size,
+		Body:       requestBody(c),
+	}); err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		case errors.Is(err, service.ErrConflict):
+			return c.Status(fiber.StatusConflict).SendString("chunk already uploaded or transfer not uploading")
+		case errors.Is(err, service.ErrQuotaExceeded):
+			return fiber.NewError(fiber.StatusRequestEntityTooLarge, "chunks exceed the transfer's total size")
+		}
+		return fmt.Errorf("failed to upload history transfer chunk: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+func (me *TransferHandler) HandleDownloadChunk(c *fiber.Ctx) error {
+	transferID, err := uuid.Parse(c.Params("transferID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	body, size, err := me.transferService.OpenChunk(getCurrentDeviceID(c), transferID, index)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrNotFound
+		}
+		return fmt.Errorf("failed to open history transfer chunk: %w", err)
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+	// fiber closes the body once it's sent.
+	return c.SendStream(body, int(size))
+}
+
+// HandleCompleteTransfer is sent by the target device once it imported the
+// history.
+func (me *TransferHandler) HandleCompleteTransfer(c *fiber.Ctx) error {
+	transferID, err := uuid.Parse(c.Params("transferID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	if err := me.transferService.CompleteTransfer(getCurrentDeviceID(c), transferID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		case errors.Is(err, service.ErrConflict):
+			return c.Status(fiber.StatusConflict).SendString("transfer is not ready")
+		}
+		return fmt.Errorf("failed to complete history transfer: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *TransferHandler) HandleCancelTransfer(c *fiber.Ctx) error {
+	transferID, err := uuid.Parse(c.Params("transferID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	if err := me.transferService.CancelTransfer(getCurrentDeviceID(c), transferID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			return fiber.ErrNotFound
+		case errors.Is(err, service.ErrConflict):
+			return c.Status(fiber.StatusConflict).SendString("transfer is already over")
+		}
+		return fmt.Errorf("failed to cancel history transfer: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/transparency"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TransparencyHandler struct {
+	transparencyService *transparency.TransparencyService
+}
+
+func NewTransparencyHandler(transparencyService *transparency.TransparencyService) *TransparencyHandler {
+	return &TransparencyHandler{
+		transparencyService: transparencyService,
+	}
+}
+
+func (me *TransparencyHandler) HandleGetPublicKey(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"publicKey": []byte(me.transparencyService.PublicKey()),
+	})
+}
+
+func (me *TransparencyHandler) HandleGetTreeHead(c *fiber.Ctx) error {
+	treeSize, err := parseUintQuery(c, "tree-size")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"tree-size": "must be a non-negative integer",
+		})
+	}
+
+	treeHead, err := me.transparencyService.GetTreeHead(treeSize)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		}
+		return fmt.Errorf("failed to get tree head: %w", err)
+	}
+
+	return c.JSON(treeHead)
+}
+
+func (me *TransparencyHandler) HandleGetInclusionProof(c *fiber.Ctx) error {
+	leafIndex, err := strconv.ParseUint(c.Params("leafIndex"), 10, 64)
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	treeSize, err := parseUintQuery(c, "tree-size")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"tree-size": "must be a non-negative integer",
+		})
+	}
+
+	proof, err := me.transparencyService.GetInclusionProof(leafIndex, treeSize)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		}
+		return fmt.Errorf("failed to get inclusion proof: %w", err)
+	}
+
+	return c.JSON(proof)
+}
+
+func (me *TransparencyHandler) HandleGetConsistencyProof(c *fiber.Ctx) error {
+	first, err := parseUintQuery(c, "first")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"first": "must be a non-negative integer",
+		})
+	}
+
+	second, err := parseUintQuery(c, "second")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"second": "must be a non-negative integer",
+		})
+	}
+
+	proof, err := me.transparencyService.GetConsistencyProof(first, second)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		}
+		return fmt.Errorf("failed to get consistency proof: %w", err)
+	}
+
+	return c.JSON(proof)
+}
+
+func (me *TransparencyHandler) HandleGetMonitorReport(c *fiber.Ctx) error {
+	report, err := me.transparencyService.GetMonitorReport(getCurrentUserID(c))
+	if err != nil {
+		return fmt.Errorf("failed to get monitor report: %w", err)
+	}
+
+	return c.JSON(report)
+}
+
+// parseUintQuery parses an optional unsigned integer query param, returning 0
+// when it's missing.
+func parseUintQuery(c *fiber.Ctx, key string) (uint64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
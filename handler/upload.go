@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/attachment"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// tus 1.0 resumable uploads, see https://tus.io/protocols/resumable-upload.
+// Supported extensions are creation, expiration and termination. A completed
+// upload is downloaded like any other attachment, under the same ID.
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,expiration,termination"
+	tusContentType = "application/offset+octet-stream"
+)
+
+type UploadHandler struct {
+	attachmentService *attachment.AttachmentService
+}
+
+func NewUploadHandler(attachmentService *attachment.AttachmentService) *UploadHandler {
+	return &UploadHandler{
+		attachmentService: attachmentService,
+	}
+}
+
+// WithTusResumable rejects requests made with another version of the protocol.
+// OPTIONS requests are exempt so clients can discover the supported version.
+func (me *UploadHandler) WithTusResumable(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	if c.Method() != fiber.MethodOptions && c.Get("Tus-Resumable") != tusVersion {
+		c.Set("Tus-Version", tusVersion)
+		return fiber.ErrPreconditionFailed
+	}
+	return c.Next()
+}
+
+func (me *UploadHandler) HandleOptions(c *fiber.Ctx) error {
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	c.Set("Tus-Max-Size", strconv.FormatInt(config.MaxAttachmentSize, 10))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// handleUploadError maps the errors shared by the upload endpoints.
+func handleUploadError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return fiber.ErrNotFound
+	case errors.Is(err, service.ErrExpired):
+		return fiber.ErrGone
+	case errors.Is(err, service.ErrOffsetMismatch):
+		return fiber.ErrConflict
+	case errors.Is(err, service.ErrConflict):
+		return fiber.ErrLocked
+	}
+	return fmt.Errorf("failed to handle upload: %w", err)
+}
+
+func setUploadExpires(c *fiber.Ctx, upload repo.Attachment) {
+	if !upload.UploadedAt.Valid && upload.UploadExpiresAt.Valid {
+		c.Set("Upload-Expires", upload.UploadExpiresAt.Time.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (me *UploadHandler) HandleCreateUpload(c *fiber.Ctx) error {
+	if c.Get("Upload-Defer-Length") != "" {
+		return fiber.NewError(fiber.StatusBadRequest, "deferred upload length isn't supported")
+	}
+	size, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid Upload-Length")
+	}
+	if size > config.MaxAttachmentSize {
+		return fiber.ErrRequestEntityTooLarge
+	}
+
+	upload, err := me.attachmentService.CreateUpload(getCurrentUserID(c), size)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		case errors.Is(err, service.ErrQuotaExceeded):
+			return fiber.NewError(fiber.StatusInsufficientStorage, "attachment quota exceeded")
+		}
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	setUploadExpires(c, upload)
+	c.Location(fmt.Sprintf("%s/uploads/%s", config.AppBaseUrl, upload.ID))
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+func (me *UploadHandler) HandleGetUploadOffset(c *fiber.Ctx) error {
+	uploadID, err := uuid.Parse(c.Params("uploadID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	upload, err := me.attachmentService.GetUpload(getCurrentUserID(c), uploadID)
+	if err != nil {
+		return handleUploadError(err)
+	}
+
+	setUploadExpires(c, upload)
+	c.Set("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *UploadHandler) HandleAppendUpload(c *fiber.Ctx) error {
+	uploadID, err := uuid.Parse(c.Params("uploadID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	if c.Get(fiber.HeaderContentType) != tusContentType {
+		return fiber.ErrUnsupportedMediaType
+	}
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid Upload-Offset")
+	}
+
+	newOffset, err := me.attachmentService.AppendUpload(getCurrentUserID(c), uploadID, offset, requestBody(c))
+	if err != nil {
+		return handleUploadError(err)
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (me *UploadHandler) HandleTerminateUpload(c *fiber.Ctx) error {
+	uploadID, err := uuid.Parse(c.Params("uploadID"))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+
+	if err := me.attachmentService.TerminateUpload(getCurrentUserID(c), uploadID); err != nil {
+		return handleUploadError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
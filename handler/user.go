@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"chatapp/service"
+	"chatapp/service/user"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type UserHandler struct {
+	userService *user.UserService
+}
+
+func NewUserHandler(userService *user.UserService) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+	}
+}
+
+// WithUser loads the user owning the current session. It must run after
+// AuthHandler.WithSession.
+func (me *UserHandler) WithUser(c *fiber.Ctx) error {
+	currentUser, err := me.userService.GetUserByCredentialsID(getCurrentUserCredentialsID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return fiber.ErrUnauthorized
+		}
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	c.Locals("user.userID", currentUser.ID)
+	return c.Next()
+}
+
+func getCurrentUserID(c *fiber.Ctx) uuid.UUID {
+	return c.Locals("user.userID").(uuid.UUID)
+}
+
+type userResponse struct {
+	ID                  uuid.UUID `json:"id"`
+	Name                string    `json:"name"`
+	Username            string    `json:"username"`
+	ReadReceiptsEnabled bool      `json:"readReceiptsEnabled"`
+	PresenceVisibility  string    `json:"presenceVisibility"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+func (me *UserHandler) HandleGetMe(c *fiber.Ctx) error {
+	currentUser, err := me.userService.GetUserByCredentialsID(getCurrentUserCredentialsID(c))
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	return c.JSON(userResponse{
+		ID:                  currentUser.ID,
+		Name:                currentUser.Name,
+		Username:            currentUser.Username,
+		ReadReceiptsEnabled: currentUser.ReadReceiptsEnabled,
+		PresenceVisibility:  currentUser.PresenceVisibility,
+		CreatedAt:           currentUser.CreatedAt,
+	})
+}
+
+func (me *UserHandler) HandleSetDeliveryAccessKey(c *fiber.Ctx) error {
+	accessKey, err := base64.StdEncoding.DecodeString(c.FormValue("access-key"))
+	if err != nil || len(accessKey) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"access-key": "must be base64 encoded",
+		})
+	}
+
+	if err := me.userService.SetDeliveryAccessKey(getCurrentUserID(c), accessKey); err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		}
+		return fmt.Errorf("failed to set delivery access key: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *UserHandler) HandleDeleteDeliveryAccessKey(c *fiber.Ctx) error {
+	if err := me.userService.SetDeliveryAccessKey(getCurrentUserID(c), nil); err != nil {
+		return fmt.Errorf("failed to delete delivery access key: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *UserHandler) HandleSetReadReceipts(c *fiber.Ctx) error {
+	enabled, err := strconv.ParseBool(c.FormValue("enabled"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"enabled": "must be a boolean",
+		})
+	}
+
+	if err := me.userService.SetReadReceiptsEnabled(getCurrentUserID(c), enabled); err != nil {
+		return fmt.Errorf("failed to set read receipts: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (me *UserHandler) HandleSetPresenceVisibility(c *fiber.Ctx) error {
+	if err := me.userService.SetPresenceVisibility(getCurrentUserID(c), c.FormValue("visibility")); err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			if errs, ok := service.ExtractValidationErrorsMap(err); ok {
+				return c.Status(fiber.StatusBadRequest).JSON(errs)
+			}
+			return fmt.Errorf("failed to exctract validation errors")
+		}
+		return fmt.Errorf("failed to set presence visibility: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
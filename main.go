@@ -2,9 +2,25 @@ package main
 
 import (
 	"chatapp/app"
+	"chatapp/config"
 	"chatapp/db"
 	"chatapp/repo"
+	"chatapp/service/account"
+	"chatapp/service/attachment"
+	"chatapp/service/audit"
 	"chatapp/service/auth"
+	"chatapp/service/blob"
+	"chatapp/service/cluster"
+	"chatapp/service/conversation"
+	"chatapp/service/geoip"
+	"chatapp/service/keys"
+	"chatapp/service/message"
+	"chatapp/service/presence"
+	"chatapp/service/push"
+	"chatapp/service/ratelimit"
+	"chatapp/service/realtime"
+	"chatapp/service/transfer"
+	"chatapp/service/transparency"
 	"chatapp/service/user"
 	"context"
 	"log/slog"
@@ -23,15 +39,96 @@ func main() {
 	workersCtx, workersCancel := context.WithCancel(context.Background())
 	defer workersCancel()
 
-	authService := auth.NewAuthService(logger, repo.New(db.DB))
+	rateLimitStore, err := ratelimit.NewStore(logger, repo.New(db.DB), db.Valkey)
+	if err != nil {
+		logger.Error("failed to create rate limit store", "error", err)
+		os.Exit(1)
+	}
+	if store, ok := rateLimitStore.(*ratelimit.PostgresStore); ok {
+		store.StartCleanupWorker(workersCtx)
+	}
+	limiter := ratelimit.NewLimiter(rateLimitStore)
+
+	auditService := audit.NewAuditService(logger, db.DB, repo.New(db.DB))
+
+	var geo *geoip.Database
+	if config.GeoIPDatabasePath != "" {
+		if geo, err = geoip.Open(config.GeoIPDatabasePath); err != nil {
+			logger.Error("failed to open ip database", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	webAuthn, err := auth.NewWebAuthn()
+	if err != nil {
+		logger.Error("failed to create webauthn relying party", "error", err)
+		os.Exit(1)
+	}
+
+	opaqueServer, err := auth.NewOpaqueServer()
+	if err != nil {
+		logger.Error("failed to create opaque server", "error", err)
+		os.Exit(1)
+	}
+
+	authService := auth.NewAuthService(logger, db.DB, repo.New(db.DB), db.Valkey, limiter, auditService, geo, webAuthn, opaqueServer)
 	authService.StartEmailVerificationCleanupWorker(workersCtx)
 
 	userService := user.NewUserService(repo.New(db.DB))
 
+	keyService := keys.NewKeyService(db.DB, repo.New(db.DB))
+
+	conversationService := conversation.NewConversationService(db.DB, repo.New(db.DB))
+
+	transparencyService := transparency.NewTransparencyService(repo.New(db.DB))
+
+	bus, err := cluster.NewBus(logger, db.DB, db.Valkey)
+	if err != nil {
+		logger.Error("failed to create cluster bus", "error", err)
+		os.Exit(1)
+	}
+	dispatcher := realtime.NewDispatcher(logger, bus)
+	if err := dispatcher.Start(workersCtx); err != nil {
+		logger.Error("failed to start dispatcher", "error", err)
+		os.Exit(1)
+	}
+
+	pushService := push.NewPushService(logger, repo.New(db.DB))
+
+	messageService := message.NewMessageService(logger, db.DB, repo.New(db.DB), dispatcher, pushService)
+	messageService.StartPurgeWorker(workersCtx)
+
+	blobStore, err := blob.NewStore()
+	if err != nil {
+		logger.Error("failed to create blob store", "error", err)
+		os.Exit(1)
+	}
+	attachmentService := attachment.NewAttachmentService(logger, db.DB, repo.New(db.DB), blobStore)
+	attachmentService.StartGarbageCollectionWorker(workersCtx)
+
+	presenceService := presence.NewPresenceService(logger, repo.New(db.DB), dispatcher)
+
+	transferService := transfer.NewTransferService(logger, db.DB, repo.New(db.DB), blobStore, dispatcher)
+	transferService.StartGarbageCollectionWorker(workersCtx)
+
+	accountService := account.NewAccountService(logger, repo.New(db.DB), authService, attachmentService, transferService, auditService, dispatcher)
+	accountService.StartDeletionWorker(workersCtx)
+
 	app := app.NewApp(
 		logger,
 		authService,
 		userService,
+		keyService,
+		conversationService,
+		transparencyService,
+		messageService,
+		dispatcher,
+		attachmentService,
+		pushService,
+		presenceService,
+		limiter,
+		auditService,
+		transferService,
 	)
 	if err := app.Run(); err != nil {
 		logger.Error("failed to run app", "error", err)
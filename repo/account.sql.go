@@ -0,0 +1,148 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: account.sql
+
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const cancelAccountDeletion = `-- name: CancelAccountDeletion :one
+delete from account_deletions where id = $1 and scheduled_at > now()
+returning id, credentials_id, created_at, scheduled_at
+`
+
+// once scheduled_at passed, the purge may have started already.
+func (q *Queries) CancelAccountDeletion(ctx context.Context, id uuid.UUID) (AccountDeletion, error) {
+	row := q.queryRow(ctx, q.cancelAccountDeletionStmt, cancelAccountDeletion, id)
+	var i AccountDeletion
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.CreatedAt,
+		&i.ScheduledAt,
+	)
+	return i, err
+}
+
+const cancelCredentialsAccountDeletion = `-- name: CancelCredentialsAccountDeletion :one
+delete from account_deletions where credentials_id = $1 and scheduled_at > now()
+returning id, credentials_id, created_at, scheduled_at
+`
+
+func (q *Queries) CancelCredentialsAccountDeletion(ctx context.Context, credentialsID uuid.UUID) (AccountDeletion, error) {
+	row := q.queryRow(ctx, q.cancelCredentialsAccountDeletionStmt, cancelCredentialsAccountDeletion, credentialsID)
+	var i AccountDeletion
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.CreatedAt,
+		&i.ScheduledAt,
+	)
+	return i, err
+}
+
+const deleteCredentials = `-- name: DeleteCredentials :exec
+delete from credentials where id = $1
+`
+
+// everything else of the account goes with it through on delete cascade, but
+// blobs have to be deleted before.
+func (q *Queries) DeleteCredentials(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteCredentialsStmt, deleteCredentials, id)
+	return err
+}
+
+const getAccountDeletionByCredentialsID = `-- name: GetAccountDeletionByCredentialsID :one
+select id, credentials_id, created_at, scheduled_at from account_deletions where credentials_id = $1
+`
+
+func (q *Queries) GetAccountDeletionByCredentialsID(ctx context.Context, credentialsID uuid.UUID) (AccountDeletion, error) {
+	row := q.queryRow(ctx, q.getAccountDeletionByCredentialsIDStmt, getAccountDeletionByCredentialsID, credentialsID)
+	var i AccountDeletion
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.CreatedAt,
+		&i.ScheduledAt,
+	)
+	return i, err
+}
+
+const insertAccountDeletedEvents = `-- name: InsertAccountDeletedEvents :exec
+insert into conversation_events (conversation_id, type, subject_user_id)
+select cp.conversation_id, 'account_deleted', $1::uuid
+from conversation_participants cp
+where cp.user_id = $1
+    and not exists (
+        select 1 from conversation_events e
+        where e.conversation_id = cp.conversation_id and e.type = 'account_deleted' and e.subject_user_id = $1
+    )
+`
+
+// the subject is set null with the user, the events stay for its peers. A
+// purge that is retried doesn't add them twice.
+func (q *Queries) InsertAccountDeletedEvents(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.exec(ctx, q.insertAccountDeletedEventsStmt, insertAccountDeletedEvents, userID)
+	return err
+}
+
+const insertAccountDeletion = `-- name: InsertAccountDeletion :execrows
+insert into account_deletions (id, credentials_id, scheduled_at)
+values ($1, $2, $3)
+on conflict (credentials_id) do nothing
+`
+
+type InsertAccountDeletionParams struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+	ScheduledAt   time.Time
+}
+
+func (q *Queries) InsertAccountDeletion(ctx context.Context, arg InsertAccountDeletionParams) (int64, error) {
+	result, err := q.exec(ctx, q.insertAccountDeletionStmt, insertAccountDeletion, arg.ID, arg.CredentialsID, arg.ScheduledAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listDueAccountDeletions = `-- name: ListDueAccountDeletions :many
+select id, credentials_id, created_at, scheduled_at from account_deletions
+where scheduled_at <= now()
+order by scheduled_at
+limit $1
+`
+
+func (q *Queries) ListDueAccountDeletions(ctx context.Context, maxCount int32) ([]AccountDeletion, error) {
+	rows, err := q.query(ctx, q.listDueAccountDeletionsStmt, listDueAccountDeletions, maxCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AccountDeletion{}
+	for rows.Next() {
+		var i AccountDeletion
+		if err := rows.Scan(
+			&i.ID,
+			&i.CredentialsID,
+			&i.CreatedAt,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
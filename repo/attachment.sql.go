@@ -0,0 +1,316 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: attachment.sql
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const checkAttachmentAccess = `-- name: CheckAttachmentAccess :one
+select exists (
+    select 1 from attachments a
+    where a.id = $1 and a.uploaded_at is not null and (
+        a.owner_user_id = $2 or exists (
+            select 1 from message_attachments ma
+            join messages m on m.id = ma.message_id
+            join conversation_participants cp on cp.conversation_id = m.conversation_id
+            where ma.attachment_id = a.id and cp.user_id = $2
+        )
+    )
+)
+`
+
+type CheckAttachmentAccessParams struct {
+	AttachmentID uuid.UUID
+	UserID       uuid.UUID
+}
+
+// the owner and the participants of conversations with messages referencing
+// the attachment can download it.
+func (q *Queries) CheckAttachmentAccess(ctx context.Context, arg CheckAttachmentAccessParams) (bool, error) {
+	row := q.queryRow(ctx, q.checkAttachmentAccessStmt, checkAttachmentAccess, arg.AttachmentID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const deleteAttachment = `-- name: DeleteAttachment :exec
+delete from attachments where id = $1
+`
+
+func (q *Queries) DeleteAttachment(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteAttachmentStmt, deleteAttachment, id)
+	return err
+}
+
+const getAttachmentByID = `-- name: GetAttachmentByID :one
+select id, owner_user_id, size, uploaded_at, created_at, upload_offset, upload_expires_at from attachments where id = $1
+`
+
+func (q *Queries) GetAttachmentByID(ctx context.Context, id uuid.UUID) (Attachment, error) {
+	row := q.queryRow(ctx, q.getAttachmentByIDStmt, getAttachmentByID, id)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerUserID,
+		&i.Size,
+		&i.UploadedAt,
+		&i.CreatedAt,
+		&i.UploadOffset,
+		&i.UploadExpiresAt,
+	)
+	return i, err
+}
+
+const getUserAttachmentUsage = `-- name: GetUserAttachmentUsage :one
+select coalesce(sum(size), 0)::bigint from attachments where owner_user_id = $1
+`
+
+func (q *Queries) GetUserAttachmentUsage(ctx context.Context, ownerUserID uuid.UUID) (int64, error) {
+	row := q.queryRow(ctx, q.getUserAttachmentUsageStmt, getUserAttachmentUsage, ownerUserID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const insertAttachment = `-- name: InsertAttachment :one
+insert into attachments (id, owner_user_id, size, upload_expires_at)
+values ($1, $2, $3, $4)
+returning id, owner_user_id, size, uploaded_at, created_at, upload_offset, upload_expires_at
+`
+
+type InsertAttachmentParams struct {
+	ID              uuid.UUID
+	OwnerUserID     uuid.UUID
+	Size            int64
+	UploadExpiresAt sql.NullTime
+}
+
+func (q *Queries) InsertAttachment(ctx context.Context, arg InsertAttachmentParams) (Attachment, error) {
+	row := q.queryRow(ctx, q.insertAttachmentStmt, insertAttachment,
+		arg.ID,
+		arg.OwnerUserID,
+		arg.Size,
+		arg.UploadExpiresAt,
+	)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerUserID,
+		&i.Size,
+		&i.UploadedAt,
+		&i.CreatedAt,
+		&i.UploadOffset,
+		&i.UploadExpiresAt,
+	)
+	return i, err
+}
+
+const insertMessageAttachment = `-- name: InsertMessageAttachment :execrows
+insert into message_attachments (message_id, attachment_id)
+select $1, a.id from attachments a
+where a.id = $2 and a.owner_user_id = $3 and a.uploaded_at is not null
+`
+
+type InsertMessageAttachmentParams struct {
+	MessageID    uuid.UUID
+	AttachmentID uuid.UUID
+	OwnerUserID  uuid.UUID
+}
+
+// only uploaded attachments of the sender can be attached.
+func (q *Queries) InsertMessageAttachment(ctx context.Context, arg InsertMessageAttachmentParams) (int64, error) {
+	result, err := q.exec(ctx, q.insertMessageAttachmentStmt, insertMessageAttachment, arg.MessageID, arg.AttachmentID, arg.OwnerUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listCollectableAttachments = `-- name: ListCollectableAttachments :many
+select a.id, a.owner_user_id, a.size, a.uploaded_at, a.created_at, a.upload_offset, a.upload_expires_at from attachments a
+where a.created_at < $1
+    and (a.uploaded_at is not null or a.upload_expires_at is null)
+    and not exists (
+        select 1 from message_attachments ma
+        join envelopes e on e.message_id = ma.message_id
+        where ma.attachment_id = a.id
+    )
+order by a.created_at
+limit $2
+`
+
+type ListCollectableAttachmentsParams struct {
+	CreatedBefore time.Time
+	MaxCount      int32
+}
+
+// attachments past their TTL that no message waiting in a mailbox references.
+// Resumable uploads in progress are only collected once they expire.
+func (q *Queries) ListCollectableAttachments(ctx context.Context, arg ListCollectableAttachmentsParams) ([]Attachment, error) {
+	rows, err := q.query(ctx, q.listCollectableAttachmentsStmt, listCollectableAttachments, arg.CreatedBefore, arg.MaxCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Attachment{}
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerUserID,
+			&i.Size,
+			&i.UploadedAt,
+			&i.CreatedAt,
+			&i.UploadOffset,
+			&i.UploadExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredUploads = `-- name: ListExpiredUploads :many
+select id, owner_user_id, size, uploaded_at, created_at, upload_offset, upload_expires_at from attachments
+where uploaded_at is null and upload_expires_at < now()
+order by upload_expires_at
+limit $1
+`
+
+func (q *Queries) ListExpiredUploads(ctx context.Context, limit int32) ([]Attachment, error) {
+	rows, err := q.query(ctx, q.listExpiredUploadsStmt, listExpiredUploads, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Attachment{}
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerUserID,
+			&i.Size,
+			&i.UploadedAt,
+			&i.CreatedAt,
+			&i.UploadOffset,
+			&i.UploadExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessageAttachmentIDs = `-- name: ListMessageAttachmentIDs :many
+select attachment_id from message_attachments where message_id = $1 order by attachment_id
+`
+
+func (q *Queries) ListMessageAttachmentIDs(ctx context.Context, messageID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.listMessageAttachmentIDsStmt, listMessageAttachmentIDs, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var attachment_id uuid.UUID
+		if err := rows.Scan(&attachment_id); err != nil {
+			return nil, err
+		}
+		items = append(items, attachment_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserAttachmentIDs = `-- name: ListUserAttachmentIDs :many
+select id from attachments where owner_user_id = $1
+`
+
+func (q *Queries) ListUserAttachmentIDs(ctx context.Context, ownerUserID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.listUserAttachmentIDsStmt, listUserAttachmentIDs, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUser = `-- name: LockUser :exec
+select id from users where id = $1 for update
+`
+
+// serializes the quota checks of concurrent uploads by the same user.
+func (q *Queries) LockUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.lockUserStmt, lockUser, id)
+	return err
+}
+
+const markAttachmentUploaded = `-- name: MarkAttachmentUploaded :exec
+update attachments set uploaded_at = now(), upload_offset = size where id = $1
+`
+
+func (q *Queries) MarkAttachmentUploaded(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.markAttachmentUploadedStmt, markAttachmentUploaded, id)
+	return err
+}
+
+const updateAttachmentUploadOffset = `-- name: UpdateAttachmentUploadOffset :execrows
+update attachments set upload_offset = $1
+where id = $2 and upload_offset = $3 and uploaded_at is null
+`
+
+type UpdateAttachmentUploadOffsetParams struct {
+	NewOffset int64
+	ID        uuid.UUID
+	OldOffset int64
+}
+
+func (q *Queries) UpdateAttachmentUploadOffset(ctx context.Context, arg UpdateAttachmentUploadOffsetParams) (int64, error) {
+	result, err := q.exec(ctx, q.updateAttachmentUploadOffsetStmt, updateAttachmentUploadOffset, arg.NewOffset, arg.ID, arg.OldOffset)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,175 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: audit.sql
+
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getLatestAuditEventHash = `-- name: GetLatestAuditEventHash :one
+select hash
+from audit_events
+order by id desc
+limit 1
+`
+
+func (q *Queries) GetLatestAuditEventHash(ctx context.Context) ([]byte, error) {
+	row := q.queryRow(ctx, q.getLatestAuditEventHashStmt, getLatestAuditEventHash)
+	var hash []byte
+	err := row.Scan(&hash)
+	return hash, err
+}
+
+const insertAuditEvent = `-- name: InsertAuditEvent :exec
+insert into audit_events (id, credentials_id, type, ip, user_agent, details, created_at, prev_hash, hash)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+`
+
+type InsertAuditEventParams struct {
+	ID            int64
+	CredentialsID uuid.NullUUID
+	Type          string
+	Ip            string
+	UserAgent     string
+	Details       string
+	CreatedAt     time.Time
+	PrevHash      []byte
+	Hash          []byte
+}
+
+func (q *Queries) InsertAuditEvent(ctx context.Context, arg InsertAuditEventParams) error {
+	_, err := q.exec(ctx, q.insertAuditEventStmt, insertAuditEvent,
+		arg.ID,
+		arg.CredentialsID,
+		arg.Type,
+		arg.Ip,
+		arg.UserAgent,
+		arg.Details,
+		arg.CreatedAt,
+		arg.PrevHash,
+		arg.Hash,
+	)
+	return err
+}
+
+const listAuditEventsAfterID = `-- name: ListAuditEventsAfterID :many
+select id, credentials_id, type, ip, user_agent, details, created_at, prev_hash, hash
+from audit_events
+where id > $1
+order by id
+limit $2
+`
+
+type ListAuditEventsAfterIDParams struct {
+	ID    int64
+	Limit int32
+}
+
+func (q *Queries) ListAuditEventsAfterID(ctx context.Context, arg ListAuditEventsAfterIDParams) ([]AuditEvent, error) {
+	rows, err := q.query(ctx, q.listAuditEventsAfterIDStmt, listAuditEventsAfterID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.CredentialsID,
+			&i.Type,
+			&i.Ip,
+			&i.UserAgent,
+			&i.Details,
+			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCredentialsAuditEvents = `-- name: ListCredentialsAuditEvents :many
+select id, credentials_id, type, ip, user_agent, details, created_at, prev_hash, hash
+from audit_events
+where credentials_id = $1::uuid
+    and id < $2::bigint
+order by id desc
+limit $3
+`
+
+type ListCredentialsAuditEventsParams struct {
+	CredentialsID uuid.UUID
+	BeforeID      int64
+	PageSize      int32
+}
+
+func (q *Queries) ListCredentialsAuditEvents(ctx context.Context, arg ListCredentialsAuditEventsParams) ([]AuditEvent, error) {
+	rows, err := q.query(ctx, q.listCredentialsAuditEventsStmt, listCredentialsAuditEvents, arg.CredentialsID, arg.BeforeID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.CredentialsID,
+			&i.Type,
+			&i.Ip,
+			&i.UserAgent,
+			&i.Details,
+			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockAuditEvents = `-- name: LockAuditEvents :exec
+select pg_advisory_xact_lock(hashtext('audit_events'))
+`
+
+// serializes appends until the end of the transaction, the chain needs the
+// previous row's hash.
+func (q *Queries) LockAuditEvents(ctx context.Context) error {
+	_, err := q.exec(ctx, q.lockAuditEventsStmt, lockAuditEvents)
+	return err
+}
+
+const nextAuditEventID = `-- name: NextAuditEventID :one
+select nextval('audit_events_id_seq')::bigint
+`
+
+func (q *Queries) NextAuditEventID(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.nextAuditEventIDStmt, nextAuditEventID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
@@ -7,6 +7,7 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,6 +24,135 @@ func (q *Queries) CheckEmail(ctx context.Context, email string) (bool, error) {
 	return exists, err
 }
 
+const deleteCredentialsPasswordResetTokens = `-- name: DeleteCredentialsPasswordResetTokens :exec
+delete from password_reset_tokens where credentials_id = $1
+`
+
+func (q *Queries) DeleteCredentialsPasswordResetTokens(ctx context.Context, credentialsID uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteCredentialsPasswordResetTokensStmt, deleteCredentialsPasswordResetTokens, credentialsID)
+	return err
+}
+
+const deleteCredentialsSessions = `-- name: DeleteCredentialsSessions :many
+delete from sessions where credentials_id = $1
+returning id
+`
+
+func (q *Queries) DeleteCredentialsSessions(ctx context.Context, credentialsID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.deleteCredentialsSessionsStmt, deleteCredentialsSessions, credentialsID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteLoginAlert = `-- name: DeleteLoginAlert :one
+delete from login_alerts where id = $1
+returning id, credentials_id, session_id, created_at, expires_at
+`
+
+func (q *Queries) DeleteLoginAlert(ctx context.Context, id uuid.UUID) (LoginAlert, error) {
+	row := q.queryRow(ctx, q.deleteLoginAlertStmt, deleteLoginAlert, id)
+	var i LoginAlert
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.SessionID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const deleteLoginLinkToken = `-- name: DeleteLoginLinkToken :one
+delete from email_verification_tokens
+where id = $1 and purpose = 'login' and browser_hash = $2
+returning id, email, created_at, expires_at, purpose, browser_hash
+`
+
+type DeleteLoginLinkTokenParams struct {
+	ID          uuid.UUID
+	BrowserHash []byte
+}
+
+// a link opened in another browser, or by a mail scanner, stays usable.
+func (q *Queries) DeleteLoginLinkToken(ctx context.Context, arg DeleteLoginLinkTokenParams) (EmailVerificationToken, error) {
+	row := q.queryRow(ctx, q.deleteLoginLinkTokenStmt, deleteLoginLinkToken, arg.ID, arg.BrowserHash)
+	var i EmailVerificationToken
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.Purpose,
+		&i.BrowserHash,
+	)
+	return i, err
+}
+
+const deleteOldLoginHistory = `-- name: DeleteOldLoginHistory :exec
+delete from login_history
+where login_history.credentials_id = $1
+    and login_history.id not in (
+        select recent.id from login_history recent
+        where recent.credentials_id = $1
+        order by recent.created_at desc
+        limit $2
+    )
+`
+
+type DeleteOldLoginHistoryParams struct {
+	CredentialsID uuid.UUID
+	Keep          int32
+}
+
+// keeps the latest logins.
+func (q *Queries) DeleteOldLoginHistory(ctx context.Context, arg DeleteOldLoginHistoryParams) error {
+	_, err := q.exec(ctx, q.deleteOldLoginHistoryStmt, deleteOldLoginHistory, arg.CredentialsID, arg.Keep)
+	return err
+}
+
+const deletePasswordResetToken = `-- name: DeletePasswordResetToken :one
+delete from password_reset_tokens where id = $1
+returning id, credentials_id, created_at, expires_at
+`
+
+func (q *Queries) DeletePasswordResetToken(ctx context.Context, id uuid.UUID) (PasswordResetToken, error) {
+	row := q.queryRow(ctx, q.deletePasswordResetTokenStmt, deletePasswordResetToken, id)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const deleteSession = `-- name: DeleteSession :exec
+delete from sessions where id = $1
+`
+
+func (q *Queries) DeleteSession(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteSessionStmt, deleteSession, id)
+	return err
+}
+
 const deleteStaleEmailVerificationTokens = `-- name: DeleteStaleEmailVerificationTokens :exec
 delete from email_verification_tokens where expires_at <= now()
 `
@@ -32,8 +162,26 @@ func (q *Queries) DeleteStaleEmailVerificationTokens(ctx context.Context) error
 	return err
 }
 
+const deleteStaleLoginAlerts = `-- name: DeleteStaleLoginAlerts :exec
+delete from login_alerts where expires_at <= now()
+`
+
+func (q *Queries) DeleteStaleLoginAlerts(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteStaleLoginAlertsStmt, deleteStaleLoginAlerts)
+	return err
+}
+
+const deleteStalePasswordResetTokens = `-- name: DeleteStalePasswordResetTokens :exec
+delete from password_reset_tokens where expires_at <= now()
+`
+
+func (q *Queries) DeleteStalePasswordResetTokens(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteStalePasswordResetTokensStmt, deleteStalePasswordResetTokens)
+	return err
+}
+
 const getCredentialsByEmail = `-- name: GetCredentialsByEmail :one
-select id, email, email_is_verified, password_hash, created_at from credentials where email = $1
+select id, email, email_is_verified, password_hash, created_at, password_reset_required, totp_secret, totp_enabled, totp_last_counter, opaque_record from credentials where email = $1
 `
 
 func (q *Queries) GetCredentialsByEmail(ctx context.Context, email string) (Credential, error) {
@@ -45,12 +193,39 @@ func (q *Queries) GetCredentialsByEmail(ctx context.Context, email string) (Cred
 		&i.EmailIsVerified,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.PasswordResetRequired,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.OpaqueRecord,
+	)
+	return i, err
+}
+
+const getCredentialsByID = `-- name: GetCredentialsByID :one
+select id, email, email_is_verified, password_hash, created_at, password_reset_required, totp_secret, totp_enabled, totp_last_counter, opaque_record from credentials where id = $1
+`
+
+func (q *Queries) GetCredentialsByID(ctx context.Context, id uuid.UUID) (Credential, error) {
+	row := q.queryRow(ctx, q.getCredentialsByIDStmt, getCredentialsByID, id)
+	var i Credential
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.EmailIsVerified,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.PasswordResetRequired,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.OpaqueRecord,
 	)
 	return i, err
 }
 
 const getEmailVerificationTokenByID = `-- name: GetEmailVerificationTokenByID :one
-select id, email, created_at, expires_at from email_verification_tokens where id = $1
+select id, email, created_at, expires_at, purpose, browser_hash from email_verification_tokens where id = $1 and purpose = 'verify-email'
 `
 
 func (q *Queries) GetEmailVerificationTokenByID(ctx context.Context, id uuid.UUID) (EmailVerificationToken, error) {
@@ -61,6 +236,8 @@ func (q *Queries) GetEmailVerificationTokenByID(ctx context.Context, id uuid.UUI
 		&i.Email,
 		&i.CreatedAt,
 		&i.ExpiresAt,
+		&i.Purpose,
+		&i.BrowserHash,
 	)
 	return i, err
 }
@@ -114,6 +291,94 @@ func (q *Queries) InsertEmailVerificationToken(ctx context.Context, arg InsertEm
 	return err
 }
 
+const insertLoginAlert = `-- name: InsertLoginAlert :exec
+insert into login_alerts (id, credentials_id, session_id, expires_at)
+values ($1, $2, $3, $4)
+`
+
+type InsertLoginAlertParams struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+	SessionID     uuid.UUID
+	ExpiresAt     time.Time
+}
+
+func (q *Queries) InsertLoginAlert(ctx context.Context, arg InsertLoginAlertParams) error {
+	_, err := q.exec(ctx, q.insertLoginAlertStmt, insertLoginAlert,
+		arg.ID,
+		arg.CredentialsID,
+		arg.SessionID,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const insertLoginHistory = `-- name: InsertLoginHistory :exec
+insert into login_history (credentials_id, session_id, ip_prefix, user_agent_family, region, latitude, longitude)
+values ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type InsertLoginHistoryParams struct {
+	CredentialsID   uuid.UUID
+	SessionID       uuid.UUID
+	IpPrefix        string
+	UserAgentFamily string
+	Region          sql.NullString
+	Latitude        sql.NullFloat64
+	Longitude       sql.NullFloat64
+}
+
+func (q *Queries) InsertLoginHistory(ctx context.Context, arg InsertLoginHistoryParams) error {
+	_, err := q.exec(ctx, q.insertLoginHistoryStmt, insertLoginHistory,
+		arg.CredentialsID,
+		arg.SessionID,
+		arg.IpPrefix,
+		arg.UserAgentFamily,
+		arg.Region,
+		arg.Latitude,
+		arg.Longitude,
+	)
+	return err
+}
+
+const insertLoginLinkToken = `-- name: InsertLoginLinkToken :exec
+insert into email_verification_tokens (id, email, purpose, browser_hash, expires_at)
+values ($1, $2, 'login', $3, $4)
+`
+
+type InsertLoginLinkTokenParams struct {
+	ID          uuid.UUID
+	Email       string
+	BrowserHash []byte
+	ExpiresAt   time.Time
+}
+
+func (q *Queries) InsertLoginLinkToken(ctx context.Context, arg InsertLoginLinkTokenParams) error {
+	_, err := q.exec(ctx, q.insertLoginLinkTokenStmt, insertLoginLinkToken,
+		arg.ID,
+		arg.Email,
+		arg.BrowserHash,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const insertPasswordResetToken = `-- name: InsertPasswordResetToken :exec
+insert into password_reset_tokens (id, credentials_id, expires_at)
+values ($1, $2, $3)
+`
+
+type InsertPasswordResetTokenParams struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+	ExpiresAt     time.Time
+}
+
+func (q *Queries) InsertPasswordResetToken(ctx context.Context, arg InsertPasswordResetTokenParams) error {
+	_, err := q.exec(ctx, q.insertPasswordResetTokenStmt, insertPasswordResetToken, arg.ID, arg.CredentialsID, arg.ExpiresAt)
+	return err
+}
+
 const insertSession = `-- name: InsertSession :one
 insert into sessions (id, credentials_id, token, csrf_token)
 values ($1, $2, $3, $4)
@@ -145,6 +410,51 @@ func (q *Queries) InsertSession(ctx context.Context, arg InsertSessionParams) (S
 	return i, err
 }
 
+const listRecentLoginHistory = `-- name: ListRecentLoginHistory :many
+select id, credentials_id, session_id, ip_prefix, user_agent_family, region, latitude, longitude, created_at from login_history
+where credentials_id = $1
+order by created_at desc
+limit $2
+`
+
+type ListRecentLoginHistoryParams struct {
+	CredentialsID uuid.UUID
+	Limit         int32
+}
+
+func (q *Queries) ListRecentLoginHistory(ctx context.Context, arg ListRecentLoginHistoryParams) ([]LoginHistory, error) {
+	rows, err := q.query(ctx, q.listRecentLoginHistoryStmt, listRecentLoginHistory, arg.CredentialsID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LoginHistory{}
+	for rows.Next() {
+		var i LoginHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.CredentialsID,
+			&i.SessionID,
+			&i.IpPrefix,
+			&i.UserAgentFamily,
+			&i.Region,
+			&i.Latitude,
+			&i.Longitude,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const markEmailAsVerified = `-- name: MarkEmailAsVerified :exec
 update credentials set email_is_verified = true where email = $1
 `
@@ -153,3 +463,28 @@ func (q *Queries) MarkEmailAsVerified(ctx context.Context, email string) error {
 	_, err := q.exec(ctx, q.markEmailAsVerifiedStmt, markEmailAsVerified, email)
 	return err
 }
+
+const setPasswordResetRequired = `-- name: SetPasswordResetRequired :exec
+update credentials set password_reset_required = true where id = $1
+`
+
+func (q *Queries) SetPasswordResetRequired(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.setPasswordResetRequiredStmt, setPasswordResetRequired, id)
+	return err
+}
+
+const updateCredentialsPassword = `-- name: UpdateCredentialsPassword :exec
+update credentials
+set password_hash = $2, password_reset_required = false, opaque_record = null
+where id = $1
+`
+
+type UpdateCredentialsPasswordParams struct {
+	ID           uuid.UUID
+	PasswordHash string
+}
+
+func (q *Queries) UpdateCredentialsPassword(ctx context.Context, arg UpdateCredentialsPasswordParams) error {
+	_, err := q.exec(ctx, q.updateCredentialsPasswordStmt, updateCredentialsPassword, arg.ID, arg.PasswordHash)
+	return err
+}
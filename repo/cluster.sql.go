@@ -0,0 +1,110 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: cluster.sql
+
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+const deleteClusterEventsBefore = `-- name: DeleteClusterEventsBefore :execrows
+delete from cluster_events
+where created_at < $1
+`
+
+func (q *Queries) DeleteClusterEventsBefore(ctx context.Context, createdAt time.Time) (int64, error) {
+	result, err := q.exec(ctx, q.deleteClusterEventsBeforeStmt, deleteClusterEventsBefore, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getLatestClusterEventID = `-- name: GetLatestClusterEventID :one
+select coalesce(max(id), 0)::bigint
+from cluster_events
+`
+
+func (q *Queries) GetLatestClusterEventID(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.getLatestClusterEventIDStmt, getLatestClusterEventID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const insertClusterEvent = `-- name: InsertClusterEvent :one
+insert into cluster_events (payload)
+values ($1)
+returning id
+`
+
+func (q *Queries) InsertClusterEvent(ctx context.Context, payload []byte) (int64, error) {
+	row := q.queryRow(ctx, q.insertClusterEventStmt, insertClusterEvent, payload)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const listClusterEventsAfterID = `-- name: ListClusterEventsAfterID :many
+select id, payload
+from cluster_events
+where id > $1
+order by id
+limit $2
+`
+
+type ListClusterEventsAfterIDParams struct {
+	ID    int64
+	Limit int32
+}
+
+type ListClusterEventsAfterIDRow struct {
+	ID      int64
+	Payload []byte
+}
+
+func (q *Queries) ListClusterEventsAfterID(ctx context.Context, arg ListClusterEventsAfterIDParams) ([]ListClusterEventsAfterIDRow, error) {
+	rows, err := q.query(ctx, q.listClusterEventsAfterIDStmt, listClusterEventsAfterID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListClusterEventsAfterIDRow{}
+	for rows.Next() {
+		var i ListClusterEventsAfterIDRow
+		if err := rows.Scan(&i.ID, &i.Payload); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockClusterEvents = `-- name: LockClusterEvents :exec
+select pg_advisory_xact_lock(hashtext('cluster_events'))
+`
+
+// serializes publishers until the end of the transaction, so events commit
+// in id order and listeners never skip one.
+func (q *Queries) LockClusterEvents(ctx context.Context) error {
+	_, err := q.exec(ctx, q.lockClusterEventsStmt, lockClusterEvents)
+	return err
+}
+
+const notifyClusterEvent = `-- name: NotifyClusterEvent :exec
+select pg_notify('cluster_events', ($1::bigint)::text)
+`
+
+func (q *Queries) NotifyClusterEvent(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.notifyClusterEventStmt, notifyClusterEvent, id)
+	return err
+}
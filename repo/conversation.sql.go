@@ -0,0 +1,298 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: conversation.sql
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const checkContact = `-- name: CheckContact :one
+select exists (
+    select 1
+    from conversation_participants self
+    join conversation_participants peer on peer.conversation_id = self.conversation_id
+    where self.user_id = $1 and peer.user_id = $2
+)
+`
+
+type CheckContactParams struct {
+	UserID        uuid.UUID
+	ContactUserID uuid.UUID
+}
+
+func (q *Queries) CheckContact(ctx context.Context, arg CheckContactParams) (bool, error) {
+	row := q.queryRow(ctx, q.checkContactStmt, checkContact, arg.UserID, arg.ContactUserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const checkConversationParticipant = `-- name: CheckConversationParticipant :one
+select exists (select 1 from conversation_participants where conversation_id = $1 and user_id = $2)
+`
+
+type CheckConversationParticipantParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) CheckConversationParticipant(ctx context.Context, arg CheckConversationParticipantParams) (bool, error) {
+	row := q.queryRow(ctx, q.checkConversationParticipantStmt, checkConversationParticipant, arg.ConversationID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const getDirectConversationID = `-- name: GetDirectConversationID :one
+select conversation_id
+from direct_conversations
+where user_low = least($1::uuid, $2::uuid)
+and user_high = greatest($1::uuid, $2::uuid)
+`
+
+type GetDirectConversationIDParams struct {
+	UserID     uuid.UUID
+	PeerUserID uuid.UUID
+}
+
+func (q *Queries) GetDirectConversationID(ctx context.Context, arg GetDirectConversationIDParams) (uuid.UUID, error) {
+	row := q.queryRow(ctx, q.getDirectConversationIDStmt, getDirectConversationID, arg.UserID, arg.PeerUserID)
+	var conversation_id uuid.UUID
+	err := row.Scan(&conversation_id)
+	return conversation_id, err
+}
+
+const insertConversation = `-- name: InsertConversation :exec
+insert into conversations (id)
+values ($1)
+`
+
+func (q *Queries) InsertConversation(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.insertConversationStmt, insertConversation, id)
+	return err
+}
+
+const insertConversationEvent = `-- name: InsertConversationEvent :exec
+insert into conversation_events (conversation_id, type, subject_user_id, disappearing_timer)
+values ($1, $2, $3, $4)
+`
+
+type InsertConversationEventParams struct {
+	ConversationID    uuid.UUID
+	Type              string
+	SubjectUserID     uuid.NullUUID
+	DisappearingTimer sql.NullInt32
+}
+
+func (q *Queries) InsertConversationEvent(ctx context.Context, arg InsertConversationEventParams) error {
+	_, err := q.exec(ctx, q.insertConversationEventStmt, insertConversationEvent,
+		arg.ConversationID,
+		arg.Type,
+		arg.SubjectUserID,
+		arg.DisappearingTimer,
+	)
+	return err
+}
+
+const insertConversationParticipant = `-- name: InsertConversationParticipant :exec
+insert into conversation_participants (conversation_id, user_id)
+values ($1, $2)
+`
+
+type InsertConversationParticipantParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) InsertConversationParticipant(ctx context.Context, arg InsertConversationParticipantParams) error {
+	_, err := q.exec(ctx, q.insertConversationParticipantStmt, insertConversationParticipant, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const insertDirectConversation = `-- name: InsertDirectConversation :exec
+insert into direct_conversations (user_low, user_high, conversation_id)
+values (least($1::uuid, $2::uuid), greatest($1::uuid, $2::uuid), $3)
+`
+
+type InsertDirectConversationParams struct {
+	UserID         uuid.UUID
+	PeerUserID     uuid.UUID
+	ConversationID uuid.UUID
+}
+
+func (q *Queries) InsertDirectConversation(ctx context.Context, arg InsertDirectConversationParams) error {
+	_, err := q.exec(ctx, q.insertDirectConversationStmt, insertDirectConversation, arg.UserID, arg.PeerUserID, arg.ConversationID)
+	return err
+}
+
+const listContactUserIDs = `-- name: ListContactUserIDs :many
+select distinct peer.user_id
+from conversation_participants self
+join conversation_participants peer on peer.conversation_id = self.conversation_id and peer.user_id <> self.user_id
+where self.user_id = $1
+`
+
+// contacts are the users sharing a conversation with the user.
+func (q *Queries) ListContactUserIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.listContactUserIDsStmt, listContactUserIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationEvents = `-- name: ListConversationEvents :many
+select id, conversation_id, type, subject_user_id, subject_device_id, audience_user_id, created_at, disappearing_timer from conversation_events
+where conversation_id = $1 and (audience_user_id is null or audience_user_id = $2::uuid)
+order by created_at
+`
+
+type ListConversationEventsParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) ListConversationEvents(ctx context.Context, arg ListConversationEventsParams) ([]ConversationEvent, error) {
+	rows, err := q.query(ctx, q.listConversationEventsStmt, listConversationEvents, arg.ConversationID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ConversationEvent{}
+	for rows.Next() {
+		var i ConversationEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ConversationID,
+			&i.Type,
+			&i.SubjectUserID,
+			&i.SubjectDeviceID,
+			&i.AudienceUserID,
+			&i.CreatedAt,
+			&i.DisappearingTimer,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationParticipantUserIDs = `-- name: ListConversationParticipantUserIDs :many
+select user_id from conversation_participants where conversation_id = $1
+`
+
+func (q *Queries) ListConversationParticipantUserIDs(ctx context.Context, conversationID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.listConversationParticipantUserIDsStmt, listConversationParticipantUserIDs, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationsByUserID = `-- name: ListConversationsByUserID :many
+select c.id, c.disappearing_timer, c.created_at, u.id as peer_user_id, u.username as peer_username, u.name as peer_name
+from conversations c
+join conversation_participants self on self.conversation_id = c.id
+left join conversation_participants peer on peer.conversation_id = c.id and peer.user_id <> self.user_id
+left join users u on u.id = peer.user_id
+where self.user_id = $1
+order by c.created_at desc
+`
+
+type ListConversationsByUserIDRow struct {
+	ID                uuid.UUID
+	DisappearingTimer int32
+	CreatedAt         time.Time
+	PeerUserID        uuid.NullUUID
+	PeerUsername      sql.NullString
+	PeerName          sql.NullString
+}
+
+// the peer is gone once its account was deleted.
+func (q *Queries) ListConversationsByUserID(ctx context.Context, userID uuid.UUID) ([]ListConversationsByUserIDRow, error) {
+	rows, err := q.query(ctx, q.listConversationsByUserIDStmt, listConversationsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListConversationsByUserIDRow{}
+	for rows.Next() {
+		var i ListConversationsByUserIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.DisappearingTimer,
+			&i.CreatedAt,
+			&i.PeerUserID,
+			&i.PeerUsername,
+			&i.PeerName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateConversationDisappearingTimer = `-- name: UpdateConversationDisappearingTimer :exec
+update conversations set disappearing_timer = $2 where id = $1
+`
+
+type UpdateConversationDisappearingTimerParams struct {
+	ID                uuid.UUID
+	DisappearingTimer int32
+}
+
+func (q *Queries) UpdateConversationDisappearingTimer(ctx context.Context, arg UpdateConversationDisappearingTimerParams) error {
+	_, err := q.exec(ctx, q.updateConversationDisappearingTimerStmt, updateConversationDisappearingTimer, arg.ID, arg.DisappearingTimer)
+	return err
+}
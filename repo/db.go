@@ -24,63 +24,604 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.appendKeyTransparencyEntryStmt, err = db.PrepareContext(ctx, appendKeyTransparencyEntry); err != nil {
+		return nil, fmt.Errorf("error preparing query AppendKeyTransparencyEntry: %w", err)
+	}
 	if q.beginStmt, err = db.PrepareContext(ctx, begin); err != nil {
 		return nil, fmt.Errorf("error preparing query Begin: %w", err)
 	}
+	if q.cancelAccountDeletionStmt, err = db.PrepareContext(ctx, cancelAccountDeletion); err != nil {
+		return nil, fmt.Errorf("error preparing query CancelAccountDeletion: %w", err)
+	}
+	if q.cancelCredentialsAccountDeletionStmt, err = db.PrepareContext(ctx, cancelCredentialsAccountDeletion); err != nil {
+		return nil, fmt.Errorf("error preparing query CancelCredentialsAccountDeletion: %w", err)
+	}
+	if q.cancelHistoryTransferStmt, err = db.PrepareContext(ctx, cancelHistoryTransfer); err != nil {
+		return nil, fmt.Errorf("error preparing query CancelHistoryTransfer: %w", err)
+	}
+	if q.checkAttachmentAccessStmt, err = db.PrepareContext(ctx, checkAttachmentAccess); err != nil {
+		return nil, fmt.Errorf("error preparing query CheckAttachmentAccess: %w", err)
+	}
+	if q.checkContactStmt, err = db.PrepareContext(ctx, checkContact); err != nil {
+		return nil, fmt.Errorf("error preparing query CheckContact: %w", err)
+	}
+	if q.checkConversationParticipantStmt, err = db.PrepareContext(ctx, checkConversationParticipant); err != nil {
+		return nil, fmt.Errorf("error preparing query CheckConversationParticipant: %w", err)
+	}
 	if q.checkEmailStmt, err = db.PrepareContext(ctx, checkEmail); err != nil {
 		return nil, fmt.Errorf("error preparing query CheckEmail: %w", err)
 	}
+	if q.checkMessageDeletedStmt, err = db.PrepareContext(ctx, checkMessageDeleted); err != nil {
+		return nil, fmt.Errorf("error preparing query CheckMessageDeleted: %w", err)
+	}
 	if q.checkUsernameStmt, err = db.PrepareContext(ctx, checkUsername); err != nil {
 		return nil, fmt.Errorf("error preparing query CheckUsername: %w", err)
 	}
 	if q.commitStmt, err = db.PrepareContext(ctx, commit); err != nil {
 		return nil, fmt.Errorf("error preparing query Commit: %w", err)
 	}
+	if q.countCredentialsPasskeysStmt, err = db.PrepareContext(ctx, countCredentialsPasskeys); err != nil {
+		return nil, fmt.Errorf("error preparing query CountCredentialsPasskeys: %w", err)
+	}
+	if q.countOpenHistoryTransfersStmt, err = db.PrepareContext(ctx, countOpenHistoryTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query CountOpenHistoryTransfers: %w", err)
+	}
+	if q.countRecoveryCodesStmt, err = db.PrepareContext(ctx, countRecoveryCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query CountRecoveryCodes: %w", err)
+	}
+	if q.deleteAttachmentStmt, err = db.PrepareContext(ctx, deleteAttachment); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteAttachment: %w", err)
+	}
+	if q.deleteClusterEventsBeforeStmt, err = db.PrepareContext(ctx, deleteClusterEventsBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteClusterEventsBefore: %w", err)
+	}
+	if q.deleteContactVerificationStmt, err = db.PrepareContext(ctx, deleteContactVerification); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteContactVerification: %w", err)
+	}
+	if q.deleteCredentialsStmt, err = db.PrepareContext(ctx, deleteCredentials); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCredentials: %w", err)
+	}
+	if q.deleteCredentialsPasswordResetTokensStmt, err = db.PrepareContext(ctx, deleteCredentialsPasswordResetTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCredentialsPasswordResetTokens: %w", err)
+	}
+	if q.deleteCredentialsSessionsStmt, err = db.PrepareContext(ctx, deleteCredentialsSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCredentialsSessions: %w", err)
+	}
+	if q.deleteEnvelopeStmt, err = db.PrepareContext(ctx, deleteEnvelope); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteEnvelope: %w", err)
+	}
+	if q.deleteExpiredEnvelopesStmt, err = db.PrepareContext(ctx, deleteExpiredEnvelopes); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredEnvelopes: %w", err)
+	}
+	if q.deleteExpiredMessagesStmt, err = db.PrepareContext(ctx, deleteExpiredMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredMessages: %w", err)
+	}
+	if q.deleteExpiredRateLimitCountersStmt, err = db.PrepareContext(ctx, deleteExpiredRateLimitCounters); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredRateLimitCounters: %w", err)
+	}
+	if q.deleteHistoryTransferStmt, err = db.PrepareContext(ctx, deleteHistoryTransfer); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteHistoryTransfer: %w", err)
+	}
+	if q.deleteHistoryTransferChunksStmt, err = db.PrepareContext(ctx, deleteHistoryTransferChunks); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteHistoryTransferChunks: %w", err)
+	}
+	if q.deleteKeyBackupStmt, err = db.PrepareContext(ctx, deleteKeyBackup); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteKeyBackup: %w", err)
+	}
+	if q.deleteLoginAlertStmt, err = db.PrepareContext(ctx, deleteLoginAlert); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteLoginAlert: %w", err)
+	}
+	if q.deleteLoginLinkTokenStmt, err = db.PrepareContext(ctx, deleteLoginLinkToken); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteLoginLinkToken: %w", err)
+	}
+	if q.deleteMFAChallengeStmt, err = db.PrepareContext(ctx, deleteMFAChallenge); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMFAChallenge: %w", err)
+	}
+	if q.deleteMessageEnvelopesStmt, err = db.PrepareContext(ctx, deleteMessageEnvelopes); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMessageEnvelopes: %w", err)
+	}
+	if q.deleteOldLoginHistoryStmt, err = db.PrepareContext(ctx, deleteOldLoginHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOldLoginHistory: %w", err)
+	}
+	if q.deleteOpaqueLoginStmt, err = db.PrepareContext(ctx, deleteOpaqueLogin); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOpaqueLogin: %w", err)
+	}
+	if q.deleteOpaqueRegistrationStmt, err = db.PrepareContext(ctx, deleteOpaqueRegistration); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOpaqueRegistration: %w", err)
+	}
+	if q.deleteOpaqueUpgradeStmt, err = db.PrepareContext(ctx, deleteOpaqueUpgrade); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOpaqueUpgrade: %w", err)
+	}
+	if q.deletePasskeyStmt, err = db.PrepareContext(ctx, deletePasskey); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePasskey: %w", err)
+	}
+	if q.deletePasswordResetTokenStmt, err = db.PrepareContext(ctx, deletePasswordResetToken); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePasswordResetToken: %w", err)
+	}
+	if q.deletePushSubscriptionStmt, err = db.PrepareContext(ctx, deletePushSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePushSubscription: %w", err)
+	}
+	if q.deletePushSubscriptionByEndpointStmt, err = db.PrepareContext(ctx, deletePushSubscriptionByEndpoint); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePushSubscriptionByEndpoint: %w", err)
+	}
+	if q.deleteRateLimitCounterStmt, err = db.PrepareContext(ctx, deleteRateLimitCounter); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteRateLimitCounter: %w", err)
+	}
+	if q.deleteRecoveryCodesStmt, err = db.PrepareContext(ctx, deleteRecoveryCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteRecoveryCodes: %w", err)
+	}
+	if q.deleteSessionStmt, err = db.PrepareContext(ctx, deleteSession); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSession: %w", err)
+	}
 	if q.deleteStaleEmailVerificationTokensStmt, err = db.PrepareContext(ctx, deleteStaleEmailVerificationTokens); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteStaleEmailVerificationTokens: %w", err)
 	}
+	if q.deleteStaleLoginAlertsStmt, err = db.PrepareContext(ctx, deleteStaleLoginAlerts); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStaleLoginAlerts: %w", err)
+	}
+	if q.deleteStaleMFAChallengesStmt, err = db.PrepareContext(ctx, deleteStaleMFAChallenges); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStaleMFAChallenges: %w", err)
+	}
+	if q.deleteStaleOpaqueLoginsStmt, err = db.PrepareContext(ctx, deleteStaleOpaqueLogins); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStaleOpaqueLogins: %w", err)
+	}
+	if q.deleteStaleOpaqueRegistrationsStmt, err = db.PrepareContext(ctx, deleteStaleOpaqueRegistrations); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStaleOpaqueRegistrations: %w", err)
+	}
+	if q.deleteStaleOpaqueUpgradesStmt, err = db.PrepareContext(ctx, deleteStaleOpaqueUpgrades); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStaleOpaqueUpgrades: %w", err)
+	}
+	if q.deleteStalePasswordResetTokensStmt, err = db.PrepareContext(ctx, deleteStalePasswordResetTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStalePasswordResetTokens: %w", err)
+	}
+	if q.deleteStaleWebAuthnCeremoniesStmt, err = db.PrepareContext(ctx, deleteStaleWebAuthnCeremonies); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStaleWebAuthnCeremonies: %w", err)
+	}
+	if q.deleteWebAuthnCeremonyStmt, err = db.PrepareContext(ctx, deleteWebAuthnCeremony); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebAuthnCeremony: %w", err)
+	}
+	if q.disableTOTPStmt, err = db.PrepareContext(ctx, disableTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query DisableTOTP: %w", err)
+	}
+	if q.enableTOTPStmt, err = db.PrepareContext(ctx, enableTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query EnableTOTP: %w", err)
+	}
+	if q.getAccountDeletionByCredentialsIDStmt, err = db.PrepareContext(ctx, getAccountDeletionByCredentialsID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountDeletionByCredentialsID: %w", err)
+	}
+	if q.getAttachmentByIDStmt, err = db.PrepareContext(ctx, getAttachmentByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAttachmentByID: %w", err)
+	}
+	if q.getContactVerificationStmt, err = db.PrepareContext(ctx, getContactVerification); err != nil {
+		return nil, fmt.Errorf("error preparing query GetContactVerification: %w", err)
+	}
+	if q.getConversationLastSeqStmt, err = db.PrepareContext(ctx, getConversationLastSeq); err != nil {
+		return nil, fmt.Errorf("error preparing query GetConversationLastSeq: %w", err)
+	}
 	if q.getCredentialsByEmailStmt, err = db.PrepareContext(ctx, getCredentialsByEmail); err != nil {
 		return nil, fmt.Errorf("error preparing query GetCredentialsByEmail: %w", err)
 	}
+	if q.getCredentialsByIDStmt, err = db.PrepareContext(ctx, getCredentialsByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCredentialsByID: %w", err)
+	}
+	if q.getDeviceByIDStmt, err = db.PrepareContext(ctx, getDeviceByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDeviceByID: %w", err)
+	}
+	if q.getDirectConversationIDStmt, err = db.PrepareContext(ctx, getDirectConversationID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDirectConversationID: %w", err)
+	}
 	if q.getEmailVerificationTokenByIDStmt, err = db.PrepareContext(ctx, getEmailVerificationTokenByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetEmailVerificationTokenByID: %w", err)
 	}
+	if q.getHistoryTransferStmt, err = db.PrepareContext(ctx, getHistoryTransfer); err != nil {
+		return nil, fmt.Errorf("error preparing query GetHistoryTransfer: %w", err)
+	}
+	if q.getHistoryTransferChunkStmt, err = db.PrepareContext(ctx, getHistoryTransferChunk); err != nil {
+		return nil, fmt.Errorf("error preparing query GetHistoryTransferChunk: %w", err)
+	}
+	if q.getHistoryTransferProgressStmt, err = db.PrepareContext(ctx, getHistoryTransferProgress); err != nil {
+		return nil, fmt.Errorf("error preparing query GetHistoryTransferProgress: %w", err)
+	}
+	if q.getKeyBackupStmt, err = db.PrepareContext(ctx, getKeyBackup); err != nil {
+		return nil, fmt.Errorf("error preparing query GetKeyBackup: %w", err)
+	}
+	if q.getKeyTransparencyEntryStmt, err = db.PrepareContext(ctx, getKeyTransparencyEntry); err != nil {
+		return nil, fmt.Errorf("error preparing query GetKeyTransparencyEntry: %w", err)
+	}
+	if q.getKeyTransparencyTreeHeadStmt, err = db.PrepareContext(ctx, getKeyTransparencyTreeHead); err != nil {
+		return nil, fmt.Errorf("error preparing query GetKeyTransparencyTreeHead: %w", err)
+	}
+	if q.getKeyTransparencyTreeSizeStmt, err = db.PrepareContext(ctx, getKeyTransparencyTreeSize); err != nil {
+		return nil, fmt.Errorf("error preparing query GetKeyTransparencyTreeSize: %w", err)
+	}
+	if q.getLatestAuditEventHashStmt, err = db.PrepareContext(ctx, getLatestAuditEventHash); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLatestAuditEventHash: %w", err)
+	}
+	if q.getLatestClusterEventIDStmt, err = db.PrepareContext(ctx, getLatestClusterEventID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLatestClusterEventID: %w", err)
+	}
+	if q.getMFAChallengeStmt, err = db.PrepareContext(ctx, getMFAChallenge); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMFAChallenge: %w", err)
+	}
+	if q.getMessageByIDStmt, err = db.PrepareContext(ctx, getMessageByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageByID: %w", err)
+	}
+	if q.getMessageBySenderClientMessageIDStmt, err = db.PrepareContext(ctx, getMessageBySenderClientMessageID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageBySenderClientMessageID: %w", err)
+	}
+	if q.getPushSubscriptionByDeviceIDStmt, err = db.PrepareContext(ctx, getPushSubscriptionByDeviceID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPushSubscriptionByDeviceID: %w", err)
+	}
+	if q.getRateLimitCounterStmt, err = db.PrepareContext(ctx, getRateLimitCounter); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRateLimitCounter: %w", err)
+	}
 	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
 	}
+	if q.getUserAttachmentUsageStmt, err = db.PrepareContext(ctx, getUserAttachmentUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserAttachmentUsage: %w", err)
+	}
+	if q.getUserByCredentialsIDStmt, err = db.PrepareContext(ctx, getUserByCredentialsID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByCredentialsID: %w", err)
+	}
+	if q.getUserByIDStmt, err = db.PrepareContext(ctx, getUserByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByID: %w", err)
+	}
+	if q.getUserByUsernameStmt, err = db.PrepareContext(ctx, getUserByUsername); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByUsername: %w", err)
+	}
+	if q.hitRateLimitCounterStmt, err = db.PrepareContext(ctx, hitRateLimitCounter); err != nil {
+		return nil, fmt.Errorf("error preparing query HitRateLimitCounter: %w", err)
+	}
+	if q.insertAccountDeletedEventsStmt, err = db.PrepareContext(ctx, insertAccountDeletedEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertAccountDeletedEvents: %w", err)
+	}
+	if q.insertAccountDeletionStmt, err = db.PrepareContext(ctx, insertAccountDeletion); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertAccountDeletion: %w", err)
+	}
+	if q.insertAttachmentStmt, err = db.PrepareContext(ctx, insertAttachment); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertAttachment: %w", err)
+	}
+	if q.insertAuditEventStmt, err = db.PrepareContext(ctx, insertAuditEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertAuditEvent: %w", err)
+	}
+	if q.insertClusterEventStmt, err = db.PrepareContext(ctx, insertClusterEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertClusterEvent: %w", err)
+	}
+	if q.insertConversationStmt, err = db.PrepareContext(ctx, insertConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertConversation: %w", err)
+	}
+	if q.insertConversationEventStmt, err = db.PrepareContext(ctx, insertConversationEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertConversationEvent: %w", err)
+	}
+	if q.insertConversationParticipantStmt, err = db.PrepareContext(ctx, insertConversationParticipant); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertConversationParticipant: %w", err)
+	}
 	if q.insertCredentialsStmt, err = db.PrepareContext(ctx, insertCredentials); err != nil {
 		return nil, fmt.Errorf("error preparing query InsertCredentials: %w", err)
 	}
+	if q.insertDeviceStmt, err = db.PrepareContext(ctx, insertDevice); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertDevice: %w", err)
+	}
+	if q.insertDirectConversationStmt, err = db.PrepareContext(ctx, insertDirectConversation); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertDirectConversation: %w", err)
+	}
 	if q.insertEmailVerificationTokenStmt, err = db.PrepareContext(ctx, insertEmailVerificationToken); err != nil {
 		return nil, fmt.Errorf("error preparing query InsertEmailVerificationToken: %w", err)
 	}
+	if q.insertEnvelopeStmt, err = db.PrepareContext(ctx, insertEnvelope); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertEnvelope: %w", err)
+	}
+	if q.insertHistoryTransferStmt, err = db.PrepareContext(ctx, insertHistoryTransfer); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertHistoryTransfer: %w", err)
+	}
+	if q.insertHistoryTransferChunkStmt, err = db.PrepareContext(ctx, insertHistoryTransferChunk); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertHistoryTransferChunk: %w", err)
+	}
+	if q.insertIdentityKeyChangedEventsStmt, err = db.PrepareContext(ctx, insertIdentityKeyChangedEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertIdentityKeyChangedEvents: %w", err)
+	}
+	if q.insertIdentityKeyHistoryStmt, err = db.PrepareContext(ctx, insertIdentityKeyHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertIdentityKeyHistory: %w", err)
+	}
+	if q.insertKeyBackupStmt, err = db.PrepareContext(ctx, insertKeyBackup); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertKeyBackup: %w", err)
+	}
+	if q.insertKeyTransparencyTreeHeadStmt, err = db.PrepareContext(ctx, insertKeyTransparencyTreeHead); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertKeyTransparencyTreeHead: %w", err)
+	}
+	if q.insertLoginAlertStmt, err = db.PrepareContext(ctx, insertLoginAlert); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertLoginAlert: %w", err)
+	}
+	if q.insertLoginHistoryStmt, err = db.PrepareContext(ctx, insertLoginHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertLoginHistory: %w", err)
+	}
+	if q.insertLoginLinkTokenStmt, err = db.PrepareContext(ctx, insertLoginLinkToken); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertLoginLinkToken: %w", err)
+	}
+	if q.insertMFAChallengeStmt, err = db.PrepareContext(ctx, insertMFAChallenge); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertMFAChallenge: %w", err)
+	}
+	if q.insertMessageStmt, err = db.PrepareContext(ctx, insertMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertMessage: %w", err)
+	}
+	if q.insertMessageAttachmentStmt, err = db.PrepareContext(ctx, insertMessageAttachment); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertMessageAttachment: %w", err)
+	}
+	if q.insertMessageDeliveryStmt, err = db.PrepareContext(ctx, insertMessageDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertMessageDelivery: %w", err)
+	}
+	if q.insertOpaqueCredentialsStmt, err = db.PrepareContext(ctx, insertOpaqueCredentials); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertOpaqueCredentials: %w", err)
+	}
+	if q.insertOpaqueLoginStmt, err = db.PrepareContext(ctx, insertOpaqueLogin); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertOpaqueLogin: %w", err)
+	}
+	if q.insertOpaqueRegistrationStmt, err = db.PrepareContext(ctx, insertOpaqueRegistration); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertOpaqueRegistration: %w", err)
+	}
+	if q.insertOpaqueUpgradeStmt, err = db.PrepareContext(ctx, insertOpaqueUpgrade); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertOpaqueUpgrade: %w", err)
+	}
+	if q.insertPasskeyStmt, err = db.PrepareContext(ctx, insertPasskey); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertPasskey: %w", err)
+	}
+	if q.insertPasswordResetTokenStmt, err = db.PrepareContext(ctx, insertPasswordResetToken); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertPasswordResetToken: %w", err)
+	}
+	if q.insertRecoveryCodesStmt, err = db.PrepareContext(ctx, insertRecoveryCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertRecoveryCodes: %w", err)
+	}
 	if q.insertSessionStmt, err = db.PrepareContext(ctx, insertSession); err != nil {
 		return nil, fmt.Errorf("error preparing query InsertSession: %w", err)
 	}
 	if q.insertUserStmt, err = db.PrepareContext(ctx, insertUser); err != nil {
 		return nil, fmt.Errorf("error preparing query InsertUser: %w", err)
 	}
+	if q.insertVerifiedIdentityKeyChangedEventsStmt, err = db.PrepareContext(ctx, insertVerifiedIdentityKeyChangedEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertVerifiedIdentityKeyChangedEvents: %w", err)
+	}
+	if q.insertWebAuthnCeremonyStmt, err = db.PrepareContext(ctx, insertWebAuthnCeremony); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertWebAuthnCeremony: %w", err)
+	}
+	if q.listAuditEventsAfterIDStmt, err = db.PrepareContext(ctx, listAuditEventsAfterID); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAuditEventsAfterID: %w", err)
+	}
+	if q.listClusterEventsAfterIDStmt, err = db.PrepareContext(ctx, listClusterEventsAfterID); err != nil {
+		return nil, fmt.Errorf("error preparing query ListClusterEventsAfterID: %w", err)
+	}
+	if q.listCollectableAttachmentsStmt, err = db.PrepareContext(ctx, listCollectableAttachments); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCollectableAttachments: %w", err)
+	}
+	if q.listContactUserIDsStmt, err = db.PrepareContext(ctx, listContactUserIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListContactUserIDs: %w", err)
+	}
+	if q.listConversationDevicesStmt, err = db.PrepareContext(ctx, listConversationDevices); err != nil {
+		return nil, fmt.Errorf("error preparing query ListConversationDevices: %w", err)
+	}
+	if q.listConversationEventsStmt, err = db.PrepareContext(ctx, listConversationEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query ListConversationEvents: %w", err)
+	}
+	if q.listConversationMessagesAfterSeqStmt, err = db.PrepareContext(ctx, listConversationMessagesAfterSeq); err != nil {
+		return nil, fmt.Errorf("error preparing query ListConversationMessagesAfterSeq: %w", err)
+	}
+	if q.listConversationParticipantUserIDsStmt, err = db.PrepareContext(ctx, listConversationParticipantUserIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListConversationParticipantUserIDs: %w", err)
+	}
+	if q.listConversationsByUserIDStmt, err = db.PrepareContext(ctx, listConversationsByUserID); err != nil {
+		return nil, fmt.Errorf("error preparing query ListConversationsByUserID: %w", err)
+	}
+	if q.listCredentialsAuditEventsStmt, err = db.PrepareContext(ctx, listCredentialsAuditEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCredentialsAuditEvents: %w", err)
+	}
+	if q.listCredentialsPasskeysStmt, err = db.PrepareContext(ctx, listCredentialsPasskeys); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCredentialsPasskeys: %w", err)
+	}
+	if q.listDeviceHistoryTransfersStmt, err = db.PrepareContext(ctx, listDeviceHistoryTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDeviceHistoryTransfers: %w", err)
+	}
+	if q.listDevicesByUserIDStmt, err = db.PrepareContext(ctx, listDevicesByUserID); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDevicesByUserID: %w", err)
+	}
+	if q.listDueAccountDeletionsStmt, err = db.PrepareContext(ctx, listDueAccountDeletions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDueAccountDeletions: %w", err)
+	}
+	if q.listExpiredHistoryTransfersStmt, err = db.PrepareContext(ctx, listExpiredHistoryTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListExpiredHistoryTransfers: %w", err)
+	}
+	if q.listExpiredUploadsStmt, err = db.PrepareContext(ctx, listExpiredUploads); err != nil {
+		return nil, fmt.Errorf("error preparing query ListExpiredUploads: %w", err)
+	}
+	if q.listHistoryTransferChunkBlobsStmt, err = db.PrepareContext(ctx, listHistoryTransferChunkBlobs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListHistoryTransferChunkBlobs: %w", err)
+	}
+	if q.listIdentityKeyHistoryByDeviceIDStmt, err = db.PrepareContext(ctx, listIdentityKeyHistoryByDeviceID); err != nil {
+		return nil, fmt.Errorf("error preparing query ListIdentityKeyHistoryByDeviceID: %w", err)
+	}
+	if q.listKeyTransparencyEntriesByUsernameStmt, err = db.PrepareContext(ctx, listKeyTransparencyEntriesByUsername); err != nil {
+		return nil, fmt.Errorf("error preparing query ListKeyTransparencyEntriesByUsername: %w", err)
+	}
+	if q.listKeyTransparencyLeafHashesStmt, err = db.PrepareContext(ctx, listKeyTransparencyLeafHashes); err != nil {
+		return nil, fmt.Errorf("error preparing query ListKeyTransparencyLeafHashes: %w", err)
+	}
+	if q.listMailboxEnvelopesStmt, err = db.PrepareContext(ctx, listMailboxEnvelopes); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMailboxEnvelopes: %w", err)
+	}
+	if q.listMessageAttachmentIDsStmt, err = db.PrepareContext(ctx, listMessageAttachmentIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMessageAttachmentIDs: %w", err)
+	}
+	if q.listMessageDeliveriesStmt, err = db.PrepareContext(ctx, listMessageDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMessageDeliveries: %w", err)
+	}
+	if q.listRecentLoginHistoryStmt, err = db.PrepareContext(ctx, listRecentLoginHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query ListRecentLoginHistory: %w", err)
+	}
+	if q.listUserAttachmentIDsStmt, err = db.PrepareContext(ctx, listUserAttachmentIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUserAttachmentIDs: %w", err)
+	}
+	if q.listUserDeviceIDsStmt, err = db.PrepareContext(ctx, listUserDeviceIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUserDeviceIDs: %w", err)
+	}
+	if q.listUserHistoryTransfersStmt, err = db.PrepareContext(ctx, listUserHistoryTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUserHistoryTransfers: %w", err)
+	}
+	if q.lockAuditEventsStmt, err = db.PrepareContext(ctx, lockAuditEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query LockAuditEvents: %w", err)
+	}
+	if q.lockClusterEventsStmt, err = db.PrepareContext(ctx, lockClusterEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query LockClusterEvents: %w", err)
+	}
+	if q.lockUserStmt, err = db.PrepareContext(ctx, lockUser); err != nil {
+		return nil, fmt.Errorf("error preparing query LockUser: %w", err)
+	}
+	if q.markAttachmentUploadedStmt, err = db.PrepareContext(ctx, markAttachmentUploaded); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkAttachmentUploaded: %w", err)
+	}
+	if q.markContactVerificationsKeyChangedStmt, err = db.PrepareContext(ctx, markContactVerificationsKeyChanged); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkContactVerificationsKeyChanged: %w", err)
+	}
 	if q.markEmailAsVerifiedStmt, err = db.PrepareContext(ctx, markEmailAsVerified); err != nil {
 		return nil, fmt.Errorf("error preparing query MarkEmailAsVerified: %w", err)
 	}
+	if q.markEnvelopesSentStmt, err = db.PrepareContext(ctx, markEnvelopesSent); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkEnvelopesSent: %w", err)
+	}
+	if q.markHistoryTransferChunkDownloadedStmt, err = db.PrepareContext(ctx, markHistoryTransferChunkDownloaded); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkHistoryTransferChunkDownloaded: %w", err)
+	}
+	if q.markMessageDeliveredStmt, err = db.PrepareContext(ctx, markMessageDelivered); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkMessageDelivered: %w", err)
+	}
+	if q.markMessagesReadStmt, err = db.PrepareContext(ctx, markMessagesRead); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkMessagesRead: %w", err)
+	}
+	if q.nextAuditEventIDStmt, err = db.PrepareContext(ctx, nextAuditEventID); err != nil {
+		return nil, fmt.Errorf("error preparing query NextAuditEventID: %w", err)
+	}
+	if q.notifyClusterEventStmt, err = db.PrepareContext(ctx, notifyClusterEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query NotifyClusterEvent: %w", err)
+	}
+	if q.resetKeyBackupGuessesStmt, err = db.PrepareContext(ctx, resetKeyBackupGuesses); err != nil {
+		return nil, fmt.Errorf("error preparing query ResetKeyBackupGuesses: %w", err)
+	}
 	if q.rollbackStmt, err = db.PrepareContext(ctx, rollback); err != nil {
 		return nil, fmt.Errorf("error preparing query Rollback: %w", err)
 	}
+	if q.setMFAChallengeWebAuthnSessionStmt, err = db.PrepareContext(ctx, setMFAChallengeWebAuthnSession); err != nil {
+		return nil, fmt.Errorf("error preparing query SetMFAChallengeWebAuthnSession: %w", err)
+	}
+	if q.setOpaqueRecordStmt, err = db.PrepareContext(ctx, setOpaqueRecord); err != nil {
+		return nil, fmt.Errorf("error preparing query SetOpaqueRecord: %w", err)
+	}
+	if q.setPasswordResetRequiredStmt, err = db.PrepareContext(ctx, setPasswordResetRequired); err != nil {
+		return nil, fmt.Errorf("error preparing query SetPasswordResetRequired: %w", err)
+	}
+	if q.setPendingTOTPSecretStmt, err = db.PrepareContext(ctx, setPendingTOTPSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query SetPendingTOTPSecret: %w", err)
+	}
+	if q.updateAttachmentUploadOffsetStmt, err = db.PrepareContext(ctx, updateAttachmentUploadOffset); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateAttachmentUploadOffset: %w", err)
+	}
+	if q.updateConversationDisappearingTimerStmt, err = db.PrepareContext(ctx, updateConversationDisappearingTimer); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateConversationDisappearingTimer: %w", err)
+	}
+	if q.updateCredentialsPasswordStmt, err = db.PrepareContext(ctx, updateCredentialsPassword); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateCredentialsPassword: %w", err)
+	}
+	if q.updateDeviceIdentityKeyStmt, err = db.PrepareContext(ctx, updateDeviceIdentityKey); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateDeviceIdentityKey: %w", err)
+	}
+	if q.updateHistoryTransferStatusStmt, err = db.PrepareContext(ctx, updateHistoryTransferStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateHistoryTransferStatus: %w", err)
+	}
+	if q.updateKeyBackupCiphertextStmt, err = db.PrepareContext(ctx, updateKeyBackupCiphertext); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateKeyBackupCiphertext: %w", err)
+	}
+	if q.updateKeyBackupPINStmt, err = db.PrepareContext(ctx, updateKeyBackupPIN); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateKeyBackupPIN: %w", err)
+	}
+	if q.updatePasskeyUseStmt, err = db.PrepareContext(ctx, updatePasskeyUse); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdatePasskeyUse: %w", err)
+	}
+	if q.updateUserDeliveryAccessKeyStmt, err = db.PrepareContext(ctx, updateUserDeliveryAccessKey); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateUserDeliveryAccessKey: %w", err)
+	}
+	if q.updateUserPresenceVisibilityStmt, err = db.PrepareContext(ctx, updateUserPresenceVisibility); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateUserPresenceVisibility: %w", err)
+	}
+	if q.updateUserReadReceiptsEnabledStmt, err = db.PrepareContext(ctx, updateUserReadReceiptsEnabled); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateUserReadReceiptsEnabled: %w", err)
+	}
+	if q.upsertContactVerificationStmt, err = db.PrepareContext(ctx, upsertContactVerification); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertContactVerification: %w", err)
+	}
+	if q.upsertPushSubscriptionStmt, err = db.PrepareContext(ctx, upsertPushSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertPushSubscription: %w", err)
+	}
+	if q.useKeyBackupGuessStmt, err = db.PrepareContext(ctx, useKeyBackupGuess); err != nil {
+		return nil, fmt.Errorf("error preparing query UseKeyBackupGuess: %w", err)
+	}
+	if q.useRecoveryCodeStmt, err = db.PrepareContext(ctx, useRecoveryCode); err != nil {
+		return nil, fmt.Errorf("error preparing query UseRecoveryCode: %w", err)
+	}
+	if q.useTOTPCounterStmt, err = db.PrepareContext(ctx, useTOTPCounter); err != nil {
+		return nil, fmt.Errorf("error preparing query UseTOTPCounter: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.appendKeyTransparencyEntryStmt != nil {
+		if cerr := q.appendKeyTransparencyEntryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing appendKeyTransparencyEntryStmt: %w", cerr)
+		}
+	}
 	if q.beginStmt != nil {
 		if cerr := q.beginStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing beginStmt: %w", cerr)
 		}
 	}
+	if q.cancelAccountDeletionStmt != nil {
+		if cerr := q.cancelAccountDeletionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing cancelAccountDeletionStmt: %w", cerr)
+		}
+	}
+	if q.cancelCredentialsAccountDeletionStmt != nil {
+		if cerr := q.cancelCredentialsAccountDeletionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing cancelCredentialsAccountDeletionStmt: %w", cerr)
+		}
+	}
+	if q.cancelHistoryTransferStmt != nil {
+		if cerr := q.cancelHistoryTransferStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing cancelHistoryTransferStmt: %w", cerr)
+		}
+	}
+	if q.checkAttachmentAccessStmt != nil {
+		if cerr := q.checkAttachmentAccessStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing checkAttachmentAccessStmt: %w", cerr)
+		}
+	}
+	if q.checkContactStmt != nil {
+		if cerr := q.checkContactStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing checkContactStmt: %w", cerr)
+		}
+	}
+	if q.checkConversationParticipantStmt != nil {
+		if cerr := q.checkConversationParticipantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing checkConversationParticipantStmt: %w", cerr)
+		}
+	}
 	if q.checkEmailStmt != nil {
 		if cerr := q.checkEmailStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing checkEmailStmt: %w", cerr)
 		}
 	}
+	if q.checkMessageDeletedStmt != nil {
+		if cerr := q.checkMessageDeletedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing checkMessageDeletedStmt: %w", cerr)
+		}
+	}
 	if q.checkUsernameStmt != nil {
 		if cerr := q.checkUsernameStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing checkUsernameStmt: %w", cerr)
@@ -91,36 +632,526 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing commitStmt: %w", cerr)
 		}
 	}
+	if q.countCredentialsPasskeysStmt != nil {
+		if cerr := q.countCredentialsPasskeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countCredentialsPasskeysStmt: %w", cerr)
+		}
+	}
+	if q.countOpenHistoryTransfersStmt != nil {
+		if cerr := q.countOpenHistoryTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countOpenHistoryTransfersStmt: %w", cerr)
+		}
+	}
+	if q.countRecoveryCodesStmt != nil {
+		if cerr := q.countRecoveryCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countRecoveryCodesStmt: %w", cerr)
+		}
+	}
+	if q.deleteAttachmentStmt != nil {
+		if cerr := q.deleteAttachmentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteAttachmentStmt: %w", cerr)
+		}
+	}
+	if q.deleteClusterEventsBeforeStmt != nil {
+		if cerr := q.deleteClusterEventsBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteClusterEventsBeforeStmt: %w", cerr)
+		}
+	}
+	if q.deleteContactVerificationStmt != nil {
+		if cerr := q.deleteContactVerificationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteContactVerificationStmt: %w", cerr)
+		}
+	}
+	if q.deleteCredentialsStmt != nil {
+		if cerr := q.deleteCredentialsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCredentialsStmt: %w", cerr)
+		}
+	}
+	if q.deleteCredentialsPasswordResetTokensStmt != nil {
+		if cerr := q.deleteCredentialsPasswordResetTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCredentialsPasswordResetTokensStmt: %w", cerr)
+		}
+	}
+	if q.deleteCredentialsSessionsStmt != nil {
+		if cerr := q.deleteCredentialsSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCredentialsSessionsStmt: %w", cerr)
+		}
+	}
+	if q.deleteEnvelopeStmt != nil {
+		if cerr := q.deleteEnvelopeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteEnvelopeStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredEnvelopesStmt != nil {
+		if cerr := q.deleteExpiredEnvelopesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredEnvelopesStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredMessagesStmt != nil {
+		if cerr := q.deleteExpiredMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredMessagesStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredRateLimitCountersStmt != nil {
+		if cerr := q.deleteExpiredRateLimitCountersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredRateLimitCountersStmt: %w", cerr)
+		}
+	}
+	if q.deleteHistoryTransferStmt != nil {
+		if cerr := q.deleteHistoryTransferStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteHistoryTransferStmt: %w", cerr)
+		}
+	}
+	if q.deleteHistoryTransferChunksStmt != nil {
+		if cerr := q.deleteHistoryTransferChunksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteHistoryTransferChunksStmt: %w", cerr)
+		}
+	}
+	if q.deleteKeyBackupStmt != nil {
+		if cerr := q.deleteKeyBackupStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteKeyBackupStmt: %w", cerr)
+		}
+	}
+	if q.deleteLoginAlertStmt != nil {
+		if cerr := q.deleteLoginAlertStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteLoginAlertStmt: %w", cerr)
+		}
+	}
+	if q.deleteLoginLinkTokenStmt != nil {
+		if cerr := q.deleteLoginLinkTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteLoginLinkTokenStmt: %w", cerr)
+		}
+	}
+	if q.deleteMFAChallengeStmt != nil {
+		if cerr := q.deleteMFAChallengeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMFAChallengeStmt: %w", cerr)
+		}
+	}
+	if q.deleteMessageEnvelopesStmt != nil {
+		if cerr := q.deleteMessageEnvelopesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMessageEnvelopesStmt: %w", cerr)
+		}
+	}
+	if q.deleteOldLoginHistoryStmt != nil {
+		if cerr := q.deleteOldLoginHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOldLoginHistoryStmt: %w", cerr)
+		}
+	}
+	if q.deleteOpaqueLoginStmt != nil {
+		if cerr := q.deleteOpaqueLoginStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOpaqueLoginStmt: %w", cerr)
+		}
+	}
+	if q.deleteOpaqueRegistrationStmt != nil {
+		if cerr := q.deleteOpaqueRegistrationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOpaqueRegistrationStmt: %w", cerr)
+		}
+	}
+	if q.deleteOpaqueUpgradeStmt != nil {
+		if cerr := q.deleteOpaqueUpgradeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOpaqueUpgradeStmt: %w", cerr)
+		}
+	}
+	if q.deletePasskeyStmt != nil {
+		if cerr := q.deletePasskeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePasskeyStmt: %w", cerr)
+		}
+	}
+	if q.deletePasswordResetTokenStmt != nil {
+		if cerr := q.deletePasswordResetTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePasswordResetTokenStmt: %w", cerr)
+		}
+	}
+	if q.deletePushSubscriptionStmt != nil {
+		if cerr := q.deletePushSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePushSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.deletePushSubscriptionByEndpointStmt != nil {
+		if cerr := q.deletePushSubscriptionByEndpointStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePushSubscriptionByEndpointStmt: %w", cerr)
+		}
+	}
+	if q.deleteRateLimitCounterStmt != nil {
+		if cerr := q.deleteRateLimitCounterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteRateLimitCounterStmt: %w", cerr)
+		}
+	}
+	if q.deleteRecoveryCodesStmt != nil {
+		if cerr := q.deleteRecoveryCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteRecoveryCodesStmt: %w", cerr)
+		}
+	}
+	if q.deleteSessionStmt != nil {
+		if cerr := q.deleteSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSessionStmt: %w", cerr)
+		}
+	}
 	if q.deleteStaleEmailVerificationTokensStmt != nil {
 		if cerr := q.deleteStaleEmailVerificationTokensStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteStaleEmailVerificationTokensStmt: %w", cerr)
 		}
 	}
+	if q.deleteStaleLoginAlertsStmt != nil {
+		if cerr := q.deleteStaleLoginAlertsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStaleLoginAlertsStmt: %w", cerr)
+		}
+	}
+	if q.deleteStaleMFAChallengesStmt != nil {
+		if cerr := q.deleteStaleMFAChallengesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStaleMFAChallengesStmt: %w", cerr)
+		}
+	}
+	if q.deleteStaleOpaqueLoginsStmt != nil {
+		if cerr := q.deleteStaleOpaqueLoginsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStaleOpaqueLoginsStmt: %w", cerr)
+		}
+	}
+	if q.deleteStaleOpaqueRegistrationsStmt != nil {
+		if cerr := q.deleteStaleOpaqueRegistrationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStaleOpaqueRegistrationsStmt: %w", cerr)
+		}
+	}
+	if q.deleteStaleOpaqueUpgradesStmt != nil {
+		if cerr := q.deleteStaleOpaqueUpgradesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStaleOpaqueUpgradesStmt: %w", cerr)
+		}
+	}
+	if q.deleteStalePasswordResetTokensStmt != nil {
+		if cerr := q.deleteStalePasswordResetTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStalePasswordResetTokensStmt: %w", cerr)
+		}
+	}
+	if q.deleteStaleWebAuthnCeremoniesStmt != nil {
+		if cerr := q.deleteStaleWebAuthnCeremoniesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStaleWebAuthnCeremoniesStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebAuthnCeremonyStmt != nil {
+		if cerr := q.deleteWebAuthnCeremonyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebAuthnCeremonyStmt: %w", cerr)
+		}
+	}
+	if q.disableTOTPStmt != nil {
+		if cerr := q.disableTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing disableTOTPStmt: %w", cerr)
+		}
+	}
+	if q.enableTOTPStmt != nil {
+		if cerr := q.enableTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing enableTOTPStmt: %w", cerr)
+		}
+	}
+	if q.getAccountDeletionByCredentialsIDStmt != nil {
+		if cerr := q.getAccountDeletionByCredentialsIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountDeletionByCredentialsIDStmt: %w", cerr)
+		}
+	}
+	if q.getAttachmentByIDStmt != nil {
+		if cerr := q.getAttachmentByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAttachmentByIDStmt: %w", cerr)
+		}
+	}
+	if q.getContactVerificationStmt != nil {
+		if cerr := q.getContactVerificationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getContactVerificationStmt: %w", cerr)
+		}
+	}
+	if q.getConversationLastSeqStmt != nil {
+		if cerr := q.getConversationLastSeqStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getConversationLastSeqStmt: %w", cerr)
+		}
+	}
 	if q.getCredentialsByEmailStmt != nil {
 		if cerr := q.getCredentialsByEmailStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getCredentialsByEmailStmt: %w", cerr)
 		}
 	}
+	if q.getCredentialsByIDStmt != nil {
+		if cerr := q.getCredentialsByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCredentialsByIDStmt: %w", cerr)
+		}
+	}
+	if q.getDeviceByIDStmt != nil {
+		if cerr := q.getDeviceByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDeviceByIDStmt: %w", cerr)
+		}
+	}
+	if q.getDirectConversationIDStmt != nil {
+		if cerr := q.getDirectConversationIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDirectConversationIDStmt: %w", cerr)
+		}
+	}
 	if q.getEmailVerificationTokenByIDStmt != nil {
 		if cerr := q.getEmailVerificationTokenByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getEmailVerificationTokenByIDStmt: %w", cerr)
 		}
 	}
+	if q.getHistoryTransferStmt != nil {
+		if cerr := q.getHistoryTransferStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getHistoryTransferStmt: %w", cerr)
+		}
+	}
+	if q.getHistoryTransferChunkStmt != nil {
+		if cerr := q.getHistoryTransferChunkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getHistoryTransferChunkStmt: %w", cerr)
+		}
+	}
+	if q.getHistoryTransferProgressStmt != nil {
+		if cerr := q.getHistoryTransferProgressStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getHistoryTransferProgressStmt: %w", cerr)
+		}
+	}
+	if q.getKeyBackupStmt != nil {
+		if cerr := q.getKeyBackupStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getKeyBackupStmt: %w", cerr)
+		}
+	}
+	if q.getKeyTransparencyEntryStmt != nil {
+		if cerr := q.getKeyTransparencyEntryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getKeyTransparencyEntryStmt: %w", cerr)
+		}
+	}
+	if q.getKeyTransparencyTreeHeadStmt != nil {
+		if cerr := q.getKeyTransparencyTreeHeadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getKeyTransparencyTreeHeadStmt: %w", cerr)
+		}
+	}
+	if q.getKeyTransparencyTreeSizeStmt != nil {
+		if cerr := q.getKeyTransparencyTreeSizeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getKeyTransparencyTreeSizeStmt: %w", cerr)
+		}
+	}
+	if q.getLatestAuditEventHashStmt != nil {
+		if cerr := q.getLatestAuditEventHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLatestAuditEventHashStmt: %w", cerr)
+		}
+	}
+	if q.getLatestClusterEventIDStmt != nil {
+		if cerr := q.getLatestClusterEventIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLatestClusterEventIDStmt: %w", cerr)
+		}
+	}
+	if q.getMFAChallengeStmt != nil {
+		if cerr := q.getMFAChallengeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMFAChallengeStmt: %w", cerr)
+		}
+	}
+	if q.getMessageByIDStmt != nil {
+		if cerr := q.getMessageByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageByIDStmt: %w", cerr)
+		}
+	}
+	if q.getMessageBySenderClientMessageIDStmt != nil {
+		if cerr := q.getMessageBySenderClientMessageIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageBySenderClientMessageIDStmt: %w", cerr)
+		}
+	}
+	if q.getPushSubscriptionByDeviceIDStmt != nil {
+		if cerr := q.getPushSubscriptionByDeviceIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPushSubscriptionByDeviceIDStmt: %w", cerr)
+		}
+	}
+	if q.getRateLimitCounterStmt != nil {
+		if cerr := q.getRateLimitCounterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRateLimitCounterStmt: %w", cerr)
+		}
+	}
 	if q.getSessionByIDStmt != nil {
 		if cerr := q.getSessionByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
 		}
 	}
+	if q.getUserAttachmentUsageStmt != nil {
+		if cerr := q.getUserAttachmentUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserAttachmentUsageStmt: %w", cerr)
+		}
+	}
+	if q.getUserByCredentialsIDStmt != nil {
+		if cerr := q.getUserByCredentialsIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByCredentialsIDStmt: %w", cerr)
+		}
+	}
+	if q.getUserByIDStmt != nil {
+		if cerr := q.getUserByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByIDStmt: %w", cerr)
+		}
+	}
+	if q.getUserByUsernameStmt != nil {
+		if cerr := q.getUserByUsernameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByUsernameStmt: %w", cerr)
+		}
+	}
+	if q.hitRateLimitCounterStmt != nil {
+		if cerr := q.hitRateLimitCounterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing hitRateLimitCounterStmt: %w", cerr)
+		}
+	}
+	if q.insertAccountDeletedEventsStmt != nil {
+		if cerr := q.insertAccountDeletedEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertAccountDeletedEventsStmt: %w", cerr)
+		}
+	}
+	if q.insertAccountDeletionStmt != nil {
+		if cerr := q.insertAccountDeletionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertAccountDeletionStmt: %w", cerr)
+		}
+	}
+	if q.insertAttachmentStmt != nil {
+		if cerr := q.insertAttachmentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertAttachmentStmt: %w", cerr)
+		}
+	}
+	if q.insertAuditEventStmt != nil {
+		if cerr := q.insertAuditEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertAuditEventStmt: %w", cerr)
+		}
+	}
+	if q.insertClusterEventStmt != nil {
+		if cerr := q.insertClusterEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertClusterEventStmt: %w", cerr)
+		}
+	}
+	if q.insertConversationStmt != nil {
+		if cerr := q.insertConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertConversationStmt: %w", cerr)
+		}
+	}
+	if q.insertConversationEventStmt != nil {
+		if cerr := q.insertConversationEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertConversationEventStmt: %w", cerr)
+		}
+	}
+	if q.insertConversationParticipantStmt != nil {
+		if cerr := q.insertConversationParticipantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertConversationParticipantStmt: %w", cerr)
+		}
+	}
 	if q.insertCredentialsStmt != nil {
 		if cerr := q.insertCredentialsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing insertCredentialsStmt: %w", cerr)
 		}
 	}
+	if q.insertDeviceStmt != nil {
+		if cerr := q.insertDeviceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertDeviceStmt: %w", cerr)
+		}
+	}
+	if q.insertDirectConversationStmt != nil {
+		if cerr := q.insertDirectConversationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertDirectConversationStmt: %w", cerr)
+		}
+	}
 	if q.insertEmailVerificationTokenStmt != nil {
 		if cerr := q.insertEmailVerificationTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing insertEmailVerificationTokenStmt: %w", cerr)
 		}
 	}
+	if q.insertEnvelopeStmt != nil {
+		if cerr := q.insertEnvelopeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertEnvelopeStmt: %w", cerr)
+		}
+	}
+	if q.insertHistoryTransferStmt != nil {
+		if cerr := q.insertHistoryTransferStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertHistoryTransferStmt: %w", cerr)
+		}
+	}
+	if q.insertHistoryTransferChunkStmt != nil {
+		if cerr := q.insertHistoryTransferChunkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertHistoryTransferChunkStmt: %w", cerr)
+		}
+	}
+	if q.insertIdentityKeyChangedEventsStmt != nil {
+		if cerr := q.insertIdentityKeyChangedEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertIdentityKeyChangedEventsStmt: %w", cerr)
+		}
+	}
+	if q.insertIdentityKeyHistoryStmt != nil {
+		if cerr := q.insertIdentityKeyHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertIdentityKeyHistoryStmt: %w", cerr)
+		}
+	}
+	if q.insertKeyBackupStmt != nil {
+		if cerr := q.insertKeyBackupStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertKeyBackupStmt: %w", cerr)
+		}
+	}
+	if q.insertKeyTransparencyTreeHeadStmt != nil {
+		if cerr := q.insertKeyTransparencyTreeHeadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertKeyTransparencyTreeHeadStmt: %w", cerr)
+		}
+	}
+	if q.insertLoginAlertStmt != nil {
+		if cerr := q.insertLoginAlertStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertLoginAlertStmt: %w", cerr)
+		}
+	}
+	if q.insertLoginHistoryStmt != nil {
+		if cerr := q.insertLoginHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertLoginHistoryStmt: %w", cerr)
+		}
+	}
+	if q.insertLoginLinkTokenStmt != nil {
+		if cerr := q.insertLoginLinkTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertLoginLinkTokenStmt: %w", cerr)
+		}
+	}
+	if q.insertMFAChallengeStmt != nil {
+		if cerr := q.insertMFAChallengeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertMFAChallengeStmt: %w", cerr)
+		}
+	}
+	if q.insertMessageStmt != nil {
+		if cerr := q.insertMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertMessageStmt: %w", cerr)
+		}
+	}
+	if q.insertMessageAttachmentStmt != nil {
+		if cerr := q.insertMessageAttachmentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertMessageAttachmentStmt: %w", cerr)
+		}
+	}
+	if q.insertMessageDeliveryStmt != nil {
+		if cerr := q.insertMessageDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertMessageDeliveryStmt: %w", cerr)
+		}
+	}
+	if q.insertOpaqueCredentialsStmt != nil {
+		if cerr := q.insertOpaqueCredentialsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertOpaqueCredentialsStmt: %w", cerr)
+		}
+	}
+	if q.insertOpaqueLoginStmt != nil {
+		if cerr := q.insertOpaqueLoginStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertOpaqueLoginStmt: %w", cerr)
+		}
+	}
+	if q.insertOpaqueRegistrationStmt != nil {
+		if cerr := q.insertOpaqueRegistrationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertOpaqueRegistrationStmt: %w", cerr)
+		}
+	}
+	if q.insertOpaqueUpgradeStmt != nil {
+		if cerr := q.insertOpaqueUpgradeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertOpaqueUpgradeStmt: %w", cerr)
+		}
+	}
+	if q.insertPasskeyStmt != nil {
+		if cerr := q.insertPasskeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertPasskeyStmt: %w", cerr)
+		}
+	}
+	if q.insertPasswordResetTokenStmt != nil {
+		if cerr := q.insertPasswordResetTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertPasswordResetTokenStmt: %w", cerr)
+		}
+	}
+	if q.insertRecoveryCodesStmt != nil {
+		if cerr := q.insertRecoveryCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertRecoveryCodesStmt: %w", cerr)
+		}
+	}
 	if q.insertSessionStmt != nil {
 		if cerr := q.insertSessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing insertSessionStmt: %w", cerr)
@@ -131,16 +1162,321 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing insertUserStmt: %w", cerr)
 		}
 	}
+	if q.insertVerifiedIdentityKeyChangedEventsStmt != nil {
+		if cerr := q.insertVerifiedIdentityKeyChangedEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertVerifiedIdentityKeyChangedEventsStmt: %w", cerr)
+		}
+	}
+	if q.insertWebAuthnCeremonyStmt != nil {
+		if cerr := q.insertWebAuthnCeremonyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertWebAuthnCeremonyStmt: %w", cerr)
+		}
+	}
+	if q.listAuditEventsAfterIDStmt != nil {
+		if cerr := q.listAuditEventsAfterIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAuditEventsAfterIDStmt: %w", cerr)
+		}
+	}
+	if q.listClusterEventsAfterIDStmt != nil {
+		if cerr := q.listClusterEventsAfterIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listClusterEventsAfterIDStmt: %w", cerr)
+		}
+	}
+	if q.listCollectableAttachmentsStmt != nil {
+		if cerr := q.listCollectableAttachmentsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCollectableAttachmentsStmt: %w", cerr)
+		}
+	}
+	if q.listContactUserIDsStmt != nil {
+		if cerr := q.listContactUserIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listContactUserIDsStmt: %w", cerr)
+		}
+	}
+	if q.listConversationDevicesStmt != nil {
+		if cerr := q.listConversationDevicesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listConversationDevicesStmt: %w", cerr)
+		}
+	}
+	if q.listConversationEventsStmt != nil {
+		if cerr := q.listConversationEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listConversationEventsStmt: %w", cerr)
+		}
+	}
+	if q.listConversationMessagesAfterSeqStmt != nil {
+		if cerr := q.listConversationMessagesAfterSeqStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listConversationMessagesAfterSeqStmt: %w", cerr)
+		}
+	}
+	if q.listConversationParticipantUserIDsStmt != nil {
+		if cerr := q.listConversationParticipantUserIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listConversationParticipantUserIDsStmt: %w", cerr)
+		}
+	}
+	if q.listConversationsByUserIDStmt != nil {
+		if cerr := q.listConversationsByUserIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listConversationsByUserIDStmt: %w", cerr)
+		}
+	}
+	if q.listCredentialsAuditEventsStmt != nil {
+		if cerr := q.listCredentialsAuditEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCredentialsAuditEventsStmt: %w", cerr)
+		}
+	}
+	if q.listCredentialsPasskeysStmt != nil {
+		if cerr := q.listCredentialsPasskeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCredentialsPasskeysStmt: %w", cerr)
+		}
+	}
+	if q.listDeviceHistoryTransfersStmt != nil {
+		if cerr := q.listDeviceHistoryTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDeviceHistoryTransfersStmt: %w", cerr)
+		}
+	}
+	if q.listDevicesByUserIDStmt != nil {
+		if cerr := q.listDevicesByUserIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDevicesByUserIDStmt: %w", cerr)
+		}
+	}
+	if q.listDueAccountDeletionsStmt != nil {
+		if cerr := q.listDueAccountDeletionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDueAccountDeletionsStmt: %w", cerr)
+		}
+	}
+	if q.listExpiredHistoryTransfersStmt != nil {
+		if cerr := q.listExpiredHistoryTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listExpiredHistoryTransfersStmt: %w", cerr)
+		}
+	}
+	if q.listExpiredUploadsStmt != nil {
+		if cerr := q.listExpiredUploadsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listExpiredUploadsStmt: %w", cerr)
+		}
+	}
+	if q.listHistoryTransferChunkBlobsStmt != nil {
+		if cerr := q.listHistoryTransferChunkBlobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listHistoryTransferChunkBlobsStmt: %w", cerr)
+		}
+	}
+	if q.listIdentityKeyHistoryByDeviceIDStmt != nil {
+		if cerr := q.listIdentityKeyHistoryByDeviceIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listIdentityKeyHistoryByDeviceIDStmt: %w", cerr)
+		}
+	}
+	if q.listKeyTransparencyEntriesByUsernameStmt != nil {
+		if cerr := q.listKeyTransparencyEntriesByUsernameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listKeyTransparencyEntriesByUsernameStmt: %w", cerr)
+		}
+	}
+	if q.listKeyTransparencyLeafHashesStmt != nil {
+		if cerr := q.listKeyTransparencyLeafHashesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listKeyTransparencyLeafHashesStmt: %w", cerr)
+		}
+	}
+	if q.listMailboxEnvelopesStmt != nil {
+		if cerr := q.listMailboxEnvelopesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMailboxEnvelopesStmt: %w", cerr)
+		}
+	}
+	if q.listMessageAttachmentIDsStmt != nil {
+		if cerr := q.listMessageAttachmentIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMessageAttachmentIDsStmt: %w", cerr)
+		}
+	}
+	if q.listMessageDeliveriesStmt != nil {
+		if cerr := q.listMessageDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMessageDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.listRecentLoginHistoryStmt != nil {
+		if cerr := q.listRecentLoginHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listRecentLoginHistoryStmt: %w", cerr)
+		}
+	}
+	if q.listUserAttachmentIDsStmt != nil {
+		if cerr := q.listUserAttachmentIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUserAttachmentIDsStmt: %w", cerr)
+		}
+	}
+	if q.listUserDeviceIDsStmt != nil {
+		if cerr := q.listUserDeviceIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUserDeviceIDsStmt: %w", cerr)
+		}
+	}
+	if q.listUserHistoryTransfersStmt != nil {
+		if cerr := q.listUserHistoryTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUserHistoryTransfersStmt: %w", cerr)
+		}
+	}
+	if q.lockAuditEventsStmt != nil {
+		if cerr := q.lockAuditEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockAuditEventsStmt: %w", cerr)
+		}
+	}
+	if q.lockClusterEventsStmt != nil {
+		if cerr := q.lockClusterEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockClusterEventsStmt: %w", cerr)
+		}
+	}
+	if q.lockUserStmt != nil {
+		if cerr := q.lockUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockUserStmt: %w", cerr)
+		}
+	}
+	if q.markAttachmentUploadedStmt != nil {
+		if cerr := q.markAttachmentUploadedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markAttachmentUploadedStmt: %w", cerr)
+		}
+	}
+	if q.markContactVerificationsKeyChangedStmt != nil {
+		if cerr := q.markContactVerificationsKeyChangedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markContactVerificationsKeyChangedStmt: %w", cerr)
+		}
+	}
 	if q.markEmailAsVerifiedStmt != nil {
 		if cerr := q.markEmailAsVerifiedStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing markEmailAsVerifiedStmt: %w", cerr)
 		}
 	}
+	if q.markEnvelopesSentStmt != nil {
+		if cerr := q.markEnvelopesSentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markEnvelopesSentStmt: %w", cerr)
+		}
+	}
+	if q.markHistoryTransferChunkDownloadedStmt != nil {
+		if cerr := q.markHistoryTransferChunkDownloadedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markHistoryTransferChunkDownloadedStmt: %w", cerr)
+		}
+	}
+	if q.markMessageDeliveredStmt != nil {
+		if cerr := q.markMessageDeliveredStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markMessageDeliveredStmt: %w", cerr)
+		}
+	}
+	if q.markMessagesReadStmt != nil {
+		if cerr := q.markMessagesReadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markMessagesReadStmt: %w", cerr)
+		}
+	}
+	if q.nextAuditEventIDStmt != nil {
+		if cerr := q.nextAuditEventIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing nextAuditEventIDStmt: %w", cerr)
+		}
+	}
+	if q.notifyClusterEventStmt != nil {
+		if cerr := q.notifyClusterEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing notifyClusterEventStmt: %w", cerr)
+		}
+	}
+	if q.resetKeyBackupGuessesStmt != nil {
+		if cerr := q.resetKeyBackupGuessesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing resetKeyBackupGuessesStmt: %w", cerr)
+		}
+	}
 	if q.rollbackStmt != nil {
 		if cerr := q.rollbackStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing rollbackStmt: %w", cerr)
 		}
 	}
+	if q.setMFAChallengeWebAuthnSessionStmt != nil {
+		if cerr := q.setMFAChallengeWebAuthnSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setMFAChallengeWebAuthnSessionStmt: %w", cerr)
+		}
+	}
+	if q.setOpaqueRecordStmt != nil {
+		if cerr := q.setOpaqueRecordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setOpaqueRecordStmt: %w", cerr)
+		}
+	}
+	if q.setPasswordResetRequiredStmt != nil {
+		if cerr := q.setPasswordResetRequiredStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setPasswordResetRequiredStmt: %w", cerr)
+		}
+	}
+	if q.setPendingTOTPSecretStmt != nil {
+		if cerr := q.setPendingTOTPSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setPendingTOTPSecretStmt: %w", cerr)
+		}
+	}
+	if q.updateAttachmentUploadOffsetStmt != nil {
+		if cerr := q.updateAttachmentUploadOffsetStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateAttachmentUploadOffsetStmt: %w", cerr)
+		}
+	}
+	if q.updateConversationDisappearingTimerStmt != nil {
+		if cerr := q.updateConversationDisappearingTimerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateConversationDisappearingTimerStmt: %w", cerr)
+		}
+	}
+	if q.updateCredentialsPasswordStmt != nil {
+		if cerr := q.updateCredentialsPasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateCredentialsPasswordStmt: %w", cerr)
+		}
+	}
+	if q.updateDeviceIdentityKeyStmt != nil {
+		if cerr := q.updateDeviceIdentityKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateDeviceIdentityKeyStmt: %w", cerr)
+		}
+	}
+	if q.updateHistoryTransferStatusStmt != nil {
+		if cerr := q.updateHistoryTransferStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateHistoryTransferStatusStmt: %w", cerr)
+		}
+	}
+	if q.updateKeyBackupCiphertextStmt != nil {
+		if cerr := q.updateKeyBackupCiphertextStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateKeyBackupCiphertextStmt: %w", cerr)
+		}
+	}
+	if q.updateKeyBackupPINStmt != nil {
+		if cerr := q.updateKeyBackupPINStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateKeyBackupPINStmt: %w", cerr)
+		}
+	}
+	if q.updatePasskeyUseStmt != nil {
+		if cerr := q.updatePasskeyUseStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updatePasskeyUseStmt: %w", cerr)
+		}
+	}
+	if q.updateUserDeliveryAccessKeyStmt != nil {
+		if cerr := q.updateUserDeliveryAccessKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateUserDeliveryAccessKeyStmt: %w", cerr)
+		}
+	}
+	if q.updateUserPresenceVisibilityStmt != nil {
+		if cerr := q.updateUserPresenceVisibilityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateUserPresenceVisibilityStmt: %w", cerr)
+		}
+	}
+	if q.updateUserReadReceiptsEnabledStmt != nil {
+		if cerr := q.updateUserReadReceiptsEnabledStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateUserReadReceiptsEnabledStmt: %w", cerr)
+		}
+	}
+	if q.upsertContactVerificationStmt != nil {
+		if cerr := q.upsertContactVerificationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertContactVerificationStmt: %w", cerr)
+		}
+	}
+	if q.upsertPushSubscriptionStmt != nil {
+		if cerr := q.upsertPushSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertPushSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.useKeyBackupGuessStmt != nil {
+		if cerr := q.useKeyBackupGuessStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useKeyBackupGuessStmt: %w", cerr)
+		}
+	}
+	if q.useRecoveryCodeStmt != nil {
+		if cerr := q.useRecoveryCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useRecoveryCodeStmt: %w", cerr)
+		}
+	}
+	if q.useTOTPCounterStmt != nil {
+		if cerr := q.useTOTPCounterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useTOTPCounterStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -178,41 +1514,375 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                                     DBTX
-	tx                                     *sql.Tx
-	beginStmt                              *sql.Stmt
-	checkEmailStmt                         *sql.Stmt
-	checkUsernameStmt                      *sql.Stmt
-	commitStmt                             *sql.Stmt
-	deleteStaleEmailVerificationTokensStmt *sql.Stmt
-	getCredentialsByEmailStmt              *sql.Stmt
-	getEmailVerificationTokenByIDStmt      *sql.Stmt
-	getSessionByIDStmt                     *sql.Stmt
-	insertCredentialsStmt                  *sql.Stmt
-	insertEmailVerificationTokenStmt       *sql.Stmt
-	insertSessionStmt                      *sql.Stmt
-	insertUserStmt                         *sql.Stmt
-	markEmailAsVerifiedStmt                *sql.Stmt
-	rollbackStmt                           *sql.Stmt
+	db                                         DBTX
+	tx                                         *sql.Tx
+	appendKeyTransparencyEntryStmt             *sql.Stmt
+	beginStmt                                  *sql.Stmt
+	cancelAccountDeletionStmt                  *sql.Stmt
+	cancelCredentialsAccountDeletionStmt       *sql.Stmt
+	cancelHistoryTransferStmt                  *sql.Stmt
+	checkAttachmentAccessStmt                  *sql.Stmt
+	checkContactStmt                           *sql.Stmt
+	checkConversationParticipantStmt           *sql.Stmt
+	checkEmailStmt                             *sql.Stmt
+	checkMessageDeletedStmt                    *sql.Stmt
+	checkUsernameStmt                          *sql.Stmt
+	commitStmt                                 *sql.Stmt
+	countCredentialsPasskeysStmt               *sql.Stmt
+	countOpenHistoryTransfersStmt              *sql.Stmt
+	countRecoveryCodesStmt                     *sql.Stmt
+	deleteAttachmentStmt                       *sql.Stmt
+	deleteClusterEventsBeforeStmt              *sql.Stmt
+	deleteContactVerificationStmt              *sql.Stmt
+	deleteCredentialsStmt                      *sql.Stmt
+	deleteCredentialsPasswordResetTokensStmt   *sql.Stmt
+	deleteCredentialsSessionsStmt              *sql.Stmt
+	deleteEnvelopeStmt                         *sql.Stmt
+	deleteExpiredEnvelopesStmt                 *sql.Stmt
+	deleteExpiredMessagesStmt                  *sql.Stmt
+	deleteExpiredRateLimitCountersStmt         *sql.Stmt
+	deleteHistoryTransferStmt                  *sql.Stmt
+	deleteHistoryTransferChunksStmt            *sql.Stmt
+	deleteKeyBackupStmt                        *sql.Stmt
+	deleteLoginAlertStmt                       *sql.Stmt
+	deleteLoginLinkTokenStmt                   *sql.Stmt
+	deleteMFAChallengeStmt                     *sql.Stmt
+	deleteMessageEnvelopesStmt                 *sql.Stmt
+	deleteOldLoginHistoryStmt                  *sql.Stmt
+	deleteOpaqueLoginStmt                      *sql.Stmt
+	deleteOpaqueRegistrationStmt               *sql.Stmt
+	deleteOpaqueUpgradeStmt                    *sql.Stmt
+	deletePasskeyStmt                          *sql.Stmt
+	deletePasswordResetTokenStmt               *sql.Stmt
+	deletePushSubscriptionStmt                 *sql.Stmt
+	deletePushSubscriptionByEndpointStmt       *sql.Stmt
+	deleteRateLimitCounterStmt                 *sql.Stmt
+	deleteRecoveryCodesStmt                    *sql.Stmt
+	deleteSessionStmt                          *sql.Stmt
+	deleteStaleEmailVerificationTokensStmt     *sql.Stmt
+	deleteStaleLoginAlertsStmt                 *sql.Stmt
+	deleteStaleMFAChallengesStmt               *sql.Stmt
+	deleteStaleOpaqueLoginsStmt                *sql.Stmt
+	deleteStaleOpaqueRegistrationsStmt         *sql.Stmt
+	deleteStaleOpaqueUpgradesStmt              *sql.Stmt
+	deleteStalePasswordResetTokensStmt         *sql.Stmt
+	deleteStaleWebAuthnCeremoniesStmt          *sql.Stmt
+	deleteWebAuthnCeremonyStmt                 *sql.Stmt
+	disableTOTPStmt                            *sql.Stmt
+	enableTOTPStmt                             *sql.Stmt
+	getAccountDeletionByCredentialsIDStmt      *sql.Stmt
+	getAttachmentByIDStmt                      *sql.Stmt
+	getContactVerificationStmt                 *sql.Stmt
+	getConversationLastSeqStmt                 *sql.Stmt
+	getCredentialsByEmailStmt                  *sql.Stmt
+	getCredentialsByIDStmt                     *sql.Stmt
+	getDeviceByIDStmt                          *sql.Stmt
+	getDirectConversationIDStmt                *sql.Stmt
+	getEmailVerificationTokenByIDStmt          *sql.Stmt
+	getHistoryTransferStmt                     *sql.Stmt
+	getHistoryTransferChunkStmt                *sql.Stmt
+	getHistoryTransferProgressStmt             *sql.Stmt
+	getKeyBackupStmt                           *sql.Stmt
+	getKeyTransparencyEntryStmt                *sql.Stmt
+	getKeyTransparencyTreeHeadStmt             *sql.Stmt
+	getKeyTransparencyTreeSizeStmt             *sql.Stmt
+	getLatestAuditEventHashStmt                *sql.Stmt
+	getLatestClusterEventIDStmt                *sql.Stmt
+	getMFAChallengeStmt                        *sql.Stmt
+	getMessageByIDStmt                         *sql.Stmt
+	getMessageBySenderClientMessageIDStmt      *sql.Stmt
+	getPushSubscriptionByDeviceIDStmt          *sql.Stmt
+	getRateLimitCounterStmt                    *sql.Stmt
+	getSessionByIDStmt                         *sql.Stmt
+	getUserAttachmentUsageStmt                 *sql.Stmt
+	getUserByCredentialsIDStmt                 *sql.Stmt
+	getUserByIDStmt                            *sql.Stmt
+	getUserByUsernameStmt                      *sql.Stmt
+	hitRateLimitCounterStmt                    *sql.Stmt
+	insertAccountDeletedEventsStmt             *sql.Stmt
+	insertAccountDeletionStmt                  *sql.Stmt
+	insertAttachmentStmt                       *sql.Stmt
+	insertAuditEventStmt                       *sql.Stmt
+	insertClusterEventStmt                     *sql.Stmt
+	insertConversationStmt                     *sql.Stmt
+	insertConversationEventStmt                *sql.Stmt
+	insertConversationParticipantStmt          *sql.Stmt
+	insertCredentialsStmt                      *sql.Stmt
+	insertDeviceStmt                           *sql.Stmt
+	insertDirectConversationStmt               *sql.Stmt
+	insertEmailVerificationTokenStmt           *sql.Stmt
+	insertEnvelopeStmt                         *sql.Stmt
+	insertHistoryTransferStmt                  *sql.Stmt
+	insertHistoryTransferChunkStmt             *sql.Stmt
+	insertIdentityKeyChangedEventsStmt         *sql.Stmt
+	insertIdentityKeyHistoryStmt               *sql.Stmt
+	insertKeyBackupStmt                        *sql.Stmt
+	insertKeyTransparencyTreeHeadStmt          *sql.Stmt
+	insertLoginAlertStmt                       *sql.Stmt
+	insertLoginHistoryStmt                     *sql.Stmt
+	insertLoginLinkTokenStmt                   *sql.Stmt
+	insertMFAChallengeStmt                     *sql.Stmt
+	insertMessageStmt                          *sql.Stmt
+	insertMessageAttachmentStmt                *sql.Stmt
+	insertMessageDeliveryStmt                  *sql.Stmt
+	insertOpaqueCredentialsStmt                *sql.Stmt
+	insertOpaqueLoginStmt                      *sql.Stmt
+	insertOpaqueRegistrationStmt               *sql.Stmt
+	insertOpaqueUpgradeStmt                    *sql.Stmt
+	insertPasskeyStmt                          *sql.Stmt
+	insertPasswordResetTokenStmt               *sql.Stmt
+	insertRecoveryCodesStmt                    *sql.Stmt
+	insertSessionStmt                          *sql.Stmt
+	insertUserStmt                             *sql.Stmt
+	insertVerifiedIdentityKeyChangedEventsStmt *sql.Stmt
+	insertWebAuthnCeremonyStmt                 *sql.Stmt
+	listAuditEventsAfterIDStmt                 *sql.Stmt
+	listClusterEventsAfterIDStmt               *sql.Stmt
+	listCollectableAttachmentsStmt             *sql.Stmt
+	listContactUserIDsStmt                     *sql.Stmt
+	listConversationDevicesStmt                *sql.Stmt
+	listConversationEventsStmt                 *sql.Stmt
+	listConversationMessagesAfterSeqStmt       *sql.Stmt
+	listConversationParticipantUserIDsStmt     *sql.Stmt
+	listConversationsByUserIDStmt              *sql.Stmt
+	listCredentialsAuditEventsStmt             *sql.Stmt
+	listCredentialsPasskeysStmt                *sql.Stmt
+	listDeviceHistoryTransfersStmt             *sql.Stmt
+	listDevicesByUserIDStmt                    *sql.Stmt
+	listDueAccountDeletionsStmt                *sql.Stmt
+	listExpiredHistoryTransfersStmt            *sql.Stmt
+	listExpiredUploadsStmt                     *sql.Stmt
+	listHistoryTransferChunkBlobsStmt          *sql.Stmt
+	listIdentityKeyHistoryByDeviceIDStmt       *sql.Stmt
+	listKeyTransparencyEntriesByUsernameStmt   *sql.Stmt
+	listKeyTransparencyLeafHashesStmt          *sql.Stmt
+	listMailboxEnvelopesStmt                   *sql.Stmt
+	listMessageAttachmentIDsStmt               *sql.Stmt
+	listMessageDeliveriesStmt                  *sql.Stmt
+	listRecentLoginHistoryStmt                 *sql.Stmt
+	listUserAttachmentIDsStmt                  *sql.Stmt
+	listUserDeviceIDsStmt                      *sql.Stmt
+	listUserHistoryTransfersStmt               *sql.Stmt
+	lockAuditEventsStmt                        *sql.Stmt
+	lockClusterEventsStmt                      *sql.Stmt
+	lockUserStmt                               *sql.Stmt
+	markAttachmentUploadedStmt                 *sql.Stmt
+	markContactVerificationsKeyChangedStmt     *sql.Stmt
+	markEmailAsVerifiedStmt                    *sql.Stmt
+	markEnvelopesSentStmt                      *sql.Stmt
+	markHistoryTransferChunkDownloadedStmt     *sql.Stmt
+	markMessageDeliveredStmt                   *sql.Stmt
+	markMessagesReadStmt                       *sql.Stmt
+	nextAuditEventIDStmt                       *sql.Stmt
+	notifyClusterEventStmt                     *sql.Stmt
+	resetKeyBackupGuessesStmt                  *sql.Stmt
+	rollbackStmt                               *sql.Stmt
+	setMFAChallengeWebAuthnSessionStmt         *sql.Stmt
+	setOpaqueRecordStmt                        *sql.Stmt
+	setPasswordResetRequiredStmt               *sql.Stmt
+	setPendingTOTPSecretStmt                   *sql.Stmt
+	updateAttachmentUploadOffsetStmt           *sql.Stmt
+	updateConversationDisappearingTimerStmt    *sql.Stmt
+	updateCredentialsPasswordStmt              *sql.Stmt
+	updateDeviceIdentityKeyStmt                *sql.Stmt
+	updateHistoryTransferStatusStmt            *sql.Stmt
+	updateKeyBackupCiphertextStmt              *sql.Stmt
+	updateKeyBackupPINStmt                     *sql.Stmt
+	updatePasskeyUseStmt                       *sql.Stmt
+	updateUserDeliveryAccessKeyStmt            *sql.Stmt
+	updateUserPresenceVisibilityStmt           *sql.Stmt
+	updateUserReadReceiptsEnabledStmt          *sql.Stmt
+	upsertContactVerificationStmt              *sql.Stmt
+	upsertPushSubscriptionStmt                 *sql.Stmt
+	useKeyBackupGuessStmt                      *sql.Stmt
+	useRecoveryCodeStmt                        *sql.Stmt
+	useTOTPCounterStmt                         *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                                     tx,
-		tx:                                     tx,
-		beginStmt:                              q.beginStmt,
-		checkEmailStmt:                         q.checkEmailStmt,
-		checkUsernameStmt:                      q.checkUsernameStmt,
-		commitStmt:                             q.commitStmt,
-		deleteStaleEmailVerificationTokensStmt: q.deleteStaleEmailVerificationTokensStmt,
-		getCredentialsByEmailStmt:              q.getCredentialsByEmailStmt,
-		getEmailVerificationTokenByIDStmt:      q.getEmailVerificationTokenByIDStmt,
-		getSessionByIDStmt:                     q.getSessionByIDStmt,
-		insertCredentialsStmt:                  q.insertCredentialsStmt,
-		insertEmailVerificationTokenStmt:       q.insertEmailVerificationTokenStmt,
-		insertSessionStmt:                      q.insertSessionStmt,
-		insertUserStmt:                         q.insertUserStmt,
-		markEmailAsVerifiedStmt:                q.markEmailAsVerifiedStmt,
-		rollbackStmt:                           q.rollbackStmt,
+		db:                                         tx,
+		tx:                                         tx,
+		appendKeyTransparencyEntryStmt:             q.appendKeyTransparencyEntryStmt,
+		beginStmt:                                  q.beginStmt,
+		cancelAccountDeletionStmt:                  q.cancelAccountDeletionStmt,
+		cancelCredentialsAccountDeletionStmt:       q.cancelCredentialsAccountDeletionStmt,
+		cancelHistoryTransferStmt:                  q.cancelHistoryTransferStmt,
+		checkAttachmentAccessStmt:                  q.checkAttachmentAccessStmt,
+		checkContactStmt:                           q.checkContactStmt,
+		checkConversationParticipantStmt:           q.checkConversationParticipantStmt,
+		checkEmailStmt:                             q.checkEmailStmt,
+		checkMessageDeletedStmt:                    q.checkMessageDeletedStmt,
+		checkUsernameStmt:                          q.checkUsernameStmt,
+		commitStmt:                                 q.commitStmt,
+		countCredentialsPasskeysStmt:               q.countCredentialsPasskeysStmt,
+		countOpenHistoryTransfersStmt:              q.countOpenHistoryTransfersStmt,
+		countRecoveryCodesStmt:                     q.countRecoveryCodesStmt,
+		deleteAttachmentStmt:                       q.deleteAttachmentStmt,
+		deleteClusterEventsBeforeStmt:              q.deleteClusterEventsBeforeStmt,
+		deleteContactVerificationStmt:              q.deleteContactVerificationStmt,
+		deleteCredentialsStmt:                      q.deleteCredentialsStmt,
+		deleteCredentialsPasswordResetTokensStmt:   q.deleteCredentialsPasswordResetTokensStmt,
+		deleteCredentialsSessionsStmt:              q.deleteCredentialsSessionsStmt,
+		deleteEnvelopeStmt:                         q.deleteEnvelopeStmt,
+		deleteExpiredEnvelopesStmt:                 q.deleteExpiredEnvelopesStmt,
+		deleteExpiredMessagesStmt:                  q.deleteExpiredMessagesStmt,
+		deleteExpiredRateLimitCountersStmt:         q.deleteExpiredRateLimitCountersStmt,
+		deleteHistoryTransferStmt:                  q.deleteHistoryTransferStmt,
+		deleteHistoryTransferChunksStmt:            q.deleteHistoryTransferChunksStmt,
+		deleteKeyBackupStmt:                        q.deleteKeyBackupStmt,
+		deleteLoginAlertStmt:                       q.deleteLoginAlertStmt,
+		deleteLoginLinkTokenStmt:                   q.deleteLoginLinkTokenStmt,
+		deleteMFAChallengeStmt:                     q.deleteMFAChallengeStmt,
+		deleteMessageEnvelopesStmt:                 q.deleteMessageEnvelopesStmt,
+		deleteOldLoginHistoryStmt:                  q.deleteOldLoginHistoryStmt,
+		deleteOpaqueLoginStmt:                      q.deleteOpaqueLoginStmt,
+		deleteOpaqueRegistrationStmt:               q.deleteOpaqueRegistrationStmt,
+		deleteOpaqueUpgradeStmt:                    q.deleteOpaqueUpgradeStmt,
+		deletePasskeyStmt:                          q.deletePasskeyStmt,
+		deletePasswordResetTokenStmt:               q.deletePasswordResetTokenStmt,
+		deletePushSubscriptionStmt:                 q.deletePushSubscriptionStmt,
+		deletePushSubscriptionByEndpointStmt:       q.deletePushSubscriptionByEndpointStmt,
+		deleteRateLimitCounterStmt:                 q.deleteRateLimitCounterStmt,
+		deleteRecoveryCodesStmt:                    q.deleteRecoveryCodesStmt,
+		deleteSessionStmt:                          q.deleteSessionStmt,
+		deleteStaleEmailVerificationTokensStmt:     q.deleteStaleEmailVerificationTokensStmt,
+		deleteStaleLoginAlertsStmt:                 q.deleteStaleLoginAlertsStmt,
+		deleteStaleMFAChallengesStmt:               q.deleteStaleMFAChallengesStmt,
+		deleteStaleOpaqueLoginsStmt:                q.deleteStaleOpaqueLoginsStmt,
+		deleteStaleOpaqueRegistrationsStmt:         q.deleteStaleOpaqueRegistrationsStmt,
+		deleteStaleOpaqueUpgradesStmt:              q.deleteStaleOpaqueUpgradesStmt,
+		deleteStalePasswordResetTokensStmt:         q.deleteStalePasswordResetTokensStmt,
+		deleteStaleWebAuthnCeremoniesStmt:          q.deleteStaleWebAuthnCeremoniesStmt,
+		deleteWebAuthnCeremonyStmt:                 q.deleteWebAuthnCeremonyStmt,
+		disableTOTPStmt:                            q.disableTOTPStmt,
+		enableTOTPStmt:                             q.enableTOTPStmt,
+		getAccountDeletionByCredentialsIDStmt:      q.getAccountDeletionByCredentialsIDStmt,
+		getAttachmentByIDStmt:                      q.getAttachmentByIDStmt,
+		getContactVerificationStmt:                 q.getContactVerificationStmt,
+		getConversationLastSeqStmt:                 q.getConversationLastSeqStmt,
+		getCredentialsByEmailStmt:                  q.getCredentialsByEmailStmt,
+		getCredentialsByIDStmt:                     q.getCredentialsByIDStmt,
+		getDeviceByIDStmt:                          q.getDeviceByIDStmt,
+		getDirectConversationIDStmt:                q.getDirectConversationIDStmt,
+		getEmailVerificationTokenByIDStmt:          q.getEmailVerificationTokenByIDStmt,
+		getHistoryTransferStmt:                     q.getHistoryTransferStmt,
+		getHistoryTransferChunkStmt:                q.getHistoryTransferChunkStmt,
+		getHistoryTransferProgressStmt:             q.getHistoryTransferProgressStmt,
+		getKeyBackupStmt:                           q.getKeyBackupStmt,
+		getKeyTransparencyEntryStmt:                q.getKeyTransparencyEntryStmt,
+		getKeyTransparencyTreeHeadStmt:             q.getKeyTransparencyTreeHeadStmt,
+		getKeyTransparencyTreeSizeStmt:             q.getKeyTransparencyTreeSizeStmt,
+		getLatestAuditEventHashStmt:                q.getLatestAuditEventHashStmt,
+		getLatestClusterEventIDStmt:                q.getLatestClusterEventIDStmt,
+		getMFAChallengeStmt:                        q.getMFAChallengeStmt,
+		getMessageByIDStmt:                         q.getMessageByIDStmt,
+		getMessageBySenderClientMessageIDStmt:      q.getMessageBySenderClientMessageIDStmt,
+		getPushSubscriptionByDeviceIDStmt:          q.getPushSubscriptionByDeviceIDStmt,
+		getRateLimitCounterStmt:                    q.getRateLimitCounterStmt,
+		getSessionByIDStmt:                         q.getSessionByIDStmt,
+		getUserAttachmentUsageStmt:                 q.getUserAttachmentUsageStmt,
+		getUserByCredentialsIDStmt:                 q.getUserByCredentialsIDStmt,
+		getUserByIDStmt:                            q.getUserByIDStmt,
+		getUserByUsernameStmt:                      q.getUserByUsernameStmt,
+		hitRateLimitCounterStmt:                    q.hitRateLimitCounterStmt,
+		insertAccountDeletedEventsStmt:             q.insertAccountDeletedEventsStmt,
+		insertAccountDeletionStmt:                  q.insertAccountDeletionStmt,
+		insertAttachmentStmt:                       q.insertAttachmentStmt,
+		insertAuditEventStmt:                       q.insertAuditEventStmt,
+		insertClusterEventStmt:                     q.insertClusterEventStmt,
+		insertConversationStmt:                     q.insertConversationStmt,
+		insertConversationEventStmt:                q.insertConversationEventStmt,
+		insertConversationParticipantStmt:          q.insertConversationParticipantStmt,
+		insertCredentialsStmt:                      q.insertCredentialsStmt,
+		insertDeviceStmt:                           q.insertDeviceStmt,
+		insertDirectConversationStmt:               q.insertDirectConversationStmt,
+		insertEmailVerificationTokenStmt:           q.insertEmailVerificationTokenStmt,
+		insertEnvelopeStmt:                         q.insertEnvelopeStmt,
+		insertHistoryTransferStmt:                  q.insertHistoryTransferStmt,
+		insertHistoryTransferChunkStmt:             q.insertHistoryTransferChunkStmt,
+		insertIdentityKeyChangedEventsStmt:         q.insertIdentityKeyChangedEventsStmt,
+		insertIdentityKeyHistoryStmt:               q.insertIdentityKeyHistoryStmt,
+		insertKeyBackupStmt:                        q.insertKeyBackupStmt,
+		insertKeyTransparencyTreeHeadStmt:          q.insertKeyTransparencyTreeHeadStmt,
+		insertLoginAlertStmt:                       q.insertLoginAlertStmt,
+		insertLoginHistoryStmt:                     q.insertLoginHistoryStmt,
+		insertLoginLinkTokenStmt:                   q.insertLoginLinkTokenStmt,
+		insertMFAChallengeStmt:                     q.insertMFAChallengeStmt,
+		insertMessageStmt:                          q.insertMessageStmt,
+		insertMessageAttachmentStmt:                q.insertMessageAttachmentStmt,
+		insertMessageDeliveryStmt:                  q.insertMessageDeliveryStmt,
+		insertOpaqueCredentialsStmt:                q.insertOpaqueCredentialsStmt,
+		insertOpaqueLoginStmt:                      q.insertOpaqueLoginStmt,
+		insertOpaqueRegistrationStmt:               q.insertOpaqueRegistrationStmt,
+		insertOpaqueUpgradeStmt:                    q.insertOpaqueUpgradeStmt,
+		insertPasskeyStmt:                          q.insertPasskeyStmt,
+		insertPasswordResetTokenStmt:               q.insertPasswordResetTokenStmt,
+		insertRecoveryCodesStmt:                    q.insertRecoveryCodesStmt,
+		insertSessionStmt:                          q.insertSessionStmt,
+		insertUserStmt:                             q.insertUserStmt,
+		insertVerifiedIdentityKeyChangedEventsStmt: q.insertVerifiedIdentityKeyChangedEventsStmt,
+		insertWebAuthnCeremonyStmt:                 q.insertWebAuthnCeremonyStmt,
+		listAuditEventsAfterIDStmt:                 q.listAuditEventsAfterIDStmt,
+		listClusterEventsAfterIDStmt:               q.listClusterEventsAfterIDStmt,
+		listCollectableAttachmentsStmt:             q.listCollectableAttachmentsStmt,
+		listContactUserIDsStmt:                     q.listContactUserIDsStmt,
+		listConversationDevicesStmt:                q.listConversationDevicesStmt,
+		listConversationEventsStmt:                 q.listConversationEventsStmt,
+		listConversationMessagesAfterSeqStmt:       q.listConversationMessagesAfterSeqStmt,
+		listConversationParticipantUserIDsStmt:     q.listConversationParticipantUserIDsStmt,
+		listConversationsByUserIDStmt:              q.listConversationsByUserIDStmt,
+		listCredentialsAuditEventsStmt:             q.listCredentialsAuditEventsStmt,
+		listCredentialsPasskeysStmt:                q.listCredentialsPasskeysStmt,
+		listDeviceHistoryTransfersStmt:             q.listDeviceHistoryTransfersStmt,
+		listDevicesByUserIDStmt:                    q.listDevicesByUserIDStmt,
+		listDueAccountDeletionsStmt:                q.listDueAccountDeletionsStmt,
+		listExpiredHistoryTransfersStmt:            q.listExpiredHistoryTransfersStmt,
+		listExpiredUploadsStmt:                     q.listExpiredUploadsStmt,
+		listHistoryTransferChunkBlobsStmt:          q.listHistoryTransferChunkBlobsStmt,
+		listIdentityKeyHistoryByDeviceIDStmt:       q.listIdentityKeyHistoryByDeviceIDStmt,
+		listKeyTransparencyEntriesByUsernameStmt:   q.listKeyTransparencyEntriesByUsernameStmt,
+		listKeyTransparencyLeafHashesStmt:          q.listKeyTransparencyLeafHashesStmt,
+		listMailboxEnvelopesStmt:                   q.listMailboxEnvelopesStmt,
+		listMessageAttachmentIDsStmt:               q.listMessageAttachmentIDsStmt,
+		listMessageDeliveriesStmt:                  q.listMessageDeliveriesStmt,
+		listRecentLoginHistoryStmt:                 q.listRecentLoginHistoryStmt,
+		listUserAttachmentIDsStmt:                  q.listUserAttachmentIDsStmt,
+		listUserDeviceIDsStmt:                      q.listUserDeviceIDsStmt,
+		listUserHistoryTransfersStmt:               q.listUserHistoryTransfersStmt,
+		lockAuditEventsStmt:                        q.lockAuditEventsStmt,
+		lockClusterEventsStmt:                      q.lockClusterEventsStmt,
+		lockUserStmt:                               q.lockUserStmt,
+		markAttachmentUploadedStmt:                 q.markAttachmentUploadedStmt,
+		markContactVerificationsKeyChangedStmt:     q.markContactVerificationsKeyChangedStmt,
+		markEmailAsVerifiedStmt:                    q.markEmailAsVerifiedStmt,
+		markEnvelopesSentStmt:                      q.markEnvelopesSentStmt,
+		markHistoryTransferChunkDownloadedStmt:     q.markHistoryTransferChunkDownloadedStmt,
+		markMessageDeliveredStmt:                   q.markMessageDeliveredStmt,
+		markMessagesReadStmt:                       q.markMessagesReadStmt,
+		nextAuditEventIDStmt:                       q.nextAuditEventIDStmt,
+		notifyClusterEventStmt:                     q.notifyClusterEventStmt,
+		resetKeyBackupGuessesStmt:                  q.resetKeyBackupGuessesStmt,
+		rollbackStmt:                               q.rollbackStmt,
+		setMFAChallengeWebAuthnSessionStmt:         q.setMFAChallengeWebAuthnSessionStmt,
+		setOpaqueRecordStmt:                        q.setOpaqueRecordStmt,
+		setPasswordResetRequiredStmt:               q.setPasswordResetRequiredStmt,
+		setPendingTOTPSecretStmt:                   q.setPendingTOTPSecretStmt,
+		updateAttachmentUploadOffsetStmt:           q.updateAttachmentUploadOffsetStmt,
+		updateConversationDisappearingTimerStmt:    q.updateConversationDisappearingTimerStmt,
+		updateCredentialsPasswordStmt:              q.updateCredentialsPasswordStmt,
+		updateDeviceIdentityKeyStmt:                q.updateDeviceIdentityKeyStmt,
+		updateHistoryTransferStatusStmt:            q.updateHistoryTransferStatusStmt,
+		updateKeyBackupCiphertextStmt:              q.updateKeyBackupCiphertextStmt,
+		updateKeyBackupPINStmt:                     q.updateKeyBackupPINStmt,
+		updatePasskeyUseStmt:                       q.updatePasskeyUseStmt,
+		updateUserDeliveryAccessKeyStmt:            q.updateUserDeliveryAccessKeyStmt,
+		updateUserPresenceVisibilityStmt:           q.updateUserPresenceVisibilityStmt,
+		updateUserReadReceiptsEnabledStmt:          q.updateUserReadReceiptsEnabledStmt,
+		upsertContactVerificationStmt:              q.upsertContactVerificationStmt,
+		upsertPushSubscriptionStmt:                 q.upsertPushSubscriptionStmt,
+		useKeyBackupGuessStmt:                      q.useKeyBackupGuessStmt,
+		useRecoveryCodeStmt:                        q.useRecoveryCodeStmt,
+		useTOTPCounterStmt:                         q.useTOTPCounterStmt,
 	}
 }
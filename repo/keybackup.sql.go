@@ -0,0 +1,146 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: keybackup.sql
+
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const deleteKeyBackup = `-- name: DeleteKeyBackup :execrows
+delete from key_backups where user_id = $1
+`
+
+func (q *Queries) DeleteKeyBackup(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.exec(ctx, q.deleteKeyBackupStmt, deleteKeyBackup, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getKeyBackup = `-- name: GetKeyBackup :one
+select user_id, salt, verifier, ciphertext, failed_guesses, created_at, updated_at from key_backups where user_id = $1
+`
+
+func (q *Queries) GetKeyBackup(ctx context.Context, userID uuid.UUID) (KeyBackup, error) {
+	row := q.queryRow(ctx, q.getKeyBackupStmt, getKeyBackup, userID)
+	var i KeyBackup
+	err := row.Scan(
+		&i.UserID,
+		&i.Salt,
+		&i.Verifier,
+		&i.Ciphertext,
+		&i.FailedGuesses,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const insertKeyBackup = `-- name: InsertKeyBackup :execrows
+insert into key_backups (user_id, salt, verifier, ciphertext)
+values ($1, $2, $3, $4)
+on conflict (user_id) do nothing
+`
+
+type InsertKeyBackupParams struct {
+	UserID     uuid.UUID
+	Salt       []byte
+	Verifier   []byte
+	Ciphertext []byte
+}
+
+func (q *Queries) InsertKeyBackup(ctx context.Context, arg InsertKeyBackupParams) (int64, error) {
+	result, err := q.exec(ctx, q.insertKeyBackupStmt, insertKeyBackup,
+		arg.UserID,
+		arg.Salt,
+		arg.Verifier,
+		arg.Ciphertext,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resetKeyBackupGuesses = `-- name: ResetKeyBackupGuesses :exec
+update key_backups set failed_guesses = 0 where user_id = $1
+`
+
+func (q *Queries) ResetKeyBackupGuesses(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.exec(ctx, q.resetKeyBackupGuessesStmt, resetKeyBackupGuesses, userID)
+	return err
+}
+
+const updateKeyBackupCiphertext = `-- name: UpdateKeyBackupCiphertext :exec
+update key_backups
+set ciphertext = $2, failed_guesses = 0, updated_at = now()
+where user_id = $1
+`
+
+type UpdateKeyBackupCiphertextParams struct {
+	UserID     uuid.UUID
+	Ciphertext []byte
+}
+
+func (q *Queries) UpdateKeyBackupCiphertext(ctx context.Context, arg UpdateKeyBackupCiphertextParams) error {
+	_, err := q.exec(ctx, q.updateKeyBackupCiphertextStmt, updateKeyBackupCiphertext, arg.UserID, arg.Ciphertext)
+	return err
+}
+
+const updateKeyBackupPIN = `-- name: UpdateKeyBackupPIN :exec
+update key_backups
+set salt = $2, verifier = $3, ciphertext = $4, failed_guesses = 0, updated_at = now()
+where user_id = $1
+`
+
+type UpdateKeyBackupPINParams struct {
+	UserID     uuid.UUID
+	Salt       []byte
+	Verifier   []byte
+	Ciphertext []byte
+}
+
+func (q *Queries) UpdateKeyBackupPIN(ctx context.Context, arg UpdateKeyBackupPINParams) error {
+	_, err := q.exec(ctx, q.updateKeyBackupPINStmt, updateKeyBackupPIN,
+		arg.UserID,
+		arg.Salt,
+		arg.Verifier,
+		arg.Ciphertext,
+	)
+	return err
+}
+
+const useKeyBackupGuess = `-- name: UseKeyBackupGuess :one
+update key_backups
+set failed_guesses = failed_guesses + 1
+where user_id = $1 and failed_guesses < $2::int
+returning user_id, salt, verifier, ciphertext, failed_guesses, created_at, updated_at
+`
+
+type UseKeyBackupGuessParams struct {
+	UserID     uuid.UUID
+	MaxGuesses int32
+}
+
+// counts the guess before it is checked, so that concurrent guesses can't go
+// over the limit.
+func (q *Queries) UseKeyBackupGuess(ctx context.Context, arg UseKeyBackupGuessParams) (KeyBackup, error) {
+	row := q.queryRow(ctx, q.useKeyBackupGuessStmt, useKeyBackupGuess, arg.UserID, arg.MaxGuesses)
+	var i KeyBackup
+	err := row.Scan(
+		&i.UserID,
+		&i.Salt,
+		&i.Verifier,
+		&i.Ciphertext,
+		&i.FailedGuesses,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
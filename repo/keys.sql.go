@@ -0,0 +1,254 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: keys.sql
+
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const deleteContactVerification = `-- name: DeleteContactVerification :exec
+delete from contact_verifications where user_id = $1 and contact_user_id = $2
+`
+
+type DeleteContactVerificationParams struct {
+	UserID        uuid.UUID
+	ContactUserID uuid.UUID
+}
+
+func (q *Queries) DeleteContactVerification(ctx context.Context, arg DeleteContactVerificationParams) error {
+	_, err := q.exec(ctx, q.deleteContactVerificationStmt, deleteContactVerification, arg.UserID, arg.ContactUserID)
+	return err
+}
+
+const getContactVerification = `-- name: GetContactVerification :one
+select user_id, contact_user_id, verified_at, key_changed_at from contact_verifications where user_id = $1 and contact_user_id = $2
+`
+
+type GetContactVerificationParams struct {
+	UserID        uuid.UUID
+	ContactUserID uuid.UUID
+}
+
+func (q *Queries) GetContactVerification(ctx context.Context, arg GetContactVerificationParams) (ContactVerification, error) {
+	row := q.queryRow(ctx, q.getContactVerificationStmt, getContactVerification, arg.UserID, arg.ContactUserID)
+	var i ContactVerification
+	err := row.Scan(
+		&i.UserID,
+		&i.ContactUserID,
+		&i.VerifiedAt,
+		&i.KeyChangedAt,
+	)
+	return i, err
+}
+
+const getDeviceByID = `-- name: GetDeviceByID :one
+select id, user_id, name, identity_key, created_at, updated_at from devices where id = $1
+`
+
+func (q *Queries) GetDeviceByID(ctx context.Context, id uuid.UUID) (Device, error) {
+	row := q.queryRow(ctx, q.getDeviceByIDStmt, getDeviceByID, id)
+	var i Device
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.IdentityKey,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const insertDevice = `-- name: InsertDevice :one
+insert into devices (id, user_id, name, identity_key)
+values ($1, $2, $3, $4)
+returning id, user_id, name, identity_key, created_at, updated_at
+`
+
+type InsertDeviceParams struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	IdentityKey []byte
+}
+
+func (q *Queries) InsertDevice(ctx context.Context, arg InsertDeviceParams) (Device, error) {
+	row := q.queryRow(ctx, q.insertDeviceStmt, insertDevice,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.IdentityKey,
+	)
+	var i Device
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.IdentityKey,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const insertIdentityKeyChangedEvents = `-- name: InsertIdentityKeyChangedEvents :exec
+insert into conversation_events (conversation_id, type, subject_user_id, subject_device_id)
+select conversation_id, 'identity_key_changed', $1::uuid, $2::uuid
+from conversation_participants
+where user_id = $1
+`
+
+type InsertIdentityKeyChangedEventsParams struct {
+	UserID   uuid.UUID
+	DeviceID uuid.UUID
+}
+
+func (q *Queries) InsertIdentityKeyChangedEvents(ctx context.Context, arg InsertIdentityKeyChangedEventsParams) error {
+	_, err := q.exec(ctx, q.insertIdentityKeyChangedEventsStmt, insertIdentityKeyChangedEvents, arg.UserID, arg.DeviceID)
+	return err
+}
+
+const insertIdentityKeyHistory = `-- name: InsertIdentityKeyHistory :exec
+insert into identity_key_history (device_id, identity_key)
+values ($1, $2)
+`
+
+type InsertIdentityKeyHistoryParams struct {
+	DeviceID    uuid.UUID
+	IdentityKey []byte
+}
+
+func (q *Queries) InsertIdentityKeyHistory(ctx context.Context, arg InsertIdentityKeyHistoryParams) error {
+	_, err := q.exec(ctx, q.insertIdentityKeyHistoryStmt, insertIdentityKeyHistory, arg.DeviceID, arg.IdentityKey)
+	return err
+}
+
+const insertVerifiedIdentityKeyChangedEvents = `-- name: InsertVerifiedIdentityKeyChangedEvents :exec
+insert into conversation_events (conversation_id, type, subject_user_id, subject_device_id, audience_user_id)
+select verifier.conversation_id, 'verified_identity_key_changed', $1::uuid, $2::uuid, cv.user_id
+from contact_verifications cv
+join conversation_participants verifier on verifier.user_id = cv.user_id
+join conversation_participants contact on contact.conversation_id = verifier.conversation_id and contact.user_id = cv.contact_user_id
+where cv.contact_user_id = $1 and cv.key_changed_at is null
+`
+
+type InsertVerifiedIdentityKeyChangedEventsParams struct {
+	UserID   uuid.UUID
+	DeviceID uuid.UUID
+}
+
+func (q *Queries) InsertVerifiedIdentityKeyChangedEvents(ctx context.Context, arg InsertVerifiedIdentityKeyChangedEventsParams) error {
+	_, err := q.exec(ctx, q.insertVerifiedIdentityKeyChangedEventsStmt, insertVerifiedIdentityKeyChangedEvents, arg.UserID, arg.DeviceID)
+	return err
+}
+
+const listDevicesByUserID = `-- name: ListDevicesByUserID :many
+select id, user_id, name, identity_key, created_at, updated_at from devices where user_id = $1 order by created_at
+`
+
+func (q *Queries) ListDevicesByUserID(ctx context.Context, userID uuid.UUID) ([]Device, error) {
+	rows, err := q.query(ctx, q.listDevicesByUserIDStmt, listDevicesByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Device{}
+	for rows.Next() {
+		var i Device
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.IdentityKey,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIdentityKeyHistoryByDeviceID = `-- name: ListIdentityKeyHistoryByDeviceID :many
+select id, device_id, identity_key, created_at from identity_key_history where device_id = $1 order by id
+`
+
+func (q *Queries) ListIdentityKeyHistoryByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]IdentityKeyHistory, error) {
+	rows, err := q.query(ctx, q.listIdentityKeyHistoryByDeviceIDStmt, listIdentityKeyHistoryByDeviceID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []IdentityKeyHistory{}
+	for rows.Next() {
+		var i IdentityKeyHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeviceID,
+			&i.IdentityKey,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markContactVerificationsKeyChanged = `-- name: MarkContactVerificationsKeyChanged :exec
+update contact_verifications set key_changed_at = now()
+where contact_user_id = $1 and key_changed_at is null
+`
+
+func (q *Queries) MarkContactVerificationsKeyChanged(ctx context.Context, contactUserID uuid.UUID) error {
+	_, err := q.exec(ctx, q.markContactVerificationsKeyChangedStmt, markContactVerificationsKeyChanged, contactUserID)
+	return err
+}
+
+const updateDeviceIdentityKey = `-- name: UpdateDeviceIdentityKey :exec
+update devices set identity_key = $2, updated_at = now() where id = $1
+`
+
+type UpdateDeviceIdentityKeyParams struct {
+	ID          uuid.UUID
+	IdentityKey []byte
+}
+
+func (q *Queries) UpdateDeviceIdentityKey(ctx context.Context, arg UpdateDeviceIdentityKeyParams) error {
+	_, err := q.exec(ctx, q.updateDeviceIdentityKeyStmt, updateDeviceIdentityKey, arg.ID, arg.IdentityKey)
+	return err
+}
+
+const upsertContactVerification = `-- name: UpsertContactVerification :exec
+insert into contact_verifications (user_id, contact_user_id)
+values ($1, $2)
+on conflict (user_id, contact_user_id) do update set verified_at = now(), key_changed_at = null
+`
+
+type UpsertContactVerificationParams struct {
+	UserID        uuid.UUID
+	ContactUserID uuid.UUID
+}
+
+func (q *Queries) UpsertContactVerification(ctx context.Context, arg UpsertContactVerificationParams) error {
+	_, err := q.exec(ctx, q.upsertContactVerificationStmt, upsertContactVerification, arg.UserID, arg.ContactUserID)
+	return err
+}
@@ -0,0 +1,611 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: message.sql
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const checkMessageDeleted = `-- name: CheckMessageDeleted :one
+select exists (select 1 from messages where reference_message_id = $1::uuid and kind = 'delete')
+`
+
+func (q *Queries) CheckMessageDeleted(ctx context.Context, messageID uuid.UUID) (bool, error) {
+	row := q.queryRow(ctx, q.checkMessageDeletedStmt, checkMessageDeleted, messageID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const deleteEnvelope = `-- name: DeleteEnvelope :one
+delete from envelopes where id = $1 and recipient_device_id = $2
+returning message_id
+`
+
+type DeleteEnvelopeParams struct {
+	ID                uuid.UUID
+	RecipientDeviceID uuid.UUID
+}
+
+func (q *Queries) DeleteEnvelope(ctx context.Context, arg DeleteEnvelopeParams) (uuid.NullUUID, error) {
+	row := q.queryRow(ctx, q.deleteEnvelopeStmt, deleteEnvelope, arg.ID, arg.RecipientDeviceID)
+	var message_id uuid.NullUUID
+	err := row.Scan(&message_id)
+	return message_id, err
+}
+
+const deleteExpiredEnvelopes = `-- name: DeleteExpiredEnvelopes :execrows
+delete from envelopes
+where id in (
+    select e.id from envelopes e
+    where e.expires_at <= now() or (e.expires_at is null and e.created_at <= $1)
+    limit $2
+)
+`
+
+type DeleteExpiredEnvelopesParams struct {
+	CreatedBefore time.Time
+	MaxCount      int32
+}
+
+// envelopes from before expires_at existed have none, they are expired once
+// older than the retention.
+func (q *Queries) DeleteExpiredEnvelopes(ctx context.Context, arg DeleteExpiredEnvelopesParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteExpiredEnvelopesStmt, deleteExpiredEnvelopes, arg.CreatedBefore, arg.MaxCount)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteExpiredMessages = `-- name: DeleteExpiredMessages :execrows
+delete from messages
+where id in (select id from messages where expires_at <= now() limit $1)
+`
+
+// their envelopes and delivery state go with them.
+func (q *Queries) DeleteExpiredMessages(ctx context.Context, limit int32) (int64, error) {
+	result, err := q.exec(ctx, q.deleteExpiredMessagesStmt, deleteExpiredMessages, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteMessageEnvelopes = `-- name: DeleteMessageEnvelopes :many
+delete from envelopes where message_id = $1::uuid
+returning recipient_device_id, sent_at
+`
+
+type DeleteMessageEnvelopesRow struct {
+	RecipientDeviceID uuid.UUID
+	SentAt            sql.NullTime
+}
+
+// purges the envelopes of a message that are still waiting in mailboxes.
+func (q *Queries) DeleteMessageEnvelopes(ctx context.Context, messageID uuid.UUID) ([]DeleteMessageEnvelopesRow, error) {
+	rows, err := q.query(ctx, q.deleteMessageEnvelopesStmt, deleteMessageEnvelopes, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DeleteMessageEnvelopesRow{}
+	for rows.Next() {
+		var i DeleteMessageEnvelopesRow
+		if err := rows.Scan(&i.RecipientDeviceID, &i.SentAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationLastSeq = `-- name: GetConversationLastSeq :one
+select last_seq from conversations where id = $1
+`
+
+func (q *Queries) GetConversationLastSeq(ctx context.Context, id uuid.UUID) (int64, error) {
+	row := q.queryRow(ctx, q.getConversationLastSeqStmt, getConversationLastSeq, id)
+	var last_seq int64
+	err := row.Scan(&last_seq)
+	return last_seq, err
+}
+
+const getMessageByID = `-- name: GetMessageByID :one
+select id, conversation_id, sender_user_id, sender_device_id, created_at, seq, client_message_id, kind, reference_message_id, expires_at from messages where id = $1
+`
+
+func (q *Queries) GetMessageByID(ctx context.Context, id uuid.UUID) (Message, error) {
+	row := q.queryRow(ctx, q.getMessageByIDStmt, getMessageByID, id)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.SenderUserID,
+		&i.SenderDeviceID,
+		&i.CreatedAt,
+		&i.Seq,
+		&i.ClientMessageID,
+		&i.Kind,
+		&i.ReferenceMessageID,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getMessageBySenderClientMessageID = `-- name: GetMessageBySenderClientMessageID :one
+select id, conversation_id, sender_user_id, sender_device_id, created_at, seq, client_message_id, kind, reference_message_id, expires_at from messages where sender_user_id = $1 and client_message_id = $2
+`
+
+type GetMessageBySenderClientMessageIDParams struct {
+	SenderUserID    uuid.UUID
+	ClientMessageID uuid.UUID
+}
+
+func (q *Queries) GetMessageBySenderClientMessageID(ctx context.Context, arg GetMessageBySenderClientMessageIDParams) (Message, error) {
+	row := q.queryRow(ctx, q.getMessageBySenderClientMessageIDStmt, getMessageBySenderClientMessageID, arg.SenderUserID, arg.ClientMessageID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.SenderUserID,
+		&i.SenderDeviceID,
+		&i.CreatedAt,
+		&i.Seq,
+		&i.ClientMessageID,
+		&i.Kind,
+		&i.ReferenceMessageID,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const insertEnvelope = `-- name: InsertEnvelope :one
+insert into envelopes (id, message_id, recipient_device_id, content, expires_at)
+values ($1, $2, $3, $4, $5::timestamptz)
+returning id, message_id, recipient_device_id, content, created_at, expires_at, sent_at
+`
+
+type InsertEnvelopeParams struct {
+	ID                uuid.UUID
+	MessageID         uuid.NullUUID
+	RecipientDeviceID uuid.UUID
+	Content           []byte
+	ExpiresAt         time.Time
+}
+
+func (q *Queries) InsertEnvelope(ctx context.Context, arg InsertEnvelopeParams) (Envelope, error) {
+	row := q.queryRow(ctx, q.insertEnvelopeStmt, insertEnvelope,
+		arg.ID,
+		arg.MessageID,
+		arg.RecipientDeviceID,
+		arg.Content,
+		arg.ExpiresAt,
+	)
+	var i Envelope
+	err := row.Scan(
+		&i.ID,
+		&i.MessageID,
+		&i.RecipientDeviceID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.SentAt,
+	)
+	return i, err
+}
+
+const insertMessage = `-- name: InsertMessage :one
+with next as (
+    update conversations set last_seq = last_seq + 1
+    where id = $2
+    returning last_seq, disappearing_timer
+)
+insert into messages (id, conversation_id, sender_user_id, sender_device_id, client_message_id, kind, reference_message_id, seq, expires_at)
+select $1, $2, $3, $4, $5, $6, $7, next.last_seq,
+    case when next.disappearing_timer > 0 then now() + make_interval(secs => next.disappearing_timer) end
+from next
+returning id, conversation_id, sender_user_id, sender_device_id, created_at, seq, client_message_id, kind, reference_message_id, expires_at
+`
+
+type InsertMessageParams struct {
+	ID                 uuid.UUID
+	ConversationID     uuid.UUID
+	SenderUserID       uuid.UUID
+	SenderDeviceID     uuid.NullUUID
+	ClientMessageID    uuid.UUID
+	Kind               string
+	ReferenceMessageID uuid.NullUUID
+}
+
+// the conversation row lock serializes concurrent sends, so sequence numbers
+// are handed out without gaps or duplicates. The expiration follows the
+// disappearing timer the conversation has when the message is sent.
+func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) (Message, error) {
+	row := q.queryRow(ctx, q.insertMessageStmt, insertMessage,
+		arg.ID,
+		arg.ConversationID,
+		arg.SenderUserID,
+		arg.SenderDeviceID,
+		arg.ClientMessageID,
+		arg.Kind,
+		arg.ReferenceMessageID,
+	)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.SenderUserID,
+		&i.SenderDeviceID,
+		&i.CreatedAt,
+		&i.Seq,
+		&i.ClientMessageID,
+		&i.Kind,
+		&i.ReferenceMessageID,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const insertMessageDelivery = `-- name: InsertMessageDelivery :exec
+insert into message_deliveries (message_id, recipient_device_id, recipient_user_id)
+values ($1, $2, $3)
+`
+
+type InsertMessageDeliveryParams struct {
+	MessageID         uuid.UUID
+	RecipientDeviceID uuid.UUID
+	RecipientUserID   uuid.UUID
+}
+
+func (q *Queries) InsertMessageDelivery(ctx context.Context, arg InsertMessageDeliveryParams) error {
+	_, err := q.exec(ctx, q.insertMessageDeliveryStmt, insertMessageDelivery, arg.MessageID, arg.RecipientDeviceID, arg.RecipientUserID)
+	return err
+}
+
+const listConversationDevices = `-- name: ListConversationDevices :many
+select d.id, d.user_id
+from devices d
+join conversation_participants cp on cp.user_id = d.user_id
+where cp.conversation_id = $1
+`
+
+type ListConversationDevicesRow struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) ListConversationDevices(ctx context.Context, conversationID uuid.UUID) ([]ListConversationDevicesRow, error) {
+	rows, err := q.query(ctx, q.listConversationDevicesStmt, listConversationDevices, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListConversationDevicesRow{}
+	for rows.Next() {
+		var i ListConversationDevicesRow
+		if err := rows.Scan(&i.ID, &i.UserID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationMessagesAfterSeq = `-- name: ListConversationMessagesAfterSeq :many
+select m.id, m.client_message_id, m.seq, m.kind, m.reference_message_id, m.sender_user_id, m.sender_device_id, m.created_at, m.expires_at, e.id as envelope_id, e.content
+from messages m
+left join envelopes e on e.message_id = m.id and e.recipient_device_id = $1 and (e.expires_at is null or e.expires_at > now())
+where m.conversation_id = $2 and m.seq > $3
+    and (m.expires_at is null or m.expires_at > now())
+order by m.seq
+limit $4
+`
+
+type ListConversationMessagesAfterSeqParams struct {
+	DeviceID       uuid.UUID
+	ConversationID uuid.UUID
+	AfterSeq       int64
+	MaxCount       int32
+}
+
+type ListConversationMessagesAfterSeqRow struct {
+	ID                 uuid.UUID
+	ClientMessageID    uuid.UUID
+	Seq                int64
+	Kind               string
+	ReferenceMessageID uuid.NullUUID
+	SenderUserID       uuid.UUID
+	SenderDeviceID     uuid.NullUUID
+	CreatedAt          time.Time
+	ExpiresAt          sql.NullTime
+	EnvelopeID         uuid.NullUUID
+	Content            []byte
+}
+
+func (q *Queries) ListConversationMessagesAfterSeq(ctx context.Context, arg ListConversationMessagesAfterSeqParams) ([]ListConversationMessagesAfterSeqRow, error) {
+	rows, err := q.query(ctx, q.listConversationMessagesAfterSeqStmt, listConversationMessagesAfterSeq,
+		arg.DeviceID,
+		arg.ConversationID,
+		arg.AfterSeq,
+		arg.MaxCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListConversationMessagesAfterSeqRow{}
+	for rows.Next() {
+		var i ListConversationMessagesAfterSeqRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClientMessageID,
+			&i.Seq,
+			&i.Kind,
+			&i.ReferenceMessageID,
+			&i.SenderUserID,
+			&i.SenderDeviceID,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.EnvelopeID,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMailboxEnvelopes = `-- name: ListMailboxEnvelopes :many
+select e.id, e.message_id, e.content, e.created_at, e.expires_at, m.conversation_id, m.seq, m.client_message_id, m.kind, m.reference_message_id, m.sender_user_id, m.sender_device_id
+from envelopes e
+left join messages m on m.id = e.message_id
+where e.recipient_device_id = $1 and (e.expires_at is null or e.expires_at > now())
+order by e.created_at
+limit $2
+`
+
+type ListMailboxEnvelopesParams struct {
+	RecipientDeviceID uuid.UUID
+	Limit             int32
+}
+
+type ListMailboxEnvelopesRow struct {
+	ID                 uuid.UUID
+	MessageID          uuid.NullUUID
+	Content            []byte
+	CreatedAt          time.Time
+	ExpiresAt          sql.NullTime
+	ConversationID     uuid.NullUUID
+	Seq                sql.NullInt64
+	ClientMessageID    uuid.NullUUID
+	Kind               sql.NullString
+	ReferenceMessageID uuid.NullUUID
+	SenderUserID       uuid.NullUUID
+	SenderDeviceID     uuid.NullUUID
+}
+
+func (q *Queries) ListMailboxEnvelopes(ctx context.Context, arg ListMailboxEnvelopesParams) ([]ListMailboxEnvelopesRow, error) {
+	rows, err := q.query(ctx, q.listMailboxEnvelopesStmt, listMailboxEnvelopes, arg.RecipientDeviceID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMailboxEnvelopesRow{}
+	for rows.Next() {
+		var i ListMailboxEnvelopesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.Content,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.ConversationID,
+			&i.Seq,
+			&i.ClientMessageID,
+			&i.Kind,
+			&i.ReferenceMessageID,
+			&i.SenderUserID,
+			&i.SenderDeviceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessageDeliveries = `-- name: ListMessageDeliveries :many
+select message_id, recipient_device_id, recipient_user_id, delivered_at, read_at from message_deliveries where message_id = $1 order by recipient_user_id, recipient_device_id
+`
+
+func (q *Queries) ListMessageDeliveries(ctx context.Context, messageID uuid.UUID) ([]MessageDelivery, error) {
+	rows, err := q.query(ctx, q.listMessageDeliveriesStmt, listMessageDeliveries, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MessageDelivery{}
+	for rows.Next() {
+		var i MessageDelivery
+		if err := rows.Scan(
+			&i.MessageID,
+			&i.RecipientDeviceID,
+			&i.RecipientUserID,
+			&i.DeliveredAt,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserDeviceIDs = `-- name: ListUserDeviceIDs :many
+select id from devices where user_id = $1
+`
+
+func (q *Queries) ListUserDeviceIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.listUserDeviceIDsStmt, listUserDeviceIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEnvelopesSent = `-- name: MarkEnvelopesSent :exec
+update envelopes set sent_at = now()
+where id = any($1::uuid[]) and sent_at is null
+`
+
+func (q *Queries) MarkEnvelopesSent(ctx context.Context, ids []uuid.UUID) error {
+	_, err := q.exec(ctx, q.markEnvelopesSentStmt, markEnvelopesSent, pq.Array(ids))
+	return err
+}
+
+const markMessageDelivered = `-- name: MarkMessageDelivered :one
+update message_deliveries md set delivered_at = now()
+from messages m
+where m.id = md.message_id and md.message_id = $1 and md.recipient_device_id = $2 and md.delivered_at is null
+returning md.message_id, m.conversation_id, m.sender_user_id, md.recipient_user_id, md.recipient_device_id, md.delivered_at
+`
+
+type MarkMessageDeliveredParams struct {
+	MessageID         uuid.UUID
+	RecipientDeviceID uuid.UUID
+}
+
+type MarkMessageDeliveredRow struct {
+	MessageID         uuid.UUID
+	ConversationID    uuid.UUID
+	SenderUserID      uuid.UUID
+	RecipientUserID   uuid.UUID
+	RecipientDeviceID uuid.UUID
+	DeliveredAt       sql.NullTime
+}
+
+func (q *Queries) MarkMessageDelivered(ctx context.Context, arg MarkMessageDeliveredParams) (MarkMessageDeliveredRow, error) {
+	row := q.queryRow(ctx, q.markMessageDeliveredStmt, markMessageDelivered, arg.MessageID, arg.RecipientDeviceID)
+	var i MarkMessageDeliveredRow
+	err := row.Scan(
+		&i.MessageID,
+		&i.ConversationID,
+		&i.SenderUserID,
+		&i.RecipientUserID,
+		&i.RecipientDeviceID,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
+
+const markMessagesRead = `-- name: MarkMessagesRead :many
+update message_deliveries md set read_at = now()
+from messages m
+join users sender on sender.id = m.sender_user_id
+where m.id = md.message_id
+    and m.conversation_id = $1
+    and m.seq <= $2
+    and md.recipient_user_id = $3
+    and md.read_at is null
+returning md.message_id, m.sender_user_id, sender.read_receipts_enabled as sender_read_receipts_enabled, md.recipient_device_id, md.read_at
+`
+
+type MarkMessagesReadParams struct {
+	ConversationID uuid.UUID
+	UpToSeq        int64
+	ReaderUserID   uuid.UUID
+}
+
+type MarkMessagesReadRow struct {
+	MessageID                 uuid.UUID
+	SenderUserID              uuid.UUID
+	SenderReadReceiptsEnabled bool
+	RecipientDeviceID         uuid.UUID
+	ReadAt                    sql.NullTime
+}
+
+// marks every message of the conversation up to the given sequence number as
+// read by the reader on all of their devices.
+func (q *Queries) MarkMessagesRead(ctx context.Context, arg MarkMessagesReadParams) ([]MarkMessagesReadRow, error) {
+	rows, err := q.query(ctx, q.markMessagesReadStmt, markMessagesRead, arg.ConversationID, arg.UpToSeq, arg.ReaderUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MarkMessagesReadRow{}
+	for rows.Next() {
+		var i MarkMessagesReadRow
+		if err := rows.Scan(
+			&i.MessageID,
+			&i.SenderUserID,
+			&i.SenderReadReceiptsEnabled,
+			&i.RecipientDeviceID,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
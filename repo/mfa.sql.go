@@ -0,0 +1,184 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: mfa.sql
+
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const countRecoveryCodes = `-- name: CountRecoveryCodes :one
+select count(*) from recovery_codes where credentials_id = $1
+`
+
+func (q *Queries) CountRecoveryCodes(ctx context.Context, credentialsID uuid.UUID) (int64, error) {
+	row := q.queryRow(ctx, q.countRecoveryCodesStmt, countRecoveryCodes, credentialsID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteMFAChallenge = `-- name: DeleteMFAChallenge :execrows
+delete from mfa_challenges where id = $1
+`
+
+func (q *Queries) DeleteMFAChallenge(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.exec(ctx, q.deleteMFAChallengeStmt, deleteMFAChallenge, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+delete from recovery_codes where credentials_id = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, credentialsID uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteRecoveryCodesStmt, deleteRecoveryCodes, credentialsID)
+	return err
+}
+
+const deleteStaleMFAChallenges = `-- name: DeleteStaleMFAChallenges :exec
+delete from mfa_challenges where expires_at <= now()
+`
+
+func (q *Queries) DeleteStaleMFAChallenges(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteStaleMFAChallengesStmt, deleteStaleMFAChallenges)
+	return err
+}
+
+const disableTOTP = `-- name: DisableTOTP :exec
+update credentials
+set totp_secret = null, totp_enabled = false, totp_last_counter = 0
+where id = $1
+`
+
+func (q *Queries) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.disableTOTPStmt, disableTOTP, id)
+	return err
+}
+
+const enableTOTP = `-- name: EnableTOTP :execrows
+update credentials
+set totp_enabled = true
+where id = $1 and not totp_enabled and totp_secret is not null
+`
+
+func (q *Queries) EnableTOTP(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.exec(ctx, q.enableTOTPStmt, enableTOTP, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getMFAChallenge = `-- name: GetMFAChallenge :one
+select id, credentials_id, created_at, expires_at, webauthn_session from mfa_challenges where id = $1
+`
+
+func (q *Queries) GetMFAChallenge(ctx context.Context, id uuid.UUID) (MfaChallenge, error) {
+	row := q.queryRow(ctx, q.getMFAChallengeStmt, getMFAChallenge, id)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.WebauthnSession,
+	)
+	return i, err
+}
+
+const insertMFAChallenge = `-- name: InsertMFAChallenge :exec
+insert into mfa_challenges (id, credentials_id, expires_at)
+values ($1, $2, $3)
+`
+
+type InsertMFAChallengeParams struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+	ExpiresAt     time.Time
+}
+
+func (q *Queries) InsertMFAChallenge(ctx context.Context, arg InsertMFAChallengeParams) error {
+	_, err := q.exec(ctx, q.insertMFAChallengeStmt, insertMFAChallenge, arg.ID, arg.CredentialsID, arg.ExpiresAt)
+	return err
+}
+
+const insertRecoveryCodes = `-- name: InsertRecoveryCodes :exec
+insert into recovery_codes (credentials_id, code_hash)
+select $1, unnest($2::bytea[])
+`
+
+type InsertRecoveryCodesParams struct {
+	CredentialsID uuid.UUID
+	CodeHashes    [][]byte
+}
+
+func (q *Queries) InsertRecoveryCodes(ctx context.Context, arg InsertRecoveryCodesParams) error {
+	_, err := q.exec(ctx, q.insertRecoveryCodesStmt, insertRecoveryCodes, arg.CredentialsID, pq.Array(arg.CodeHashes))
+	return err
+}
+
+const setPendingTOTPSecret = `-- name: SetPendingTOTPSecret :execrows
+update credentials
+set totp_secret = $2, totp_last_counter = 0
+where id = $1 and not totp_enabled
+`
+
+type SetPendingTOTPSecretParams struct {
+	ID         uuid.UUID
+	TotpSecret []byte
+}
+
+func (q *Queries) SetPendingTOTPSecret(ctx context.Context, arg SetPendingTOTPSecretParams) (int64, error) {
+	result, err := q.exec(ctx, q.setPendingTOTPSecretStmt, setPendingTOTPSecret, arg.ID, arg.TotpSecret)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+delete from recovery_codes where credentials_id = $1 and code_hash = $2
+`
+
+type UseRecoveryCodeParams struct {
+	CredentialsID uuid.UUID
+	CodeHash      []byte
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.exec(ctx, q.useRecoveryCodeStmt, useRecoveryCode, arg.CredentialsID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const useTOTPCounter = `-- name: UseTOTPCounter :execrows
+update credentials
+set totp_last_counter = $2
+where id = $1 and totp_last_counter < $2
+`
+
+type UseTOTPCounterParams struct {
+	ID              uuid.UUID
+	TotpLastCounter int64
+}
+
+// fails for counters at or before the last accepted one.
+func (q *Queries) UseTOTPCounter(ctx context.Context, arg UseTOTPCounterParams) (int64, error) {
+	result, err := q.exec(ctx, q.useTOTPCounterStmt, useTOTPCounter, arg.ID, arg.TotpLastCounter)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
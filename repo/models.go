@@ -5,26 +5,307 @@
 package repo
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-type Credential struct {
+type AccountDeletion struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+	CreatedAt     time.Time
+	ScheduledAt   time.Time
+}
+
+type Attachment struct {
 	ID              uuid.UUID
-	Email           string
-	EmailIsVerified bool
-	PasswordHash    string
+	OwnerUserID     uuid.UUID
+	Size            int64
+	UploadedAt      sql.NullTime
 	CreatedAt       time.Time
+	UploadOffset    int64
+	UploadExpiresAt sql.NullTime
+}
+
+type AuditEvent struct {
+	ID            int64
+	CredentialsID uuid.NullUUID
+	Type          string
+	Ip            string
+	UserAgent     string
+	Details       string
+	CreatedAt     time.Time
+	PrevHash      []byte
+	Hash          []byte
+}
+
+type ClusterEvent struct {
+	ID        int64
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+type ContactVerification struct {
+	UserID        uuid.UUID
+	ContactUserID uuid.UUID
+	VerifiedAt    time.Time
+	KeyChangedAt  sql.NullTime
+}
+
+type Conversation struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	LastSeq           int64
+	DisappearingTimer int32
+}
+
+type ConversationEvent struct {
+	ID                uuid.UUID
+	ConversationID    uuid.UUID
+	Type              string
+	SubjectUserID     uuid.NullUUID
+	SubjectDeviceID   uuid.NullUUID
+	AudienceUserID    uuid.NullUUID
+	CreatedAt         time.Time
+	DisappearingTimer sql.NullInt32
+}
+
+type ConversationParticipant struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+}
+
+type Credential struct {
+	ID                    uuid.UUID
+	Email                 string
+	EmailIsVerified       bool
+	PasswordHash          string
+	CreatedAt             time.Time
+	PasswordResetRequired bool
+	TotpSecret            []byte
+	TotpEnabled           bool
+	TotpLastCounter       int64
+	OpaqueRecord          []byte
+}
+
+type Device struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	IdentityKey []byte
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type DirectConversation struct {
+	UserLow        uuid.UUID
+	UserHigh       uuid.UUID
+	ConversationID uuid.UUID
 }
 
 type EmailVerificationToken struct {
+	ID          uuid.UUID
+	Email       string
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	Purpose     string
+	BrowserHash []byte
+}
+
+type Envelope struct {
+	ID                uuid.UUID
+	MessageID         uuid.NullUUID
+	RecipientDeviceID uuid.UUID
+	Content           []byte
+	CreatedAt         time.Time
+	ExpiresAt         sql.NullTime
+	SentAt            sql.NullTime
+}
+
+type HistoryTransfer struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	SourceDeviceID uuid.UUID
+	TargetDeviceID uuid.UUID
+	EncryptedKey   []byte
+	ChunkCount     int32
+	TotalSize      int64
+	Status         string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	ExpiresAt      time.Time
+}
+
+type HistoryTransferChunk struct {
+	TransferID   uuid.UUID
+	Index        int32
+	BlobID       uuid.UUID
+	Size         int64
+	UploadedAt   time.Time
+	DownloadedAt sql.NullTime
+}
+
+type IdentityKeyHistory struct {
+	ID          int64
+	DeviceID    uuid.UUID
+	IdentityKey []byte
+	CreatedAt   time.Time
+}
+
+type KeyBackup struct {
+	UserID        uuid.UUID
+	Salt          []byte
+	Verifier      []byte
+	Ciphertext    []byte
+	FailedGuesses int32
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type KtEntry struct {
+	LeafIndex   int64
+	Username    string
+	DeviceID    uuid.UUID
+	IdentityKey []byte
+	LeafHash    []byte
+	CreatedAt   time.Time
+}
+
+type KtLog struct {
+	ID       int32
+	TreeSize int64
+}
+
+type KtTreeHead struct {
+	TreeSize  int64
+	RootHash  []byte
+	Timestamp int64
+	Signature []byte
+	CreatedAt time.Time
+}
+
+type LoginAlert struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+	SessionID     uuid.UUID
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+type LoginHistory struct {
+	ID              uuid.UUID
+	CredentialsID   uuid.UUID
+	SessionID       uuid.UUID
+	IpPrefix        string
+	UserAgentFamily string
+	Region          sql.NullString
+	Latitude        sql.NullFloat64
+	Longitude       sql.NullFloat64
+	CreatedAt       time.Time
+}
+
+type Message struct {
+	ID                 uuid.UUID
+	ConversationID     uuid.UUID
+	SenderUserID       uuid.UUID
+	SenderDeviceID     uuid.NullUUID
+	CreatedAt          time.Time
+	Seq                int64
+	ClientMessageID    uuid.UUID
+	Kind               string
+	ReferenceMessageID uuid.NullUUID
+	ExpiresAt          sql.NullTime
+}
+
+type MessageAttachment struct {
+	MessageID    uuid.UUID
+	AttachmentID uuid.UUID
+}
+
+type MessageDelivery struct {
+	MessageID         uuid.UUID
+	RecipientDeviceID uuid.UUID
+	RecipientUserID   uuid.UUID
+	DeliveredAt       sql.NullTime
+	ReadAt            sql.NullTime
+}
+
+type MfaChallenge struct {
+	ID              uuid.UUID
+	CredentialsID   uuid.UUID
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+	WebauthnSession []byte
+}
+
+type OpaqueLogin struct {
+	ID                uuid.UUID
+	CredentialsID     uuid.NullUUID
+	Email             string
+	ExpectedClientMac []byte
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+}
+
+type OpaqueRegistration struct {
 	ID        uuid.UUID
-	Email     string
 	CreatedAt time.Time
 	ExpiresAt time.Time
 }
 
+type OpaqueUpgrade struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+type Passkey struct {
+	ID              []byte
+	CredentialsID   uuid.UUID
+	Name            string
+	PublicKey       []byte
+	AttestationType string
+	Transports      []string
+	Aaguid          []byte
+	SignCount       int64
+	BackupEligible  bool
+	BackupState     bool
+	CreatedAt       time.Time
+	LastUsedAt      sql.NullTime
+}
+
+type PasswordResetToken struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+type PushSubscription struct {
+	DeviceID  uuid.UUID
+	Endpoint  string
+	P256dh    []byte
+	Auth      []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Provider  string
+}
+
+type RateLimitCounter struct {
+	Key     string
+	Hits    int64
+	ResetAt time.Time
+}
+
+type RecoveryCode struct {
+	CredentialsID uuid.UUID
+	CodeHash      []byte
+	CreatedAt     time.Time
+}
+
 type Session struct {
 	ID            uuid.UUID
 	CredentialsID uuid.UUID
@@ -34,9 +315,21 @@ type Session struct {
 }
 
 type User struct {
+	ID                  uuid.UUID
+	Name                string
+	Username            string
+	CredentialsID       uuid.UUID
+	CreatedAt           time.Time
+	DeliveryAccessKey   []byte
+	ReadReceiptsEnabled bool
+	PresenceVisibility  string
+}
+
+type WebauthnCeremony struct {
 	ID            uuid.UUID
-	Name          string
-	Username      string
-	CredentialsID uuid.UUID
+	CredentialsID uuid.NullUUID
+	Purpose       string
+	SessionData   []byte
 	CreatedAt     time.Time
+	ExpiresAt     time.Time
 }
@@ -0,0 +1,182 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: opaque.sql
+
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteOpaqueLogin = `-- name: DeleteOpaqueLogin :one
+delete from opaque_logins where id = $1
+returning id, credentials_id, email, expected_client_mac, created_at, expires_at
+`
+
+func (q *Queries) DeleteOpaqueLogin(ctx context.Context, id uuid.UUID) (OpaqueLogin, error) {
+	row := q.queryRow(ctx, q.deleteOpaqueLoginStmt, deleteOpaqueLogin, id)
+	var i OpaqueLogin
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.Email,
+		&i.ExpectedClientMac,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const deleteOpaqueRegistration = `-- name: DeleteOpaqueRegistration :one
+delete from opaque_registrations where id = $1
+returning id, created_at, expires_at
+`
+
+func (q *Queries) DeleteOpaqueRegistration(ctx context.Context, id uuid.UUID) (OpaqueRegistration, error) {
+	row := q.queryRow(ctx, q.deleteOpaqueRegistrationStmt, deleteOpaqueRegistration, id)
+	var i OpaqueRegistration
+	err := row.Scan(&i.ID, &i.CreatedAt, &i.ExpiresAt)
+	return i, err
+}
+
+const deleteOpaqueUpgrade = `-- name: DeleteOpaqueUpgrade :one
+delete from opaque_upgrades where id = $1 and credentials_id = $2
+returning id, credentials_id, created_at, expires_at
+`
+
+type DeleteOpaqueUpgradeParams struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+}
+
+func (q *Queries) DeleteOpaqueUpgrade(ctx context.Context, arg DeleteOpaqueUpgradeParams) (OpaqueUpgrade, error) {
+	row := q.queryRow(ctx, q.deleteOpaqueUpgradeStmt, deleteOpaqueUpgrade, arg.ID, arg.CredentialsID)
+	var i OpaqueUpgrade
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const deleteStaleOpaqueLogins = `-- name: DeleteStaleOpaqueLogins :exec
+delete from opaque_logins where expires_at <= now()
+`
+
+func (q *Queries) DeleteStaleOpaqueLogins(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteStaleOpaqueLoginsStmt, deleteStaleOpaqueLogins)
+	return err
+}
+
+const deleteStaleOpaqueRegistrations = `-- name: DeleteStaleOpaqueRegistrations :exec
+delete from opaque_registrations where expires_at <= now()
+`
+
+func (q *Queries) DeleteStaleOpaqueRegistrations(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteStaleOpaqueRegistrationsStmt, deleteStaleOpaqueRegistrations)
+	return err
+}
+
+const deleteStaleOpaqueUpgrades = `-- name: DeleteStaleOpaqueUpgrades :exec
+delete from opaque_upgrades where expires_at <= now()
+`
+
+func (q *Queries) DeleteStaleOpaqueUpgrades(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteStaleOpaqueUpgradesStmt, deleteStaleOpaqueUpgrades)
+	return err
+}
+
+const insertOpaqueCredentials = `-- name: InsertOpaqueCredentials :exec
+insert into credentials (id, email, password_hash, opaque_record)
+values ($1, $2, '', $3)
+`
+
+type InsertOpaqueCredentialsParams struct {
+	ID           uuid.UUID
+	Email        string
+	OpaqueRecord []byte
+}
+
+// password_hash is empty, no password matches it.
+func (q *Queries) InsertOpaqueCredentials(ctx context.Context, arg InsertOpaqueCredentialsParams) error {
+	_, err := q.exec(ctx, q.insertOpaqueCredentialsStmt, insertOpaqueCredentials, arg.ID, arg.Email, arg.OpaqueRecord)
+	return err
+}
+
+const insertOpaqueLogin = `-- name: InsertOpaqueLogin :exec
+insert into opaque_logins (id, credentials_id, email, expected_client_mac, expires_at)
+values ($1, $2, $3, $4, $5)
+`
+
+type InsertOpaqueLoginParams struct {
+	ID                uuid.UUID
+	CredentialsID     uuid.NullUUID
+	Email             string
+	ExpectedClientMac []byte
+	ExpiresAt         time.Time
+}
+
+func (q *Queries) InsertOpaqueLogin(ctx context.Context, arg InsertOpaqueLoginParams) error {
+	_, err := q.exec(ctx, q.insertOpaqueLoginStmt, insertOpaqueLogin,
+		arg.ID,
+		arg.CredentialsID,
+		arg.Email,
+		arg.ExpectedClientMac,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const insertOpaqueRegistration = `-- name: InsertOpaqueRegistration :exec
+insert into opaque_registrations (id, expires_at)
+values ($1, $2)
+`
+
+type InsertOpaqueRegistrationParams struct {
+	ID        uuid.UUID
+	ExpiresAt time.Time
+}
+
+func (q *Queries) InsertOpaqueRegistration(ctx context.Context, arg InsertOpaqueRegistrationParams) error {
+	_, err := q.exec(ctx, q.insertOpaqueRegistrationStmt, insertOpaqueRegistration, arg.ID, arg.ExpiresAt)
+	return err
+}
+
+const insertOpaqueUpgrade = `-- name: InsertOpaqueUpgrade :exec
+insert into opaque_upgrades (id, credentials_id, expires_at)
+values ($1, $2, $3)
+`
+
+type InsertOpaqueUpgradeParams struct {
+	ID            uuid.UUID
+	CredentialsID uuid.UUID
+	ExpiresAt     time.Time
+}
+
+func (q *Queries) InsertOpaqueUpgrade(ctx context.Context, arg InsertOpaqueUpgradeParams) error {
+	_, err := q.exec(ctx, q.insertOpaqueUpgradeStmt, insertOpaqueUpgrade, arg.ID, arg.CredentialsID, arg.ExpiresAt)
+	return err
+}
+
+const setOpaqueRecord = `-- name: SetOpaqueRecord :exec
+update credentials
+set opaque_record = $2, password_hash = ''
+where id = $1
+`
+
+type SetOpaqueRecordParams struct {
+	ID           uuid.UUID
+	OpaqueRecord []byte
+}
+
+// a pending password reset still applies, it replaces the record.
+func (q *Queries) SetOpaqueRecord(ctx context.Context, arg SetOpaqueRecordParams) error {
+	_, err := q.exec(ctx, q.setOpaqueRecordStmt, setOpaqueRecord, arg.ID, arg.OpaqueRecord)
+	return err
+}
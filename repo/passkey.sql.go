@@ -0,0 +1,220 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: passkey.sql
+
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const countCredentialsPasskeys = `-- name: CountCredentialsPasskeys :one
+select count(*) from passkeys where credentials_id = $1
+`
+
+func (q *Queries) CountCredentialsPasskeys(ctx context.Context, credentialsID uuid.UUID) (int64, error) {
+	row := q.queryRow(ctx, q.countCredentialsPasskeysStmt, countCredentialsPasskeys, credentialsID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deletePasskey = `-- name: DeletePasskey :execrows
+delete from passkeys where id = $1 and credentials_id = $2
+`
+
+type DeletePasskeyParams struct {
+	ID            []byte
+	CredentialsID uuid.UUID
+}
+
+func (q *Queries) DeletePasskey(ctx context.Context, arg DeletePasskeyParams) (int64, error) {
+	result, err := q.exec(ctx, q.deletePasskeyStmt, deletePasskey, arg.ID, arg.CredentialsID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteStaleWebAuthnCeremonies = `-- name: DeleteStaleWebAuthnCeremonies :exec
+delete from webauthn_ceremonies where expires_at <= now()
+`
+
+func (q *Queries) DeleteStaleWebAuthnCeremonies(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteStaleWebAuthnCeremoniesStmt, deleteStaleWebAuthnCeremonies)
+	return err
+}
+
+const deleteWebAuthnCeremony = `-- name: DeleteWebAuthnCeremony :one
+delete from webauthn_ceremonies where id = $1
+returning id, credentials_id, purpose, session_data, created_at, expires_at
+`
+
+func (q *Queries) DeleteWebAuthnCeremony(ctx context.Context, id uuid.UUID) (WebauthnCeremony, error) {
+	row := q.queryRow(ctx, q.deleteWebAuthnCeremonyStmt, deleteWebAuthnCeremony, id)
+	var i WebauthnCeremony
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.Purpose,
+		&i.SessionData,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const insertPasskey = `-- name: InsertPasskey :one
+insert into passkeys (id, credentials_id, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+returning id, credentials_id, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, created_at, last_used_at
+`
+
+type InsertPasskeyParams struct {
+	ID              []byte
+	CredentialsID   uuid.UUID
+	Name            string
+	PublicKey       []byte
+	AttestationType string
+	Transports      []string
+	Aaguid          []byte
+	SignCount       int64
+	BackupEligible  bool
+	BackupState     bool
+}
+
+func (q *Queries) InsertPasskey(ctx context.Context, arg InsertPasskeyParams) (Passkey, error) {
+	row := q.queryRow(ctx, q.insertPasskeyStmt, insertPasskey,
+		arg.ID,
+		arg.CredentialsID,
+		arg.Name,
+		arg.PublicKey,
+		arg.AttestationType,
+		pq.Array(arg.Transports),
+		arg.Aaguid,
+		arg.SignCount,
+		arg.BackupEligible,
+		arg.BackupState,
+	)
+	var i Passkey
+	err := row.Scan(
+		&i.ID,
+		&i.CredentialsID,
+		&i.Name,
+		&i.PublicKey,
+		&i.AttestationType,
+		pq.Array(&i.Transports),
+		&i.Aaguid,
+		&i.SignCount,
+		&i.BackupEligible,
+		&i.BackupState,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const insertWebAuthnCeremony = `-- name: InsertWebAuthnCeremony :exec
+insert into webauthn_ceremonies (id, credentials_id, purpose, session_data, expires_at)
+values ($1, $2, $3, $4, $5)
+`
+
+type InsertWebAuthnCeremonyParams struct {
+	ID            uuid.UUID
+	CredentialsID uuid.NullUUID
+	Purpose       string
+	SessionData   []byte
+	ExpiresAt     time.Time
+}
+
+func (q *Queries) InsertWebAuthnCeremony(ctx context.Context, arg InsertWebAuthnCeremonyParams) error {
+	_, err := q.exec(ctx, q.insertWebAuthnCeremonyStmt, insertWebAuthnCeremony,
+		arg.ID,
+		arg.CredentialsID,
+		arg.Purpose,
+		arg.SessionData,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const listCredentialsPasskeys = `-- name: ListCredentialsPasskeys :many
+select id, credentials_id, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, created_at, last_used_at from passkeys
+where credentials_id = $1
+order by created_at
+`
+
+func (q *Queries) ListCredentialsPasskeys(ctx context.Context, credentialsID uuid.UUID) ([]Passkey, error) {
+	rows, err := q.query(ctx, q.listCredentialsPasskeysStmt, listCredentialsPasskeys, credentialsID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Passkey{}
+	for rows.Next() {
+		var i Passkey
+		if err := rows.Scan(
+			&i.ID,
+			&i.CredentialsID,
+			&i.Name,
+			&i.PublicKey,
+			&i.AttestationType,
+			pq.Array(&i.Transports),
+			&i.Aaguid,
+			&i.SignCount,
+			&i.BackupEligible,
+			&i.BackupState,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setMFAChallengeWebAuthnSession = `-- name: SetMFAChallengeWebAuthnSession :execrows
+update mfa_challenges set webauthn_session = $2 where id = $1
+`
+
+type SetMFAChallengeWebAuthnSessionParams struct {
+	ID              uuid.UUID
+	WebauthnSession []byte
+}
+
+func (q *Queries) SetMFAChallengeWebAuthnSession(ctx context.Context, arg SetMFAChallengeWebAuthnSessionParams) (int64, error) {
+	result, err := q.exec(ctx, q.setMFAChallengeWebAuthnSessionStmt, setMFAChallengeWebAuthnSession, arg.ID, arg.WebauthnSession)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updatePasskeyUse = `-- name: UpdatePasskeyUse :exec
+update passkeys
+set sign_count = $2, backup_state = $3, last_used_at = now()
+where id = $1
+`
+
+type UpdatePasskeyUseParams struct {
+	ID          []byte
+	SignCount   int64
+	BackupState bool
+}
+
+func (q *Queries) UpdatePasskeyUse(ctx context.Context, arg UpdatePasskeyUseParams) error {
+	_, err := q.exec(ctx, q.updatePasskeyUseStmt, updatePasskeyUse, arg.ID, arg.SignCount, arg.BackupState)
+	return err
+}
@@ -0,0 +1,80 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: push.sql
+
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const deletePushSubscription = `-- name: DeletePushSubscription :exec
+delete from push_subscriptions where device_id = $1
+`
+
+func (q *Queries) DeletePushSubscription(ctx context.Context, deviceID uuid.UUID) error {
+	_, err := q.exec(ctx, q.deletePushSubscriptionStmt, deletePushSubscription, deviceID)
+	return err
+}
+
+const deletePushSubscriptionByEndpoint = `-- name: DeletePushSubscriptionByEndpoint :exec
+delete from push_subscriptions where device_id = $1 and endpoint = $2
+`
+
+type DeletePushSubscriptionByEndpointParams struct {
+	DeviceID uuid.UUID
+	Endpoint string
+}
+
+// only removes the subscription if it wasn't replaced in the meantime.
+func (q *Queries) DeletePushSubscriptionByEndpoint(ctx context.Context, arg DeletePushSubscriptionByEndpointParams) error {
+	_, err := q.exec(ctx, q.deletePushSubscriptionByEndpointStmt, deletePushSubscriptionByEndpoint, arg.DeviceID, arg.Endpoint)
+	return err
+}
+
+const getPushSubscriptionByDeviceID = `-- name: GetPushSubscriptionByDeviceID :one
+select device_id, endpoint, p256dh, auth, created_at, updated_at, provider from push_subscriptions where device_id = $1
+`
+
+func (q *Queries) GetPushSubscriptionByDeviceID(ctx context.Context, deviceID uuid.UUID) (PushSubscription, error) {
+	row := q.queryRow(ctx, q.getPushSubscriptionByDeviceIDStmt, getPushSubscriptionByDeviceID, deviceID)
+	var i PushSubscription
+	err := row.Scan(
+		&i.DeviceID,
+		&i.Endpoint,
+		&i.P256dh,
+		&i.Auth,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Provider,
+	)
+	return i, err
+}
+
+const upsertPushSubscription = `-- name: UpsertPushSubscription :exec
+insert into push_subscriptions (device_id, provider, endpoint, p256dh, auth)
+values ($1, $2, $3, $4, $5)
+on conflict (device_id) do update set provider = excluded.provider, endpoint = excluded.endpoint, p256dh = excluded.p256dh, auth = excluded.auth, updated_at = now()
+`
+
+type UpsertPushSubscriptionParams struct {
+	DeviceID uuid.UUID
+	Provider string
+	Endpoint string
+	P256dh   []byte
+	Auth     []byte
+}
+
+func (q *Queries) UpsertPushSubscription(ctx context.Context, arg UpsertPushSubscriptionParams) error {
+	_, err := q.exec(ctx, q.upsertPushSubscriptionStmt, upsertPushSubscription,
+		arg.DeviceID,
+		arg.Provider,
+		arg.Endpoint,
+		arg.P256dh,
+		arg.Auth,
+	)
+	return err
+}
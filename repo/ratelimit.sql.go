@@ -0,0 +1,86 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: ratelimit.sql
+
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+const deleteExpiredRateLimitCounters = `-- name: DeleteExpiredRateLimitCounters :execrows
+delete from rate_limit_counters
+where reset_at <= now()
+`
+
+func (q *Queries) DeleteExpiredRateLimitCounters(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteExpiredRateLimitCountersStmt, deleteExpiredRateLimitCounters)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteRateLimitCounter = `-- name: DeleteRateLimitCounter :exec
+delete from rate_limit_counters
+where key = $1
+`
+
+func (q *Queries) DeleteRateLimitCounter(ctx context.Context, key string) error {
+	_, err := q.exec(ctx, q.deleteRateLimitCounterStmt, deleteRateLimitCounter, key)
+	return err
+}
+
+const getRateLimitCounter = `-- name: GetRateLimitCounter :one
+select hits, reset_at
+from rate_limit_counters
+where key = $1 and reset_at > now()
+`
+
+type GetRateLimitCounterRow struct {
+	Hits    int64
+	ResetAt time.Time
+}
+
+func (q *Queries) GetRateLimitCounter(ctx context.Context, key string) (GetRateLimitCounterRow, error) {
+	row := q.queryRow(ctx, q.getRateLimitCounterStmt, getRateLimitCounter, key)
+	var i GetRateLimitCounterRow
+	err := row.Scan(&i.Hits, &i.ResetAt)
+	return i, err
+}
+
+const hitRateLimitCounter = `-- name: HitRateLimitCounter :one
+insert into rate_limit_counters (key, hits, reset_at)
+values ($1, 1, $2::timestamptz)
+on conflict (key) do update
+set hits = case
+        when rate_limit_counters.reset_at <= $3::timestamptz then 1
+        else rate_limit_counters.hits + 1
+    end,
+    reset_at = case
+        when rate_limit_counters.reset_at <= $3::timestamptz then excluded.reset_at
+        else rate_limit_counters.reset_at
+    end
+returning hits, reset_at
+`
+
+type HitRateLimitCounterParams struct {
+	Key     string
+	ResetAt time.Time
+	Now     time.Time
+}
+
+type HitRateLimitCounterRow struct {
+	Hits    int64
+	ResetAt time.Time
+}
+
+// a counter past its window starts over.
+func (q *Queries) HitRateLimitCounter(ctx context.Context, arg HitRateLimitCounterParams) (HitRateLimitCounterRow, error) {
+	row := q.queryRow(ctx, q.hitRateLimitCounterStmt, hitRateLimitCounter, arg.Key, arg.ResetAt, arg.Now)
+	var i HitRateLimitCounterRow
+	err := row.Scan(&i.Hits, &i.ResetAt)
+	return i, err
+}
@@ -0,0 +1,358 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: transfer.sql
+
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const cancelHistoryTransfer = `-- name: CancelHistoryTransfer :execrows
+update history_transfers
+set status = 'canceled', updated_at = now()
+where id = $1 and status in ('uploading', 'ready')
+`
+
+func (q *Queries) CancelHistoryTransfer(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.exec(ctx, q.cancelHistoryTransferStmt, cancelHistoryTransfer, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countOpenHistoryTransfers = `-- name: CountOpenHistoryTransfers :one
+select count(*) from history_transfers
+where user_id = $1 and status in ('uploading', 'ready') and expires_at > now()
+`
+
+func (q *Queries) CountOpenHistoryTransfers(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.queryRow(ctx, q.countOpenHistoryTransfersStmt, countOpenHistoryTransfers, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteHistoryTransfer = `-- name: DeleteHistoryTransfer :exec
+delete from history_transfers where id = $1
+`
+
+func (q *Queries) DeleteHistoryTransfer(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteHistoryTransferStmt, deleteHistoryTransfer, id)
+	return err
+}
+
+const deleteHistoryTransferChunks = `-- name: DeleteHistoryTransferChunks :exec
+delete from history_transfer_chunks where transfer_id = $1
+`
+
+func (q *Queries) DeleteHistoryTransferChunks(ctx context.Context, transferID uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteHistoryTransferChunksStmt, deleteHistoryTransferChunks, transferID)
+	return err
+}
+
+const getHistoryTransfer = `-- name: GetHistoryTransfer :one
+select id, user_id, source_device_id, target_device_id, encrypted_key, chunk_count, total_size, status, created_at, updated_at, expires_at from history_transfers where id = $1
+`
+
+func (q *Queries) GetHistoryTransfer(ctx context.Context, id uuid.UUID) (HistoryTransfer, error) {
+	row := q.queryRow(ctx, q.getHistoryTransferStmt, getHistoryTransfer, id)
+	var i HistoryTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SourceDeviceID,
+		&i.TargetDeviceID,
+		&i.EncryptedKey,
+		&i.ChunkCount,
+		&i.TotalSize,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getHistoryTransferChunk = `-- name: GetHistoryTransferChunk :one
+select transfer_id, index, blob_id, size, uploaded_at, downloaded_at from history_transfer_chunks where transfer_id = $1 and index = $2
+`
+
+type GetHistoryTransferChunkParams struct {
+	TransferID uuid.UUID
+	Index      int32
+}
+
+func (q *Queries) GetHistoryTransferChunk(ctx context.Context, arg GetHistoryTransferChunkParams) (HistoryTransferChunk, error) {
+	row := q.queryRow(ctx, q.getHistoryTransferChunkStmt, getHistoryTransferChunk, arg.TransferID, arg.Index)
+	var i HistoryTransferChunk
+	err := row.Scan(
+		&i.TransferID,
+		&i.Index,
+		&i.BlobID,
+		&i.Size,
+		&i.UploadedAt,
+		&i.DownloadedAt,
+	)
+	return i, err
+}
+
+const getHistoryTransferProgress = `-- name: GetHistoryTransferProgress :one
+select
+    count(*)::int as uploaded_chunks,
+    count(downloaded_at)::int as downloaded_chunks,
+    coalesce(sum(size), 0)::bigint as uploaded_size
+from history_transfer_chunks
+where transfer_id = $1
+`
+
+type GetHistoryTransferProgressRow struct {
+	UploadedChunks   int32
+	DownloadedChunks int32
+	UploadedSize     int64
+}
+
+func (q *Queries) GetHistoryTransferProgress(ctx context.Context, transferID uuid.UUID) (GetHistoryTransferProgressRow, error) {
+	row := q.queryRow(ctx, q.getHistoryTransferProgressStmt, getHistoryTransferProgress, transferID)
+	var i GetHistoryTransferProgressRow
+	err := row.Scan(&i.UploadedChunks, &i.DownloadedChunks, &i.UploadedSize)
+	return i, err
+}
+
+const insertHistoryTransfer = `-- name: InsertHistoryTransfer :one
+insert into history_transfers (id, user_id, source_device_id, target_device_id, encrypted_key, chunk_count, total_size, expires_at)
+values ($1, $2, $3, $4, $5, $6, $7, $8)
+returning id, user_id, source_device_id, target_device_id, encrypted_key, chunk_count, total_size, status, created_at, updated_at, expires_at
+`
+
+type InsertHistoryTransferParams struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	SourceDeviceID uuid.UUID
+	TargetDeviceID uuid.UUID
+	EncryptedKey   []byte
+	ChunkCount     int32
+	TotalSize      int64
+	ExpiresAt      time.Time
+}
+
+func (q *Queries) InsertHistoryTransfer(ctx context.Context, arg InsertHistoryTransferParams) (HistoryTransfer, error) {
+	row := q.queryRow(ctx, q.insertHistoryTransferStmt, insertHistoryTransfer,
+		arg.ID,
+		arg.UserID,
+		arg.SourceDeviceID,
+		arg.TargetDeviceID,
+		arg.EncryptedKey,
+		arg.ChunkCount,
+		arg.TotalSize,
+		arg.ExpiresAt,
+	)
+	var i HistoryTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SourceDeviceID,
+		&i.TargetDeviceID,
+		&i.EncryptedKey,
+		&i.ChunkCount,
+		&i.TotalSize,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const insertHistoryTransferChunk = `-- name: InsertHistoryTransferChunk :execrows
+insert into history_transfer_chunks (transfer_id, index, blob_id, size)
+values ($1, $2, $3, $4)
+on conflict (transfer_id, index) do nothing
+`
+
+type InsertHistoryTransferChunkParams struct {
+	TransferID uuid.UUID
+	Index      int32
+	BlobID     uuid.UUID
+	Size       int64
+}
+
+func (q *Queries) InsertHistoryTransferChunk(ctx context.Context, arg InsertHistoryTransferChunkParams) (int64, error) {
+	result, err := q.exec(ctx, q.insertHistoryTransferChunkStmt, insertHistoryTransferChunk,
+		arg.TransferID,
+		arg.Index,
+		arg.BlobID,
+		arg.Size,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listDeviceHistoryTransfers = `-- name: ListDeviceHistoryTransfers :many
+select id, user_id, source_device_id, target_device_id, encrypted_key, chunk_count, total_size, status, created_at, updated_at, expires_at from history_transfers
+where (source_device_id = $1 or target_device_id = $1)
+    and expires_at > now()
+order by created_at desc
+`
+
+func (q *Queries) ListDeviceHistoryTransfers(ctx context.Context, deviceID uuid.UUID) ([]HistoryTransfer, error) {
+	rows, err := q.query(ctx, q.listDeviceHistoryTransfersStmt, listDeviceHistoryTransfers, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []HistoryTransfer{}
+	for rows.Next() {
+		var i HistoryTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SourceDeviceID,
+			&i.TargetDeviceID,
+			&i.EncryptedKey,
+			&i.ChunkCount,
+			&i.TotalSize,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredHistoryTransfers = `-- name: ListExpiredHistoryTransfers :many
+select id from history_transfers
+where expires_at <= now()
+limit $1
+`
+
+func (q *Queries) ListExpiredHistoryTransfers(ctx context.Context, maxCount int32) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.listExpiredHistoryTransfersStmt, listExpiredHistoryTransfers, maxCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHistoryTransferChunkBlobs = `-- name: ListHistoryTransferChunkBlobs :many
+select blob_id from history_transfer_chunks where transfer_id = $1
+`
+
+func (q *Queries) ListHistoryTransferChunkBlobs(ctx context.Context, transferID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.listHistoryTransferChunkBlobsStmt, listHistoryTransferChunkBlobs, transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var blob_id uuid.UUID
+		if err := rows.Scan(&blob_id); err != nil {
+			return nil, err
+		}
+		items = append(items, blob_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserHistoryTransfers = `-- name: ListUserHistoryTransfers :many
+select id from history_transfers where user_id = $1
+`
+
+func (q *Queries) ListUserHistoryTransfers(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.listUserHistoryTransfersStmt, listUserHistoryTransfers, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markHistoryTransferChunkDownloaded = `-- name: MarkHistoryTransferChunkDownloaded :exec
+update history_transfer_chunks
+set downloaded_at = coalesce(downloaded_at, now())
+where transfer_id = $1 and index = $2
+`
+
+type MarkHistoryTransferChunkDownloadedParams struct {
+	TransferID uuid.UUID
+	Index      int32
+}
+
+func (q *Queries) MarkHistoryTransferChunkDownloaded(ctx context.Context, arg MarkHistoryTransferChunkDownloadedParams) error {
+	_, err := q.exec(ctx, q.markHistoryTransferChunkDownloadedStmt, markHistoryTransferChunkDownloaded, arg.TransferID, arg.Index)
+	return err
+}
+
+const updateHistoryTransferStatus = `-- name: UpdateHistoryTransferStatus :execrows
+update history_transfers
+set status = $1, updated_at = now()
+where id = $2 and status = $3
+`
+
+type UpdateHistoryTransferStatusParams struct {
+	Status     string
+	ID         uuid.UUID
+	FromStatus string
+}
+
+// only moves on from the given status, so that a canceled transfer stays so.
+func (q *Queries) UpdateHistoryTransferStatus(ctx context.Context, arg UpdateHistoryTransferStatusParams) (int64, error) {
+	result, err := q.exec(ctx, q.updateHistoryTransferStatusStmt, updateHistoryTransferStatus, arg.Status, arg.ID, arg.FromStatus)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,187 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: transparency.sql
+
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const appendKeyTransparencyEntry = `-- name: AppendKeyTransparencyEntry :one
+with next as (
+    update kt_log set tree_size = tree_size + 1
+    where id = 1
+    returning tree_size - 1 as leaf_index
+)
+insert into kt_entries (leaf_index, username, device_id, identity_key, leaf_hash)
+select next.leaf_index, $1, $2, $3, $4
+from next
+returning leaf_index
+`
+
+type AppendKeyTransparencyEntryParams struct {
+	Username    string
+	DeviceID    uuid.UUID
+	IdentityKey []byte
+	LeafHash    []byte
+}
+
+func (q *Queries) AppendKeyTransparencyEntry(ctx context.Context, arg AppendKeyTransparencyEntryParams) (int64, error) {
+	row := q.queryRow(ctx, q.appendKeyTransparencyEntryStmt, appendKeyTransparencyEntry,
+		arg.Username,
+		arg.DeviceID,
+		arg.IdentityKey,
+		arg.LeafHash,
+	)
+	var leaf_index int64
+	err := row.Scan(&leaf_index)
+	return leaf_index, err
+}
+
+const getKeyTransparencyEntry = `-- name: GetKeyTransparencyEntry :one
+select leaf_index, username, device_id, identity_key, leaf_hash, created_at from kt_entries where leaf_index = $1
+`
+
+func (q *Queries) GetKeyTransparencyEntry(ctx context.Context, leafIndex int64) (KtEntry, error) {
+	row := q.queryRow(ctx, q.getKeyTransparencyEntryStmt, getKeyTransparencyEntry, leafIndex)
+	var i KtEntry
+	err := row.Scan(
+		&i.LeafIndex,
+		&i.Username,
+		&i.DeviceID,
+		&i.IdentityKey,
+		&i.LeafHash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getKeyTransparencyTreeHead = `-- name: GetKeyTransparencyTreeHead :one
+select tree_size, root_hash, timestamp, signature, created_at from kt_tree_heads where tree_size = $1
+`
+
+func (q *Queries) GetKeyTransparencyTreeHead(ctx context.Context, treeSize int64) (KtTreeHead, error) {
+	row := q.queryRow(ctx, q.getKeyTransparencyTreeHeadStmt, getKeyTransparencyTreeHead, treeSize)
+	var i KtTreeHead
+	err := row.Scan(
+		&i.TreeSize,
+		&i.RootHash,
+		&i.Timestamp,
+		&i.Signature,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getKeyTransparencyTreeSize = `-- name: GetKeyTransparencyTreeSize :one
+select tree_size from kt_log where id = 1
+`
+
+func (q *Queries) GetKeyTransparencyTreeSize(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.getKeyTransparencyTreeSizeStmt, getKeyTransparencyTreeSize)
+	var tree_size int64
+	err := row.Scan(&tree_size)
+	return tree_size, err
+}
+
+const insertKeyTransparencyTreeHead = `-- name: InsertKeyTransparencyTreeHead :exec
+insert into kt_tree_heads (tree_size, root_hash, timestamp, signature)
+values ($1, $2, $3, $4)
+on conflict (tree_size) do nothing
+`
+
+type InsertKeyTransparencyTreeHeadParams struct {
+	TreeSize  int64
+	RootHash  []byte
+	Timestamp int64
+	Signature []byte
+}
+
+func (q *Queries) InsertKeyTransparencyTreeHead(ctx context.Context, arg InsertKeyTransparencyTreeHeadParams) error {
+	_, err := q.exec(ctx, q.insertKeyTransparencyTreeHeadStmt, insertKeyTransparencyTreeHead,
+		arg.TreeSize,
+		arg.RootHash,
+		arg.Timestamp,
+		arg.Signature,
+	)
+	return err
+}
+
+const listKeyTransparencyEntriesByUsername = `-- name: ListKeyTransparencyEntriesByUsername :many
+select leaf_index, username, device_id, identity_key, leaf_hash, created_at from kt_entries
+where username = $1 and leaf_index < $2
+order by leaf_index
+`
+
+type ListKeyTransparencyEntriesByUsernameParams struct {
+	Username string
+	TreeSize int64
+}
+
+func (q *Queries) ListKeyTransparencyEntriesByUsername(ctx context.Context, arg ListKeyTransparencyEntriesByUsernameParams) ([]KtEntry, error) {
+	rows, err := q.query(ctx, q.listKeyTransparencyEntriesByUsernameStmt, listKeyTransparencyEntriesByUsername, arg.Username, arg.TreeSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []KtEntry{}
+	for rows.Next() {
+		var i KtEntry
+		if err := rows.Scan(
+			&i.LeafIndex,
+			&i.Username,
+			&i.DeviceID,
+			&i.IdentityKey,
+			&i.LeafHash,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listKeyTransparencyLeafHashes = `-- name: ListKeyTransparencyLeafHashes :many
+select leaf_hash from kt_entries
+where leaf_index >= $1 and leaf_index < $2
+order by leaf_index
+`
+
+type ListKeyTransparencyLeafHashesParams struct {
+	FromIndex int64
+	ToIndex   int64
+}
+
+func (q *Queries) ListKeyTransparencyLeafHashes(ctx context.Context, arg ListKeyTransparencyLeafHashesParams) ([][]byte, error) {
+	rows, err := q.query(ctx, q.listKeyTransparencyLeafHashesStmt, listKeyTransparencyLeafHashes, arg.FromIndex, arg.ToIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := [][]byte{}
+	for rows.Next() {
+		var leaf_hash []byte
+		if err := rows.Scan(&leaf_hash); err != nil {
+			return nil, err
+		}
+		items = append(items, leaf_hash)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -22,6 +22,66 @@ func (q *Queries) CheckUsername(ctx context.Context, username string) (bool, err
 	return exists, err
 }
 
+const getUserByCredentialsID = `-- name: GetUserByCredentialsID :one
+select id, name, username, credentials_id, created_at, delivery_access_key, read_receipts_enabled, presence_visibility from users where credentials_id = $1
+`
+
+func (q *Queries) GetUserByCredentialsID(ctx context.Context, credentialsID uuid.UUID) (User, error) {
+	row := q.queryRow(ctx, q.getUserByCredentialsIDStmt, getUserByCredentialsID, credentialsID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Username,
+		&i.CredentialsID,
+		&i.CreatedAt,
+		&i.DeliveryAccessKey,
+		&i.ReadReceiptsEnabled,
+		&i.PresenceVisibility,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+select id, name, username, credentials_id, created_at, delivery_access_key, read_receipts_enabled, presence_visibility from users where id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.queryRow(ctx, q.getUserByIDStmt, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Username,
+		&i.CredentialsID,
+		&i.CreatedAt,
+		&i.DeliveryAccessKey,
+		&i.ReadReceiptsEnabled,
+		&i.PresenceVisibility,
+	)
+	return i, err
+}
+
+const getUserByUsername = `-- name: GetUserByUsername :one
+select id, name, username, credentials_id, created_at, delivery_access_key, read_receipts_enabled, presence_visibility from users where username = $1
+`
+
+func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	row := q.queryRow(ctx, q.getUserByUsernameStmt, getUserByUsername, username)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Username,
+		&i.CredentialsID,
+		&i.CreatedAt,
+		&i.DeliveryAccessKey,
+		&i.ReadReceiptsEnabled,
+		&i.PresenceVisibility,
+	)
+	return i, err
+}
+
 const insertUser = `-- name: InsertUser :exec
 insert into users (id, name, username, credentials_id)
 values ($1, $2, $3, $4)
@@ -43,3 +103,45 @@ func (q *Queries) InsertUser(ctx context.Context, arg InsertUserParams) error {
 	)
 	return err
 }
+
+const updateUserDeliveryAccessKey = `-- name: UpdateUserDeliveryAccessKey :exec
+update users set delivery_access_key = $2 where id = $1
+`
+
+type UpdateUserDeliveryAccessKeyParams struct {
+	ID                uuid.UUID
+	DeliveryAccessKey []byte
+}
+
+func (q *Queries) UpdateUserDeliveryAccessKey(ctx context.Context, arg UpdateUserDeliveryAccessKeyParams) error {
+	_, err := q.exec(ctx, q.updateUserDeliveryAccessKeyStmt, updateUserDeliveryAccessKey, arg.ID, arg.DeliveryAccessKey)
+	return err
+}
+
+const updateUserPresenceVisibility = `-- name: UpdateUserPresenceVisibility :exec
+update users set presence_visibility = $2 where id = $1
+`
+
+type UpdateUserPresenceVisibilityParams struct {
+	ID                 uuid.UUID
+	PresenceVisibility string
+}
+
+func (q *Queries) UpdateUserPresenceVisibility(ctx context.Context, arg UpdateUserPresenceVisibilityParams) error {
+	_, err := q.exec(ctx, q.updateUserPresenceVisibilityStmt, updateUserPresenceVisibility, arg.ID, arg.PresenceVisibility)
+	return err
+}
+
+const updateUserReadReceiptsEnabled = `-- name: UpdateUserReadReceiptsEnabled :exec
+update users set read_receipts_enabled = $2 where id = $1
+`
+
+type UpdateUserReadReceiptsEnabledParams struct {
+	ID                  uuid.UUID
+	ReadReceiptsEnabled bool
+}
+
+func (q *Queries) UpdateUserReadReceiptsEnabled(ctx context.Context, arg UpdateUserReadReceiptsEnabledParams) error {
+	_, err := q.exec(ctx, q.updateUserReadReceiptsEnabledStmt, updateUserReadReceiptsEnabled, arg.ID, arg.ReadReceiptsEnabled)
+	return err
+}
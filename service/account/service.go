@@ -0,0 +1,151 @@
+package account
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service/attachment"
+	"chatapp/service/audit"
+	"chatapp/service/auth"
+	"chatapp/service/realtime"
+	"chatapp/service/transfer"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventTypeAccountDeleted is sent to the contacts of a purged account, its
+// conversations also get a conversation.EventTypeAccountDeleted event.
+const EventTypeAccountDeleted = "account_deleted"
+
+type AccountDeletedEvent struct {
+	UserID uuid.UUID `json:"userId"`
+}
+
+// AccountService purges the accounts whose deletion, scheduled through
+// auth.RequestAccountDeletion, is due.
+type AccountService struct {
+	logger            *slog.Logger
+	queries           *repo.Queries
+	authService       *auth.AuthService
+	attachmentService *attachment.AttachmentService
+	transferService   *transfer.TransferService
+	auditService      *audit.AuditService
+	dispatcher        *realtime.Dispatcher
+}
+
+func NewAccountService(
+	logger *slog.Logger,
+	queries *repo.Queries,
+	authService *auth.AuthService,
+	attachmentService *attachment.AttachmentService,
+	transferService *transfer.TransferService,
+	auditService *audit.AuditService,
+	dispatcher *realtime.Dispatcher,
+) *AccountService {
+	return &AccountService{
+		logger:            logger,
+		queries:           queries,
+		authService:       authService,
+		attachmentService: attachmentService,
+		transferService:   transferService,
+		auditService:      auditService,
+		dispatcher:        dispatcher,
+	}
+}
+
+// StartDeletionWorker periodically purges the accounts past their grace
+// period.
+func (me *AccountService) StartDeletionWorker(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-time.After(config.AccountDeletionWorkerTick):
+				if err := me.purgeDue(ctx); err != nil {
+					me.logger.Error("failed to purge deleted accounts", "errors", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (me *AccountService) purgeDue(ctx context.Context) error {
+	for {
+		deletions, err := me.queries.ListDueAccountDeletions(ctx, int32(config.AccountDeletionBatchSize))
+		if err != nil {
+			return fmt.Errorf("failed to list due account deletions: %w", err)
+		}
+
+		for _, deletion := range deletions {
+			if err := me.purge(ctx, deletion); err != nil {
+				return err
+			}
+		}
+
+		if len(deletions) < config.AccountDeletionBatchSize {
+			return nil
+		}
+	}
+}
+
+// purge signs the account out, deletes the blobs the database doesn't know
+// how to, and then the credentials, which takes the profile, devices, keys,
+// mailboxes and everything else with it. Every step can be retried, a purge
+// failing halfway is finished on the next tick. Audit events are kept.
+func (me *AccountService) purge(ctx context.Context, deletion repo.AccountDeletion) error {
+	if err := me.authService.RevokeAllSessions(deletion.CredentialsID); err != nil {
+		return err
+	}
+
+	var (
+		userID     uuid.NullUUID
+		contactIDs []uuid.UUID
+	)
+	user, err := me.queries.GetUserByCredentialsID(ctx, deletion.CredentialsID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get user by credentials id: %w", err)
+	}
+	// accounts that never created a profile only have credentials.
+	if err == nil {
+		userID = uuid.NullUUID{UUID: user.ID, Valid: true}
+
+		if err := me.transferService.DeleteUserTransfers(user.ID); err != nil {
+			return err
+		}
+		if err := me.attachmentService.DeleteUserAttachments(user.ID); err != nil {
+			return err
+		}
+
+		if contactIDs, err = me.queries.ListContactUserIDs(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to list contacts: %w", err)
+		}
+		if err := me.queries.InsertAccountDeletedEvents(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to insert account deleted events: %w", err)
+		}
+	}
+
+	if err := me.queries.DeleteCredentials(ctx, deletion.CredentialsID); err != nil {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+
+	if userID.Valid {
+		event := realtime.Event{Type: EventTypeAccountDeleted, Data: AccountDeletedEvent{UserID: userID.UUID}}
+		for _, contactID := range contactIDs {
+			me.dispatcher.PublishToUser(contactID, event)
+		}
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: deletion.CredentialsID, Valid: true},
+		Type:          audit.TypeAccountDeleted,
+		Details:       map[string]any{"requestedAt": deletion.CreatedAt},
+	})
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package attachment
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/blob"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+type AttachmentService struct {
+	logger  *slog.Logger
+	db      *sql.DB
+	queries *repo.Queries
+	store   blob.Store
+	// uploadLocks holds a *sync.Mutex per resumable upload being appended to.
+	uploadLocks sync.Map
+}
+
+func NewAttachmentService(logger *slog.Logger, db *sql.DB, queries *repo.Queries, store blob.Store) *AttachmentService {
+	if err := os.MkdirAll(config.ResumableUploadDir, 0o700); err != nil {
+		panic(fmt.Sprintf("failed to create resumable upload directory: %v", err))
+	}
+	return &AttachmentService{
+		logger:  logger,
+		db:      db,
+		queries: queries,
+		store:   store,
+	}
+}
+
+// Upload stores a client-encrypted blob of the given size. The space is
+// reserved against the user's quota before the blob is written.
+func (me *AttachmentService) Upload(userID uuid.UUID, size int64, r io.Reader) (repo.Attachment, error) {
+	ctx := context.Background()
+	var zero repo.Attachment
+
+	if err := validation.Validate(size, validation.Required, validation.Max(config.MaxAttachmentSize)); err != nil {
+		return zero, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{"size": err})
+	}
+
+	attachment, err := me.reserve(ctx, userID, size, sql.NullTime{})
+	if err != nil {
+		return zero, err
+	}
+
+	if err := me.store.Put(ctx, attachment.ID, r, size); err != nil {
+		if err := me.queries.DeleteAttachment(ctx, attachment.ID); err != nil {
+			me.logger.Error("failed to delete failed attachment", "errors", err)
+		}
+		return zero, fmt.Errorf("failed to put blob: %w", err)
+	}
+
+	if err := me.queries.MarkAttachmentUploaded(ctx, attachment.ID); err != nil {
+		return zero, fmt.Errorf("failed to mark attachment uploaded: %w", err)
+	}
+
+	return attachment, nil
+}
+
+func (me *AttachmentService) reserve(ctx context.Context, userID uuid.UUID, size int64, uploadExpiresAt sql.NullTime) (repo.Attachment, error) {
+	var zero repo.Attachment
+
+	// the quota check needs a real transaction: the lock must hold until the
+	// attachment is committed.
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	if err := queries.LockUser(ctx, userID); err != nil {
+		return zero, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	usage, err := queries.GetUserAttachmentUsage(ctx, userID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get attachment usage: %w", err)
+	}
+	if usage+size > config.AttachmentQuota {
+		return zero, service.ErrQuotaExceeded
+	}
+
+	attachment, err := queries.InsertAttachment(ctx, repo.InsertAttachmentParams{
+		ID:              uuid.New(),
+		OwnerUserID:     userID,
+		Size:            size,
+		UploadExpiresAt: uploadExpiresAt,
+	})
+	if err != nil {
+		return zero, fmt.Errorf("failed to insert attachment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return zero, fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// GetAttachment returns an uploaded attachment the user has access to.
+func (me *AttachmentService) GetAttachment(userID, attachmentID uuid.UUID) (repo.Attachment, error) {
+	ctx := context.Background()
+	var zero repo.Attachment
+
+	ok, err := me.queries.CheckAttachmentAccess(ctx, repo.CheckAttachmentAccessParams{
+		AttachmentID: attachmentID,
+		UserID:       userID,
+	})
+	if err != nil {
+		return zero, fmt.Errorf("failed to check attachment access: %w", err)
+	}
+	if !ok {
+		return zero, service.ErrNotFound
+	}
+
+	attachment, err := me.queries.GetAttachmentByID(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrNotFound
+		}
+		return zero, fmt.Errorf("failed to get attachment by id: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// Open reads length bytes of the attachment's blob starting at offset. The
+// caller must close it.
+func (me *AttachmentService) Open(attachment repo.Attachment, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length <= 0 || offset+length > attachment.Size {
+		return nil, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"range": errors.New("out of bounds"),
+		})
+	}
+
+	body, err := me.store.Get(context.Background(), attachment.ID, offset, length)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, service.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get blob: %w", err)
+	}
+
+	return body, nil
+}
+
+// Delete removes one of the user's attachments, freeing its quota.
+func (me *AttachmentService) Delete(userID, attachmentID uuid.UUID) error {
+	ctx := context.Background()
+
+	attachment, err := me.queries.GetAttachmentByID(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return service.ErrNotFound
+		}
+		return fmt.Errorf("failed to get attachment by id: %w", err)
+	}
+	if attachment.OwnerUserID != userID {
+		return service.ErrNotFound
+	}
+
+	return me.delete(ctx, attachmentID)
+}
+
+// DeleteUserAttachments removes every attachment of a user whose account is
+// purged, the rows would go with the user but not the blobs.
+func (me *AttachmentService) DeleteUserAttachments(userID uuid.UUID) error {
+	ctx := context.Background()
+
+	attachmentIDs, err := me.queries.ListUserAttachmentIDs(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list user attachment ids: %w", err)
+	}
+	for _, attachmentID := range attachmentIDs {
+		if err := me.delete(ctx, attachmentID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// delete removes the blob before the row, so a failure never leaves a blob
+// nothing refers to.
+func (me *AttachmentService) delete(ctx context.Context, attachmentID uuid.UUID) error {
+	if err := me.store.Delete(ctx, attachmentID); err != nil {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	if err := os.Remove(me.stagingPath(attachmentID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove staging file: %w", err)
+	}
+	me.uploadLocks.Delete(attachmentID)
+	if err := me.queries.DeleteAttachment(ctx, attachmentID); err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	return nil
+}
+
+// StartGarbageCollectionWorker periodically deletes attachments past their TTL
+// that no message waiting in a mailbox references anymore, uploads that never
+// completed, and expired resumable uploads.
+func (me *AttachmentService) StartGarbageCollectionWorker(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-time.After(config.AttachmentGCWorkerTick):
+				if err := me.collectGarbage(ctx); err != nil {
+					me.logger.Error("failed to collect attachment garbage", "errors", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (me *AttachmentService) collectGarbage(ctx context.Context) error {
+	for {
+		uploads, err := me.queries.ListExpiredUploads(ctx, int32(config.AttachmentGCBatchSize))
+		if err != nil {
+			return fmt.Errorf("failed to list expired uploads: %w", err)
+		}
+
+		for _, upload := range uploads {
+			if err := me.delete(ctx, upload.ID); err != nil {
+				return err
+			}
+		}
+
+		if len(uploads) < config.AttachmentGCBatchSize {
+			break
+		}
+	}
+
+	for {
+		attachments, err := me.queries.ListCollectableAttachments(ctx, repo.ListCollectableAttachmentsParams{
+			CreatedBefore: time.Now().Add(-config.AttachmentTTL),
+			MaxCount:      int32(config.AttachmentGCBatchSize),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list collectable attachments: %w", err)
+		}
+
+		for _, attachment := range attachments {
+			if err := me.delete(ctx, attachment.ID); err != nil {
+				return err
+			}
+		}
+
+		if len(attachments) < config.AttachmentGCBatchSize {
+			return nil
+		}
+	}
+}
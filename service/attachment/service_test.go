@@ -0,0 +1,126 @@
+package attachment
+
+import (
+	"bytes"
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/blob"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/uuid"
+)
+
+// TestMain keeps the staging files of resumable uploads in a temporary
+// directory.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "uploads-")
+	if err != nil {
+		panic(err)
+	}
+	config.ResumableUploadDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestAttachmentService(t *testing.T) *AttachmentService {
+	t.Helper()
+	store, err := blob.NewFileSystemStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &AttachmentService{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		store:  store,
+	}
+}
+
+func TestOpen(t *testing.T) {
+	attachmentService := newTestAttachmentService(t)
+	content := []byte("0123456789")
+	attachment := repo.Attachment{ID: uuid.New(), Size: int64(len(content))}
+	if err := attachmentService.store.Put(t.Context(), attachment.ID, bytes.NewReader(content), attachment.Size); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := attachmentService.Open(attachment, 2, 5)
+	if err != nil {
+		t.Fatalf("Open() = %v", err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content[2:7]) {
+		t.Errorf("Open(2, 5) = %q, want %q", got, content[2:7])
+	}
+
+	for _, r := range []struct{ offset, length int64 }{{-1, 2}, {0, 0}, {5, 6}, {10, 1}} {
+		if _, err := attachmentService.Open(attachment, r.offset, r.length); !errors.Is(err, service.ErrValidation) {
+			t.Errorf("Open(%d, %d) = %v, want %v", r.offset, r.length, err, service.ErrValidation)
+		}
+	}
+
+	missing := repo.Attachment{ID: uuid.New(), Size: 10}
+	if _, err := attachmentService.Open(missing, 0, 10); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("Open() of a missing blob = %v, want %v", err, service.ErrNotFound)
+	}
+}
+
+func TestWriteChunk(t *testing.T) {
+	attachmentService := newTestAttachmentService(t)
+	attachment := repo.Attachment{ID: uuid.New(), Size: 10}
+	// an interrupted write left "XYZ" past the persisted offset of 6.
+	if err := os.WriteFile(attachmentService.stagingPath(attachment.ID), []byte("abcdefXYZ"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// a chunk is cut at the size of the upload.
+	written, err := attachmentService.writeChunk(attachment, 6, strings.NewReader("ghij-too-long"))
+	if err != nil {
+		t.Fatalf("writeChunk() = %v", err)
+	}
+	if written != 4 {
+		t.Errorf("writeChunk() wrote %d bytes, want 4", written)
+	}
+	staged, err := os.ReadFile(attachmentService.stagingPath(attachment.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(staged) != "abcdefghij" {
+		t.Errorf("staging file = %q, want %q", staged, "abcdefghij")
+	}
+}
+
+func TestWriteChunkInterrupted(t *testing.T) {
+	attachmentService := newTestAttachmentService(t)
+	attachment := repo.Attachment{ID: uuid.New(), Size: 10}
+	if err := os.WriteFile(attachmentService.stagingPath(attachment.ID), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bytes received before the connection broke are kept.
+	interrupted := io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(io.ErrUnexpectedEOF))
+	written, err := attachmentService.writeChunk(attachment, 0, interrupted)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("writeChunk() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if written != 3 {
+		t.Errorf("writeChunk() wrote %d bytes, want 3", written)
+	}
+	staged, err := os.ReadFile(attachmentService.stagingPath(attachment.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(staged) != "abc" {
+		t.Errorf("staging file = %q, want %q", staged, "abc")
+	}
+}
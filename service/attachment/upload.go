@@ -0,0 +1,179 @@
+package attachment
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// Resumable uploads receive their blob in chunks, appended to a staging file
+// at the current offset. The offset is persisted along with the attachment so
+// an upload can be resumed after a restart. Once complete, the staging file is
+// moved to the blob store.
+
+func (me *AttachmentService) stagingPath(attachmentID uuid.UUID) string {
+	return filepath.Join(config.ResumableUploadDir, attachmentID.String())
+}
+
+// CreateUpload reserves the space for a resumable upload of the given size.
+func (me *AttachmentService) CreateUpload(userID uuid.UUID, size int64) (repo.Attachment, error) {
+	ctx := context.Background()
+	var zero repo.Attachment
+
+	if err := validation.Validate(size, validation.Required, validation.Max(config.MaxAttachmentSize)); err != nil {
+		return zero, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{"size": err})
+	}
+
+	attachment, err := me.reserve(ctx, userID, size, sql.NullTime{
+		Time:  time.Now().Add(config.ResumableUploadExpiration),
+		Valid: true,
+	})
+	if err != nil {
+		return zero, err
+	}
+
+	file, err := os.OpenFile(me.stagingPath(attachment.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return zero, fmt.Errorf("failed to create staging file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return zero, fmt.Errorf("failed to close staging file: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// GetUpload returns one of the user's resumable uploads.
+func (me *AttachmentService) GetUpload(userID, attachmentID uuid.UUID) (repo.Attachment, error) {
+	var zero repo.Attachment
+
+	attachment, err := me.queries.GetAttachmentByID(context.Background(), attachmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrNotFound
+		}
+		return zero, fmt.Errorf("failed to get attachment by id: %w", err)
+	}
+	if attachment.OwnerUserID != userID || !attachment.UploadExpiresAt.Valid {
+		return zero, service.ErrNotFound
+	}
+	if !attachment.UploadedAt.Valid && time.Now().After(attachment.UploadExpiresAt.Time) {
+		return zero, service.ErrExpired
+	}
+
+	return attachment, nil
+}
+
+// AppendUpload writes the chunk read from r at offset, which must be the
+// current offset of the upload, and returns the new offset. Data received
+// before the chunk is interrupted is kept, so the client can resume from there.
+func (me *AttachmentService) AppendUpload(userID, attachmentID uuid.UUID, offset int64, r io.Reader) (int64, error) {
+	ctx := context.Background()
+
+	lock, _ := me.uploadLocks.LoadOrStore(attachmentID, &sync.Mutex{})
+	if !lock.(*sync.Mutex).TryLock() {
+		return 0, service.ErrConflict
+	}
+	defer lock.(*sync.Mutex).Unlock()
+
+	attachment, err := me.GetUpload(userID, attachmentID)
+	if err != nil {
+		return 0, err
+	}
+	if attachment.UploadedAt.Valid || offset != attachment.UploadOffset {
+		return 0, service.ErrOffsetMismatch
+	}
+
+	written, copyErr := me.writeChunk(attachment, offset, r)
+	if written > 0 {
+		updated, err := me.queries.UpdateAttachmentUploadOffset(ctx, repo.UpdateAttachmentUploadOffsetParams{
+			ID:        attachmentID,
+			NewOffset: offset + written,
+			OldOffset: offset,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to update upload offset: %w", err)
+		}
+		if updated == 0 {
+			return 0, service.ErrOffsetMismatch
+		}
+	}
+	if copyErr != nil {
+		return offset + written, copyErr
+	}
+
+	if offset+written == attachment.Size {
+		if err := me.completeUpload(ctx, attachment); err != nil {
+			return 0, err
+		}
+		me.uploadLocks.Delete(attachmentID)
+	}
+
+	return offset + written, nil
+}
+
+func (me *AttachmentService) writeChunk(attachment repo.Attachment, offset int64, r io.Reader) (int64, error) {
+	file, err := os.OpenFile(me.stagingPath(attachment.ID), os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open staging file: %w", err)
+	}
+	defer file.Close()
+
+	// drops whatever an earlier interrupted write left past the persisted
+	// offset.
+	if err := file.Truncate(offset); err != nil {
+		return 0, fmt.Errorf("failed to truncate staging file: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to seek staging file: %w", err)
+	}
+
+	written, copyErr := io.Copy(file, io.LimitReader(r, attachment.Size-offset))
+	if err := file.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync staging file: %w", err)
+	}
+	if copyErr != nil {
+		return written, fmt.Errorf("failed to write chunk: %w", copyErr)
+	}
+	return written, nil
+}
+
+func (me *AttachmentService) completeUpload(ctx context.Context, attachment repo.Attachment) error {
+	file, err := os.Open(me.stagingPath(attachment.ID))
+	if err != nil {
+		return fmt.Errorf("failed to open staging file: %w", err)
+	}
+	defer file.Close()
+
+	if err := me.store.Put(ctx, attachment.ID, file, attachment.Size); err != nil {
+		return fmt.Errorf("failed to put blob: %w", err)
+	}
+	if err := me.queries.MarkAttachmentUploaded(ctx, attachment.ID); err != nil {
+		return fmt.Errorf("failed to mark attachment uploaded: %w", err)
+	}
+	if err := os.Remove(file.Name()); err != nil {
+		me.logger.Error("failed to remove staging file", "errors", err)
+	}
+
+	return nil
+}
+
+// TerminateUpload deletes a resumable upload, whether complete or not.
+func (me *AttachmentService) TerminateUpload(userID, attachmentID uuid.UUID) error {
+	if _, err := me.GetUpload(userID, attachmentID); err != nil && !errors.Is(err, service.ErrExpired) {
+		return err
+	}
+	return me.delete(context.Background(), attachmentID)
+}
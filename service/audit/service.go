@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service/audit/chain"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// event types.
+const (
+	TypeLoginSucceeded   = "login.succeeded"
+	TypeLoginFailed      = "login.failed"
+	TypeLoginLocked      = "login.locked"
+	TypeLoginSuspicious  = "login.suspicious"
+	TypeLoginDenied      = "login.denied"
+	TypeSessionRevoked   = "session.revoked"
+	TypeMFAEnabled       = "mfa.enabled"
+	TypeMFADisabled      = "mfa.disabled"
+	TypeMFAFailed        = "mfa.failed"
+	TypeRecoveryCodeUsed = "mfa.recovery_code_used"
+	TypeRecoveryCodesNew = "mfa.recovery_codes_regenerated"
+	TypePasskeyAdded     = "passkey.added"
+	TypePasskeyRemoved   = "passkey.removed"
+	TypePasskeyCloned    = "passkey.clone_suspected"
+	TypePasswordChanged  = "password.changed"
+	TypeDeviceLinked     = "device.linked"
+	TypeIdentityKeyReset = "device.identity_key_rotated"
+	TypeKeyBackupCreated = "key_backup.created"
+	TypeKeyBackupRestore = "key_backup.restored"
+	TypeKeyBackupPIN     = "key_backup.pin_changed"
+	TypeKeyBackupFailed  = "key_backup.wrong_pin"
+	TypeKeyBackupDeleted = "key_backup.deleted"
+	TypeDeletionRequest  = "account.deletion_requested"
+	TypeDeletionCanceled = "account.deletion_canceled"
+	TypeAccountDeleted   = "account.deleted"
+)
+
+// Client is who an event comes from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+type Event struct {
+	ID        int64          `json:"id"`
+	Type      string         `json:"type"`
+	IP        string         `json:"ip"`
+	UserAgent string         `json:"userAgent"`
+	Details   map[string]any `json:"details"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// AuditService appends to the audit_events hash chain. Recording is best
+// effort for the caller: failures are logged rather than failing the audited
+// action.
+type AuditService struct {
+	logger  *slog.Logger
+	db      *sql.DB
+	queries *repo.Queries
+}
+
+func NewAuditService(logger *slog.Logger, db *sql.DB, queries *repo.Queries) *AuditService {
+	return &AuditService{
+		logger:  logger,
+		db:      db,
+		queries: queries,
+	}
+}
+
+type RecordParams struct {
+	CredentialsID uuid.NullUUID
+	Type          string
+	Client        Client
+	Details       map[string]any
+}
+
+// Record appends the event, logging failures.
+func (me *AuditService) Record(params RecordParams) {
+	if err := me.record(params); err != nil {
+		me.logger.Error("failed to record audit event", "type", params.Type, "errors", err)
+	}
+}
+
+func (me *AuditService) record(params RecordParams) error {
+	ctx := context.Background()
+
+	details := []byte("{}")
+	if params.Details != nil {
+		var err error
+		if details, err = json.Marshal(params.Details); err != nil {
+			return fmt.Errorf("failed to marshal details: %w", err)
+		}
+	}
+
+	// the chain needs a real transaction: the lock must hold until the row
+	// is committed.
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	if err := queries.LockAuditEvents(ctx); err != nil {
+		return fmt.Errorf("failed to lock audit events: %w", err)
+	}
+
+	prevHash, err := queries.GetLatestAuditEventHash(ctx)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to get latest audit event hash: %w", err)
+		}
+		prevHash = chain.Genesis
+	}
+
+	id, err := queries.NextAuditEventID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get next audit event id: %w", err)
+	}
+
+	createdAt := time.Now().Truncate(time.Microsecond)
+	event := chain.Event{
+		ID:             id,
+		CredentialsID:  params.CredentialsID,
+		Type:           params.Type,
+		IP:             params.Client.IP,
+		UserAgent:      truncate(params.Client.UserAgent, config.AuditMaxUserAgentLength),
+		Details:        string(details),
+		CreatedAtMicro: createdAt.UnixMicro(),
+	}
+
+	if err := queries.InsertAuditEvent(ctx, repo.InsertAuditEventParams{
+		ID:            event.ID,
+		CredentialsID: event.CredentialsID,
+		Type:          event.Type,
+		Ip:            event.IP,
+		UserAgent:     event.UserAgent,
+		Details:       event.Details,
+		CreatedAt:     createdAt,
+		PrevHash:      prevHash,
+		Hash:          chain.Hash(prevHash, event),
+	}); err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return nil
+}
+
+// RecordLoginFailure records a failed login for the credentials of the email,
+// if there are any.
+func (me *AuditService) RecordLoginFailure(email string, locked bool, client Client) {
+	ctx := context.Background()
+
+	params := RecordParams{
+		Type:    TypeLoginFailed,
+		Client:  client,
+		Details: map[string]any{"email": email},
+	}
+	if locked {
+		params.Type = TypeLoginLocked
+	}
+
+	credentials, err := me.queries.GetCredentialsByEmail(ctx, email)
+	switch {
+	case err == nil:
+		params.CredentialsID = uuid.NullUUID{UUID: credentials.ID, Valid: true}
+	case !errors.Is(err, sql.ErrNoRows):
+		me.logger.Error("failed to get credentials by email", "errors", err)
+	}
+
+	me.Record(params)
+}
+
+// ListEvents returns the security history of the credentials, newest first.
+// beforeID pages through older events, zero starts from the newest.
+func (me *AuditService) ListEvents(credentialsID uuid.UUID, beforeID int64) ([]Event, error) {
+	ctx := context.Background()
+
+	if beforeID <= 0 {
+		beforeID = 1<<63 - 1
+	}
+
+	rows, err := me.queries.ListCredentialsAuditEvents(ctx, repo.ListCredentialsAuditEventsParams{
+		CredentialsID: credentialsID,
+		BeforeID:      beforeID,
+		PageSize:      int32(config.AuditEventsPageSize),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	events := make([]Event, 0, len(rows))
+	for _, row := range rows {
+		event := Event{
+			ID:        row.ID,
+			Type:      row.Type,
+			IP:        row.Ip,
+			UserAgent: row.UserAgent,
+			CreatedAt: row.CreatedAt,
+		}
+		if err := json.Unmarshal([]byte(row.Details), &event.Details); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal details: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
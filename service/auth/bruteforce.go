@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/service"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LockedError is returned by Login while the account is locked out after too
+// many failed attempts.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (me *LockedError) Error() string {
+	return fmt.Sprintf("locked for %s", me.RetryAfter.Round(time.Second))
+}
+
+func (me *LockedError) Unwrap() error {
+	return service.ErrLocked
+}
+
+// failures are counted per email rather than per credentials, so unknown
+// emails behave like known ones.
+func loginFailuresKey(email string) string {
+	return "login-failures:" + strings.ToLower(email)
+}
+
+func loginLockoutKey(email string) string {
+	return "login-lockout:" + strings.ToLower(email)
+}
+
+// checkLoginAttempt returns a LockedError while the email is locked out, and
+// otherwise delays the attempt by however many failures preceded it.
+func (me *AuthService) checkLoginAttempt(ctx context.Context, email string) error {
+	store := me.limiter.Store()
+
+	if locked, resetAt, err := store.Get(ctx, loginLockoutKey(email)); err != nil {
+		return fmt.Errorf("failed to get login lockout: %w", err)
+	} else if locked > 0 {
+		return &LockedError{RetryAfter: time.Until(resetAt)}
+	}
+
+	failures, _, err := store.Get(ctx, loginFailuresKey(email))
+	if err != nil {
+		return fmt.Errorf("failed to get login failures: %w", err)
+	}
+	if excess := failures - int64(config.LoginFailureDelayAfter); excess >= 0 {
+		delay := config.LoginFailureMaxDelay
+		if excess < 16 {
+			delay = min(config.LoginFailureDelay<<excess, config.LoginFailureMaxDelay)
+		}
+		time.Sleep(delay)
+	}
+
+	return nil
+}
+
+// loginFailed counts the failure, and locks the email out once there were
+// config.LoginLockoutThreshold of them.
+func (me *AuthService) loginFailed(ctx context.Context, email string) error {
+	store := me.limiter.Store()
+
+	failures, _, err := store.Hit(ctx, loginFailuresKey(email), config.LoginFailureWindow)
+	if err != nil {
+		return fmt.Errorf("failed to count login failure: %w", err)
+	}
+	if failures < int64(config.LoginLockoutThreshold) {
+		return nil
+	}
+
+	if _, _, err := store.Hit(ctx, loginLockoutKey(email), config.LoginLockoutDuration); err != nil {
+		return fmt.Errorf("failed to lock login out: %w", err)
+	}
+	// the lockout starts the count over, each lockout takes a full round of
+	// failures.
+	if err := store.Reset(ctx, loginFailuresKey(email)); err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	me.logger.Warn("locked login out after repeated failures", "failures", failures)
+
+	return nil
+}
+
+func (me *AuthService) loginSucceeded(ctx context.Context, email string) error {
+	if err := me.limiter.Store().Reset(ctx, loginFailuresKey(email)); err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	return nil
+}
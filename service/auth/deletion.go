@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/audit"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Deleting an account is scheduled config.AccountDeletionGracePeriod ahead,
+// the owner can cancel it until then, from the emailed link or while signed
+// in. The purge itself is done by service/account.
+
+type RequestAccountDeletionParams struct {
+	CredentialsID uuid.UUID
+	Reauth        ReauthParams
+	Client        audit.Client
+}
+
+// RequestAccountDeletion schedules the account's deletion once the owner
+// authenticated again. ErrUnauthorized is returned for a wrong password,
+// ErrMFARequired for a wrong code, and ErrConflict if the deletion is already
+// scheduled.
+func (me *AuthService) RequestAccountDeletion(params RequestAccountDeletionParams) (repo.AccountDeletion, error) {
+	ctx := context.Background()
+	var zero repo.AccountDeletion
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, params.CredentialsID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if err := me.reauthenticate(ctx, credentials, params.Reauth, params.Client); err != nil {
+		return zero, err
+	}
+
+	deletion := repo.AccountDeletion{
+		ID:            uuid.New(),
+		CredentialsID: credentials.ID,
+		CreatedAt:     time.Now(),
+		ScheduledAt:   time.Now().Add(config.AccountDeletionGracePeriod),
+	}
+	rows, err := me.queries.InsertAccountDeletion(ctx, repo.InsertAccountDeletionParams{
+		ID:            deletion.ID,
+		CredentialsID: deletion.CredentialsID,
+		ScheduledAt:   deletion.ScheduledAt,
+	})
+	if err != nil {
+		return zero, fmt.Errorf("failed to insert account deletion: %w", err)
+	}
+	if rows == 0 {
+		return zero, service.ErrConflict
+	}
+
+	if err := sendAccountDeletionEmail(credentials.Email, deletion); err != nil {
+		me.logger.Error("failed to send account deletion email", "error", err)
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: credentials.ID, Valid: true},
+		Type:          audit.TypeDeletionRequest,
+		Client:        params.Client,
+		Details:       map[string]any{"scheduledAt": deletion.ScheduledAt},
+	})
+
+	return deletion, nil
+}
+
+func sendAccountDeletionEmail(email string, deletion repo.AccountDeletion) error {
+	cancelLink := fmt.Sprintf("%s/account-deletion/cancel?token=%s", config.AppBaseUrl, deletion.ID)
+	return sendEmail(
+		email,
+		"Chat App Account Deletion",
+		fmt.Sprintf(
+			`your account will be deleted on %s. If you didn't ask for this, or changed your mind, please <a href="%s"> click here </a> to keep it.`,
+			deletion.ScheduledAt.UTC().Format(time.RFC1123),
+			cancelLink,
+		),
+	)
+}
+
+// GetAccountDeletion returns ErrNotFound if the account's deletion isn't
+// scheduled.
+func (me *AuthService) GetAccountDeletion(credentialsID uuid.UUID) (repo.AccountDeletion, error) {
+	ctx := context.Background()
+	var zero repo.AccountDeletion
+
+	deletion, err := me.queries.GetAccountDeletionByCredentialsID(ctx, credentialsID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrNotFound
+		}
+		return zero, fmt.Errorf("failed to get account deletion: %w", err)
+	}
+
+	return deletion, nil
+}
+
+// CancelAccountDeletion uses the token of the emailed cancel link, and reports
+// whether it was valid. Past the grace period it isn't anymore.
+func (me *AuthService) CancelAccountDeletion(tokenID uuid.UUID, client audit.Client) (bool, error) {
+	ctx := context.Background()
+
+	deletion, err := me.queries.CancelAccountDeletion(ctx, tokenID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: deletion.CredentialsID, Valid: true},
+		Type:          audit.TypeDeletionCanceled,
+		Client:        client,
+		Details:       map[string]any{"via": "link"},
+	})
+
+	return true, nil
+}
+
+// CancelCredentialsAccountDeletion cancels the deletion from a session of the
+// account, ErrNotFound is returned if there is none to cancel.
+func (me *AuthService) CancelCredentialsAccountDeletion(credentialsID uuid.UUID, client audit.Client) error {
+	ctx := context.Background()
+
+	if _, err := me.queries.CancelCredentialsAccountDeletion(ctx, credentialsID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return service.ErrNotFound
+		}
+		return fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: credentialsID, Valid: true},
+		Type:          audit.TypeDeletionCanceled,
+		Client:        client,
+		Details:       map[string]any{"via": "session"},
+	})
+
+	return nil
+}
+
+// RevokeAllSessions signs every session of the credentials out, e.g. before
+// the account is purged.
+func (me *AuthService) RevokeAllSessions(credentialsID uuid.UUID) error {
+	return me.revokeCredentialsSessions(context.Background(), credentialsID)
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service/audit"
+	"chatapp/service/geoip"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// login anomalies.
+const (
+	AnomalyNewNetwork       = "new-network"
+	AnomalyNewBrowser       = "new-browser"
+	AnomalyNewRegion        = "new-region"
+	AnomalyUnusualTime      = "unusual-time"
+	AnomalyImpossibleTravel = "impossible-travel"
+)
+
+// fingerprint is what a login is compared on with the previous ones.
+type fingerprint struct {
+	ipPrefix        string
+	userAgentFamily string
+	region          *geoip.Region
+	at              time.Time
+}
+
+func newFingerprint(client audit.Client, geo *geoip.Database, at time.Time) fingerprint {
+	fp := fingerprint{
+		ipPrefix:        ipPrefix(client.IP),
+		userAgentFamily: userAgentFamily(client.UserAgent),
+		at:              at,
+	}
+	if addr, err := netip.ParseAddr(client.IP); err == nil && geo != nil {
+		if region, ok := geo.Lookup(addr); ok {
+			fp.region = &region
+		}
+	}
+	return fp
+}
+
+// ipPrefix is the /24 of IPv4 and the /48 of IPv6 addresses, roughly the
+// network of a household or an office.
+func ipPrefix(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ip
+	}
+	return prefix.String()
+}
+
+// userAgentFamily reduces a user agent to its browser and OS, which unlike
+// versions don't change between logins of the same person.
+func userAgentFamily(userAgent string) string {
+	browser := "other"
+	for _, candidate := range []struct{ token, name string }{
+		// order matters, most browsers also claim to be chrome or safari.
+		{"Edg/", "edge"},
+		{"OPR/", "opera"},
+		{"Firefox/", "firefox"},
+		{"Chrome/", "chrome"},
+		{"Safari/", "safari"},
+		{"curl/", "curl"},
+		{"okhttp/", "okhttp"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	system := "other"
+	for _, candidate := range []struct{ token, name string }{
+		{"Android", "android"},
+		{"iPhone", "ios"},
+		{"iPad", "ios"},
+		{"Windows", "windows"},
+		{"Mac OS X", "macos"},
+		{"CrOS", "chromeos"},
+		{"Linux", "linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			system = candidate.name
+			break
+		}
+	}
+
+	return browser + "/" + system
+}
+
+// anomalies lists how the login differs from the previous ones, latest first.
+func (me fingerprint) anomalies(history []repo.LoginHistory) []string {
+	if len(history) == 0 {
+		return nil
+	}
+
+	var (
+		knownNetwork, knownBrowser, knownRegion, usualTime bool
+		anomalies                                          []string
+	)
+	for _, login := range history {
+		knownNetwork = knownNetwork || login.IpPrefix == me.ipPrefix
+		knownBrowser = knownBrowser || login.UserAgentFamily == me.userAgentFamily
+		knownRegion = knownRegion || (me.region != nil && login.Region.Valid && login.Region.String == me.region.Name)
+		usualTime = usualTime || hoursApart(login.CreatedAt, me.at) <= config.LoginUsualTimeTolerance
+	}
+
+	if !knownNetwork {
+		anomalies = append(anomalies, AnomalyNewNetwork)
+	}
+	if !knownBrowser {
+		anomalies = append(anomalies, AnomalyNewBrowser)
+	}
+	if me.region != nil && !knownRegion {
+		anomalies = append(anomalies, AnomalyNewRegion)
+	}
+	// time patterns take a few logins to show.
+	if !usualTime && len(history) >= config.LoginUsualTimeMinHistory {
+		anomalies = append(anomalies, AnomalyUnusualTime)
+	}
+
+	last := history[0]
+	if me.region != nil && last.Latitude.Valid && last.Longitude.Valid {
+		distance := me.region.DistanceKm(geoip.Region{Latitude: last.Latitude.Float64, Longitude: last.Longitude.Float64})
+		hours := me.at.Sub(last.CreatedAt).Hours()
+		if distance > config.ImpossibleTravelMinDistanceKm &&
+			(hours <= 0 || distance/hours > config.ImpossibleTravelSpeedKmh) {
+			anomalies = append(anomalies, AnomalyImpossibleTravel)
+		}
+	}
+
+	return anomalies
+}
+
+// hoursApart is how far apart the times of day are, in hours, in UTC.
+func hoursApart(a, b time.Time) float64 {
+	dayA := a.UTC().Sub(a.UTC().Truncate(24 * time.Hour)).Hours()
+	dayB := b.UTC().Sub(b.UTC().Truncate(24 * time.Hour)).Hours()
+	diff := dayA - dayB
+	if diff < 0 {
+		diff = -diff
+	}
+	return min(diff, 24-diff)
+}
+
+// isSuspicious tells whether the anomalies are worth alerting the owner. A
+// single new network or unusual time happens all the time, a new network with
+// a new browser much less.
+func isSuspicious(anomalies []string) bool {
+	for _, anomaly := range anomalies {
+		if anomaly == AnomalyImpossibleTravel {
+			return true
+		}
+	}
+	return len(anomalies) >= 2
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/audit"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestLoginLink emails a login link if there is an account with the email.
+// The returned secret binds the link to the browser asking for it, it is
+// returned whether there is an account or not.
+func (me *AuthService) RequestLoginLink(email string) string {
+	browserSecret := createRandomHex(32)
+
+	// in the background, so that the response time doesn't tell either.
+	go func() {
+		if err := me.sendLoginLinkEmail(context.Background(), email, browserSecret); err != nil {
+			me.logger.Error("failed to send login link email", "error", err)
+		}
+	}()
+
+	return browserSecret
+}
+
+func (me *AuthService) sendLoginLinkEmail(ctx context.Context, email, browserSecret string) error {
+	credentials, err := me.queries.GetCredentialsByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get credentials by email: %w", err)
+	}
+
+	tokenID := uuid.New()
+	browserHash := sha256.Sum256([]byte(browserSecret))
+	if err := me.queries.InsertLoginLinkToken(ctx, repo.InsertLoginLinkTokenParams{
+		ID:          tokenID,
+		Email:       credentials.Email,
+		BrowserHash: browserHash[:],
+		ExpiresAt:   time.Now().Add(config.LoginLinkExpiration),
+	}); err != nil {
+		return fmt.Errorf("failed to insert login link token: %w", err)
+	}
+
+	loginLink := fmt.Sprintf("%s/login/link?token=%s", config.AppBaseUrl, tokenID)
+	return sendEmail(
+		credentials.Email,
+		"Chat App Login Link",
+		fmt.Sprintf(`please <a href="%s"> click here </a> to log in. If you didn't ask for this, you can ignore this email.`, loginLink),
+	)
+}
+
+type LoginWithLinkParams struct {
+	TokenID       uuid.UUID
+	BrowserSecret string
+	Client        audit.Client
+}
+
+// LoginWithLink uses up the token of a login link opened in the browser that
+// asked for it, and continues like Login. ErrUnauthorized is returned for
+// unknown or expired tokens and other browsers.
+func (me *AuthService) LoginWithLink(params LoginWithLinkParams) (repo.Session, error) {
+	ctx := context.Background()
+	var zero repo.Session
+
+	if params.BrowserSecret == "" {
+		return zero, service.ErrUnauthorized
+	}
+
+	browserHash := sha256.Sum256([]byte(params.BrowserSecret))
+	token, err := me.queries.DeleteLoginLinkToken(ctx, repo.DeleteLoginLinkTokenParams{
+		ID:          params.TokenID,
+		BrowserHash: browserHash[:],
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrUnauthorized
+		}
+		return zero, fmt.Errorf("failed to delete login link token: %w", err)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return zero, service.ErrUnauthorized
+	}
+
+	credentials, err := me.queries.GetCredentialsByEmail(ctx, token.Email)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get credentials by email: %w", err)
+	}
+
+	return me.firstFactorVerified(ctx, credentials, params.Client)
+}
@@ -0,0 +1,491 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/audit"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// second factor methods.
+const (
+	MFAMethodTOTP         = "totp"
+	MFAMethodPasskey      = "passkey"
+	MFAMethodRecoveryCode = "recovery-code"
+)
+
+// MFARequiredError is returned by Login instead of a session when the account
+// has a second factor, the login is finished with CompleteMFALogin or
+// FinishPasskeyMFALogin.
+type MFARequiredError struct {
+	ChallengeID uuid.UUID
+	ExpiresAt   time.Time
+	// Methods are the second factors the account can use.
+	Methods []string
+}
+
+func (me *MFARequiredError) Error() string {
+	return "mfa required"
+}
+
+func (me *MFARequiredError) Unwrap() error {
+	return service.ErrMFARequired
+}
+
+// TOTPEnrollment is what the authenticator app needs, the URI is meant to be
+// shown as a QR code.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+func mfaFailuresKey(credentialsID uuid.UUID) string {
+	return "mfa-failures:" + credentialsID.String()
+}
+
+// mfaMethods returns the second factors of the account, none when it only
+// has a password.
+func (me *AuthService) mfaMethods(ctx context.Context, credentials repo.Credential) ([]string, error) {
+	passkeys, err := me.queries.CountCredentialsPasskeys(ctx, credentials.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count passkeys: %w", err)
+	}
+
+	var methods []string
+	if credentials.TotpEnabled {
+		methods = append(methods, MFAMethodTOTP)
+	}
+	if passkeys > 0 {
+		methods = append(methods, MFAMethodPasskey)
+	}
+	if len(methods) > 0 {
+		methods = append(methods, MFAMethodRecoveryCode)
+	}
+	return methods, nil
+}
+
+func (me *AuthService) createMFAChallenge(ctx context.Context, credentials repo.Credential, methods []string) error {
+	challengeID := uuid.New()
+	expiresAt := time.Now().Add(config.MFAChallengeExpiration)
+	if err := me.queries.InsertMFAChallenge(ctx, repo.InsertMFAChallengeParams{
+		ID:            challengeID,
+		CredentialsID: credentials.ID,
+		ExpiresAt:     expiresAt,
+	}); err != nil {
+		return fmt.Errorf("failed to insert mfa challenge: %w", err)
+	}
+
+	return &MFARequiredError{ChallengeID: challengeID, ExpiresAt: expiresAt, Methods: methods}
+}
+
+type CompleteMFALoginParams struct {
+	ChallengeID uuid.UUID
+	// Code is either a TOTP code or a recovery code.
+	Code   string
+	Client audit.Client
+}
+
+// CompleteMFALogin exchanges an MFA challenge and a valid code for a session.
+// ErrUnauthorized is returned for unknown or expired challenges and wrong
+// codes, a LockedError after too many wrong codes.
+func (me *AuthService) CompleteMFALogin(params CompleteMFALoginParams) (repo.Session, error) {
+	ctx := context.Background()
+	var zero repo.Session
+
+	challenge, credentials, err := me.getMFAChallenge(ctx, params.ChallengeID)
+	if err != nil {
+		return zero, err
+	}
+	if err := me.verifySecondFactor(ctx, credentials, params.Code, params.Client); err != nil {
+		return zero, err
+	}
+
+	return me.finishMFALogin(ctx, challenge, credentials, params.Client)
+}
+
+// getMFAChallenge returns ErrUnauthorized for unknown or expired challenges.
+func (me *AuthService) getMFAChallenge(ctx context.Context, challengeID uuid.UUID) (repo.MfaChallenge, repo.Credential, error) {
+	challenge, err := me.queries.GetMFAChallenge(ctx, challengeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.MfaChallenge{}, repo.Credential{}, service.ErrUnauthorized
+		}
+		return repo.MfaChallenge{}, repo.Credential{}, fmt.Errorf("failed to get mfa challenge: %w", err)
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return repo.MfaChallenge{}, repo.Credential{}, service.ErrUnauthorized
+	}
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, challenge.CredentialsID)
+	if err != nil {
+		return repo.MfaChallenge{}, repo.Credential{}, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	return challenge, credentials, nil
+}
+
+// finishMFALogin creates the session once the second factor was checked.
+func (me *AuthService) finishMFALogin(ctx context.Context, challenge repo.MfaChallenge, credentials repo.Credential, client audit.Client) (repo.Session, error) {
+	// deleting the challenge is what makes it single use, a concurrent
+	// request with the same challenge may have got there first.
+	if n, err := me.queries.DeleteMFAChallenge(ctx, challenge.ID); err != nil {
+		return repo.Session{}, fmt.Errorf("failed to delete mfa challenge: %w", err)
+	} else if n == 0 {
+		return repo.Session{}, service.ErrUnauthorized
+	}
+
+	return me.createSession(ctx, credentials, client)
+}
+
+// checkMFAAttempt returns a LockedError while the account is locked out after
+// too many wrong second factors.
+func (me *AuthService) checkMFAAttempt(ctx context.Context, credentialsID uuid.UUID) error {
+	if failures, resetAt, err := me.limiter.Store().Get(ctx, mfaFailuresKey(credentialsID)); err != nil {
+		return fmt.Errorf("failed to get mfa failures: %w", err)
+	} else if failures >= int64(config.MFAMaxFailures) {
+		return &LockedError{RetryAfter: time.Until(resetAt)}
+	}
+	return nil
+}
+
+// mfaFailed counts a wrong second factor towards a lockout of
+// config.MFAFailureWindow, and returns ErrUnauthorized.
+func (me *AuthService) mfaFailed(ctx context.Context, credentialsID uuid.UUID, client audit.Client) error {
+	if _, _, err := me.limiter.Store().Hit(ctx, mfaFailuresKey(credentialsID), config.MFAFailureWindow); err != nil {
+		return fmt.Errorf("failed to count mfa failure: %w", err)
+	}
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: credentialsID, Valid: true},
+		Type:          audit.TypeMFAFailed,
+		Client:        client,
+	})
+	return service.ErrUnauthorized
+}
+
+func (me *AuthService) mfaSucceeded(ctx context.Context, credentialsID uuid.UUID) error {
+	if err := me.limiter.Store().Reset(ctx, mfaFailuresKey(credentialsID)); err != nil {
+		return fmt.Errorf("failed to reset mfa failures: %w", err)
+	}
+	return nil
+}
+
+// verifySecondFactor checks a TOTP code or uses up a recovery code, wrong
+// codes count towards a lockout.
+func (me *AuthService) verifySecondFactor(ctx context.Context, credentials repo.Credential, code string, client audit.Client) error {
+	if err := me.checkMFAAttempt(ctx, credentials.ID); err != nil {
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	ok := false
+	switch {
+	case isTOTPCode(code):
+		// a pending enrollment's secret isn't a second factor yet.
+		if credentials.TotpEnabled {
+			var err error
+			if ok, err = me.useTOTPCode(ctx, credentials, code); err != nil {
+				return err
+			}
+		}
+	case code != "":
+		n, err := me.queries.UseRecoveryCode(ctx, repo.UseRecoveryCodeParams{
+			CredentialsID: credentials.ID,
+			CodeHash:      hashRecoveryCode(code),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to use recovery code: %w", err)
+		}
+		if ok = n > 0; ok {
+			me.auditService.Record(audit.RecordParams{
+				CredentialsID: uuid.NullUUID{UUID: credentials.ID, Valid: true},
+				Type:          audit.TypeRecoveryCodeUsed,
+				Client:        client,
+			})
+		}
+	}
+
+	if !ok {
+		return me.mfaFailed(ctx, credentials.ID, client)
+	}
+	return me.mfaSucceeded(ctx, credentials.ID)
+}
+
+// useTOTPCode checks the code against the TOTP secret, a code that was
+// already accepted once is treated as a wrong one.
+func (me *AuthService) useTOTPCode(ctx context.Context, credentials repo.Credential, code string) (bool, error) {
+	if credentials.TotpSecret == nil {
+		return false, nil
+	}
+	counter, ok := validateTOTP(credentials.TotpSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	n, err := me.queries.UseTOTPCounter(ctx, repo.UseTOTPCounterParams{
+		ID:              credentials.ID,
+		TotpLastCounter: counter,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp counter: %w", err)
+	}
+	return n > 0, nil
+}
+
+type BeginTOTPEnrollmentParams struct {
+	CredentialsID uuid.UUID
+	Reauth        ReauthParams
+	Client        audit.Client
+}
+
+// BeginTOTPEnrollment creates a new TOTP secret for the account once the
+// owner authenticated again, see reauthenticate for its errors. It isn't used
+// before being confirmed with ConfirmTOTP, and starting over replaces it.
+// ErrConflict is returned when TOTP is already enabled.
+func (me *AuthService) BeginTOTPEnrollment(params BeginTOTPEnrollmentParams) (TOTPEnrollment, error) {
+	ctx := context.Background()
+	var zero TOTPEnrollment
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, params.CredentialsID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if credentials.TotpEnabled {
+		return zero, service.ErrConflict
+	}
+	if err := me.reauthenticate(ctx, credentials, params.Reauth, params.Client); err != nil {
+		return zero, err
+	}
+
+	secret := createTOTPSecret()
+	if n, err := me.queries.SetPendingTOTPSecret(ctx, repo.SetPendingTOTPSecretParams{
+		ID:         params.CredentialsID,
+		TotpSecret: secret,
+	}); err != nil {
+		return zero, fmt.Errorf("failed to set totp secret: %w", err)
+	} else if n == 0 {
+		return zero, service.ErrConflict
+	}
+
+	return TOTPEnrollment{
+		Secret: base32NoPadding.EncodeToString(secret),
+		URI:    totpURI(credentials.Email, secret),
+	}, nil
+}
+
+type ConfirmTOTPParams struct {
+	CredentialsID uuid.UUID
+	// Code is the authenticator app's current code for the new secret.
+	Code   string
+	Reauth ReauthParams
+	Client audit.Client
+}
+
+// ConfirmTOTP enables TOTP once the owner authenticated again and the
+// authenticator app shows it has the secret, and returns the recovery codes
+// if it's the account's first second factor. See reauthenticate for its
+// errors, ErrMFARequired is also returned for a wrong code. ErrConflict is
+// returned when there is no pending enrollment.
+func (me *AuthService) ConfirmTOTP(params ConfirmTOTPParams) ([]string, error) {
+	ctx := context.Background()
+	credentialsID := params.CredentialsID
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, credentialsID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if credentials.TotpEnabled || credentials.TotpSecret == nil {
+		return nil, service.ErrConflict
+	}
+	if err := me.reauthenticate(ctx, credentials, params.Reauth, params.Client); err != nil {
+		return nil, err
+	}
+	if err := me.checkMFAAttempt(ctx, credentialsID); err != nil {
+		return nil, err
+	}
+	if ok, err := me.useTOTPCode(ctx, credentials, strings.TrimSpace(params.Code)); err != nil {
+		return nil, err
+	} else if !ok {
+		// ErrUnauthorized stands for a wrong first factor.
+		err := me.mfaFailed(ctx, credentialsID, params.Client)
+		if errors.Is(err, service.ErrUnauthorized) {
+			return nil, service.ErrMFARequired
+		}
+		return nil, err
+	}
+	if err := me.mfaSucceeded(ctx, credentialsID); err != nil {
+		return nil, err
+	}
+
+	methods, err := me.mfaMethods(ctx, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	// verifying the code already recorded its time step as used.
+	if n, err := me.queries.EnableTOTP(ctx, credentialsID); err != nil {
+		return nil, fmt.Errorf("failed to enable totp: %w", err)
+	} else if n == 0 {
+		return nil, service.ErrConflict
+	}
+
+	codes, err := me.secondFactorAdded(ctx, credentialsID, methods)
+	if err != nil {
+		return nil, err
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: credentialsID, Valid: true},
+		Type:          audit.TypeMFAEnabled,
+		Client:        params.Client,
+		Details:       map[string]any{"method": "totp"},
+	})
+
+	return codes, nil
+}
+
+// DisableTOTP turns TOTP off with a current TOTP or recovery code, the
+// recovery codes go with the account's last second factor. ErrConflict is returned when TOTP isn't enabled.
+func (me *AuthService) DisableTOTP(credentialsID uuid.UUID, code string, client audit.Client) error {
+	ctx := context.Background()
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, credentialsID)
+	if err != nil {
+		return fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if !credentials.TotpEnabled {
+		return service.ErrConflict
+	}
+	if err := me.verifySecondFactor(ctx, credentials, code, client); err != nil {
+		return err
+	}
+
+	if err := me.queries.DisableTOTP(ctx, credentialsID); err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	credentials.TotpEnabled = false
+	if err := me.secondFactorRemoved(ctx, credentials); err != nil {
+		return err
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: credentialsID, Valid: true},
+		Type:          audit.TypeMFADisabled,
+		Client:        client,
+		Details:       map[string]any{"method": "totp"},
+	})
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, the old ones stop
+// working. It takes a TOTP or recovery code, ErrConflict is returned when the
+// account has no second factor.
+func (me *AuthService) RegenerateRecoveryCodes(credentialsID uuid.UUID, code string, client audit.Client) ([]string, error) {
+	ctx := context.Background()
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, credentialsID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if methods, err := me.mfaMethods(ctx, credentials); err != nil {
+		return nil, err
+	} else if len(methods) == 0 {
+		return nil, service.ErrConflict
+	}
+	if err := me.verifySecondFactor(ctx, credentials, code, client); err != nil {
+		return nil, err
+	}
+
+	codes, err := me.replaceRecoveryCodes(ctx, credentialsID)
+	if err != nil {
+		return nil, err
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: credentialsID, Valid: true},
+		Type:          audit.TypeRecoveryCodesNew,
+		Client:        client,
+	})
+
+	return codes, nil
+}
+
+// secondFactorAdded creates the recovery codes when the account had no second
+// factor before, methods being the ones it had.
+func (me *AuthService) secondFactorAdded(ctx context.Context, credentialsID uuid.UUID, methods []string) ([]string, error) {
+	if len(methods) > 0 {
+		return nil, nil
+	}
+	return me.replaceRecoveryCodes(ctx, credentialsID)
+}
+
+// secondFactorRemoved drops the recovery codes once the account has no second
+// factor left.
+func (me *AuthService) secondFactorRemoved(ctx context.Context, credentials repo.Credential) error {
+	methods, err := me.mfaMethods(ctx, credentials)
+	if err != nil {
+		return err
+	}
+	if len(methods) > 0 {
+		return nil
+	}
+	if err := me.queries.DeleteRecoveryCodes(ctx, credentials.ID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	return nil
+}
+
+func (me *AuthService) replaceRecoveryCodes(ctx context.Context, credentialsID uuid.UUID) ([]string, error) {
+	codes, hashes := createRecoveryCodes(config.RecoveryCodeCount)
+
+	if err := me.queries.DeleteRecoveryCodes(ctx, credentialsID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if err := me.queries.InsertRecoveryCodes(ctx, repo.InsertRecoveryCodesParams{
+		CredentialsID: credentialsID,
+		CodeHashes:    hashes,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to insert recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// MFAStatus is the account's second factors and how many recovery codes are
+// left.
+type MFAStatus struct {
+	TOTPEnabled   bool
+	Passkeys      int64
+	RecoveryCodes int64
+}
+
+func (me *AuthService) GetMFAStatus(credentialsID uuid.UUID) (MFAStatus, error) {
+	ctx := context.Background()
+	var zero MFAStatus
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, credentialsID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	passkeys, err := me.queries.CountCredentialsPasskeys(ctx, credentialsID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to count passkeys: %w", err)
+	}
+	recoveryCodes, err := me.queries.CountRecoveryCodes(ctx, credentialsID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return MFAStatus{
+		TOTPEnabled:   credentials.TotpEnabled,
+		Passkeys:      passkeys,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
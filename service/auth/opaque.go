@@ -0,0 +1,330 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/audit"
+	"chatapp/service/auth/opaque"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/google/uuid"
+)
+
+// OPAQUE accounts log in without the server ever seeing the password. The
+// server's OPRF key for an account is derived from its credentials ID, the
+// client's export key stays on the client, where it can encrypt key backups.
+
+// NewOpaqueServer derives the server's OPAQUE keys from config.
+func NewOpaqueServer() (*opaque.Server, error) {
+	return opaque.NewServer(config.OpaqueServerSecret, []byte(config.OpaqueContext))
+}
+
+type OpaqueRegistration struct {
+	ID       uuid.UUID
+	Response []byte
+}
+
+// BeginOpaqueRegistration answers the registration request of a new account,
+// the ID is its credentials ID once finished.
+func (me *AuthService) BeginOpaqueRegistration(request []byte) (OpaqueRegistration, error) {
+	ctx := context.Background()
+	var zero OpaqueRegistration
+
+	registrationID := uuid.New()
+	response, err := me.opaque.RegistrationResponse(request, registrationID[:])
+	if err != nil {
+		return zero, opaqueError(err)
+	}
+
+	if err := me.queries.InsertOpaqueRegistration(ctx, repo.InsertOpaqueRegistrationParams{
+		ID:        registrationID,
+		ExpiresAt: time.Now().Add(config.OpaqueRegistrationExpiration),
+	}); err != nil {
+		return zero, fmt.Errorf("failed to insert opaque registration: %w", err)
+	}
+
+	return OpaqueRegistration{ID: registrationID, Response: response}, nil
+}
+
+// FinishOpaqueRegistration creates the credentials like CreateCredentials, and
+// returns their ID.
+func (me *AuthService) FinishOpaqueRegistration(params FinishOpaqueRegistrationParams) (uuid.UUID, error) {
+	var zero uuid.UUID
+	if err := params.validate(); err != nil {
+		return zero, fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+	if err := opaque.ValidateRecord(params.Record); err != nil {
+		return zero, opaqueError(err)
+	}
+
+	ctx := context.Background()
+
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	registration, err := queries.DeleteOpaqueRegistration(ctx, params.RegistrationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrUnauthorized
+		}
+		return zero, fmt.Errorf("failed to delete opaque registration: %w", err)
+	}
+	if time.Now().After(registration.ExpiresAt) {
+		return zero, service.ErrUnauthorized
+	}
+
+	if ok, err := queries.CheckEmail(ctx, params.Email); err != nil {
+		return zero, fmt.Errorf("failed to check email: %w", err)
+	} else if ok {
+		return zero, service.ErrEmailConflict
+	}
+
+	if err := queries.InsertOpaqueCredentials(ctx, repo.InsertOpaqueCredentialsParams{
+		ID:           registration.ID,
+		Email:        params.Email,
+		OpaqueRecord: params.Record,
+	}); err != nil {
+		// a concurrent registration of the same email got past the check.
+		if service.IsUniqueViolation(err) {
+			return zero, service.ErrEmailConflict
+		}
+		return zero, fmt.Errorf("failed to insert credentials: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return zero, fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	if err := me.sendVerificationEmail(ctx, params.Email); err != nil {
+		me.logger.Error("failed to send verification email", "error", err)
+	}
+
+	return registration.ID, nil
+}
+
+type FinishOpaqueRegistrationParams struct {
+	RegistrationID uuid.UUID
+	Email          string
+	Record         []byte
+}
+
+func (me *FinishOpaqueRegistrationParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.Email, validation.Required, is.Email),
+	)
+}
+
+// BeginOpaqueUpgrade answers the registration request of an account that logs
+// in with a password. The password is checked one last time, like a login
+// does and counting towards the same lockout. The returned ID is needed to
+// finish the upgrade.
+func (me *AuthService) BeginOpaqueUpgrade(credentialsID uuid.UUID, password string, request []byte) (OpaqueRegistration, error) {
+	ctx := context.Background()
+	var zero OpaqueRegistration
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, credentialsID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if credentials.OpaqueRecord != nil {
+		return zero, service.ErrConflict
+	}
+
+	if err := me.checkLoginAttempt(ctx, credentials.Email); err != nil {
+		return zero, err
+	}
+	if !verifyPassword(password, credentials.PasswordHash) {
+		if err := me.loginFailed(ctx, credentials.Email); err != nil {
+			return zero, err
+		}
+		return zero, service.ErrUnauthorized
+	}
+	if err := me.loginSucceeded(ctx, credentials.Email); err != nil {
+		return zero, err
+	}
+
+	response, err := me.opaque.RegistrationResponse(request, credentials.ID[:])
+	if err != nil {
+		return zero, opaqueError(err)
+	}
+
+	upgradeID := uuid.New()
+	if err := me.queries.InsertOpaqueUpgrade(ctx, repo.InsertOpaqueUpgradeParams{
+		ID:            upgradeID,
+		CredentialsID: credentials.ID,
+		ExpiresAt:     time.Now().Add(config.OpaqueRegistrationExpiration),
+	}); err != nil {
+		return zero, fmt.Errorf("failed to insert opaque upgrade: %w", err)
+	}
+
+	return OpaqueRegistration{ID: upgradeID, Response: response}, nil
+}
+
+type FinishOpaqueUpgradeParams struct {
+	CredentialsID uuid.UUID
+	UpgradeID     uuid.UUID
+	Record        []byte
+	Client        audit.Client
+}
+
+// FinishOpaqueUpgrade replaces the password hash with the record. It takes
+// the ID of an upgrade begun within config.OpaqueRegistrationExpiration, which
+// can be finished once, ErrUnauthorized is returned otherwise. A pending
+// password reset still applies to the account afterwards.
+func (me *AuthService) FinishOpaqueUpgrade(params FinishOpaqueUpgradeParams) error {
+	ctx := context.Background()
+
+	if err := opaque.ValidateRecord(params.Record); err != nil {
+		return opaqueError(err)
+	}
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, params.CredentialsID)
+	if err != nil {
+		return fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if credentials.OpaqueRecord != nil {
+		return service.ErrConflict
+	}
+
+	upgrade, err := me.queries.DeleteOpaqueUpgrade(ctx, repo.DeleteOpaqueUpgradeParams{
+		ID:            params.UpgradeID,
+		CredentialsID: params.CredentialsID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return service.ErrUnauthorized
+		}
+		return fmt.Errorf("failed to delete opaque upgrade: %w", err)
+	}
+	if time.Now().After(upgrade.ExpiresAt) {
+		return service.ErrUnauthorized
+	}
+
+	if err := me.queries.SetOpaqueRecord(ctx, repo.SetOpaqueRecordParams{
+		ID:           params.CredentialsID,
+		OpaqueRecord: params.Record,
+	}); err != nil {
+		return fmt.Errorf("failed to set opaque record: %w", err)
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: params.CredentialsID, Valid: true},
+		Type:          audit.TypePasswordChanged,
+		Client:        params.Client,
+		Details:       map[string]any{"opaque": true},
+	})
+
+	return nil
+}
+
+type OpaqueLogin struct {
+	ID  uuid.UUID
+	KE2 []byte
+}
+
+// BeginOpaqueLogin answers KE1. Unknown emails and accounts without a record
+// get a KE2 made from a fake record, they fail at FinishOpaqueLogin like a
+// wrong password.
+func (me *AuthService) BeginOpaqueLogin(email string, ke1 []byte) (OpaqueLogin, error) {
+	ctx := context.Background()
+	var zero OpaqueLogin
+
+	if err := me.checkLoginAttempt(ctx, email); err != nil {
+		return zero, err
+	}
+
+	var (
+		record               []byte
+		credentialIdentifier = []byte(email)
+		credentialsID        uuid.NullUUID
+	)
+	credentials, err := me.queries.GetCredentialsByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return zero, fmt.Errorf("failed to get credentials by email: %w", err)
+	}
+	if err == nil && credentials.OpaqueRecord != nil {
+		record = credentials.OpaqueRecord
+		credentialIdentifier = credentials.ID[:]
+		credentialsID = uuid.NullUUID{UUID: credentials.ID, Valid: true}
+	}
+
+	ke2, state, err := me.opaque.LoginInit(record, credentialIdentifier, ke1)
+	if err != nil {
+		return zero, opaqueError(err)
+	}
+
+	loginID := uuid.New()
+	if err := me.queries.InsertOpaqueLogin(ctx, repo.InsertOpaqueLoginParams{
+		ID:                loginID,
+		CredentialsID:     credentialsID,
+		Email:             email,
+		ExpectedClientMac: state.ExpectedClientMAC,
+		ExpiresAt:         time.Now().Add(config.OpaqueLoginExpiration),
+	}); err != nil {
+		return zero, fmt.Errorf("failed to insert opaque login: %w", err)
+	}
+
+	return OpaqueLogin{ID: loginID, KE2: ke2}, nil
+}
+
+// FinishOpaqueLogin checks KE3, and continues like Login once the password is
+// verified.
+func (me *AuthService) FinishOpaqueLogin(params FinishOpaqueLoginParams) (repo.Session, error) {
+	ctx := context.Background()
+	var zero repo.Session
+
+	if err := me.checkLoginAttempt(ctx, params.Email); err != nil {
+		return zero, err
+	}
+
+	login, err := me.queries.DeleteOpaqueLogin(ctx, params.LoginID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrUnauthorized
+		}
+		return zero, fmt.Errorf("failed to delete opaque login: %w", err)
+	}
+	if time.Now().After(login.ExpiresAt) || login.Email != params.Email {
+		return zero, service.ErrUnauthorized
+	}
+
+	_, err = opaque.LoginFinish(opaque.LoginState{ExpectedClientMAC: login.ExpectedClientMac}, params.KE3)
+	if err != nil || !login.CredentialsID.Valid {
+		if err := me.loginFailed(ctx, login.Email); err != nil {
+			return zero, err
+		}
+		return zero, service.ErrUnauthorized
+	}
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, login.CredentialsID.UUID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+
+	return me.firstFactorVerified(ctx, credentials, params.Client)
+}
+
+type FinishOpaqueLoginParams struct {
+	LoginID uuid.UUID
+	Email   string
+	KE3     []byte
+	Client  audit.Client
+}
+
+func opaqueError(err error) error {
+	if errors.Is(err, opaque.ErrInvalidMessage) {
+		return service.ErrOpaque
+	}
+	return fmt.Errorf("failed to run opaque: %w", err)
+}
@@ -0,0 +1,214 @@
+package opaque
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/oprf"
+	"golang.org/x/crypto/argon2"
+)
+
+// KSF is the key stretching function applied to the OPRF output.
+type KSF func(input []byte) []byte
+
+// Argon2id stretches with Argon2id, zero salt, t=1, p=4 and the given memory
+// in KiB.
+func Argon2id(memory uint32) KSF {
+	return func(input []byte) []byte {
+		return argon2.IDKey(input, make([]byte, 16), 1, memory, 4, HashSize)
+	}
+}
+
+// Identity doesn't stretch, for tests only.
+func Identity(input []byte) []byte {
+	return input
+}
+
+type Client struct {
+	context []byte
+	ksf     KSF
+}
+
+func NewClient(context []byte, ksf KSF) *Client {
+	return &Client{context: context, ksf: ksf}
+}
+
+type blindState struct {
+	password []byte
+	finalize *oprf.FinalizeData
+}
+
+// blind blinds the password with a random scalar, or the given one for the test
+// vectors.
+func (me *Client) blind(password []byte, blind oprf.Blind) ([]byte, blindState, error) {
+	if blind == nil {
+		blind = suite.Group().RandomScalar(rand.Reader)
+	}
+	finalize, request, err := oprf.NewClient(suite).DeterministicBlind([][]byte{password}, []oprf.Blind{blind})
+	if err != nil {
+		return nil, blindState{}, err
+	}
+	blinded, err := request.Elements[0].MarshalBinaryCompress()
+	if err != nil {
+		return nil, blindState{}, err
+	}
+	return blinded, blindState{password: password, finalize: finalize}, nil
+}
+
+// randomizedPassword finalizes the OPRF and stretches its output.
+func (me *Client) randomizedPassword(state blindState, evaluated []byte) ([]byte, error) {
+	element, err := parseElement(evaluated)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := oprf.NewClient(suite).Finalize(state.finalize, &oprf.Evaluation{
+		Elements: []group.Element{element},
+	})
+	if err != nil {
+		return nil, err
+	}
+	output := outputs[0]
+	return extract(concat(output, me.ksf(output))), nil
+}
+
+// envelopeKeys are the keys derived from the randomized password and the
+// envelope nonce.
+func envelopeKeys(randomizedPassword, nonce []byte) (authKey, exportKey []byte, keyPair keyPair, err error) {
+	authKey = expand(randomizedPassword, concat(nonce, []byte("AuthKey")), HashSize)
+	exportKey = expand(randomizedPassword, concat(nonce, []byte("ExportKey")), HashSize)
+	keyPair, err = deriveDiffieHellmanKeyPair(expand(randomizedPassword, concat(nonce, []byte("PrivateKey")), SeedSize))
+	return authKey, exportKey, keyPair, err
+}
+
+type ClientRegistration struct {
+	blind blindState
+}
+
+// RegistrationRequest starts registering the password.
+func (me *Client) RegistrationRequest(password []byte) ([]byte, *ClientRegistration, error) {
+	request, state, err := me.blind(password, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return request, &ClientRegistration{blind: state}, nil
+}
+
+// FinalizeRegistration returns the record to upload and the export key, which
+// never leaves the client.
+func (me *Client) FinalizeRegistration(state *ClientRegistration, response []byte) (record, exportKey []byte, err error) {
+	nonce := make([]byte, NonceSize)
+	rand.Read(nonce)
+	return me.finalizeRegistration(state, response, nonce)
+}
+
+func (me *Client) finalizeRegistration(state *ClientRegistration, response, nonce []byte) (record, exportKey []byte, err error) {
+	if len(response) != RegistrationResponseSize {
+		return nil, nil, ErrInvalidMessage
+	}
+	evaluated, serverPublicKey := response[:ElementSize], response[ElementSize:]
+
+	randomizedPassword, err := me.randomizedPassword(state.blind, evaluated)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maskingKey := expand(randomizedPassword, []byte("MaskingKey"), HashSize)
+	authKey, exportKey, keyPair, err := envelopeKeys(randomizedPassword, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, _, cleartext := cleartextCredentials(serverPublicKey, keyPair.public)
+	envelope := concat(nonce, mac(authKey, nonce, cleartext))
+
+	return concat(keyPair.public, maskingKey, envelope), exportKey, nil
+}
+
+type ClientLogin struct {
+	blind    blindState
+	keyshare keyPair
+	ke1      []byte
+}
+
+// LoginInit returns KE1.
+func (me *Client) LoginInit(password []byte) ([]byte, *ClientLogin, error) {
+	nonce := make([]byte, NonceSize)
+	rand.Read(nonce)
+	keyshareSeed := make([]byte, SeedSize)
+	rand.Read(keyshareSeed)
+	return me.loginInit(password, nil, nonce, keyshareSeed)
+}
+
+func (me *Client) loginInit(password []byte, blind oprf.Blind, nonce, keyshareSeed []byte) ([]byte, *ClientLogin, error) {
+	blinded, state, err := me.blind(password, blind)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyshare, err := deriveDiffieHellmanKeyPair(keyshareSeed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ke1 := concat(blinded, nonce, keyshare.public)
+	return ke1, &ClientLogin{blind: state, keyshare: keyshare, ke1: ke1}, nil
+}
+
+// LoginFinish checks KE2 and returns KE3, the session key and the export key.
+// ErrAuthentication means a wrong password or a server that doesn't hold the
+// record.
+func (me *Client) LoginFinish(state *ClientLogin, ke2 []byte) (ke3, sessionKey, exportKey []byte, err error) {
+	if len(ke2) != KE2Size {
+		return nil, nil, nil, ErrInvalidMessage
+	}
+	var (
+		credentialResponse = ke2[:credentialResponseSize]
+		evaluated          = credentialResponse[:ElementSize]
+		maskingNonce       = credentialResponse[ElementSize : ElementSize+NonceSize]
+		maskedResponse     = credentialResponse[ElementSize+NonceSize:]
+		serverNonce        = ke2[credentialResponseSize : credentialResponseSize+NonceSize]
+		serverKeyshare     = ke2[credentialResponseSize+NonceSize : credentialResponseSize+NonceSize+PublicKeySize]
+		serverMAC          = ke2[credentialResponseSize+NonceSize+PublicKeySize:]
+	)
+
+	randomizedPassword, err := me.randomizedPassword(state.blind, evaluated)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	maskingKey := expand(randomizedPassword, []byte("MaskingKey"), HashSize)
+	pad := expand(maskingKey, concat(maskingNonce, []byte("CredentialResponsePad")), PublicKeySize+EnvelopeSize)
+	unmasked := xor(pad, maskedResponse)
+	serverPublicKey, envelope := unmasked[:PublicKeySize], unmasked[PublicKeySize:]
+	envelopeNonce, authTag := envelope[:NonceSize], envelope[NonceSize:]
+
+	authKey, exportKey, keyPair, err := envelopeKeys(randomizedPassword, envelopeNonce)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serverIdentity, clientIdentity, cleartext := cleartextCredentials(serverPublicKey, keyPair.public)
+	if subtle.ConstantTimeCompare(authTag, mac(authKey, envelopeNonce, cleartext)) != 1 {
+		return nil, nil, nil, ErrAuthentication
+	}
+
+	dh1, err := diffieHellman(state.keyshare.private, serverKeyshare)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dh2, err := diffieHellman(state.keyshare.private, serverPublicKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dh3, err := diffieHellman(keyPair.private, serverKeyshare)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	preamble := preamble(me.context, clientIdentity, state.ke1, serverIdentity, credentialResponse, serverNonce, serverKeyshare)
+	km2, km3, sessionKey := deriveKeys(concat(dh1, dh2, dh3), preamble)
+
+	if subtle.ConstantTimeCompare(serverMAC, mac(km2, hash(preamble))) != 1 {
+		return nil, nil, nil, ErrAuthentication
+	}
+	ke3 = mac(km3, hash(preamble, serverMAC))
+
+	return ke3, sessionKey, exportKey, nil
+}
@@ -0,0 +1,198 @@
+// Package opaque implements the OPAQUE-3DH augmented PAKE of RFC 9807 with
+// the ristretto255-SHA512 OPRF, HKDF-SHA512, HMAC-SHA512 and SHA-512. The
+// server only ever stores a registration record, the password never leaves
+// the client.
+//
+// Identities are left out, so both default to the parties' public keys. The
+// key stretching function runs on the client only and must be the same for
+// registration and login.
+package opaque
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/oprf"
+	"golang.org/x/crypto/hkdf"
+)
+
+// sizes for ristretto255-SHA512.
+const (
+	NonceSize     = 32
+	SeedSize      = 32
+	ElementSize   = 32
+	ScalarSize    = 32
+	PublicKeySize = 32
+	HashSize      = 64
+	MACSize       = 64
+	EnvelopeSize  = NonceSize + MACSize
+
+	// RecordSize is client_public_key, masking_key and envelope.
+	RecordSize = PublicKeySize + HashSize + EnvelopeSize
+	// RegistrationResponseSize is the evaluated element and the server's
+	// public key.
+	RegistrationResponseSize = ElementSize + PublicKeySize
+	// KE1Size is the blinded element, client nonce and client key share.
+	KE1Size                = ElementSize + NonceSize + PublicKeySize
+	credentialResponseSize = ElementSize + NonceSize + PublicKeySize + EnvelopeSize
+	// KE2Size is the credential response, server nonce, server key share and
+	// server MAC.
+	KE2Size = credentialResponseSize + NonceSize + PublicKeySize + MACSize
+	// KE3Size is the client MAC.
+	KE3Size = MACSize
+)
+
+var (
+	ErrInvalidMessage = errors.New("opaque: invalid message")
+	// ErrAuthentication is a wrong password on the client, or a client that
+	// couldn't prove it knows the password on the server.
+	ErrAuthentication = errors.New("opaque: authentication failed")
+)
+
+var suite = oprf.SuiteRistretto255
+
+func expand(prk, info []byte, length int) []byte {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(sha512.New, prk, info), out); err != nil {
+		panic(err) // only for lengths over 255 hashes.
+	}
+	return out
+}
+
+func extract(ikm []byte) []byte {
+	return hkdf.Extract(sha512.New, ikm, nil)
+}
+
+func mac(key []byte, parts ...[]byte) []byte {
+	h := hmac.New(sha512.New, key)
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+func hash(parts ...[]byte) []byte {
+	h := sha512.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+func lengthPrefixed(data []byte) []byte {
+	return binary.BigEndian.AppendUint16(nil, uint16(len(data)))
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+// keyPair is a Diffie-Hellman key pair of the group.
+type keyPair struct {
+	private group.Scalar
+	public  []byte
+}
+
+// deriveKeyPair is DeriveKeyPair of RFC 9497 with the given info.
+func deriveKeyPair(seed []byte, info string) (keyPair, error) {
+	key, err := oprf.DeriveKey(suite, oprf.BaseMode, seed, []byte(info))
+	if err != nil {
+		return keyPair{}, fmt.Errorf("failed to derive key pair: %w", err)
+	}
+	private, err := key.MarshalBinary()
+	if err != nil {
+		return keyPair{}, err
+	}
+	public, err := key.Public().MarshalBinary()
+	if err != nil {
+		return keyPair{}, err
+	}
+
+	scalar := suite.Group().NewScalar()
+	if err := scalar.UnmarshalBinary(private); err != nil {
+		return keyPair{}, err
+	}
+	return keyPair{private: scalar, public: public}, nil
+}
+
+func deriveDiffieHellmanKeyPair(seed []byte) (keyPair, error) {
+	return deriveKeyPair(seed, "OPAQUE-DeriveDiffieHellmanKeyPair")
+}
+
+// parseElement rejects invalid encodings and the identity element.
+func parseElement(data []byte) (group.Element, error) {
+	element := suite.Group().NewElement()
+	if err := element.UnmarshalBinary(data); err != nil || element.IsIdentity() {
+		return nil, ErrInvalidMessage
+	}
+	return element, nil
+}
+
+func diffieHellman(private group.Scalar, public []byte) ([]byte, error) {
+	element, err := parseElement(public)
+	if err != nil {
+		return nil, err
+	}
+	return suite.Group().NewElement().Mul(element, private).MarshalBinaryCompress()
+}
+
+// cleartextCredentials are what the envelope authenticates.
+func cleartextCredentials(serverPublicKey, clientPublicKey []byte) (serverIdentity, clientIdentity, serialized []byte) {
+	serverIdentity, clientIdentity = serverPublicKey, clientPublicKey
+	serialized = concat(
+		serverPublicKey,
+		lengthPrefixed(serverIdentity), serverIdentity,
+		lengthPrefixed(clientIdentity), clientIdentity,
+	)
+	return serverIdentity, clientIdentity, serialized
+}
+
+func preamble(context, clientIdentity, ke1, serverIdentity, credentialResponse, serverNonce, serverKeyshare []byte) []byte {
+	return concat(
+		[]byte("OPAQUEv1-"), lengthPrefixed(context), context,
+		lengthPrefixed(clientIdentity), clientIdentity,
+		ke1,
+		lengthPrefixed(serverIdentity), serverIdentity,
+		credentialResponse,
+		serverNonce,
+		serverKeyshare,
+	)
+}
+
+func expandLabel(secret []byte, label string, context []byte, length int) []byte {
+	label = "OPAQUE-" + label
+	info := binary.BigEndian.AppendUint16(nil, uint16(length))
+	info = append(info, byte(len(label)))
+	info = append(info, label...)
+	info = append(info, byte(len(context)))
+	info = append(info, context...)
+	return expand(secret, info, length)
+}
+
+// deriveKeys returns Km2, Km3 and the session key.
+func deriveKeys(ikm, preamble []byte) (km2, km3, sessionKey []byte) {
+	prk := extract(ikm)
+	transcript := hash(preamble)
+	handshakeSecret := expandLabel(prk, "HandshakeSecret", transcript, HashSize)
+	sessionKey = expandLabel(prk, "SessionKey", transcript, HashSize)
+	km2 = expandLabel(handshakeSecret, "ServerMAC", nil, HashSize)
+	km3 = expandLabel(handshakeSecret, "ClientMAC", nil, HashSize)
+	return km2, km3, sessionKey
+}
@@ -0,0 +1,323 @@
+package opaque
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/cloudflare/circl/group"
+)
+
+var (
+	testSecret  = bytes.Repeat([]byte{0x42}, 32)
+	testContext = []byte("chatapp-test")
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	server, err := NewServer(testSecret, testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func register(t *testing.T, server *Server, client *Client, password, credentialIdentifier []byte) (record, exportKey []byte) {
+	t.Helper()
+	request, state, err := client.RegistrationRequest(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := server.RegistrationResponse(request, credentialIdentifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, exportKey, err = client.FinalizeRegistration(state, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateRecord(record); err != nil {
+		t.Fatalf("ValidateRecord() = %v", err)
+	}
+	return record, exportKey
+}
+
+// login runs a login, and returns the error of whichever side failed.
+func login(t *testing.T, server *Server, client *Client, password, record, credentialIdentifier []byte) (sessionKey, exportKey []byte, err error) {
+	t.Helper()
+	ke1, clientState, err := client.LoginInit(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ke2, serverState, err := server.LoginInit(record, credentialIdentifier, ke1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ke3, clientSessionKey, exportKey, err := client.LoginFinish(clientState, ke2)
+	if err != nil {
+		return nil, nil, err
+	}
+	serverSessionKey, err := LoginFinish(serverState, ke3)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(clientSessionKey, serverSessionKey) {
+		t.Fatal("client and server session keys differ")
+	}
+	return serverSessionKey, exportKey, nil
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	server := newTestServer(t)
+	client := NewClient(testContext, Identity)
+	password := []byte("correct horse battery staple")
+	credentialIdentifier := []byte("credentials-1")
+
+	record, registrationExportKey := register(t, server, client, password, credentialIdentifier)
+
+	sessionKey, exportKey, err := login(t, server, client, password, record, credentialIdentifier)
+	if err != nil {
+		t.Fatalf("login = %v", err)
+	}
+	if len(sessionKey) == 0 {
+		t.Error("empty session key")
+	}
+	if !bytes.Equal(exportKey, registrationExportKey) {
+		t.Error("login export key differs from the registration's")
+	}
+
+	// every login has its own session key, the export key stays.
+	otherSessionKey, otherExportKey, err := login(t, server, client, password, record, credentialIdentifier)
+	if err != nil {
+		t.Fatalf("second login = %v", err)
+	}
+	if bytes.Equal(otherSessionKey, sessionKey) {
+		t.Error("two logins share a session key")
+	}
+	if !bytes.Equal(otherExportKey, exportKey) {
+		t.Error("export key changed between logins")
+	}
+}
+
+func TestRegistrationAndLoginArgon2id(t *testing.T) {
+	server := newTestServer(t)
+	client := NewClient(testContext, Argon2id(1024))
+	password := []byte("correct horse battery staple")
+
+	record, _ := register(t, server, client, password, []byte("credentials-1"))
+	if _, _, err := login(t, server, client, password, record, []byte("credentials-1")); err != nil {
+		t.Fatalf("login = %v", err)
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	server := newTestServer(t)
+	client := NewClient(testContext, Identity)
+	password := []byte("correct horse battery staple")
+	credentialIdentifier := []byte("credentials-1")
+	record, _ := register(t, server, client, password, credentialIdentifier)
+
+	otherServer, err := NewServer(bytes.Repeat([]byte{0x43}, 32), testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                 string
+		server               *Server
+		client               *Client
+		password             []byte
+		record               []byte
+		credentialIdentifier []byte
+	}{
+		{"wrong password", server, client, []byte("wrong"), record, credentialIdentifier},
+		// the OPRF key is derived from the identifier, a record only works
+		// with its own.
+		{"other credentials", server, client, password, record, []byte("credentials-2")},
+		{"unknown credentials", server, client, password, nil, credentialIdentifier},
+		{"other server", otherServer, client, password, record, credentialIdentifier},
+		{"other context", server, NewClient([]byte("other"), Identity), password, record, credentialIdentifier},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := login(t, test.server, test.client, test.password, test.record, test.credentialIdentifier)
+			if !errors.Is(err, ErrAuthentication) {
+				t.Errorf("login = %v, want %v", err, ErrAuthentication)
+			}
+		})
+	}
+}
+
+func TestLoginFinishWrongKE3(t *testing.T) {
+	server := newTestServer(t)
+	client := NewClient(testContext, Identity)
+	password := []byte("correct horse battery staple")
+	record, _ := register(t, server, client, password, []byte("credentials-1"))
+
+	ke1, _, err := client.LoginInit(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, state, err := server.LoginInit(record, []byte("credentials-1"), ke1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoginFinish(state, make([]byte, KE3Size)); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("LoginFinish() = %v, want %v", err, ErrAuthentication)
+	}
+	if _, err := LoginFinish(state, nil); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("LoginFinish() of an empty ke3 = %v, want %v", err, ErrAuthentication)
+	}
+}
+
+func TestInvalidMessages(t *testing.T) {
+	server := newTestServer(t)
+
+	if _, err := server.RegistrationResponse([]byte("short"), []byte("credentials-1")); err == nil {
+		t.Error("RegistrationResponse() accepted a malformed request")
+	}
+	if _, _, err := server.LoginInit(nil, []byte("credentials-1"), []byte("short")); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("LoginInit() = %v, want %v", err, ErrInvalidMessage)
+	}
+	if err := ValidateRecord([]byte("short")); err == nil {
+		t.Error("ValidateRecord() accepted a malformed record")
+	}
+}
+
+// rfcVector is OPAQUE-3DH Real Test Vector 1 of RFC 9807 appendix C.1.1:
+// ristretto255-SHA512, the identity KSF and no identities.
+var rfcVector = struct {
+	context, oprfSeed, credentialIdentifier, password             string
+	serverPrivateKey, serverPublicKey                             string
+	envelopeNonce, maskingNonce, serverNonce, clientNonce         string
+	clientKeyshareSeed, serverKeyshareSeed                        string
+	blindRegistration, blindLogin                                 string
+	randomizedPassword, authKey, envelope                         string
+	registrationRequest, registrationResponse, registrationUpload string
+	ke1, ke2, ke3, exportKey, sessionKey                          string
+}{
+	context:              "4f50415155452d504f43",
+	oprfSeed:             "f433d0227b0b9dd54f7c4422b600e764e47fb503f1f9a0f0a47c6606b054a7fdc65347f1a08f277e22358bbabe26f823fca82c7848e9a75661f4ec5d5c1989ef",
+	credentialIdentifier: "31323334",
+	password:             "436f7272656374486f72736542617474657279537461706c65",
+	serverPrivateKey:     "47451a85372f8b3537e249d7b54188091fb18edde78094b43e2ba42b5eb89f0d",
+	serverPublicKey:      "b2fe7af9f48cc502d016729d2fe25cdd433f2c4bc904660b2a382c9b79df1a78",
+	envelopeNonce:        "ac13171b2f17bc2c74997f0fce1e1f35bec6b91fe2e12dbd323d23ba7a38dfec",
+	maskingNonce:         "38fe59af0df2c79f57b8780278f5ae47355fe1f817119041951c80f612fdfc6d",
+	serverNonce:          "71cd9960ecef2fe0d0f7494986fa3d8b2bb01963537e60efb13981e138e3d4a1",
+	clientNonce:          "da7e07376d6d6f034cfa9bb537d11b8c6b4238c334333d1f0aebb380cae6a6cc",
+	clientKeyshareSeed:   "82850a697b42a505f5b68fcdafce8c31f0af2b581f063cf1091933541936304b",
+	serverKeyshareSeed:   "05a4f54206eef1ba2f615bc0aa285cb22f26d1153b5b40a1e85ff80da12f982f",
+	blindRegistration:    "76cfbfe758db884bebb33582331ba9f159720ca8784a2a070a265d9c2d6abe01",
+	blindLogin:           "6ecc102d2e7a7cf49617aad7bbe188556792d4acd60a1a8a8d2b65d4b0790308",
+	randomizedPassword:   "aac48c25ab036e30750839d31d6e73007344cb1155289fb7d329beb932e9adeea73d5d5c22a0ce1952f8aba6d66007615cd1698d4ac85ef1fcf150031d1435d9",
+	authKey:              "6cd32316f18d72a9a927a83199fa030663a38ce0c11fbaef82aa90037730494fc555c4d49506284516edd1628c27965b7555a4ebfed2223199f6c67966dde822",
+	envelope:             "ac13171b2f17bc2c74997f0fce1e1f35bec6b91fe2e12dbd323d23ba7a38dfec634b0f5b96109c198a8027da51854c35bee90d1e1c781806d07d49b76de6a28b8d9e9b6c93b9f8b64d16dddd9c5bfb5fea48ee8fd2f75012a8b308605cdd8ba5",
+	registrationRequest:  "5059ff249eb1551b7ce4991f3336205bde44a105a032e747d21bf382e75f7a71",
+	registrationResponse: "7408a268083e03abc7097fc05b587834539065e86fb0c7b6342fcf5e01e5b019b2fe7af9f48cc502d016729d2fe25cdd433f2c4bc904660b2a382c9b79df1a78",
+	registrationUpload:   "76a845464c68a5d2f7e442436bb1424953b17d3e2e289ccbaccafb57ac5c36751ac5844383c7708077dea41cbefe2fa15724f449e535dd7dd562e66f5ecfb95864eadddec9db5874959905117dad40a4524111849799281fefe3c51fa82785c5ac13171b2f17bc2c74997f0fce1e1f35bec6b91fe2e12dbd323d23ba7a38dfec634b0f5b96109c198a8027da51854c35bee90d1e1c781806d07d49b76de6a28b8d9e9b6c93b9f8b64d16dddd9c5bfb5fea48ee8fd2f75012a8b308605cdd8ba5",
+	ke1:                  "c4dedb0ba6ed5d965d6f250fbe554cd45cba5dfcce3ce836e4aee778aa3cd44dda7e07376d6d6f034cfa9bb537d11b8c6b4238c334333d1f0aebb380cae6a6cc6e29bee50701498605b2c085d7b241ca15ba5c32027dd21ba420b94ce60da326",
+	ke2:                  "7e308140890bcde30cbcea28b01ea1ecfbd077cff62c4def8efa075aabcbb47138fe59af0df2c79f57b8780278f5ae47355fe1f817119041951c80f612fdfc6dd6ec60bcdb26dc455ddf3e718f1020490c192d70dfc7e403981179d8073d1146a4f9aa1ced4e4cd984c657eb3b54ced3848326f70331953d91b02535af44d9fedc80188ca46743c52786e0382f95ad85c08f6afcd1ccfbff95e2bdeb015b166c6b20b92f832cc6df01e0b86a7efd92c1c804ff865781fa93f2f20b446c8371b671cd9960ecef2fe0d0f7494986fa3d8b2bb01963537e60efb13981e138e3d4a1c4f62198a9d6fa9170c42c3c71f1971b29eb1d5d0bd733e40816c91f7912cc4a660c48dae03e57aaa38f3d0cffcfc21852ebc8b405d15bd6744945ba1a93438a162b6111699d98a16bb55b7bdddfe0fc5608b23da246e7bd73b47369169c5c90",
+	ke3:                  "4455df4f810ac31a6748835888564b536e6da5d9944dfea9e34defb9575fe5e2661ef61d2ae3929bcf57e53d464113d364365eb7d1a57b629707ca48da18e442",
+	exportKey:            "1ef15b4fa99e8a852412450ab78713aad30d21fa6966c9b8c9fb3262a970dc62950d4dd4ed62598229b1b72794fc0335199d9f7fcc6eaedde92cc04870e63f16",
+	sessionKey:           "42afde6f5aca0cfa5c163763fbad55e73a41db6b41bc87b8e7b62214a8eedc6731fa3cb857d657ab9b3764b89a84e91ebcb4785166fbb02cedfcbdfda215b96f",
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %q", s)
+	}
+	return b
+}
+
+func decodeScalar(t *testing.T, s string) group.Scalar {
+	t.Helper()
+	scalar := suite.Group().NewScalar()
+	if err := scalar.UnmarshalBinary(decodeHex(t, s)); err != nil {
+		t.Fatalf("invalid scalar %q", s)
+	}
+	return scalar
+}
+
+func checkVector(t *testing.T, name string, got []byte, want string) {
+	t.Helper()
+	if !bytes.Equal(got, decodeHex(t, want)) {
+		t.Errorf("%s = %x, want %s", name, got, want)
+	}
+}
+
+func TestRFCVector(t *testing.T) {
+	v := rfcVector
+	serverPrivateKey := decodeScalar(t, v.serverPrivateKey)
+	serverPublicKey, err := suite.Group().NewElement().MulGen(serverPrivateKey).MarshalBinaryCompress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, "server public key", serverPublicKey, v.serverPublicKey)
+
+	server := newServer(keyPair{private: serverPrivateKey, public: serverPublicKey}, decodeHex(t, v.oprfSeed), decodeHex(t, v.context))
+	client := NewClient(decodeHex(t, v.context), Identity)
+	password := decodeHex(t, v.password)
+	credentialIdentifier := decodeHex(t, v.credentialIdentifier)
+
+	request, blind, err := client.blind(password, decodeScalar(t, v.blindRegistration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, "registration request", request, v.registrationRequest)
+
+	response, err := server.RegistrationResponse(request, credentialIdentifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, "registration response", response, v.registrationResponse)
+
+	randomizedPassword, err := client.randomizedPassword(blind, response[:ElementSize])
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, "randomized password", randomizedPassword, v.randomizedPassword)
+	authKey, _, _, err := envelopeKeys(randomizedPassword, decodeHex(t, v.envelopeNonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, "auth key", authKey, v.authKey)
+
+	record, exportKey, err := client.finalizeRegistration(&ClientRegistration{blind: blind}, response, decodeHex(t, v.envelopeNonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, "registration upload", record, v.registrationUpload)
+	checkVector(t, "envelope", record[PublicKeySize+HashSize:], v.envelope)
+	checkVector(t, "registration export key", exportKey, v.exportKey)
+
+	ke1, clientState, err := client.loginInit(password, decodeScalar(t, v.blindLogin), decodeHex(t, v.clientNonce), decodeHex(t, v.clientKeyshareSeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, "KE1", ke1, v.ke1)
+
+	ke2, serverState, err := server.loginInit(record, credentialIdentifier, ke1, decodeHex(t, v.maskingNonce), decodeHex(t, v.serverNonce), decodeHex(t, v.serverKeyshareSeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, "KE2", ke2, v.ke2)
+
+	ke3, clientSessionKey, exportKey, err := client.LoginFinish(clientState, ke2)
+	if err != nil {
+		t.Fatalf("client LoginFinish() = %v", err)
+	}
+	checkVector(t, "KE3", ke3, v.ke3)
+	checkVector(t, "client session key", clientSessionKey, v.sessionKey)
+	checkVector(t, "login export key", exportKey, v.exportKey)
+
+	serverSessionKey, err := LoginFinish(serverState, ke3)
+	if err != nil {
+		t.Fatalf("server LoginFinish() = %v", err)
+	}
+	checkVector(t, "server session key", serverSessionKey, v.sessionKey)
+}
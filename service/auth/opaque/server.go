@@ -0,0 +1,188 @@
+package opaque
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/cloudflare/circl/group"
+	"github.com/cloudflare/circl/oprf"
+)
+
+// Server holds the server's long term keys: the AKE key pair and the seed the
+// per-credential OPRF keys are derived from. Changing them invalidates every
+// registration record.
+type Server struct {
+	keyPair  keyPair
+	oprfSeed []byte
+	context  []byte
+}
+
+// NewServer derives the server keys from a secret of at least 32 bytes.
+// Context binds the handshakes to the application, clients must use the same.
+func NewServer(secret, context []byte) (*Server, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("opaque: server secret must be at least 32 bytes")
+	}
+	prk := extract(secret)
+
+	keyPair, err := deriveDiffieHellmanKeyPair(expand(prk, []byte("ServerKeyPair"), SeedSize))
+	if err != nil {
+		return nil, err
+	}
+	return newServer(keyPair, expand(prk, []byte("OprfSeed"), HashSize), context), nil
+}
+
+// newServer takes the server keys as they are, for the test vectors.
+func newServer(keyPair keyPair, oprfSeed, context []byte) *Server {
+	return &Server{keyPair: keyPair, oprfSeed: oprfSeed, context: context}
+}
+
+func (me *Server) PublicKey() []byte {
+	return me.keyPair.public
+}
+
+// evaluate blindly evaluates the client's element with the credential's
+// OPRF key.
+func (me *Server) evaluate(blinded, credentialIdentifier []byte) ([]byte, error) {
+	element, err := parseElement(blinded)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := expand(me.oprfSeed, concat(credentialIdentifier, []byte("OprfKey")), ScalarSize)
+	key, err := oprf.DeriveKey(suite, oprf.BaseMode, seed, []byte("OPAQUE-DeriveKeyPair"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive oprf key: %w", err)
+	}
+	evaluation, err := oprf.NewServer(suite, key).Evaluate(&oprf.EvaluationRequest{
+		Elements: []group.Element{element},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate oprf: %w", err)
+	}
+	return evaluation.Elements[0].MarshalBinaryCompress()
+}
+
+// RegistrationResponse answers the client's registration request. The
+// credential identifier must stay the same for the life of the record.
+func (me *Server) RegistrationResponse(request, credentialIdentifier []byte) ([]byte, error) {
+	if len(request) != ElementSize {
+		return nil, ErrInvalidMessage
+	}
+	evaluated, err := me.evaluate(request, credentialIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	return concat(evaluated, me.keyPair.public), nil
+}
+
+// ValidateRecord checks the shape of a registration record from a client.
+func ValidateRecord(record []byte) error {
+	if len(record) != RecordSize {
+		return ErrInvalidMessage
+	}
+	_, err := parseElement(record[:PublicKeySize])
+	return err
+}
+
+// LoginState is what the server keeps between KE2 and KE3.
+type LoginState struct {
+	ExpectedClientMAC []byte
+	SessionKey        []byte
+}
+
+// fakeRecord stands in for unknown or unregistered credentials, so that their
+// KE2 can't be told apart from a real one.
+func fakeRecord() ([]byte, error) {
+	seed := make([]byte, SeedSize)
+	rand.Read(seed)
+	keyPair, err := deriveDiffieHellmanKeyPair(seed)
+	if err != nil {
+		return nil, err
+	}
+	maskingKey := make([]byte, HashSize)
+	rand.Read(maskingKey)
+	return concat(keyPair.public, maskingKey, make([]byte, EnvelopeSize)), nil
+}
+
+// LoginInit answers the client's KE1 with KE2. A nil record makes up a fake
+// one, the login then fails at LoginFinish like with a wrong password.
+func (me *Server) LoginInit(record, credentialIdentifier, ke1 []byte) ([]byte, LoginState, error) {
+	maskingNonce := make([]byte, NonceSize)
+	rand.Read(maskingNonce)
+	serverNonce := make([]byte, NonceSize)
+	rand.Read(serverNonce)
+	keyshareSeed := make([]byte, SeedSize)
+	rand.Read(keyshareSeed)
+	return me.loginInit(record, credentialIdentifier, ke1, maskingNonce, serverNonce, keyshareSeed)
+}
+
+func (me *Server) loginInit(record, credentialIdentifier, ke1, maskingNonce, serverNonce, keyshareSeed []byte) ([]byte, LoginState, error) {
+	var zero LoginState
+	if len(ke1) != KE1Size {
+		return nil, zero, ErrInvalidMessage
+	}
+
+	if record == nil {
+		var err error
+		if record, err = fakeRecord(); err != nil {
+			return nil, zero, err
+		}
+	} else if err := ValidateRecord(record); err != nil {
+		return nil, zero, err
+	}
+	clientPublicKey := record[:PublicKeySize]
+	maskingKey := record[PublicKeySize : PublicKeySize+HashSize]
+	envelope := record[PublicKeySize+HashSize:]
+
+	var (
+		blinded        = ke1[:ElementSize]
+		clientKeyshare = ke1[ElementSize+NonceSize:]
+	)
+
+	evaluated, err := me.evaluate(blinded, credentialIdentifier)
+	if err != nil {
+		return nil, zero, err
+	}
+	pad := expand(maskingKey, concat(maskingNonce, []byte("CredentialResponsePad")), PublicKeySize+EnvelopeSize)
+	maskedResponse := xor(pad, concat(me.keyPair.public, envelope))
+	credentialResponse := concat(evaluated, maskingNonce, maskedResponse)
+
+	keyshare, err := deriveDiffieHellmanKeyPair(keyshareSeed)
+	if err != nil {
+		return nil, zero, err
+	}
+
+	serverIdentity, clientIdentity, _ := cleartextCredentials(me.keyPair.public, clientPublicKey)
+	preamble := preamble(me.context, clientIdentity, ke1, serverIdentity, credentialResponse, serverNonce, keyshare.public)
+
+	dh1, err := diffieHellman(keyshare.private, clientKeyshare)
+	if err != nil {
+		return nil, zero, err
+	}
+	dh2, err := diffieHellman(me.keyPair.private, clientKeyshare)
+	if err != nil {
+		return nil, zero, err
+	}
+	dh3, err := diffieHellman(keyshare.private, clientPublicKey)
+	if err != nil {
+		return nil, zero, err
+	}
+	km2, km3, sessionKey := deriveKeys(concat(dh1, dh2, dh3), preamble)
+
+	serverMAC := mac(km2, hash(preamble))
+	expectedClientMAC := mac(km3, hash(preamble, serverMAC))
+
+	ke2 := concat(credentialResponse, serverNonce, keyshare.public, serverMAC)
+	return ke2, LoginState{ExpectedClientMAC: expectedClientMAC, SessionKey: sessionKey}, nil
+}
+
+// LoginFinish checks the client's KE3, and returns the session key both sides
+// now share.
+func LoginFinish(state LoginState, ke3 []byte) ([]byte, error) {
+	if len(ke3) != KE3Size || subtle.ConstantTimeCompare(ke3, state.ExpectedClientMAC) != 1 {
+		return nil, ErrAuthentication
+	}
+	return state.SessionKey, nil
+}
@@ -0,0 +1,570 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/audit"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// webauthn ceremony purposes.
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// NewWebAuthn returns the relying party the passkeys are registered with.
+func NewWebAuthn() (*webauthn.WebAuthn, error) {
+	return webauthn.New(&webauthn.Config{
+		RPID:          config.WebAuthnRPID,
+		RPDisplayName: config.WebAuthnRPDisplayName,
+		RPOrigins:     []string{config.WebAuthnRPOrigin},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login: webauthn.TimeoutConfig{
+				Enforce: true,
+				Timeout: config.WebAuthnCeremonyExpiration,
+			},
+			Registration: webauthn.TimeoutConfig{
+				Enforce: true,
+				Timeout: config.WebAuthnCeremonyExpiration,
+			},
+		},
+	})
+}
+
+// webauthnUser is an account as the relying party sees it. The user handle is
+// the credentials ID, which says nothing about the user.
+type webauthnUser struct {
+	credentials repo.Credential
+	passkeys    []repo.Passkey
+}
+
+func (me *webauthnUser) WebAuthnID() []byte {
+	return me.credentials.ID[:]
+}
+
+func (me *webauthnUser) WebAuthnName() string {
+	return me.credentials.Email
+}
+
+func (me *webauthnUser) WebAuthnDisplayName() string {
+	return me.credentials.Email
+}
+
+func (me *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(me.passkeys))
+	for i, passkey := range me.passkeys {
+		transports := make([]protocol.AuthenticatorTransport, len(passkey.Transports))
+		for j, transport := range passkey.Transports {
+			transports[j] = protocol.AuthenticatorTransport(transport)
+		}
+		credentials[i] = webauthn.Credential{
+			ID:              passkey.ID,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.Aaguid,
+				SignCount: uint32(passkey.SignCount),
+			},
+		}
+	}
+	return credentials
+}
+
+func (me *AuthService) getWebAuthnUser(ctx context.Context, credentials repo.Credential) (*webauthnUser, error) {
+	passkeys, err := me.queries.ListCredentialsPasskeys(ctx, credentials.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+	return &webauthnUser{credentials: credentials, passkeys: passkeys}, nil
+}
+
+// PasskeyCeremony is the first step of a registration or login, Options are
+// handed to navigator.credentials on the client and the ID comes back with
+// its response.
+type PasskeyCeremony struct {
+	ID      uuid.UUID
+	Options any
+}
+
+type Passkey struct {
+	ID         []byte
+	Name       string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	// Synced is whether the passkey is backed up, e.g. to a password manager.
+	Synced bool
+}
+
+func newPasskey(passkey repo.Passkey) Passkey {
+	p := Passkey{
+		ID:        passkey.ID,
+		Name:      passkey.Name,
+		CreatedAt: passkey.CreatedAt,
+		Synced:    passkey.BackupState,
+	}
+	if passkey.LastUsedAt.Valid {
+		p.LastUsedAt = &passkey.LastUsedAt.Time
+	}
+	return p
+}
+
+func (me *AuthService) saveCeremony(ctx context.Context, credentialsID uuid.NullUUID, purpose string, session *webauthn.SessionData) (uuid.UUID, error) {
+	sessionData, err := json.Marshal(session)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to marshal webauthn session: %w", err)
+	}
+
+	ceremonyID := uuid.New()
+	if err := me.queries.InsertWebAuthnCeremony(ctx, repo.InsertWebAuthnCeremonyParams{
+		ID:            ceremonyID,
+		CredentialsID: credentialsID,
+		Purpose:       purpose,
+		SessionData:   sessionData,
+		ExpiresAt:     time.Now().Add(config.WebAuthnCeremonyExpiration),
+	}); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to insert webauthn ceremony: %w", err)
+	}
+	return ceremonyID, nil
+}
+
+// takeCeremony uses up a ceremony, ErrUnauthorized is returned for unknown or
+// expired ones and ones for another purpose or account.
+func (me *AuthService) takeCeremony(ctx context.Context, ceremonyID uuid.UUID, purpose string, credentialsID uuid.NullUUID) (webauthn.SessionData, error) {
+	var zero webauthn.SessionData
+
+	ceremony, err := me.queries.DeleteWebAuthnCeremony(ctx, ceremonyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrUnauthorized
+		}
+		return zero, fmt.Errorf("failed to delete webauthn ceremony: %w", err)
+	}
+	if time.Now().After(ceremony.ExpiresAt) || ceremony.Purpose != purpose || ceremony.CredentialsID != credentialsID {
+		return zero, service.ErrUnauthorized
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(ceremony.SessionData, &session); err != nil {
+		return zero, fmt.Errorf("failed to unmarshal webauthn session: %w", err)
+	}
+	return session, nil
+}
+
+// usePasskey stores the new sign counter of a passkey that was just verified.
+// A counter that didn't grow means the passkey was cloned, and it's refused.
+func (me *AuthService) usePasskey(ctx context.Context, credentialsID uuid.UUID, credential *webauthn.Credential, client audit.Client) error {
+	if credential.Authenticator.CloneWarning {
+		me.logger.Warn("passkey sign counter went backwards", "credentialsID", credentialsID)
+		me.auditService.Record(audit.RecordParams{
+			CredentialsID: uuid.NullUUID{UUID: credentialsID, Valid: true},
+			Type:          audit.TypePasskeyCloned,
+			Client:        client,
+			Details:       map[string]any{"passkeyId": credential.ID},
+		})
+		return service.ErrUnauthorized
+	}
+
+	if err := me.queries.UpdatePasskeyUse(ctx, repo.UpdatePasskeyUseParams{
+		ID:          credential.ID,
+		SignCount:   int64(credential.Authenticator.SignCount),
+		BackupState: credential.Flags.BackupState,
+	}); err != nil {
+		return fmt.Errorf("failed to update passkey use: %w", err)
+	}
+	return nil
+}
+
+type BeginPasskeyRegistrationParams struct {
+	CredentialsID uuid.UUID
+	Reauth        ReauthParams
+	Client        audit.Client
+}
+
+// BeginPasskeyRegistration starts adding a passkey to the account once the
+// owner authenticated again, see reauthenticate for its errors.
+// ErrQuotaExceeded is returned when it has config.MaxPasskeys already.
+func (me *AuthService) BeginPasskeyRegistration(params BeginPasskeyRegistrationParams) (PasskeyCeremony, error) {
+	ctx := context.Background()
+	var zero PasskeyCeremony
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, params.CredentialsID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if err := me.reauthenticate(ctx, credentials, params.Reauth, params.Client); err != nil {
+		return zero, err
+	}
+	user, err := me.getWebAuthnUser(ctx, credentials)
+	if err != nil {
+		return zero, err
+	}
+	if len(user.passkeys) >= config.MaxPasskeys {
+		return zero, service.ErrQuotaExceeded
+	}
+
+	creation, session, err := me.webauthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+		// discoverable, so the passkey can log in without a password too.
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return zero, fmt.Errorf("failed to begin webauthn registration: %w", err)
+	}
+
+	ceremonyID, err := me.saveCeremony(ctx, uuid.NullUUID{UUID: credentials.ID, Valid: true}, ceremonyRegistration, session)
+	if err != nil {
+		return zero, err
+	}
+	return PasskeyCeremony{ID: ceremonyID, Options: creation}, nil
+}
+
+type FinishPasskeyRegistrationParams struct {
+	CredentialsID uuid.UUID
+	CeremonyID    uuid.UUID
+	Name          string
+	// Response is the client's JSON encoded PublicKeyCredential.
+	Response []byte
+	Client   audit.Client
+}
+
+func (me *FinishPasskeyRegistrationParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.Name, validation.Required, validation.Length(1, 100)),
+	)
+}
+
+// FinishPasskeyRegistration verifies the authenticator's response and stores
+// the passkey. The ceremony is only handed out by BeginPasskeyRegistration
+// after re-authenticating, and can be used once. The recovery codes are
+// returned if it's the account's first second factor. ErrUnauthorized is
+// returned for unknown or expired ceremonies, ErrWebAuthn for responses that
+// don't verify.
+func (me *AuthService) FinishPasskeyRegistration(params FinishPasskeyRegistrationParams) (Passkey, []string, error) {
+	var zero Passkey
+	if err := params.validate(); err != nil {
+		return zero, nil, fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	session, err := me.takeCeremony(ctx, params.CeremonyID, ceremonyRegistration, uuid.NullUUID{UUID: params.CredentialsID, Valid: true})
+	if err != nil {
+		return zero, nil, err
+	}
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, params.CredentialsID)
+	if err != nil {
+		return zero, nil, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	user, err := me.getWebAuthnUser(ctx, credentials)
+	if err != nil {
+		return zero, nil, err
+	}
+	methods, err := me.mfaMethods(ctx, credentials)
+	if err != nil {
+		return zero, nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(params.Response)
+	if err != nil {
+		return zero, nil, fmt.Errorf("%w: %w", service.ErrWebAuthn, err)
+	}
+	credential, err := me.webauthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		return zero, nil, fmt.Errorf("%w: %w", service.ErrWebAuthn, err)
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+	passkey, err := me.queries.InsertPasskey(ctx, repo.InsertPasskeyParams{
+		ID:              credential.ID,
+		CredentialsID:   params.CredentialsID,
+		Name:            params.Name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		Aaguid:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	})
+	if err != nil {
+		if service.IsUniqueViolation(err) {
+			return zero, nil, service.ErrConflict
+		}
+		return zero, nil, fmt.Errorf("failed to insert passkey: %w", err)
+	}
+
+	codes, err := me.secondFactorAdded(ctx, params.CredentialsID, methods)
+	if err != nil {
+		return zero, nil, err
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: params.CredentialsID, Valid: true},
+		Type:          audit.TypePasskeyAdded,
+		Client:        params.Client,
+		Details:       map[string]any{"passkeyId": passkey.ID, "name": passkey.Name},
+	})
+	if len(methods) == 0 {
+		me.auditService.Record(audit.RecordParams{
+			CredentialsID: uuid.NullUUID{UUID: params.CredentialsID, Valid: true},
+			Type:          audit.TypeMFAEnabled,
+			Client:        params.Client,
+			Details:       map[string]any{"method": MFAMethodPasskey},
+		})
+	}
+
+	return newPasskey(passkey), codes, nil
+}
+
+func (me *AuthService) ListPasskeys(credentialsID uuid.UUID) ([]Passkey, error) {
+	ctx := context.Background()
+
+	rows, err := me.queries.ListCredentialsPasskeys(ctx, credentialsID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	passkeys := make([]Passkey, len(rows))
+	for i, row := range rows {
+		passkeys[i] = newPasskey(row)
+	}
+	return passkeys, nil
+}
+
+type DeletePasskeyParams struct {
+	CredentialsID uuid.UUID
+	PasskeyID     []byte
+	Reauth        ReauthParams
+	Client        audit.Client
+}
+
+// DeletePasskey removes a passkey of the account once the owner authenticated
+// again, see reauthenticate for its errors. The recovery codes go with the
+// last second factor.
+func (me *AuthService) DeletePasskey(params DeletePasskeyParams) error {
+	ctx := context.Background()
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, params.CredentialsID)
+	if err != nil {
+		return fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if err := me.reauthenticate(ctx, credentials, params.Reauth, params.Client); err != nil {
+		return err
+	}
+
+	if n, err := me.queries.DeletePasskey(ctx, repo.DeletePasskeyParams{
+		ID:            params.PasskeyID,
+		CredentialsID: params.CredentialsID,
+	}); err != nil {
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	} else if n == 0 {
+		return service.ErrNotFound
+	}
+
+	if err := me.secondFactorRemoved(ctx, credentials); err != nil {
+		return err
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: params.CredentialsID, Valid: true},
+		Type:          audit.TypePasskeyRemoved,
+		Client:        params.Client,
+		Details:       map[string]any{"passkeyId": params.PasskeyID},
+	})
+
+	return nil
+}
+
+// BeginPasskeyMFA starts using one of the account's passkeys as the second
+// factor of a login. ErrConflict is returned when it has none.
+func (me *AuthService) BeginPasskeyMFA(challengeID uuid.UUID) (any, error) {
+	ctx := context.Background()
+
+	challenge, credentials, err := me.getMFAChallenge(ctx, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := me.getWebAuthnUser(ctx, credentials)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.passkeys) == 0 {
+		return nil, service.ErrConflict
+	}
+
+	assertion, session, err := me.webauthn.BeginLogin(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin webauthn login: %w", err)
+	}
+	sessionData, err := json.Marshal(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webauthn session: %w", err)
+	}
+	if n, err := me.queries.SetMFAChallengeWebAuthnSession(ctx, repo.SetMFAChallengeWebAuthnSessionParams{
+		ID:              challenge.ID,
+		WebauthnSession: sessionData,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to set mfa challenge webauthn session: %w", err)
+	} else if n == 0 {
+		return nil, service.ErrUnauthorized
+	}
+
+	return assertion, nil
+}
+
+type FinishPasskeyMFALoginParams struct {
+	ChallengeID uuid.UUID
+	// Response is the client's JSON encoded PublicKeyCredential.
+	Response []byte
+	Client   audit.Client
+}
+
+// FinishPasskeyMFALogin exchanges an MFA challenge and a passkey assertion
+// for a session. Failed assertions count towards the same lockout as wrong
+// codes.
+func (me *AuthService) FinishPasskeyMFALogin(params FinishPasskeyMFALoginParams) (repo.Session, error) {
+	ctx := context.Background()
+	var zero repo.Session
+
+	challenge, credentials, err := me.getMFAChallenge(ctx, params.ChallengeID)
+	if err != nil {
+		return zero, err
+	}
+	if challenge.WebauthnSession == nil {
+		return zero, service.ErrUnauthorized
+	}
+	if err := me.checkMFAAttempt(ctx, credentials.ID); err != nil {
+		return zero, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(challenge.WebauthnSession, &session); err != nil {
+		return zero, fmt.Errorf("failed to unmarshal webauthn session: %w", err)
+	}
+	user, err := me.getWebAuthnUser(ctx, credentials)
+	if err != nil {
+		return zero, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(params.Response)
+	if err != nil {
+		return zero, me.mfaFailed(ctx, credentials.ID, params.Client)
+	}
+	credential, err := me.webauthn.ValidateLogin(user, session, parsed)
+	if err != nil {
+		return zero, me.mfaFailed(ctx, credentials.ID, params.Client)
+	}
+	if err := me.usePasskey(ctx, credentials.ID, credential, params.Client); err != nil {
+		return zero, err
+	}
+	if err := me.mfaSucceeded(ctx, credentials.ID); err != nil {
+		return zero, err
+	}
+
+	return me.finishMFALogin(ctx, challenge, credentials, params.Client)
+}
+
+// BeginPasskeyLogin starts a passwordless login, the authenticator picks the
+// account.
+func (me *AuthService) BeginPasskeyLogin() (PasskeyCeremony, error) {
+	ctx := context.Background()
+	var zero PasskeyCeremony
+
+	assertion, session, err := me.webauthn.BeginDiscoverableLogin(
+		// the passkey stands for both factors, so the user must be verified.
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return zero, fmt.Errorf("failed to begin webauthn login: %w", err)
+	}
+
+	ceremonyID, err := me.saveCeremony(ctx, uuid.NullUUID{}, ceremonyLogin, session)
+	if err != nil {
+		return zero, err
+	}
+	return PasskeyCeremony{ID: ceremonyID, Options: assertion}, nil
+}
+
+type FinishPasskeyLoginParams struct {
+	CeremonyID uuid.UUID
+	// Response is the client's JSON encoded PublicKeyCredential.
+	Response []byte
+	Client   audit.Client
+}
+
+// FinishPasskeyLogin verifies a passwordless login and creates the same
+// session as Login. ErrUnauthorized is returned for unknown ceremonies and
+// assertions that don't verify.
+func (me *AuthService) FinishPasskeyLogin(params FinishPasskeyLoginParams) (repo.Session, error) {
+	ctx := context.Background()
+	var zero repo.Session
+
+	session, err := me.takeCeremony(ctx, params.CeremonyID, ceremonyLogin, uuid.NullUUID{})
+	if err != nil {
+		return zero, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(params.Response)
+	if err != nil {
+		return zero, service.ErrUnauthorized
+	}
+
+	var user *webauthnUser
+	_, credential, err := me.webauthn.ValidatePasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		credentialsID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		credentials, err := me.queries.GetCredentialsByID(ctx, credentialsID)
+		if err != nil {
+			return nil, err
+		}
+		user, err = me.getWebAuthnUser(ctx, credentials)
+		return user, err
+	}, session, parsed)
+	if err != nil {
+		if user != nil {
+			me.auditService.Record(audit.RecordParams{
+				CredentialsID: uuid.NullUUID{UUID: user.credentials.ID, Valid: true},
+				Type:          audit.TypeLoginFailed,
+				Client:        params.Client,
+				Details:       map[string]any{"method": MFAMethodPasskey},
+			})
+		}
+		return zero, service.ErrUnauthorized
+	}
+
+	credentials := user.credentials
+	if err := me.usePasskey(ctx, credentials.ID, credential, params.Client); err != nil {
+		return zero, err
+	}
+	if !credentials.EmailIsVerified {
+		return zero, service.ErrEmailNotVerified
+	}
+	if credentials.PasswordResetRequired {
+		return zero, service.ErrPasswordResetNeeded
+	}
+
+	return me.createSession(ctx, credentials, params.Client)
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/audit"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+func (me *AuthService) sendPasswordResetEmail(ctx context.Context, credentials repo.Credential) error {
+	tokenID := uuid.New()
+	if err := me.queries.InsertPasswordResetToken(ctx, repo.InsertPasswordResetTokenParams{
+		ID:            tokenID,
+		CredentialsID: credentials.ID,
+		ExpiresAt:     time.Now().Add(config.PasswordResetTokenExpiration),
+	}); err != nil {
+		return fmt.Errorf("failed to insert password reset token: %w", err)
+	}
+
+	resetLink := fmt.Sprintf("%s/reset-password?token=%s", config.AppBaseUrl, tokenID)
+	return sendEmail(
+		credentials.Email,
+		"Chat App Password Reset",
+		fmt.Sprintf(`please <a href="%s"> click here </a> to reset your password.`, resetLink),
+	)
+}
+
+// RequestPasswordReset emails a reset link if there is an account with the
+// email. Whether there is one isn't revealed.
+func (me *AuthService) RequestPasswordReset(email string) error {
+	ctx := context.Background()
+
+	credentials, err := me.queries.GetCredentialsByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get credentials by email: %w", err)
+	}
+
+	if err := me.sendPasswordResetEmail(ctx, credentials); err != nil {
+		me.logger.Error("failed to send password reset email", "error", err)
+	}
+	return nil
+}
+
+type ResetPasswordParams struct {
+	TokenID        uuid.UUID
+	Password       string
+	VerifyPassword string
+	Client         audit.Client
+}
+
+func (me *ResetPasswordParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.Password, validation.Required, validation.Length(8, 50)),
+		validation.Field(&me.VerifyPassword, validation.Required, validation.By(func(value any) error {
+			if me.Password != me.VerifyPassword {
+				return validation.NewError("validation-password-mismatch", "passwords do not match")
+			}
+			return nil
+		})),
+	)
+}
+
+// ResetPassword sets a new password with a token from a reset email, and
+// signs every session of the account out. ErrUnauthorized is returned for
+// unknown or expired tokens.
+func (me *AuthService) ResetPassword(params ResetPasswordParams) error {
+	if err := params.validate(); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	token, err := me.queries.DeletePasswordResetToken(ctx, params.TokenID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return service.ErrUnauthorized
+		}
+		return fmt.Errorf("failed to delete password reset token: %w", err)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return service.ErrUnauthorized
+	}
+
+	passwordHash, err := hashPassword(params.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := me.queries.UpdateCredentialsPassword(ctx, repo.UpdateCredentialsPasswordParams{
+		ID:           token.CredentialsID,
+		PasswordHash: passwordHash,
+	}); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if err := me.queries.DeleteCredentialsPasswordResetTokens(ctx, token.CredentialsID); err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+	if err := me.revokeCredentialsSessions(ctx, token.CredentialsID); err != nil {
+		return err
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: token.CredentialsID, Valid: true},
+		Type:          audit.TypePasswordChanged,
+		Client:        params.Client,
+	})
+
+	return nil
+}
+
+func (me *AuthService) revokeCredentialsSessions(ctx context.Context, credentialsID uuid.UUID) error {
+	sessionIDs, err := me.queries.DeleteCredentialsSessions(ctx, credentialsID)
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	for _, sessionID := range sessionIDs {
+		if err := me.sessions.invalidate(ctx, sessionID); err != nil {
+			return fmt.Errorf("failed to invalidate session: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/audit"
+	"chatapp/service/auth/opaque"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReauthParams prove the owner of a session is still at the keyboard, before
+// changes a stolen session mustn't be able to make.
+type ReauthParams struct {
+	// Password is checked for accounts that log in with one. OPAQUE accounts
+	// start a login at /login/opaque and send its ID and KE3 instead.
+	Password      string
+	OpaqueLoginID uuid.UUID
+	KE3           []byte
+	// Code is a TOTP or recovery code, required when TOTP is enabled.
+	Code string
+}
+
+// reauthenticate checks the first factor like a login does, counting towards
+// the same lockout, then the second one if TOTP is enabled. ErrUnauthorized is
+// returned for a wrong first factor, ErrMFARequired for a wrong code.
+func (me *AuthService) reauthenticate(ctx context.Context, credentials repo.Credential, params ReauthParams, client audit.Client) error {
+	if err := me.checkLoginAttempt(ctx, credentials.Email); err != nil {
+		return err
+	}
+
+	ok := false
+	if credentials.OpaqueRecord != nil {
+		login, err := me.queries.DeleteOpaqueLogin(ctx, params.OpaqueLoginID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to delete opaque login: %w", err)
+		}
+		if err == nil && time.Now().Before(login.ExpiresAt) && login.CredentialsID.Valid && login.CredentialsID.UUID == credentials.ID {
+			_, err := opaque.LoginFinish(opaque.LoginState{ExpectedClientMAC: login.ExpectedClientMac}, params.KE3)
+			ok = err == nil
+		}
+	} else {
+		ok = verifyPassword(params.Password, credentials.PasswordHash)
+	}
+	if !ok {
+		if err := me.loginFailed(ctx, credentials.Email); err != nil {
+			return err
+		}
+		return service.ErrUnauthorized
+	}
+	if err := me.loginSucceeded(ctx, credentials.Email); err != nil {
+		return err
+	}
+
+	if credentials.TotpEnabled {
+		if err := me.verifySecondFactor(ctx, credentials, params.Code, client); err != nil {
+			if errors.Is(err, service.ErrUnauthorized) {
+				return service.ErrMFARequired
+			}
+			return err
+		}
+	}
+
+	return nil
+}
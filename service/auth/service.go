@@ -4,7 +4,12 @@ import (
 	"chatapp/config"
 	"chatapp/repo"
 	"chatapp/service"
+	"chatapp/service/audit"
+	"chatapp/service/auth/opaque"
+	"chatapp/service/geoip"
+	"chatapp/service/ratelimit"
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -13,18 +18,46 @@ import (
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 type AuthService struct {
-	queries *repo.Queries
-	logger  *slog.Logger
+	db           *sql.DB
+	queries      *repo.Queries
+	logger       *slog.Logger
+	sessions     *sessionCache
+	limiter      *ratelimit.Limiter
+	auditService *audit.AuditService
+	geo          *geoip.Database
+	webauthn     *webauthn.WebAuthn
+	opaque       *opaque.Server
 }
 
-func NewAuthService(logger *slog.Logger, queries *repo.Queries) *AuthService {
+// NewAuthService caches sessions in valkey, and locates logins with geo. Both
+// may be nil.
+func NewAuthService(
+	logger *slog.Logger,
+	db *sql.DB,
+	queries *repo.Queries,
+	valkey *redis.Client,
+	limiter *ratelimit.Limiter,
+	auditService *audit.AuditService,
+	geo *geoip.Database,
+	webauthn *webauthn.WebAuthn,
+	opaqueServer *opaque.Server,
+) *AuthService {
 	return &AuthService{
-		queries: queries,
-		logger:  logger,
+		db:           db,
+		queries:      queries,
+		logger:       logger,
+		sessions:     newSessionCache(logger, queries, valkey),
+		limiter:      limiter,
+		auditService: auditService,
+		geo:          geo,
+		webauthn:     webauthn,
+		opaque:       opaqueServer,
 	}
 }
 
@@ -118,6 +151,27 @@ func (me *AuthService) StartEmailVerificationCleanupWorker(ctx context.Context)
 				if err := me.queries.DeleteStaleEmailVerificationTokens(ctx); err != nil {
 					me.logger.Error("failed to delete stale email verification tokens", "errors", err)
 				}
+				if err := me.queries.DeleteStalePasswordResetTokens(ctx); err != nil {
+					me.logger.Error("failed to delete stale password reset tokens", "errors", err)
+				}
+				if err := me.queries.DeleteStaleLoginAlerts(ctx); err != nil {
+					me.logger.Error("failed to delete stale login alerts", "errors", err)
+				}
+				if err := me.queries.DeleteStaleMFAChallenges(ctx); err != nil {
+					me.logger.Error("failed to delete stale mfa challenges", "errors", err)
+				}
+				if err := me.queries.DeleteStaleWebAuthnCeremonies(ctx); err != nil {
+					me.logger.Error("failed to delete stale webauthn ceremonies", "errors", err)
+				}
+				if err := me.queries.DeleteStaleOpaqueRegistrations(ctx); err != nil {
+					me.logger.Error("failed to delete stale opaque registrations", "errors", err)
+				}
+				if err := me.queries.DeleteStaleOpaqueLogins(ctx); err != nil {
+					me.logger.Error("failed to delete stale opaque logins", "errors", err)
+				}
+				if err := me.queries.DeleteStaleOpaqueUpgrades(ctx); err != nil {
+					me.logger.Error("failed to delete stale opaque upgrades", "errors", err)
+				}
 			case <-ctx.Done():
 				return
 			}
@@ -147,26 +201,67 @@ func (me *AuthService) VerifyEmail(tokenID uuid.UUID) (bool, error) {
 	return true, nil
 }
 
-func (me *AuthService) Login(email, password string) (repo.Session, error) {
+func (me *AuthService) Login(email, password string, client audit.Client) (repo.Session, error) {
 	ctx := context.Background()
 	var zero repo.Session
 
+	if err := me.checkLoginAttempt(ctx, email); err != nil {
+		return zero, err
+	}
+
 	credentials, err := me.queries.GetCredentialsByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			if err := me.loginFailed(ctx, email); err != nil {
+				return zero, err
+			}
 			return zero, service.ErrUnauthorized
 		}
 		return zero, fmt.Errorf("failed to get credentials by email: %w", err)
 	}
 
 	if !verifyPassword(password, credentials.PasswordHash) {
+		if err := me.loginFailed(ctx, email); err != nil {
+			return zero, err
+		}
 		return zero, service.ErrUnauthorized
 	}
 
+	return me.firstFactorVerified(ctx, credentials, client)
+}
+
+// firstFactorVerified finishes a login once the password, or the login link,
+// was checked.
+func (me *AuthService) firstFactorVerified(ctx context.Context, credentials repo.Credential, client audit.Client) (repo.Session, error) {
+	var zero repo.Session
+
+	if err := me.loginSucceeded(ctx, credentials.Email); err != nil {
+		return zero, err
+	}
+
 	if !credentials.EmailIsVerified {
 		return zero, service.ErrEmailNotVerified
 	}
 
+	if credentials.PasswordResetRequired {
+		return zero, service.ErrPasswordResetNeeded
+	}
+
+	methods, err := me.mfaMethods(ctx, credentials)
+	if err != nil {
+		return zero, err
+	}
+	if len(methods) > 0 {
+		return zero, me.createMFAChallenge(ctx, credentials, methods)
+	}
+
+	return me.createSession(ctx, credentials, client)
+}
+
+// createSession signs the credentials in once every factor was checked.
+func (me *AuthService) createSession(ctx context.Context, credentials repo.Credential, client audit.Client) (repo.Session, error) {
+	var zero repo.Session
+
 	sessionID := uuid.New()
 	sessionToken := fmt.Sprintf("%s_%s", sessionID, createRandomHex(32))
 	csrfToken := fmt.Sprintf("%s_%s", sessionID, createRandomHex(32))
@@ -181,6 +276,10 @@ func (me *AuthService) Login(email, password string) (repo.Session, error) {
 		return zero, fmt.Errorf("failed to insert session: %w", err)
 	}
 
+	if err := me.checkLogin(ctx, credentials, session, client); err != nil {
+		return zero, err
+	}
+
 	return session, nil
 }
 
@@ -189,7 +288,7 @@ func (me *AuthService) ValidateSession(sessionID uuid.UUID, sessionToken, csrfTo
 	ctx := context.Background()
 	var zero uuid.UUID
 
-	session, err := me.queries.GetSessionByID(ctx, sessionID)
+	session, err := me.sessions.get(ctx, sessionID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return zero, service.ErrUnauthorized
@@ -197,5 +296,23 @@ func (me *AuthService) ValidateSession(sessionID uuid.UUID, sessionToken, csrfTo
 		return zero, fmt.Errorf("failed to get session by id: %w", err)
 	}
 
+	if subtle.ConstantTimeCompare([]byte(session.Token), []byte(sessionToken)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(session.CsrfToken), []byte(csrfToken)) != 1 {
+		return zero, service.ErrUnauthorized
+	}
+
 	return session.CredentialsID, nil
 }
+
+func (me *AuthService) RevokeSession(sessionID uuid.UUID) error {
+	ctx := context.Background()
+
+	if err := me.queries.DeleteSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if err := me.sessions.invalidate(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to invalidate session: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// sessionCache saves a database round trip on every authenticated request.
+// Without valkey it reads through to the database.
+type sessionCache struct {
+	logger  *slog.Logger
+	queries *repo.Queries
+	valkey  *redis.Client
+}
+
+func newSessionCache(logger *slog.Logger, queries *repo.Queries, valkey *redis.Client) *sessionCache {
+	return &sessionCache{
+		logger:  logger,
+		queries: queries,
+		valkey:  valkey,
+	}
+}
+
+func sessionCacheKey(sessionID uuid.UUID) string {
+	return "session:" + sessionID.String()
+}
+
+// get returns sql.ErrNoRows for unknown sessions, like GetSessionByID.
+func (me *sessionCache) get(ctx context.Context, sessionID uuid.UUID) (repo.Session, error) {
+	if me.valkey == nil {
+		return me.queries.GetSessionByID(ctx, sessionID)
+	}
+
+	// the cache is only an optimization, its errors fall back to the database.
+	data, err := me.valkey.Get(ctx, sessionCacheKey(sessionID)).Bytes()
+	if err == nil {
+		var session repo.Session
+		if err := json.Unmarshal(data, &session); err == nil {
+			return session, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		me.logger.Warn("failed to get cached session", "errors", err)
+	}
+
+	session, err := me.queries.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return session, err
+	}
+
+	if data, err := json.Marshal(session); err != nil {
+		me.logger.Warn("failed to marshal session", "errors", err)
+	} else if err := me.valkey.Set(ctx, sessionCacheKey(sessionID), data, config.SessionCacheTTL).Err(); err != nil {
+		me.logger.Warn("failed to cache session", "errors", err)
+	}
+
+	return session, nil
+}
+
+// invalidate must be called once the session is gone from the database.
+func (me *sessionCache) invalidate(ctx context.Context, sessionID uuid.UUID) error {
+	if me.valkey == nil {
+		return nil
+	}
+	if err := me.valkey.Del(ctx, sessionCacheKey(sessionID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete cached session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"chatapp/db/valkeyfake"
+	"chatapp/repo"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeSessions stands in for the sessions table, it only answers
+// GetSessionByID.
+type fakeSessions struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]repo.Session
+	lookups  int
+}
+
+func (me *fakeSessions) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeSessionsConn{sessions: me}, nil
+}
+
+func (me *fakeSessions) Driver() driver.Driver {
+	return nil
+}
+
+func (me *fakeSessions) put(session repo.Session) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	me.sessions[session.ID] = session
+}
+
+func (me *fakeSessions) delete(sessionID uuid.UUID) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	delete(me.sessions, sessionID)
+}
+
+func (me *fakeSessions) lookupCount() int {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return me.lookups
+}
+
+type fakeSessionsConn struct {
+	sessions *fakeSessions
+}
+
+func (me *fakeSessionsConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (me *fakeSessionsConn) Close() error {
+	return nil
+}
+
+func (me *fakeSessionsConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (me *fakeSessionsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "name: GetSessionByID") {
+		return nil, errors.New("unexpected query")
+	}
+	sessionID, err := uuid.Parse(args[0].Value.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	me.sessions.mu.Lock()
+	defer me.sessions.mu.Unlock()
+	me.sessions.lookups++
+	rows := &fakeSessionRows{}
+	if session, ok := me.sessions.sessions[sessionID]; ok {
+		rows.sessions = append(rows.sessions, session)
+	}
+	return rows, nil
+}
+
+type fakeSessionRows struct {
+	sessions []repo.Session
+}
+
+func (me *fakeSessionRows) Columns() []string {
+	return []string{"id", "credentials_id", "token", "csrf_token", "created_at"}
+}
+
+func (me *fakeSessionRows) Close() error {
+	return nil
+}
+
+func (me *fakeSessionRows) Next(dest []driver.Value) error {
+	if len(me.sessions) == 0 {
+		return io.EOF
+	}
+	session := me.sessions[0]
+	me.sessions = me.sessions[1:]
+	dest[0] = session.ID.String()
+	dest[1] = session.CredentialsID.String()
+	dest[2] = session.Token
+	dest[3] = session.CsrfToken
+	dest[4] = session.CreatedAt
+	return nil
+}
+
+func newTestSessionCache(t *testing.T) (*sessionCache, *fakeSessions) {
+	t.Helper()
+	server, err := valkeyfake.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	options, err := redis.ParseURL(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(options)
+	t.Cleanup(func() { client.Close() })
+
+	sessions := &fakeSessions{sessions: map[uuid.UUID]repo.Session{}}
+	db := sql.OpenDB(sessions)
+	t.Cleanup(func() { db.Close() })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return newSessionCache(logger, repo.New(db), client), sessions
+}
+
+func newTestSession() repo.Session {
+	return repo.Session{
+		ID:            uuid.New(),
+		CredentialsID: uuid.New(),
+		Token:         "token",
+		CsrfToken:     "csrf-token",
+		CreatedAt:     time.Now().UTC().Truncate(time.Microsecond),
+	}
+}
+
+func TestSessionCacheGet(t *testing.T) {
+	cache, sessions := newTestSessionCache(t)
+	ctx := context.Background()
+	session := newTestSession()
+	sessions.put(session)
+
+	for range 3 {
+		got, err := cache.get(ctx, session.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != session {
+			t.Errorf("get() = %+v, want %+v", got, session)
+		}
+	}
+	if lookups := sessions.lookupCount(); lookups != 1 {
+		t.Errorf("database looked up %d times, want once", lookups)
+	}
+
+	// unknown sessions aren't cached.
+	for range 2 {
+		if _, err := cache.get(ctx, uuid.New()); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("get() of an unknown session = %v, want %v", err, sql.ErrNoRows)
+		}
+	}
+	if lookups := sessions.lookupCount(); lookups != 3 {
+		t.Errorf("database looked up %d times, want 3", lookups)
+	}
+}
+
+func TestSessionCacheInvalidate(t *testing.T) {
+	cache, sessions := newTestSessionCache(t)
+	ctx := context.Background()
+	session := newTestSession()
+	sessions.put(session)
+
+	if _, err := cache.get(ctx, session.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// a signed out session is gone from the database first.
+	sessions.delete(session.ID)
+	if err := cache.invalidate(ctx, session.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.get(ctx, session.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("get() after invalidate() = %v, want %v", err, sql.ErrNoRows)
+	}
+	// invalidating a session that isn't cached is fine.
+	if err := cache.invalidate(ctx, session.ID); err != nil {
+		t.Errorf("invalidate() of an uncached session = %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service/audit"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// checkLogin compares the new session's login with the previous ones, and
+// emails the owner a "this wasn't me" link when it looks suspicious.
+func (me *AuthService) checkLogin(ctx context.Context, credentials repo.Credential, session repo.Session, client audit.Client) error {
+	fp := newFingerprint(client, me.geo, session.CreatedAt)
+
+	history, err := me.queries.ListRecentLoginHistory(ctx, repo.ListRecentLoginHistoryParams{
+		CredentialsID: credentials.ID,
+		Limit:         int32(config.LoginHistorySize),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list login history: %w", err)
+	}
+
+	params := repo.InsertLoginHistoryParams{
+		CredentialsID:   credentials.ID,
+		SessionID:       session.ID,
+		IpPrefix:        fp.ipPrefix,
+		UserAgentFamily: fp.userAgentFamily,
+	}
+	if fp.region != nil {
+		params.Region = sql.NullString{String: fp.region.Name, Valid: true}
+		params.Latitude = sql.NullFloat64{Float64: fp.region.Latitude, Valid: true}
+		params.Longitude = sql.NullFloat64{Float64: fp.region.Longitude, Valid: true}
+	}
+	if err := me.queries.InsertLoginHistory(ctx, params); err != nil {
+		return fmt.Errorf("failed to insert login history: %w", err)
+	}
+	if err := me.queries.DeleteOldLoginHistory(ctx, repo.DeleteOldLoginHistoryParams{
+		CredentialsID: credentials.ID,
+		Keep:          int32(config.LoginHistorySize),
+	}); err != nil {
+		return fmt.Errorf("failed to delete old login history: %w", err)
+	}
+
+	anomalies := fp.anomalies(history)
+	if !isSuspicious(anomalies) {
+		return nil
+	}
+
+	alertID := uuid.New()
+	if err := me.queries.InsertLoginAlert(ctx, repo.InsertLoginAlertParams{
+		ID:            alertID,
+		CredentialsID: credentials.ID,
+		SessionID:     session.ID,
+		ExpiresAt:     time.Now().Add(config.LoginAlertExpiration),
+	}); err != nil {
+		return fmt.Errorf("failed to insert login alert: %w", err)
+	}
+
+	details := map[string]any{"sessionId": session.ID, "anomalies": anomalies}
+	if fp.region != nil {
+		details["region"] = fp.region.Name
+	}
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: credentials.ID, Valid: true},
+		Type:          audit.TypeLoginSuspicious,
+		Client:        client,
+		Details:       details,
+	})
+
+	if err := me.sendLoginAlertEmail(credentials.Email, alertID, fp, client); err != nil {
+		me.logger.Error("failed to send login alert email", "error", err)
+	}
+
+	return nil
+}
+
+func (me *AuthService) sendLoginAlertEmail(email string, alertID uuid.UUID, fp fingerprint, client audit.Client) error {
+	where := client.IP
+	if fp.region != nil {
+		where = fmt.Sprintf("%s (%s)", client.IP, fp.region.Name)
+	}
+	denyLink := fmt.Sprintf("%s/login-alerts/deny?token=%s", config.AppBaseUrl, alertID)
+
+	return sendEmail(
+		email,
+		"Chat App New Login",
+		fmt.Sprintf(`there was a new login to your account from %s using %s at %s.
+if this wasn't you, <a href="%s"> click here </a> to sign it out and reset your password.`,
+			where, fp.userAgentFamily, fp.at.UTC().Format(time.RFC1123), denyLink),
+	)
+}
+
+// DenyLogin acts on a confirmed "this wasn't me" link: the session is revoked
+// and the password must be reset before logging in again. It returns false
+// for unknown or expired links.
+func (me *AuthService) DenyLogin(alertID uuid.UUID, client audit.Client) (bool, error) {
+	ctx := context.Background()
+
+	alert, err := me.queries.DeleteLoginAlert(ctx, alertID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to delete login alert: %w", err)
+	}
+	if time.Now().After(alert.ExpiresAt) {
+		return false, nil
+	}
+
+	if err := me.RevokeSession(alert.SessionID); err != nil {
+		return false, err
+	}
+	if err := me.queries.SetPasswordResetRequired(ctx, alert.CredentialsID); err != nil {
+		return false, fmt.Errorf("failed to require password reset: %w", err)
+	}
+
+	me.auditService.Record(audit.RecordParams{
+		CredentialsID: uuid.NullUUID{UUID: alert.CredentialsID, Valid: true},
+		Type:          audit.TypeLoginDenied,
+		Client:        client,
+		Details:       map[string]any{"sessionId": alert.SessionID},
+	})
+
+	credentials, err := me.queries.GetCredentialsByID(ctx, alert.CredentialsID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get credentials by id: %w", err)
+	}
+	if err := me.sendPasswordResetEmail(ctx, credentials); err != nil {
+		me.logger.Error("failed to send password reset email", "error", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"chatapp/config"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP as in RFC 6238 with the parameters every authenticator app supports:
+// HMAC-SHA1, 6 digits and 30 second time steps.
+const (
+	totpSecretSize = 20
+	totpDigits     = 6
+	totpPeriod     = 30
+)
+
+// recoveryCodeSize is the random bytes in a recovery code, formatted as four
+// groups of four base32 characters.
+const recoveryCodeSize = 10
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func createTOTPSecret() []byte {
+	secret := make([]byte, totpSecretSize)
+	rand.Read(secret)
+	return secret
+}
+
+func totpCounter(at time.Time) int64 {
+	return at.Unix() / totpPeriod
+}
+
+func totpCode(secret []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3.
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+// validateTOTP returns the time step the code is for, looking
+// config.TOTPSkew steps around the current one for clock drift.
+func validateTOTP(secret []byte, code string, at time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := totpCounter(at)
+	for counter := current - int64(config.TOTPSkew); counter <= current+int64(config.TOTPSkew); counter++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, counter)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI is the otpauth:// URI authenticator apps read from a QR code.
+func totpURI(account string, secret []byte) string {
+	label := url.PathEscape(config.TOTPIssuer) + ":" + url.PathEscape(account)
+	query := url.Values{
+		"secret":    {base32NoPadding.EncodeToString(secret)},
+		"issuer":    {config.TOTPIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(totpPeriod)},
+	}
+	// some apps don't read + as a space.
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// createRecoveryCodes returns the codes to show the user and the hashes to
+// keep.
+func createRecoveryCodes(n int) ([]string, [][]byte) {
+	codes := make([]string, n)
+	hashes := make([][]byte, n)
+	for i := range codes {
+		buf := make([]byte, recoveryCodeSize)
+		rand.Read(buf)
+		raw := base32NoPadding.EncodeToString(buf)
+		codes[i] = strings.Join([]string{raw[0:4], raw[4:8], raw[8:12], raw[12:16]}, "-")
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes
+}
+
+// hashRecoveryCode ignores case, dashes and spaces. The codes are random
+// enough that a plain hash can't be brute forced.
+func hashRecoveryCode(code string) []byte {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return sum[:]
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// FileSystemStore keeps blobs as files in a local directory, sharded by the
+// first two characters of their ID.
+type FileSystemStore struct {
+	dir string
+}
+
+func NewFileSystemStore(dir string) (*FileSystemStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &FileSystemStore{
+		dir: dir,
+	}, nil
+}
+
+func (me *FileSystemStore) path(id uuid.UUID) string {
+	name := id.String()
+	return filepath.Join(me.dir, name[:2], name)
+}
+
+func (me *FileSystemStore) Put(ctx context.Context, id uuid.UUID, r io.Reader, size int64) error {
+	path := me.path(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	// written to a temporary file first so a failed upload never leaves a
+	// truncated blob behind.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	n, err := io.Copy(tmp, io.LimitReader(r, size))
+	if err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if n != size {
+		return fmt.Errorf("failed to write blob: got %d bytes, want %d: %w", n, size, io.ErrUnexpectedEOF)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close blob: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename blob: %w", err)
+	}
+	return nil
+}
+
+func (me *FileSystemStore) Get(ctx context.Context, id uuid.UUID, offset, length int64) (io.ReadCloser, error) {
+	file, err := os.Open(me.path(id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return &sectionReadCloser{
+		SectionReader: io.NewSectionReader(file, offset, length),
+		file:          file,
+	}, nil
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (me *sectionReadCloser) Close() error {
+	return me.file.Close()
+}
+
+func (me *FileSystemStore) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := os.Remove(me.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove blob: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFileSystemStorePutGetDelete(t *testing.T) {
+	store, err := NewFileSystemStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	id := uuid.New()
+	content := []byte("0123456789abcdefghij")
+
+	if err := store.Put(ctx, id, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put() = %v", err)
+	}
+
+	body, err := store.Get(ctx, id, 5, 10)
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content[5:15]) {
+		t.Errorf("Get(5, 10) = %q, want %q", got, content[5:15])
+	}
+
+	if err := store.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if _, err := store.Get(ctx, id, 0, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() = %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete(ctx, id); err != nil {
+		t.Errorf("Delete() of a missing blob = %v", err)
+	}
+}
+
+func TestFileSystemStorePutShortReader(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSystemStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+
+	if err := store.Put(context.Background(), id, strings.NewReader("short"), 10); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Put() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// neither the blob nor its temporary file is left behind.
+	entries, err := os.ReadDir(filepath.Dir(store.path(id)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("%d files left behind after a failed Put()", len(entries))
+	}
+}
@@ -0,0 +1,41 @@
+package blob
+
+import (
+	"chatapp/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps opaque blobs under random IDs. Blobs are already encrypted by the
+// clients, stores don't need to protect their content.
+type Store interface {
+	// Put stores exactly size bytes read from r.
+	Put(ctx context.Context, id uuid.UUID, r io.Reader, size int64) error
+	// Get reads length bytes of the blob starting at offset.
+	Get(ctx context.Context, id uuid.UUID, offset, length int64) (io.ReadCloser, error)
+	// Delete doesn't fail if the blob doesn't exist.
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// NewStore creates the store selected in the config.
+func NewStore() (Store, error) {
+	switch config.BlobStore {
+	case "filesystem":
+		return NewFileSystemStore(config.BlobStoreDir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:        config.S3Endpoint,
+			Region:          config.S3Region,
+			Bucket:          config.S3Bucket,
+			AccessKeyID:     config.S3AccessKeyID,
+			SecretAccessKey: config.S3SecretAccessKey,
+		})
+	}
+	return nil, fmt.Errorf("unknown blob store: %s", config.BlobStore)
+}
@@ -0,0 +1,44 @@
+package cluster
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Handler is called with every message published on the bus, by any
+// instance, one at a time and in publication order. seq increases with every
+// message and is the same on every instance.
+type Handler func(seq uint64, payload []byte)
+
+// Bus fans messages out to every instance of the app, including the one that
+// published them.
+type Bus interface {
+	// Start delivers the messages published from now on to the handler, until
+	// the context is done.
+	Start(ctx context.Context, handler Handler) error
+	Publish(payload []byte) error
+}
+
+// NewBus returns the bus selected by config.ClusterBus, valkey may be nil
+// unless it's the one selected.
+func NewBus(logger *slog.Logger, db *sql.DB, valkey *redis.Client) (Bus, error) {
+	switch config.ClusterBus {
+	case "local":
+		return NewLocalBus(), nil
+	case "postgres":
+		return NewPostgresBus(logger, db, repo.New(db)), nil
+	case "valkey":
+		if valkey == nil {
+			return nil, errors.New("the valkey cluster bus requires VALKEY_URL")
+		}
+		return NewValkeyBus(logger, valkey), nil
+	}
+	return nil, fmt.Errorf("unknown cluster bus %q", config.ClusterBus)
+}
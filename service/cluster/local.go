@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+)
+
+// LocalBus is the bus of a single instance deployment, messages never leave
+// the process.
+type LocalBus struct {
+	mu      sync.Mutex
+	seq     uint64
+	handler Handler
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{}
+}
+
+func (me *LocalBus) Start(ctx context.Context, handler Handler) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	me.handler = handler
+
+	go func() {
+		<-ctx.Done()
+		me.mu.Lock()
+		defer me.mu.Unlock()
+		me.handler = nil
+	}()
+	return nil
+}
+
+func (me *LocalBus) Publish(payload []byte) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	me.seq++
+	if me.handler != nil {
+		me.handler(me.seq, payload)
+	}
+	return nil
+}
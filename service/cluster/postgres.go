@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// postgresChannel is the channel notified of new cluster_events rows.
+const postgresChannel = "cluster_events"
+
+// PostgresBus shares messages between instances through the cluster_events
+// table. Publishers notify the listeners with the id of the new row, which
+// then read every row after the last one they delivered, so a missed
+// notification only delays delivery.
+type PostgresBus struct {
+	logger  *slog.Logger
+	db      *sql.DB
+	queries *repo.Queries
+}
+
+func NewPostgresBus(logger *slog.Logger, db *sql.DB, queries *repo.Queries) *PostgresBus {
+	return &PostgresBus{
+		logger:  logger,
+		db:      db,
+		queries: queries,
+	}
+}
+
+func (me *PostgresBus) Publish(payload []byte) error {
+	ctx := context.Background()
+
+	// the advisory lock only holds within a real transaction.
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	if err := queries.LockClusterEvents(ctx); err != nil {
+		return fmt.Errorf("failed to lock cluster events: %w", err)
+	}
+	id, err := queries.InsertClusterEvent(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("failed to insert cluster event: %w", err)
+	}
+	if err := queries.NotifyClusterEvent(ctx, id); err != nil {
+		return fmt.Errorf("failed to notify cluster event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return nil
+}
+
+func (me *PostgresBus) Start(ctx context.Context, handler Handler) error {
+	lastID, err := me.queries.GetLatestClusterEventID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest cluster event id: %w", err)
+	}
+
+	listener := pq.NewListener(config.PGUrl, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			me.logger.Error("cluster bus listener error", "errors", err)
+		}
+	})
+	if err := listener.Listen(postgresChannel); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to listen on %s: %w", postgresChannel, err)
+	}
+
+	me.startCleanupWorker(ctx)
+
+	go func() {
+		defer listener.Close()
+		for {
+			// notifications only say there is something new, reconnects
+			// (nil notifications) and the periodic check catch up alike.
+			select {
+			case <-listener.Notify:
+			case <-time.After(config.ClusterBusPollInterval):
+				go listener.Ping()
+			case <-ctx.Done():
+				return
+			}
+
+			lastID = me.deliver(lastID, handler)
+		}
+	}()
+	return nil
+}
+
+// deliver hands the events after lastID to the handler and returns the id of
+// the last one.
+func (me *PostgresBus) deliver(lastID int64, handler Handler) int64 {
+	ctx := context.Background()
+
+	for {
+		events, err := me.queries.ListClusterEventsAfterID(ctx, repo.ListClusterEventsAfterIDParams{
+			ID:    lastID,
+			Limit: int32(config.ClusterBusBatchSize),
+		})
+		if err != nil {
+			me.logger.Error("failed to list cluster events", "errors", err)
+			return lastID
+		}
+
+		for _, event := range events {
+			handler(uint64(event.ID), event.Payload)
+			lastID = event.ID
+		}
+		if len(events) < config.ClusterBusBatchSize {
+			return lastID
+		}
+	}
+}
+
+// startCleanupWorker deletes the events every instance had the time to read.
+func (me *PostgresBus) startCleanupWorker(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-time.After(config.ClusterEventRetention):
+				deleted, err := me.queries.DeleteClusterEventsBefore(context.Background(), time.Now().Add(-config.ClusterEventRetention))
+				if err != nil {
+					me.logger.Error("failed to delete cluster events", "errors", err)
+					continue
+				}
+				if deleted > 0 {
+					me.logger.Info("deleted cluster events", "count", deleted)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	valkeyChannel = "cluster:events"
+	valkeySeqKey  = "cluster:seq"
+)
+
+// ValkeyPublishScript numbers the message and publishes it in one step, so
+// every subscriber gets the messages in sequence order. Messages are published
+// as "<seq>:<payload>".
+const ValkeyPublishScript = `
+local seq = redis.call('INCR', KEYS[1])
+redis.call('PUBLISH', KEYS[2], seq .. ':' .. ARGV[1])
+return seq
+`
+
+var valkeyPublish = redis.NewScript(ValkeyPublishScript)
+
+// ValkeyBus shares messages between instances through valkey pub/sub. Unlike
+// PostgresBus nothing is kept, messages published while an instance
+// reconnects are lost to it.
+type ValkeyBus struct {
+	logger *slog.Logger
+	valkey *redis.Client
+}
+
+func NewValkeyBus(logger *slog.Logger, valkey *redis.Client) *ValkeyBus {
+	return &ValkeyBus{
+		logger: logger,
+		valkey: valkey,
+	}
+}
+
+func (me *ValkeyBus) Publish(payload []byte) error {
+	ctx := context.Background()
+
+	if err := valkeyPublish.Run(ctx, me.valkey, []string{valkeySeqKey, valkeyChannel}, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish cluster event: %w", err)
+	}
+	return nil
+}
+
+func (me *ValkeyBus) Start(ctx context.Context, handler Handler) error {
+	sub := me.valkey.Subscribe(ctx, valkeyChannel)
+	// waits for the subscription, so nothing published after Start is missed.
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", valkeyChannel, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		sub.Close()
+	}()
+
+	go func() {
+		for msg := range sub.Channel() {
+			seq, payload, ok := bytes.Cut([]byte(msg.Payload), []byte(":"))
+			if !ok {
+				me.logger.Error("malformed cluster event", "payload", msg.Payload)
+				continue
+			}
+			n, err := strconv.ParseUint(string(seq), 10, 64)
+			if err != nil {
+				me.logger.Error("malformed cluster event sequence", "errors", err)
+				continue
+			}
+			handler(n, payload)
+		}
+	}()
+	return nil
+}
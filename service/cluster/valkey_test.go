@@ -0,0 +1,127 @@
+package cluster
+
+import (
+	"chatapp/db/valkeyfake"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type received struct {
+	seq     uint64
+	payload string
+}
+
+func newTestValkeyServer(t *testing.T) *valkeyfake.Server {
+	t.Helper()
+	server, err := valkeyfake.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	server.RegisterScript(ValkeyPublishScript, func(call func(args ...string) any, keys, args []string) any {
+		seq := call("INCR", keys[0]).(int64)
+		call("PUBLISH", keys[1], fmt.Sprintf("%d:%s", seq, args[0]))
+		return seq
+	})
+	return server
+}
+
+// newTestValkeyBus returns a bus of its own instance, with its own connection.
+func newTestValkeyBus(t *testing.T, server *valkeyfake.Server) *ValkeyBus {
+	t.Helper()
+	options, err := redis.ParseURL(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(options)
+	t.Cleanup(func() { client.Close() })
+	return NewValkeyBus(slog.New(slog.NewTextHandler(io.Discard, nil)), client)
+}
+
+func startTestValkeyBus(t *testing.T, bus *ValkeyBus) <-chan received {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	messages := make(chan received, 16)
+	if err := bus.Start(ctx, func(seq uint64, payload []byte) {
+		messages <- received{seq: seq, payload: string(payload)}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+func waitReceived(t *testing.T, messages <-chan received) received {
+	t.Helper()
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("no cluster event received")
+		return received{}
+	}
+}
+
+func TestValkeyBusPublishSubscribe(t *testing.T) {
+	server := newTestValkeyServer(t)
+	publisher := newTestValkeyBus(t, server)
+	first := startTestValkeyBus(t, publisher)
+	second := startTestValkeyBus(t, newTestValkeyBus(t, server))
+
+	payloads := []string{"a", "b:with:colons", ""}
+	for _, payload := range payloads {
+		if err := publisher.Publish([]byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// every instance, the publisher included, gets every message in order.
+	for _, messages := range []<-chan received{first, second} {
+		for i, payload := range payloads {
+			want := received{seq: uint64(i + 1), payload: payload}
+			if got := waitReceived(t, messages); got != want {
+				t.Errorf("received %+v, want %+v", got, want)
+			}
+		}
+	}
+}
+
+func TestValkeyBusStopsOnCancel(t *testing.T) {
+	server := newTestValkeyServer(t)
+	publisher := newTestValkeyBus(t, server)
+	subscriber := newTestValkeyBus(t, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan received, 16)
+	if err := subscriber.Start(ctx, func(seq uint64, payload []byte) {
+		messages <- received{seq: seq, payload: string(payload)}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Publish([]byte("before")); err != nil {
+		t.Fatal(err)
+	}
+	waitReceived(t, messages)
+
+	cancel()
+	// the subscription is closed in the background.
+	time.Sleep(100 * time.Millisecond)
+
+	if err := publisher.Publish([]byte("after")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case message := <-messages:
+		t.Errorf("received %+v after cancel", message)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
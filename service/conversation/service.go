@@ -0,0 +1,201 @@
+package conversation
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// system event types stored in conversation_events.
+const (
+	EventTypeIdentityKeyChanged         = "identity_key_changed"
+	EventTypeVerifiedIdentityKeyChanged = "verified_identity_key_changed"
+	EventTypeDisappearingTimerChanged   = "disappearing_timer_changed"
+	EventTypeAccountDeleted             = "account_deleted" // the subject is null once the account is purged
+)
+
+type ConversationService struct {
+	db      *sql.DB
+	queries *repo.Queries
+}
+
+func NewConversationService(db *sql.DB, queries *repo.Queries) *ConversationService {
+	return &ConversationService{
+		db:      db,
+		queries: queries,
+	}
+}
+
+// CreateDirectConversation returns the 1-1 conversation between the user and
+// the peer, creating it if it doesn't exist yet. The returned bool reports
+// whether a new conversation was created.
+func (me *ConversationService) CreateDirectConversation(userID uuid.UUID, peerUsername string) (uuid.UUID, bool, error) {
+	var zero uuid.UUID
+	ctx := context.Background()
+
+	peer, err := me.queries.GetUserByUsername(ctx, peerUsername)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, false, service.ErrNotFound
+		}
+		return zero, false, fmt.Errorf("failed to get user by username: %w", err)
+	}
+	if peer.ID == userID {
+		return zero, false, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"username": errors.New("cannot start a conversation with yourself"),
+		})
+	}
+
+	conversationID, err := me.queries.GetDirectConversationID(ctx, repo.GetDirectConversationIDParams{
+		UserID:     userID,
+		PeerUserID: peer.ID,
+	})
+	if err == nil {
+		return conversationID, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return zero, false, fmt.Errorf("failed to get direct conversation: %w", err)
+	}
+
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	conversationID = uuid.New()
+	if err := queries.InsertConversation(ctx, conversationID); err != nil {
+		return zero, false, fmt.Errorf("failed to insert conversation: %w", err)
+	}
+	if err := queries.InsertDirectConversation(ctx, repo.InsertDirectConversationParams{
+		UserID:         userID,
+		PeerUserID:     peer.ID,
+		ConversationID: conversationID,
+	}); err != nil {
+		// a concurrent request for the same pair won the race.
+		if service.IsUniqueViolation(err) {
+			tx.Rollback()
+			conversationID, err := me.queries.GetDirectConversationID(ctx, repo.GetDirectConversationIDParams{
+				UserID:     userID,
+				PeerUserID: peer.ID,
+			})
+			if err != nil {
+				return zero, false, fmt.Errorf("failed to get direct conversation: %w", err)
+			}
+			return conversationID, false, nil
+		}
+		return zero, false, fmt.Errorf("failed to insert direct conversation: %w", err)
+	}
+	for _, participantID := range []uuid.UUID{userID, peer.ID} {
+		if err := queries.InsertConversationParticipant(ctx, repo.InsertConversationParticipantParams{
+			ConversationID: conversationID,
+			UserID:         participantID,
+		}); err != nil {
+			return zero, false, fmt.Errorf("failed to insert conversation participant: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return zero, false, fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	return conversationID, true, nil
+}
+
+func (me *ConversationService) ListConversations(userID uuid.UUID) ([]repo.ListConversationsByUserIDRow, error) {
+	conversations, err := me.queries.ListConversationsByUserID(context.Background(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+// ListEvents returns the system events of a conversation that are visible to
+// the user.
+func (me *ConversationService) ListEvents(userID, conversationID uuid.UUID) ([]repo.ConversationEvent, error) {
+	ctx := context.Background()
+
+	if err := me.checkParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	events, err := me.queries.ListConversationEvents(ctx, repo.ListConversationEventsParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversation events: %w", err)
+	}
+
+	return events, nil
+}
+
+// SetDisappearingTimer sets how many seconds messages sent to the conversation
+// are kept before the server deletes them, 0 disabling it. The change is
+// recorded as an event visible to both participants.
+func (me *ConversationService) SetDisappearingTimer(userID, conversationID uuid.UUID, seconds int) error {
+	ctx := context.Background()
+
+	if err := validation.Validate(seconds,
+		validation.Min(0),
+		validation.Max(int(config.MaxDisappearingTimer/time.Second)),
+	); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{"seconds": err})
+	}
+
+	if err := me.checkParticipant(ctx, userID, conversationID); err != nil {
+		return err
+	}
+
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	if err := queries.UpdateConversationDisappearingTimer(ctx, repo.UpdateConversationDisappearingTimerParams{
+		ID:                conversationID,
+		DisappearingTimer: int32(seconds),
+	}); err != nil {
+		return fmt.Errorf("failed to update disappearing timer: %w", err)
+	}
+
+	if err := queries.InsertConversationEvent(ctx, repo.InsertConversationEventParams{
+		ConversationID:    conversationID,
+		Type:              EventTypeDisappearingTimerChanged,
+		SubjectUserID:     uuid.NullUUID{UUID: userID, Valid: true},
+		DisappearingTimer: sql.NullInt32{Int32: int32(seconds), Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to insert conversation event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	return nil
+}
+
+func (me *ConversationService) checkParticipant(ctx context.Context, userID, conversationID uuid.UUID) error {
+	ok, err := me.queries.CheckConversationParticipant(ctx, repo.CheckConversationParticipantParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check conversation participant: %w", err)
+	}
+	if !ok {
+		return service.ErrNotFound
+	}
+	return nil
+}
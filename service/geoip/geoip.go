@@ -0,0 +1,142 @@
+// Package geoip looks IP addresses up in a local IP-to-region database, so no
+// lookup ever leaves the server.
+//
+// The database is a CSV file of inclusive address ranges, one per line:
+//
+//	# start,end,region,latitude,longitude
+//	192.0.2.0,192.0.2.255,Paris FR,48.8566,2.3522
+//	2001:db8::,2001:db8:ffff:ffff:ffff:ffff:ffff:ffff,Berlin DE,52.52,13.405
+//
+// Ranges must not overlap.
+package geoip
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// earthRadius is the mean radius of the earth in kilometers.
+const earthRadius = 6371.0
+
+type Region struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+}
+
+// DistanceKm is the great-circle distance between the regions.
+func (me Region) DistanceKm(other Region) float64 {
+	lat1, lat2 := me.Latitude*math.Pi/180, other.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (other.Longitude - me.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+type addrRange struct {
+	start  netip.Addr
+	end    netip.Addr
+	region Region
+}
+
+type Database struct {
+	ranges []addrRange
+}
+
+// Open loads the database file.
+func Open(path string) (*Database, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ip database: %w", err)
+	}
+	defer file.Close()
+
+	var ranges []addrRange
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r, err := parseRange(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip database line %d: %w", lineNumber, err)
+		}
+		ranges = append(ranges, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ip database: %w", err)
+	}
+
+	slices.SortFunc(ranges, func(a, b addrRange) int {
+		return a.start.Compare(b.start)
+	})
+
+	return &Database{ranges: ranges}, nil
+}
+
+func parseRange(line string) (addrRange, error) {
+	var zero addrRange
+
+	fields := strings.Split(line, ",")
+	if len(fields) != 5 {
+		return zero, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	start, err := netip.ParseAddr(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return zero, fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := netip.ParseAddr(strings.TrimSpace(fields[1]))
+	if err != nil {
+		return zero, fmt.Errorf("invalid end: %w", err)
+	}
+	start, end = start.Unmap(), end.Unmap()
+	if start.Is4() != end.Is4() || end.Less(start) {
+		return zero, fmt.Errorf("invalid range %s-%s", start, end)
+	}
+
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(fields[3]), 64)
+	if err != nil {
+		return zero, fmt.Errorf("invalid latitude: %w", err)
+	}
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(fields[4]), 64)
+	if err != nil {
+		return zero, fmt.Errorf("invalid longitude: %w", err)
+	}
+
+	return addrRange{
+		start: start,
+		end:   end,
+		region: Region{
+			Name:      strings.TrimSpace(fields[2]),
+			Latitude:  latitude,
+			Longitude: longitude,
+		},
+	}, nil
+}
+
+// Lookup returns the region of the address, if the database knows it.
+func (me *Database) Lookup(addr netip.Addr) (Region, bool) {
+	addr = addr.Unmap()
+
+	// the last range starting at or before the address.
+	i, found := slices.BinarySearchFunc(me.ranges, addr, func(r addrRange, addr netip.Addr) int {
+		return r.start.Compare(addr)
+	})
+	if !found {
+		i--
+	}
+	if i < 0 || me.ranges[i].end.Less(addr) || me.ranges[i].start.Is4() != addr.Is4() {
+		return Region{}, false
+	}
+	return me.ranges[i].region, true
+}
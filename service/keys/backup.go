@@ -0,0 +1,263 @@
+package keys
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// Key backups are encrypted on the client with a key derived from the user's
+// PIN and the backup's salt. The same derivation gives an access key, which
+// the server only keeps a verifier of, and which has to be shown to get the
+// ciphertext back. Too many wrong access keys destroy the backup.
+const (
+	KeyBackupAccessKeySize = 32
+	minKeyBackupSaltSize   = 16
+	maxKeyBackupSaltSize   = 64
+)
+
+// WrongPINError is returned for a wrong access key. The backup is gone once
+// RemainingGuesses is 0.
+type WrongPINError struct {
+	RemainingGuesses int
+}
+
+func (me *WrongPINError) Error() string {
+	return fmt.Sprintf("wrong pin: %d guesses remaining", me.RemainingGuesses)
+}
+
+func (me *WrongPINError) Unwrap() error {
+	return service.ErrUnauthorized
+}
+
+type KeyBackup struct {
+	Salt             []byte
+	RemainingGuesses int
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func keyBackupVerifier(accessKey []byte) []byte {
+	mac := hmac.New(sha256.New, config.KeyBackupVerifierKey)
+	mac.Write(accessKey)
+	return mac.Sum(nil)
+}
+
+// GetKeyBackup returns what a new device needs to derive the access key,
+// ErrNotFound if there is no backup.
+func (me *KeyService) GetKeyBackup(userID uuid.UUID) (KeyBackup, error) {
+	ctx := context.Background()
+	var zero KeyBackup
+
+	backup, err := me.queries.GetKeyBackup(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrNotFound
+		}
+		return zero, fmt.Errorf("failed to get key backup: %w", err)
+	}
+
+	return KeyBackup{
+		Salt:             backup.Salt,
+		RemainingGuesses: max(config.KeyBackupMaxGuesses-int(backup.FailedGuesses), 0),
+		CreatedAt:        backup.CreatedAt,
+		UpdatedAt:        backup.UpdatedAt,
+	}, nil
+}
+
+// CreateKeyBackup returns ErrConflict if there is a backup already, it is
+// changed with RotateKeyBackup or ChangeKeyBackupPIN.
+func (me *KeyService) CreateKeyBackup(params CreateKeyBackupParams) error {
+	if err := params.validate(); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	rows, err := me.queries.InsertKeyBackup(ctx, repo.InsertKeyBackupParams{
+		UserID:     params.UserID,
+		Salt:       params.Salt,
+		Verifier:   keyBackupVerifier(params.AccessKey),
+		Ciphertext: params.Ciphertext,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert key backup: %w", err)
+	}
+	if rows == 0 {
+		return service.ErrConflict
+	}
+
+	return nil
+}
+
+type CreateKeyBackupParams struct {
+	UserID     uuid.UUID
+	Salt       []byte
+	AccessKey  []byte
+	Ciphertext []byte
+}
+
+func (me *CreateKeyBackupParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.Salt, validation.Required, validation.Length(minKeyBackupSaltSize, maxKeyBackupSaltSize)),
+		validation.Field(&me.AccessKey, validation.Required, validation.Length(KeyBackupAccessKeySize, KeyBackupAccessKeySize)),
+		validation.Field(&me.Ciphertext, validation.Required, validation.Length(1, config.MaxKeyBackupSize)),
+	)
+}
+
+// checkKeyBackupAccessKey spends a guess on the access key. A right one gives
+// the guesses back, the last wrong one destroys the backup.
+func (me *KeyService) checkKeyBackupAccessKey(ctx context.Context, userID uuid.UUID, accessKey []byte) (repo.KeyBackup, error) {
+	var zero repo.KeyBackup
+
+	backup, err := me.queries.UseKeyBackupGuess(ctx, repo.UseKeyBackupGuessParams{
+		UserID:     userID,
+		MaxGuesses: int32(config.KeyBackupMaxGuesses),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// none left, a backup still here lost a race with its last guess.
+			if _, err := me.queries.DeleteKeyBackup(ctx, userID); err != nil {
+				return zero, fmt.Errorf("failed to delete key backup: %w", err)
+			}
+			return zero, service.ErrNotFound
+		}
+		return zero, fmt.Errorf("failed to use key backup guess: %w", err)
+	}
+
+	if hmac.Equal(keyBackupVerifier(accessKey), backup.Verifier) {
+		if err := me.queries.ResetKeyBackupGuesses(ctx, userID); err != nil {
+			return zero, fmt.Errorf("failed to reset key backup guesses: %w", err)
+		}
+		return backup, nil
+	}
+
+	remaining := config.KeyBackupMaxGuesses - int(backup.FailedGuesses)
+	if remaining <= 0 {
+		if _, err := me.queries.DeleteKeyBackup(ctx, userID); err != nil {
+			return zero, fmt.Errorf("failed to delete key backup: %w", err)
+		}
+		remaining = 0
+	}
+	return zero, &WrongPINError{RemainingGuesses: remaining}
+}
+
+// RestoreKeyBackup returns the ciphertext for the right access key.
+func (me *KeyService) RestoreKeyBackup(userID uuid.UUID, accessKey []byte) ([]byte, error) {
+	ctx := context.Background()
+
+	backup, err := me.checkKeyBackupAccessKey(ctx, userID, accessKey)
+	if err != nil {
+		return nil, err
+	}
+	return backup.Ciphertext, nil
+}
+
+// RotateKeyBackup replaces the ciphertext, encrypted with the same PIN, once
+// the keys it holds changed.
+func (me *KeyService) RotateKeyBackup(params RotateKeyBackupParams) error {
+	if err := params.validate(); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	if _, err := me.checkKeyBackupAccessKey(ctx, params.UserID, params.AccessKey); err != nil {
+		return err
+	}
+
+	if err := me.queries.UpdateKeyBackupCiphertext(ctx, repo.UpdateKeyBackupCiphertextParams{
+		UserID:     params.UserID,
+		Ciphertext: params.Ciphertext,
+	}); err != nil {
+		return fmt.Errorf("failed to update key backup ciphertext: %w", err)
+	}
+
+	return nil
+}
+
+type RotateKeyBackupParams struct {
+	UserID     uuid.UUID
+	AccessKey  []byte
+	Ciphertext []byte
+}
+
+func (me *RotateKeyBackupParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.AccessKey, validation.Required, validation.Length(KeyBackupAccessKeySize, KeyBackupAccessKeySize)),
+		validation.Field(&me.Ciphertext, validation.Required, validation.Length(1, config.MaxKeyBackupSize)),
+	)
+}
+
+// ChangeKeyBackupPIN replaces the salt, verifier and ciphertext once the old
+// PIN's access key is shown.
+func (me *KeyService) ChangeKeyBackupPIN(params ChangeKeyBackupPINParams) error {
+	if err := params.validate(); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	if _, err := me.checkKeyBackupAccessKey(ctx, params.UserID, params.AccessKey); err != nil {
+		return err
+	}
+
+	if err := me.queries.UpdateKeyBackupPIN(ctx, repo.UpdateKeyBackupPINParams{
+		UserID:     params.UserID,
+		Salt:       params.NewSalt,
+		Verifier:   keyBackupVerifier(params.NewAccessKey),
+		Ciphertext: params.Ciphertext,
+	}); err != nil {
+		return fmt.Errorf("failed to update key backup pin: %w", err)
+	}
+
+	return nil
+}
+
+type ChangeKeyBackupPINParams struct {
+	UserID       uuid.UUID
+	AccessKey    []byte
+	NewSalt      []byte
+	NewAccessKey []byte
+	Ciphertext   []byte
+}
+
+func (me *ChangeKeyBackupPINParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.AccessKey, validation.Required, validation.Length(KeyBackupAccessKeySize, KeyBackupAccessKeySize)),
+		validation.Field(&me.NewSalt, validation.Required, validation.Length(minKeyBackupSaltSize, maxKeyBackupSaltSize)),
+		validation.Field(&me.NewAccessKey, validation.Required, validation.Length(KeyBackupAccessKeySize, KeyBackupAccessKeySize)),
+		validation.Field(&me.Ciphertext, validation.Required, validation.Length(1, config.MaxKeyBackupSize)),
+	)
+}
+
+// DeleteKeyBackup destroys the backup once its access key is shown, like a
+// rotation, so a stolen session can't remove the only recovery copy of the
+// keys. ErrNotFound is returned if there is no backup.
+func (me *KeyService) DeleteKeyBackup(userID uuid.UUID, accessKey []byte) error {
+	ctx := context.Background()
+
+	if _, err := me.checkKeyBackupAccessKey(ctx, userID, accessKey); err != nil {
+		return err
+	}
+
+	rows, err := me.queries.DeleteKeyBackup(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete key backup: %w", err)
+	}
+	if rows == 0 {
+		return service.ErrNotFound
+	}
+
+	return nil
+}
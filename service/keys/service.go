@@ -0,0 +1,405 @@
+package keys
+
+import (
+	"bytes"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/transparency/kt"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// IdentityKeySize is the size of a raw Curve25519 public identity key.
+const IdentityKeySize = 32
+
+type KeyService struct {
+	db      *sql.DB
+	queries *repo.Queries
+}
+
+func NewKeyService(db *sql.DB, queries *repo.Queries) *KeyService {
+	return &KeyService{
+		db:      db,
+		queries: queries,
+	}
+}
+
+func (me *KeyService) RegisterDevice(params RegisterDeviceParams) (repo.Device, error) {
+	var zero repo.Device
+	if err := params.validate(); err != nil {
+		return zero, fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	// the key transparency leaf must only be published along with the device.
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	device, err := queries.InsertDevice(ctx, repo.InsertDeviceParams{
+		ID:          uuid.New(),
+		UserID:      params.UserID,
+		Name:        params.Name,
+		IdentityKey: params.IdentityKey,
+	})
+	if err != nil {
+		return zero, fmt.Errorf("failed to insert device: %w", err)
+	}
+
+	// a new device changes the set of keys peers see for this user, so it is
+	// treated the same way as a rotated key.
+	if err := me.recordIdentityKeyChange(ctx, queries, device.UserID, device.ID, device.IdentityKey); err != nil {
+		return zero, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return zero, fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	return device, nil
+}
+
+type RegisterDeviceParams struct {
+	UserID      uuid.UUID
+	Name        string
+	IdentityKey []byte
+}
+
+func (me *RegisterDeviceParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.Name, validation.Required, validation.Length(1, 50)),
+		validation.Field(&me.IdentityKey, validation.Required, validation.Length(IdentityKeySize, IdentityKeySize)),
+	)
+}
+
+func (me *KeyService) RotateIdentityKey(params RotateIdentityKeyParams) error {
+	if err := params.validate(); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	device, err := me.getUserDevice(ctx, queries, params.UserID, params.DeviceID)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(device.IdentityKey, params.IdentityKey) {
+		return nil
+	}
+
+	if err := queries.UpdateDeviceIdentityKey(ctx, repo.UpdateDeviceIdentityKeyParams{
+		ID:          device.ID,
+		IdentityKey: params.IdentityKey,
+	}); err != nil {
+		return fmt.Errorf("failed to update device identity key: %w", err)
+	}
+
+	if err := me.recordIdentityKeyChange(ctx, queries, device.UserID, device.ID, params.IdentityKey); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	return nil
+}
+
+type RotateIdentityKeyParams struct {
+	UserID      uuid.UUID
+	DeviceID    uuid.UUID
+	IdentityKey []byte
+}
+
+func (me *RotateIdentityKeyParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.IdentityKey, validation.Required, validation.Length(IdentityKeySize, IdentityKeySize)),
+	)
+}
+
+// recordIdentityKeyChange appends the key to the device's history and to the
+// key transparency log, emits a system event into every conversation of the
+// user and alerts everyone who had verified the user.
+func (me *KeyService) recordIdentityKeyChange(ctx context.Context, queries *repo.Queries, userID, deviceID uuid.UUID, identityKey []byte) error {
+	if err := queries.InsertIdentityKeyHistory(ctx, repo.InsertIdentityKeyHistoryParams{
+		DeviceID:    deviceID,
+		IdentityKey: identityKey,
+	}); err != nil {
+		return fmt.Errorf("failed to insert identity key history: %w", err)
+	}
+
+	user, err := queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	binding := kt.Binding{
+		Username:    user.Username,
+		DeviceID:    deviceID,
+		IdentityKey: identityKey,
+	}
+	leafHash := binding.LeafHash()
+	if _, err := queries.AppendKeyTransparencyEntry(ctx, repo.AppendKeyTransparencyEntryParams{
+		Username:    binding.Username,
+		DeviceID:    binding.DeviceID,
+		IdentityKey: binding.IdentityKey,
+		LeafHash:    leafHash[:],
+	}); err != nil {
+		return fmt.Errorf("failed to append key transparency entry: %w", err)
+	}
+
+	if err := queries.InsertIdentityKeyChangedEvents(ctx, repo.InsertIdentityKeyChangedEventsParams{
+		UserID:   userID,
+		DeviceID: deviceID,
+	}); err != nil {
+		return fmt.Errorf("failed to insert identity key changed events: %w", err)
+	}
+
+	if err := queries.InsertVerifiedIdentityKeyChangedEvents(ctx, repo.InsertVerifiedIdentityKeyChangedEventsParams{
+		UserID:   userID,
+		DeviceID: deviceID,
+	}); err != nil {
+		return fmt.Errorf("failed to insert verified identity key changed events: %w", err)
+	}
+
+	if err := queries.MarkContactVerificationsKeyChanged(ctx, userID); err != nil {
+		return fmt.Errorf("failed to mark contact verifications as changed: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserDevice returns the device if it belongs to the user.
+func (me *KeyService) GetUserDevice(userID, deviceID uuid.UUID) (repo.Device, error) {
+	return me.getUserDevice(context.Background(), me.queries, userID, deviceID)
+}
+
+func (me *KeyService) getUserDevice(ctx context.Context, queries *repo.Queries, userID, deviceID uuid.UUID) (repo.Device, error) {
+	device, err := queries.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return device, service.ErrNotFound
+		}
+		return device, fmt.Errorf("failed to get device by id: %w", err)
+	}
+	if device.UserID != userID {
+		return device, service.ErrNotFound
+	}
+	return device, nil
+}
+
+// ListUserDevices is the key directory lookup: it returns the devices and
+// current identity keys of the user with the given username.
+func (me *KeyService) ListUserDevices(username string) ([]repo.Device, error) {
+	ctx := context.Background()
+
+	user, err := me.getUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := me.queries.ListDevicesByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+func (me *KeyService) ListDeviceKeyHistory(username string, deviceID uuid.UUID) ([]repo.IdentityKeyHistory, error) {
+	ctx := context.Background()
+
+	user, err := me.getUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := me.getUserDevice(ctx, me.queries, user.ID, deviceID); err != nil {
+		return nil, err
+	}
+
+	history, err := me.queries.ListIdentityKeyHistoryByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identity key history: %w", err)
+	}
+
+	return history, nil
+}
+
+// SafetyNumberKeys holds the identity keys of both parties of a conversation.
+// Clients derive the safety number (or QR fingerprint) from them locally.
+type SafetyNumberKeys struct {
+	Local  PartyKeys
+	Remote PartyKeys
+}
+
+type PartyKeys struct {
+	UserID   uuid.UUID
+	Username string
+	Devices  []repo.Device
+}
+
+func (me *KeyService) GetSafetyNumberKeys(userID uuid.UUID, contactUsername string) (SafetyNumberKeys, error) {
+	ctx := context.Background()
+	var zero SafetyNumberKeys
+
+	user, err := me.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	contact, err := me.getUserByUsername(ctx, contactUsername)
+	if err != nil {
+		return zero, err
+	}
+
+	local, err := me.getPartyKeys(ctx, user)
+	if err != nil {
+		return zero, err
+	}
+
+	remote, err := me.getPartyKeys(ctx, contact)
+	if err != nil {
+		return zero, err
+	}
+
+	return SafetyNumberKeys{Local: local, Remote: remote}, nil
+}
+
+func (me *KeyService) getPartyKeys(ctx context.Context, user repo.User) (PartyKeys, error) {
+	devices, err := me.queries.ListDevicesByUserID(ctx, user.ID)
+	if err != nil {
+		return PartyKeys{}, fmt.Errorf("failed to list devices: %w", err)
+	}
+	return PartyKeys{
+		UserID:   user.ID,
+		Username: user.Username,
+		Devices:  devices,
+	}, nil
+}
+
+func (me *KeyService) VerifyContact(userID uuid.UUID, contactUsername string) error {
+	ctx := context.Background()
+
+	contact, err := me.getContact(ctx, userID, contactUsername)
+	if err != nil {
+		return err
+	}
+
+	if err := me.queries.UpsertContactVerification(ctx, repo.UpsertContactVerificationParams{
+		UserID:        userID,
+		ContactUserID: contact.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to upsert contact verification: %w", err)
+	}
+
+	return nil
+}
+
+func (me *KeyService) UnverifyContact(userID uuid.UUID, contactUsername string) error {
+	ctx := context.Background()
+
+	contact, err := me.getContact(ctx, userID, contactUsername)
+	if err != nil {
+		return err
+	}
+
+	if err := me.queries.DeleteContactVerification(ctx, repo.DeleteContactVerificationParams{
+		UserID:        userID,
+		ContactUserID: contact.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to delete contact verification: %w", err)
+	}
+
+	return nil
+}
+
+type VerificationStatus string
+
+const (
+	VerificationStatusUnverified VerificationStatus = "unverified"
+	VerificationStatusVerified   VerificationStatus = "verified"
+	// VerificationStatusKeyChanged means the contact was verified, but one of
+	// their identity keys has changed since.
+	VerificationStatusKeyChanged VerificationStatus = "key_changed"
+)
+
+type ContactVerification struct {
+	Status       VerificationStatus
+	VerifiedAt   *time.Time
+	KeyChangedAt *time.Time
+}
+
+func (me *KeyService) GetContactVerification(userID uuid.UUID, contactUsername string) (ContactVerification, error) {
+	ctx := context.Background()
+	var zero ContactVerification
+
+	contact, err := me.getContact(ctx, userID, contactUsername)
+	if err != nil {
+		return zero, err
+	}
+
+	verification, err := me.queries.GetContactVerification(ctx, repo.GetContactVerificationParams{
+		UserID:        userID,
+		ContactUserID: contact.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ContactVerification{Status: VerificationStatusUnverified}, nil
+		}
+		return zero, fmt.Errorf("failed to get contact verification: %w", err)
+	}
+
+	result := ContactVerification{
+		Status:     VerificationStatusVerified,
+		VerifiedAt: &verification.VerifiedAt,
+	}
+	if verification.KeyChangedAt.Valid {
+		result.Status = VerificationStatusKeyChanged
+		result.KeyChangedAt = &verification.KeyChangedAt.Time
+	}
+
+	return result, nil
+}
+
+func (me *KeyService) getContact(ctx context.Context, userID uuid.UUID, contactUsername string) (repo.User, error) {
+	contact, err := me.getUserByUsername(ctx, contactUsername)
+	if err != nil {
+		return contact, err
+	}
+	if contact.ID == userID {
+		return contact, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"username": errors.New("cannot verify yourself"),
+		})
+	}
+	return contact, nil
+}
+
+func (me *KeyService) getUserByUsername(ctx context.Context, username string) (repo.User, error) {
+	user, err := me.queries.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, service.ErrNotFound
+		}
+		return user, fmt.Errorf("failed to get user by username: %w", err)
+	}
+	return user, nil
+}
@@ -0,0 +1,57 @@
+package message
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const senderCertificateSignaturePrefix = "chatapp-sender-certificate-v1"
+
+var ErrInvalidSenderCertificate = errors.New("invalid sender certificate")
+
+// SenderCertificate is a short-lived statement by the server that a device
+// with the given identity key belongs to a user. Senders put it inside the
+// encrypted part of a sealed sender envelope, so only the recipient learns who
+// sent the message, and can still trust the claimed identity.
+type SenderCertificate struct {
+	UserID      uuid.UUID `json:"userId"`
+	Username    string    `json:"username"`
+	DeviceID    uuid.UUID `json:"deviceId"`
+	IdentityKey []byte    `json:"identityKey"`
+	// ExpiresAt is in unix milliseconds.
+	ExpiresAt int64  `json:"expiresAt"`
+	Signature []byte `json:"signature"`
+}
+
+func (me SenderCertificate) signedData() []byte {
+	buf := make([]byte, 0, len(senderCertificateSignaturePrefix)+16+16+2+len(me.Username)+2+len(me.IdentityKey)+8)
+	buf = append(buf, senderCertificateSignaturePrefix...)
+	buf = append(buf, me.UserID[:]...)
+	buf = append(buf, me.DeviceID[:]...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(me.Username)))
+	buf = append(buf, me.Username...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(me.IdentityKey)))
+	buf = append(buf, me.IdentityKey...)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(me.ExpiresAt))
+	return buf
+}
+
+func (me *SenderCertificate) sign(privateKey ed25519.PrivateKey) {
+	me.Signature = ed25519.Sign(privateKey, me.signedData())
+}
+
+// Verify checks the server's signature and that the certificate hasn't
+// expired at the given time.
+func (me SenderCertificate) Verify(publicKey ed25519.PublicKey, now time.Time) error {
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, me.signedData(), me.Signature) {
+		return ErrInvalidSenderCertificate
+	}
+	if now.UnixMilli() >= me.ExpiresAt {
+		return ErrInvalidSenderCertificate
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package message
+
+import (
+	"chatapp/repo"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// realtime event types.
+const (
+	EventTypeEnvelope = "envelope"
+	EventTypeReceipt  = "receipt"
+)
+
+// Envelope is how an envelope is handed to its recipient device, both from the
+// mailbox and over the realtime channel. Sealed sender envelopes only carry
+// their content.
+type Envelope struct {
+	ID                 uuid.UUID  `json:"id"`
+	RecipientDeviceID  uuid.UUID  `json:"-"`
+	Sealed             bool       `json:"sealed"`
+	MessageID          *uuid.UUID `json:"messageId,omitempty"`
+	ClientMessageID    *uuid.UUID `json:"clientMessageId,omitempty"`
+	ConversationID     *uuid.UUID `json:"conversationId,omitempty"`
+	Seq                *int64     `json:"seq,omitempty"`
+	Kind               string     `json:"kind,omitempty"`
+	ReferenceMessageID *uuid.UUID `json:"referenceMessageId,omitempty"`
+	SenderUserID       *uuid.UUID `json:"senderUserId,omitempty"`
+	SenderDeviceID     *uuid.UUID `json:"senderDeviceId,omitempty"`
+	Content            []byte     `json:"content"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	ExpiresAt          *time.Time `json:"expiresAt"`
+}
+
+// newEnvelope builds the envelope from its row and its message, which is nil
+// for sealed sender envelopes.
+func newEnvelope(envelope repo.Envelope, message *repo.Message) Envelope {
+	result := Envelope{
+		ID:                envelope.ID,
+		RecipientDeviceID: envelope.RecipientDeviceID,
+		Sealed:            message == nil,
+		Content:           envelope.Content,
+		CreatedAt:         envelope.CreatedAt,
+	}
+	if envelope.ExpiresAt.Valid {
+		result.ExpiresAt = &envelope.ExpiresAt.Time
+	}
+	if message != nil {
+		result.MessageID = &message.ID
+		result.ClientMessageID = &message.ClientMessageID
+		result.ConversationID = &message.ConversationID
+		result.Seq = &message.Seq
+		result.Kind = message.Kind
+		result.ReferenceMessageID = nullUUIDPtr(message.ReferenceMessageID)
+		result.SenderUserID = &message.SenderUserID
+		result.SenderDeviceID = nullUUIDPtr(message.SenderDeviceID)
+	}
+	return result
+}
+
+func newMailboxEnvelope(deviceID uuid.UUID, row repo.ListMailboxEnvelopesRow) Envelope {
+	result := Envelope{
+		ID:                 row.ID,
+		RecipientDeviceID:  deviceID,
+		Sealed:             !row.MessageID.Valid,
+		MessageID:          nullUUIDPtr(row.MessageID),
+		ClientMessageID:    nullUUIDPtr(row.ClientMessageID),
+		ConversationID:     nullUUIDPtr(row.ConversationID),
+		ReferenceMessageID: nullUUIDPtr(row.ReferenceMessageID),
+		SenderUserID:       nullUUIDPtr(row.SenderUserID),
+		SenderDeviceID:     nullUUIDPtr(row.SenderDeviceID),
+		Content:            row.Content,
+		CreatedAt:          row.CreatedAt,
+	}
+	if row.ExpiresAt.Valid {
+		result.ExpiresAt = &row.ExpiresAt.Time
+	}
+	if row.Seq.Valid {
+		result.Seq = &row.Seq.Int64
+	}
+	if row.Kind.Valid {
+		result.Kind = row.Kind.String
+	}
+	return result
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
@@ -0,0 +1,128 @@
+package message
+
+import (
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/realtime"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ReceiptStatusDelivered = "delivered"
+	ReceiptStatusRead      = "read"
+)
+
+// Receipt is pushed to the sender's devices whenever the delivery state of one
+// of their messages changes for a recipient device.
+type Receipt struct {
+	MessageID         uuid.UUID `json:"messageId"`
+	ConversationID    uuid.UUID `json:"conversationId"`
+	RecipientUserID   uuid.UUID `json:"recipientUserId"`
+	RecipientDeviceID uuid.UUID `json:"recipientDeviceId"`
+	Status            string    `json:"status"`
+	At                time.Time `json:"at"`
+}
+
+func (me *MessageService) markDelivered(ctx context.Context, messageID, deviceID uuid.UUID) error {
+	delivery, err := me.queries.MarkMessageDelivered(ctx, repo.MarkMessageDeliveredParams{
+		MessageID:         messageID,
+		RecipientDeviceID: deviceID,
+	})
+	if err != nil {
+		// control messages and the sender's own devices have no delivery
+		// state.
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to mark message delivered: %w", err)
+	}
+
+	me.dispatcher.PublishToUser(delivery.SenderUserID, realtime.Event{
+		Type: EventTypeReceipt,
+		Data: Receipt{
+			MessageID:         delivery.MessageID,
+			ConversationID:    delivery.ConversationID,
+			RecipientUserID:   delivery.RecipientUserID,
+			RecipientDeviceID: delivery.RecipientDeviceID,
+			Status:            ReceiptStatusDelivered,
+			At:                delivery.DeliveredAt.Time,
+		},
+	})
+
+	return nil
+}
+
+// markRead marks every message of the conversation up to the referenced one as
+// read by the reader.
+func (me *MessageService) markRead(ctx context.Context, readerUserID uuid.UUID, reference repo.Message) error {
+	reads, err := me.queries.MarkMessagesRead(ctx, repo.MarkMessagesReadParams{
+		ConversationID: reference.ConversationID,
+		UpToSeq:        reference.Seq,
+		ReaderUserID:   readerUserID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark messages read: %w", err)
+	}
+
+	for _, read := range reads {
+		// read receipts are reciprocal: senders who don't send them don't
+		// get them either.
+		if !read.SenderReadReceiptsEnabled {
+			continue
+		}
+		me.dispatcher.PublishToUser(read.SenderUserID, realtime.Event{
+			Type: EventTypeReceipt,
+			Data: Receipt{
+				MessageID:         read.MessageID,
+				ConversationID:    reference.ConversationID,
+				RecipientUserID:   readerUserID,
+				RecipientDeviceID: read.RecipientDeviceID,
+				Status:            ReceiptStatusRead,
+				At:                read.ReadAt.Time,
+			},
+		})
+	}
+
+	return nil
+}
+
+// GetMessageStatus returns the delivery state of a message for each of its
+// recipient devices. Only the sender can see it.
+func (me *MessageService) GetMessageStatus(userID, conversationID, messageID uuid.UUID) ([]repo.MessageDelivery, error) {
+	ctx := context.Background()
+
+	message, err := me.queries.GetMessageByID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, service.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get message by id: %w", err)
+	}
+	if message.SenderUserID != userID || message.ConversationID != conversationID {
+		return nil, service.ErrNotFound
+	}
+
+	sender, err := me.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	deliveries, err := me.queries.ListMessageDeliveries(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list message deliveries: %w", err)
+	}
+
+	if !sender.ReadReceiptsEnabled {
+		for i := range deliveries {
+			deliveries[i].ReadAt = sql.NullTime{}
+		}
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,56 @@
+package message
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"context"
+	"fmt"
+	"time"
+)
+
+// StartPurgeWorker periodically hard-deletes expired messages and envelopes:
+// disappearing messages once their timer runs out, and envelopes that weren't
+// delivered within the server-wide retention.
+func (me *MessageService) StartPurgeWorker(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-time.After(config.MessagePurgeWorkerTick):
+				if err := me.purgeExpired(ctx); err != nil {
+					me.logger.Error("failed to purge expired messages", "errors", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// purgeExpired deletes in batches so a large backlog doesn't hold locks on the
+// tables for long.
+func (me *MessageService) purgeExpired(ctx context.Context) error {
+	for {
+		deleted, err := me.queries.DeleteExpiredMessages(ctx, int32(config.MessagePurgeBatchSize))
+		if err != nil {
+			return fmt.Errorf("failed to delete expired messages: %w", err)
+		}
+		if deleted < int64(config.MessagePurgeBatchSize) {
+			break
+		}
+	}
+
+	for {
+		deleted, err := me.queries.DeleteExpiredEnvelopes(ctx, repo.DeleteExpiredEnvelopesParams{
+			CreatedBefore: time.Now().Add(-config.MaxUndeliveredEnvelopeRetention),
+			MaxCount:      int32(config.MessagePurgeBatchSize),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete expired envelopes: %w", err)
+		}
+		if deleted < int64(config.MessagePurgeBatchSize) {
+			break
+		}
+	}
+
+	return nil
+}
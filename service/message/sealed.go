@@ -0,0 +1,122 @@
+package message
+
+import (
+	"chatapp/config"
+	"chatapp/service"
+	"chatapp/service/user"
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+func (me *MessageService) SenderCertificatePublicKey() ed25519.PublicKey {
+	return me.certificateSigner.Public().(ed25519.PublicKey)
+}
+
+// IssueSenderCertificate returns a short-lived certificate binding the device
+// and its identity key to the user.
+func (me *MessageService) IssueSenderCertificate(userID, deviceID uuid.UUID) (SenderCertificate, error) {
+	ctx := context.Background()
+	var zero SenderCertificate
+
+	user, err := me.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	device, err := me.queries.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrNotFound
+		}
+		return zero, fmt.Errorf("failed to get device by id: %w", err)
+	}
+	if device.UserID != user.ID {
+		return zero, service.ErrNotFound
+	}
+
+	certificate := SenderCertificate{
+		UserID:      user.ID,
+		Username:    user.Username,
+		DeviceID:    device.ID,
+		IdentityKey: device.IdentityKey,
+		ExpiresAt:   time.Now().Add(config.SenderCertificateExpiration).UnixMilli(),
+	}
+	certificate.sign(me.certificateSigner)
+
+	return certificate, nil
+}
+
+// SendSealedMessage delivers envelopes whose sender is only known to the
+// recipient. The caller is not authenticated; instead it must present the
+// recipient's delivery access key. The server learns nothing but the
+// recipient.
+func (me *MessageService) SendSealedMessage(params SendSealedMessageParams) error {
+	if err := params.validate(); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	recipient, err := me.queries.GetUserByUsername(ctx, params.RecipientUsername)
+	if err != nil {
+		// unknown users are indistinguishable from a wrong access key, so the
+		// endpoint can't be used to enumerate accounts.
+		if errors.Is(err, sql.ErrNoRows) {
+			return service.ErrUnauthorized
+		}
+		return fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	if len(recipient.DeliveryAccessKey) != user.DeliveryAccessKeySize ||
+		subtle.ConstantTimeCompare(recipient.DeliveryAccessKey, params.DeliveryAccessKey) != 1 {
+		return service.ErrUnauthorized
+	}
+
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	deviceIDs, err := queries.ListUserDeviceIDs(ctx, recipient.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list user devices: %w", err)
+	}
+	if err := matchDevices(deviceIDs, params.Envelopes); err != nil {
+		return err
+	}
+
+	envelopes, err := me.insertEnvelopes(ctx, queries, nil, params.Envelopes)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	me.publishEnvelopes(envelopes)
+
+	return nil
+}
+
+type SendSealedMessageParams struct {
+	RecipientUsername string
+	DeliveryAccessKey []byte
+	Envelopes         []EnvelopeParams
+}
+
+func (me *SendSealedMessageParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.Envelopes, validation.Required),
+	)
+}
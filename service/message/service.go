@@ -0,0 +1,595 @@
+package message
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/push"
+	"chatapp/service/realtime"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// message kinds. Everything but KindMessage is a control message: its content
+// is still end-to-end encrypted, but the server acts on its kind and
+// referenced message.
+const (
+	KindMessage     = "message"
+	KindReadReceipt = "read_receipt"
+	KindEdit        = "edit"
+	KindDelete      = "delete"
+)
+
+type MessageService struct {
+	logger            *slog.Logger
+	db                *sql.DB
+	queries           *repo.Queries
+	dispatcher        *realtime.Dispatcher
+	pushService       *push.PushService
+	certificateSigner ed25519.PrivateKey
+}
+
+func NewMessageService(logger *slog.Logger, db *sql.DB, queries *repo.Queries, dispatcher *realtime.Dispatcher, pushService *push.PushService) *MessageService {
+	if len(config.SenderCertificateSigningKey) != ed25519.SeedSize {
+		panic(fmt.Sprintf("sender certificate signing key must be a %d byte ed25519 seed", ed25519.SeedSize))
+	}
+	return &MessageService{
+		logger:            logger,
+		db:                db,
+		queries:           queries,
+		dispatcher:        dispatcher,
+		pushService:       pushService,
+		certificateSigner: ed25519.NewKeyFromSeed(config.SenderCertificateSigningKey),
+	}
+}
+
+// EnvelopeParams is the message encrypted for a single recipient device.
+type EnvelopeParams struct {
+	DeviceID uuid.UUID
+	Content  []byte
+}
+
+func (me EnvelopeParams) Validate() error {
+	return validation.ValidateStruct(&me,
+		validation.Field(&me.DeviceID, validation.Required),
+		validation.Field(&me.Content, validation.Required, validation.Length(1, config.MaxEnvelopeContentSize)),
+	)
+}
+
+// MismatchedDevicesError is returned when a message isn't encrypted for
+// exactly the recipient's current devices, so the client can refresh its view
+// of the key directory and retry.
+type MismatchedDevicesError struct {
+	Missing []uuid.UUID
+	Extra   []uuid.UUID
+}
+
+func (me *MismatchedDevicesError) Error() string {
+	return fmt.Sprintf("mismatched devices: %d missing, %d extra", len(me.Missing), len(me.Extra))
+}
+
+func (me *MismatchedDevicesError) Unwrap() error {
+	return service.ErrMismatchedDevices
+}
+
+// matchDevices checks that there is exactly one envelope for each of the
+// expected devices.
+func matchDevices(expected []uuid.UUID, envelopes []EnvelopeParams) error {
+	expectedSet := make(map[uuid.UUID]bool, len(expected))
+	for _, deviceID := range expected {
+		expectedSet[deviceID] = true
+	}
+
+	mismatch := &MismatchedDevicesError{Missing: []uuid.UUID{}, Extra: []uuid.UUID{}}
+	seen := make(map[uuid.UUID]bool, len(envelopes))
+	for _, envelope := range envelopes {
+		if !expectedSet[envelope.DeviceID] || seen[envelope.DeviceID] {
+			mismatch.Extra = append(mismatch.Extra, envelope.DeviceID)
+		}
+		seen[envelope.DeviceID] = true
+	}
+	for _, deviceID := range expected {
+		if !seen[deviceID] {
+			mismatch.Missing = append(mismatch.Missing, deviceID)
+		}
+	}
+
+	if len(mismatch.Missing) > 0 || len(mismatch.Extra) > 0 {
+		return mismatch
+	}
+	return nil
+}
+
+// SendMessage stores the message and its envelopes, assigning it the next
+// sequence number of the conversation. Sends are idempotent on the client
+// message ID: retrying a send returns the stored message. The returned bool
+// reports whether a new message was created.
+func (me *MessageService) SendMessage(params SendMessageParams) (repo.Message, bool, error) {
+	var zero repo.Message
+	if err := params.validate(); err != nil {
+		return zero, false, fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	ok, err := me.queries.CheckConversationParticipant(ctx, repo.CheckConversationParticipantParams{
+		ConversationID: params.ConversationID,
+		UserID:         params.UserID,
+	})
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to check conversation participant: %w", err)
+	}
+	if !ok {
+		return zero, false, service.ErrNotFound
+	}
+
+	if existing, found, err := me.getSentMessage(ctx, params); err != nil || found {
+		return existing, false, err
+	}
+
+	var reference repo.Message
+	if params.Kind != KindMessage {
+		if reference, err = me.checkControlMessage(ctx, params); err != nil {
+			return zero, false, err
+		}
+	}
+
+	// the sequence number is taken under the conversation's row lock, which
+	// must hold until the message and its envelopes are committed.
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	devices, err := queries.ListConversationDevices(ctx, params.ConversationID)
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to list conversation devices: %w", err)
+	}
+	// every device in the conversation but the sending one, including the
+	// sender's other devices.
+	recipientDeviceIDs := make([]uuid.UUID, 0, len(devices))
+	for _, device := range devices {
+		if device.ID != params.DeviceID {
+			recipientDeviceIDs = append(recipientDeviceIDs, device.ID)
+		}
+	}
+	if err := matchDevices(recipientDeviceIDs, params.Envelopes); err != nil {
+		return zero, false, err
+	}
+
+	message, err := queries.InsertMessage(ctx, repo.InsertMessageParams{
+		ID:                 uuid.New(),
+		ConversationID:     params.ConversationID,
+		SenderUserID:       params.UserID,
+		SenderDeviceID:     uuid.NullUUID{UUID: params.DeviceID, Valid: true},
+		ClientMessageID:    params.ClientMessageID,
+		Kind:               params.Kind,
+		ReferenceMessageID: params.ReferenceMessageID,
+	})
+	if err != nil {
+		// a concurrent retry of the same send won the race.
+		if service.IsUniqueViolation(err) {
+			tx.Rollback()
+			existing, _, err := me.getSentMessage(ctx, params)
+			return existing, false, err
+		}
+		return zero, false, fmt.Errorf("failed to insert message: %w", err)
+	}
+
+	// attachments stay downloadable by the conversation as long as a message
+	// references them.
+	linkedAttachments := make(map[uuid.UUID]bool, len(params.AttachmentIDs))
+	for _, attachmentID := range params.AttachmentIDs {
+		if linkedAttachments[attachmentID] {
+			continue
+		}
+		linkedAttachments[attachmentID] = true
+
+		linked, err := queries.InsertMessageAttachment(ctx, repo.InsertMessageAttachmentParams{
+			MessageID:    message.ID,
+			AttachmentID: attachmentID,
+			OwnerUserID:  params.UserID,
+		})
+		if err != nil {
+			return zero, false, fmt.Errorf("failed to insert message attachment: %w", err)
+		}
+		if linked == 0 {
+			return zero, false, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+				"attachmentIds": fmt.Errorf("attachment %s not found", attachmentID),
+			})
+		}
+	}
+
+	if message.Kind == KindEdit || message.Kind == KindDelete {
+		if params.Envelopes, err = me.retractUndelivered(ctx, queries, message, params.Envelopes); err != nil {
+			return zero, false, err
+		}
+	}
+
+	envelopes, err := me.insertEnvelopes(ctx, queries, &message, params.Envelopes)
+	if err != nil {
+		return zero, false, err
+	}
+
+	// delivery state is only tracked for regular messages to other users.
+	if message.Kind == KindMessage {
+		for _, device := range devices {
+			if device.UserID == params.UserID {
+				continue
+			}
+			if err := queries.InsertMessageDelivery(ctx, repo.InsertMessageDeliveryParams{
+				MessageID:         message.ID,
+				RecipientDeviceID: device.ID,
+				RecipientUserID:   device.UserID,
+			}); err != nil {
+				return zero, false, fmt.Errorf("failed to insert message delivery: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return zero, false, fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	me.publishEnvelopes(envelopes)
+
+	if message.Kind == KindReadReceipt {
+		if err := me.markRead(ctx, params.UserID, reference); err != nil {
+			return zero, false, err
+		}
+	}
+
+	return message, true, nil
+}
+
+type SendMessageParams struct {
+	UserID             uuid.UUID
+	DeviceID           uuid.UUID
+	ConversationID     uuid.UUID
+	ClientMessageID    uuid.UUID
+	Kind               string
+	ReferenceMessageID uuid.NullUUID
+	AttachmentIDs      []uuid.UUID
+	Envelopes          []EnvelopeParams
+}
+
+func (me *SendMessageParams) validate() error {
+	if me.Kind == "" {
+		me.Kind = KindMessage
+	}
+	return validation.ValidateStruct(me,
+		validation.Field(&me.ClientMessageID, validation.Required),
+		validation.Field(&me.Kind, validation.In(KindMessage, KindReadReceipt, KindEdit, KindDelete)),
+		validation.Field(&me.ReferenceMessageID, validation.By(func(value any) error {
+			if me.Kind != KindMessage && !me.ReferenceMessageID.Valid {
+				return validation.NewError("validation-reference-required", "control messages must reference a message")
+			}
+			if me.Kind == KindMessage && me.ReferenceMessageID.Valid {
+				return validation.NewError("validation-reference-forbidden", "regular messages can't reference a message")
+			}
+			return nil
+		})),
+		validation.Field(&me.AttachmentIDs,
+			validation.Length(0, config.MaxMessageAttachments),
+			validation.When(me.Kind != KindMessage && me.Kind != KindEdit, validation.Empty),
+		),
+		validation.Field(&me.Envelopes, validation.Required),
+	)
+}
+
+// getSentMessage looks up a message previously sent with the same client
+// message ID.
+func (me *MessageService) getSentMessage(ctx context.Context, params SendMessageParams) (repo.Message, bool, error) {
+	message, err := me.queries.GetMessageBySenderClientMessageID(ctx, repo.GetMessageBySenderClientMessageIDParams{
+		SenderUserID:    params.UserID,
+		ClientMessageID: params.ClientMessageID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return message, false, nil
+		}
+		return message, false, fmt.Errorf("failed to get message by client message id: %w", err)
+	}
+	if message.ConversationID != params.ConversationID {
+		return message, false, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"clientMessageId": errors.New("already used for another conversation"),
+		})
+	}
+	return message, true, nil
+}
+
+// checkControlMessage validates a control message against the message it
+// references, which it returns.
+func (me *MessageService) checkControlMessage(ctx context.Context, params SendMessageParams) (repo.Message, error) {
+	reference, err := me.queries.GetMessageByID(ctx, params.ReferenceMessageID.UUID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return reference, fmt.Errorf("failed to get referenced message: %w", err)
+	}
+	if err != nil || reference.ConversationID != params.ConversationID || reference.Kind != KindMessage {
+		return reference, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"referenceMessageId": errors.New("must be a message of the conversation"),
+		})
+	}
+
+	switch params.Kind {
+	case KindEdit, KindDelete:
+		if reference.SenderUserID != params.UserID {
+			return reference, service.ErrForbidden
+		}
+		if time.Since(reference.CreatedAt) > config.MessageEditWindow {
+			return reference, service.ErrEditWindowExpired
+		}
+		deleted, err := me.queries.CheckMessageDeleted(ctx, reference.ID)
+		if err != nil {
+			return reference, fmt.Errorf("failed to check message deleted: %w", err)
+		}
+		if deleted {
+			return reference, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+				"referenceMessageId": errors.New("message was deleted"),
+			})
+		}
+	case KindReadReceipt:
+		if reference.SenderUserID == params.UserID {
+			return reference, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+				"referenceMessageId": errors.New("can't mark your own message as read"),
+			})
+		}
+		reader, err := me.queries.GetUserByID(ctx, params.UserID)
+		if err != nil {
+			return reference, fmt.Errorf("failed to get user by id: %w", err)
+		}
+		if !reader.ReadReceiptsEnabled {
+			return reference, service.ErrReadReceiptsDisabled
+		}
+	}
+
+	return reference, nil
+}
+
+// retractUndelivered purges the envelopes of the original message that are
+// still waiting in mailboxes, so the original ciphertext never reaches those
+// devices again. A delete is then only relayed to the devices that may have
+// the original: the ones it was delivered to, the ones it was already sent to
+// over realtime or in a mailbox listing without being acked yet, and the
+// sender's own devices, which have no delivery state. An edit carries the full
+// new content, so it still goes to every device and replaces the original on
+// the ones that never got it.
+func (me *MessageService) retractUndelivered(ctx context.Context, queries *repo.Queries, message repo.Message, envelopes []EnvelopeParams) ([]EnvelopeParams, error) {
+	purged, err := queries.DeleteMessageEnvelopes(ctx, message.ReferenceMessageID.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete message envelopes: %w", err)
+	}
+	if message.Kind != KindDelete {
+		return envelopes, nil
+	}
+
+	deliveries, err := queries.ListMessageDeliveries(ctx, message.ReferenceMessageID.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list message deliveries: %w", err)
+	}
+
+	received := make(map[uuid.UUID]bool, len(deliveries)+len(purged))
+	for _, delivery := range deliveries {
+		received[delivery.RecipientDeviceID] = delivery.DeliveredAt.Valid
+	}
+	// an envelope still in the mailbox means no ack yet, but it might have
+	// been sent already.
+	for _, envelope := range purged {
+		received[envelope.RecipientDeviceID] = envelope.SentAt.Valid
+	}
+
+	relayed := make([]EnvelopeParams, 0, len(envelopes))
+	for _, envelope := range envelopes {
+		if ok, known := received[envelope.DeviceID]; ok || !known {
+			relayed = append(relayed, envelope)
+		}
+	}
+	return relayed, nil
+}
+
+func (me *MessageService) insertEnvelopes(ctx context.Context, queries *repo.Queries, message *repo.Message, envelopes []EnvelopeParams) ([]Envelope, error) {
+	result := make([]Envelope, 0, len(envelopes))
+	for _, params := range envelopes {
+		insertParams := repo.InsertEnvelopeParams{
+			ID:                uuid.New(),
+			RecipientDeviceID: params.DeviceID,
+			Content:           params.Content,
+			ExpiresAt:         envelopeExpiresAt(message),
+		}
+		if message != nil {
+			insertParams.MessageID = uuid.NullUUID{UUID: message.ID, Valid: true}
+		}
+
+		envelope, err := queries.InsertEnvelope(ctx, insertParams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert envelope: %w", err)
+		}
+		result = append(result, newEnvelope(envelope, message))
+	}
+	return result, nil
+}
+
+// envelopeExpiresAt returns when an envelope is purged if it hasn't been
+// delivered: when its message disappears, but no later than the server-wide
+// retention.
+func envelopeExpiresAt(message *repo.Message) time.Time {
+	expiresAt := time.Now().Add(config.MaxUndeliveredEnvelopeRetention)
+	if message != nil && message.ExpiresAt.Valid && message.ExpiresAt.Time.Before(expiresAt) {
+		return message.ExpiresAt.Time
+	}
+	return expiresAt
+}
+
+// publishEnvelopes pushes the envelopes to their devices if they are connected,
+// and otherwise wakes them up with a push notification.
+func (me *MessageService) publishEnvelopes(envelopes []Envelope) {
+	connected := make([]Envelope, 0, len(envelopes))
+	for _, envelope := range envelopes {
+		if !me.dispatcher.IsDeviceConnected(envelope.RecipientDeviceID) {
+			me.pushService.NotifyDevice(envelope.RecipientDeviceID)
+			continue
+		}
+		connected = append(connected, envelope)
+	}
+	if len(connected) == 0 {
+		return
+	}
+
+	if err := me.markSent(context.Background(), envelopeIDs(connected)); err != nil {
+		me.logger.Error("failed to mark envelopes sent", "error", err)
+	}
+	for _, envelope := range connected {
+		me.dispatcher.PublishToDevice(envelope.RecipientDeviceID, realtime.Event{
+			Type: EventTypeEnvelope,
+			Data: envelope,
+		})
+	}
+}
+
+// markSent records that the envelopes' content left the server, see
+// retractUndelivered.
+func (me *MessageService) markSent(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := me.queries.MarkEnvelopesSent(ctx, ids); err != nil {
+		return fmt.Errorf("failed to mark envelopes sent: %w", err)
+	}
+	return nil
+}
+
+func envelopeIDs(envelopes []Envelope) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(envelopes))
+	for _, envelope := range envelopes {
+		ids = append(ids, envelope.ID)
+	}
+	return ids
+}
+
+// ListMailbox returns the envelopes waiting for the device, oldest first.
+func (me *MessageService) ListMailbox(deviceID uuid.UUID) ([]Envelope, error) {
+	rows, err := me.queries.ListMailboxEnvelopes(context.Background(), repo.ListMailboxEnvelopesParams{
+		RecipientDeviceID: deviceID,
+		Limit:             int32(config.MailboxPageSize),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mailbox envelopes: %w", err)
+	}
+
+	envelopes := make([]Envelope, 0, len(rows))
+	for _, row := range rows {
+		envelopes = append(envelopes, newMailboxEnvelope(deviceID, row))
+	}
+	if err := me.markSent(context.Background(), envelopeIDs(envelopes)); err != nil {
+		return nil, err
+	}
+	return envelopes, nil
+}
+
+// AckEnvelope removes an envelope from the device's mailbox once the device has
+// stored it, which marks the message as delivered to the device.
+func (me *MessageService) AckEnvelope(deviceID, envelopeID uuid.UUID) error {
+	ctx := context.Background()
+
+	messageID, err := me.queries.DeleteEnvelope(ctx, repo.DeleteEnvelopeParams{
+		ID:                envelopeID,
+		RecipientDeviceID: deviceID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return service.ErrNotFound
+		}
+		return fmt.Errorf("failed to delete envelope: %w", err)
+	}
+
+	// sealed sender envelopes have no message, and therefore no delivery
+	// state the server could report to anyone.
+	if messageID.Valid {
+		if err := me.markDelivered(ctx, messageID.UUID, deviceID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type SyncMessagesParams struct {
+	UserID         uuid.UUID
+	DeviceID       uuid.UUID
+	ConversationID uuid.UUID
+	AfterSeq       int64
+	Limit          int
+}
+
+type SyncResult struct {
+	Messages []repo.ListConversationMessagesAfterSeqRow
+	// LastSeq is the sequence number of the latest message in the
+	// conversation, so clients can tell whether there is more to fetch.
+	LastSeq int64
+}
+
+// SyncMessages returns the messages of the conversation after the given
+// sequence number, oldest first. Each message carries the envelope addressed to
+// the device if it's still in the device's mailbox. Clients use it after
+// reconnecting to detect and fill gaps in the sequence.
+func (me *MessageService) SyncMessages(params SyncMessagesParams) (SyncResult, error) {
+	ctx := context.Background()
+	var zero SyncResult
+
+	if params.AfterSeq < 0 {
+		return zero, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"after-seq": errors.New("must not be negative"),
+		})
+	}
+	if params.Limit <= 0 || params.Limit > config.MessageSyncPageSize {
+		params.Limit = config.MessageSyncPageSize
+	}
+
+	ok, err := me.queries.CheckConversationParticipant(ctx, repo.CheckConversationParticipantParams{
+		ConversationID: params.ConversationID,
+		UserID:         params.UserID,
+	})
+	if err != nil {
+		return zero, fmt.Errorf("failed to check conversation participant: %w", err)
+	}
+	if !ok {
+		return zero, service.ErrNotFound
+	}
+
+	lastSeq, err := me.queries.GetConversationLastSeq(ctx, params.ConversationID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get conversation last seq: %w", err)
+	}
+
+	messages, err := me.queries.ListConversationMessagesAfterSeq(ctx, repo.ListConversationMessagesAfterSeqParams{
+		DeviceID:       params.DeviceID,
+		ConversationID: params.ConversationID,
+		AfterSeq:       params.AfterSeq,
+		MaxCount:       int32(params.Limit),
+	})
+	if err != nil {
+		return zero, fmt.Errorf("failed to list conversation messages: %w", err)
+	}
+
+	sent := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		if message.EnvelopeID.Valid {
+			sent = append(sent, message.EnvelopeID.UUID)
+		}
+	}
+	if err := me.markSent(ctx, sent); err != nil {
+		return zero, err
+	}
+
+	return SyncResult{Messages: messages, LastSeq: lastSeq}, nil
+}
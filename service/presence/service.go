@@ -0,0 +1,222 @@
+package presence
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/realtime"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// presence visibility settings.
+const (
+	VisibilityEveryone = "everyone"
+	VisibilityContacts = "contacts"
+	VisibilityNobody   = "nobody"
+)
+
+// realtime event types.
+const (
+	EventTypePresence = "presence"
+	EventTypeTyping   = "typing"
+)
+
+type Presence struct {
+	UserID uuid.UUID `json:"userId"`
+	Online bool      `json:"online"`
+	// LastSeen is only known for users who went offline since the server
+	// started.
+	LastSeen *time.Time `json:"lastSeen,omitempty"`
+}
+
+// Typing tells peers a user started or stopped typing. Clients should drop the
+// indicator on their own after config.TypingIndicatorTimeout without a refresh.
+type Typing struct {
+	ConversationID uuid.UUID `json:"conversationId"`
+	UserID         uuid.UUID `json:"userId"`
+	Typing         bool      `json:"typing"`
+}
+
+// PresenceService fans out ephemeral presence and typing events to the
+// connected peers. Nothing of it is persisted: last seen times live in memory
+// only.
+type PresenceService struct {
+	logger     *slog.Logger
+	queries    *repo.Queries
+	dispatcher *realtime.Dispatcher
+
+	mu       sync.Mutex
+	lastSeen map[uuid.UUID]time.Time
+	// online are the users last announced as online.
+	online map[uuid.UUID]struct{}
+
+	presenceThrottle *throttle
+	typingThrottle   *throttle
+}
+
+func NewPresenceService(logger *slog.Logger, queries *repo.Queries, dispatcher *realtime.Dispatcher) *PresenceService {
+	return &PresenceService{
+		logger:           logger,
+		queries:          queries,
+		dispatcher:       dispatcher,
+		lastSeen:         map[uuid.UUID]time.Time{},
+		online:           map[uuid.UUID]struct{}{},
+		presenceThrottle: newThrottle(config.PresenceThrottleInterval, config.PresenceThrottleBurst),
+		typingThrottle:   newThrottle(config.TypingThrottleInterval, config.TypingThrottleBurst),
+	}
+}
+
+func (me *PresenceService) getPresence(userID uuid.UUID) Presence {
+	presence := Presence{UserID: userID, Online: me.dispatcher.IsConnected(userID)}
+	if !presence.Online {
+		me.mu.Lock()
+		if lastSeen, ok := me.lastSeen[userID]; ok {
+			presence.LastSeen = &lastSeen
+		}
+		me.mu.Unlock()
+	}
+	return presence
+}
+
+// Connected must be called once a device of the user subscribed to the
+// dispatcher.
+func (me *PresenceService) Connected(userID uuid.UUID) {
+	me.mu.Lock()
+	_, online := me.online[userID]
+	me.online[userID] = struct{}{}
+	me.mu.Unlock()
+
+	if !online {
+		me.announce(userID, true)
+	}
+}
+
+// Disconnected must be called once a device of the user closed its
+// subscription. The user only goes offline if no device reconnects within
+// config.PresenceOfflineGrace, so reconnects and long-polls don't flap.
+func (me *PresenceService) Disconnected(userID uuid.UUID) {
+	disconnectedAt := time.Now()
+	time.AfterFunc(config.PresenceOfflineGrace, func() {
+		if me.dispatcher.IsConnected(userID) {
+			return
+		}
+		me.mu.Lock()
+		_, online := me.online[userID]
+		delete(me.online, userID)
+		me.lastSeen[userID] = disconnectedAt
+		me.mu.Unlock()
+
+		if online {
+			me.announce(userID, false)
+		}
+	})
+}
+
+// announce publishes the user's presence to their connected contacts, if the
+// user lets them see it. Flapping connections are throttled, peers catch up
+// with GetPresence. Going offline is never throttled, or a user whose last
+// announcement was dropped would stay online for their contacts.
+func (me *PresenceService) announce(userID uuid.UUID, online bool) {
+	if online && !me.presenceThrottle.allow(throttleKey{userID: userID}) {
+		return
+	}
+
+	ctx := context.Background()
+
+	user, err := me.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		me.logger.Error("failed to get user by id", "errors", err)
+		return
+	}
+	if user.PresenceVisibility == VisibilityNobody {
+		return
+	}
+
+	contactIDs, err := me.queries.ListContactUserIDs(ctx, userID)
+	if err != nil {
+		me.logger.Error("failed to list contacts", "errors", err)
+		return
+	}
+
+	event := realtime.Event{Type: EventTypePresence, Data: me.getPresence(userID)}
+	for _, contactID := range contactIDs {
+		me.dispatcher.PublishToUser(contactID, event)
+	}
+}
+
+// GetPresence returns the presence of a user as seen by the viewer.
+// ErrForbidden is returned when the user doesn't share it with the viewer.
+func (me *PresenceService) GetPresence(viewerID uuid.UUID, username string) (Presence, error) {
+	ctx := context.Background()
+	var zero Presence
+
+	user, err := me.queries.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrNotFound
+		}
+		return zero, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	if user.ID != viewerID {
+		switch user.PresenceVisibility {
+		case VisibilityNobody:
+			return zero, service.ErrForbidden
+		case VisibilityContacts:
+			ok, err := me.queries.CheckContact(ctx, repo.CheckContactParams{
+				UserID:        user.ID,
+				ContactUserID: viewerID,
+			})
+			if err != nil {
+				return zero, fmt.Errorf("failed to check contact: %w", err)
+			}
+			if !ok {
+				return zero, service.ErrForbidden
+			}
+		}
+	}
+
+	return me.getPresence(user.ID), nil
+}
+
+// SetTyping relays a typing indicator to the other participants of the
+// conversation that are connected. Indicators over the throttle are dropped
+// silently and reported with ErrThrottled.
+func (me *PresenceService) SetTyping(userID, conversationID uuid.UUID, typing bool) error {
+	if !me.typingThrottle.allow(throttleKey{userID: userID, conversationID: conversationID}) {
+		return service.ErrThrottled
+	}
+
+	participantIDs, err := me.queries.ListConversationParticipantUserIDs(context.Background(), conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to list conversation participants: %w", err)
+	}
+
+	isParticipant := false
+	for _, participantID := range participantIDs {
+		isParticipant = isParticipant || participantID == userID
+	}
+	if !isParticipant {
+		return service.ErrNotFound
+	}
+
+	event := realtime.Event{
+		Type: EventTypeTyping,
+		Data: Typing{ConversationID: conversationID, UserID: userID, Typing: typing},
+	}
+	for _, participantID := range participantIDs {
+		if participantID != userID {
+			me.dispatcher.PublishToUser(participantID, event)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package presence
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// throttleSweepInterval is how often idle buckets are forgotten.
+const throttleSweepInterval = time.Minute
+
+type throttleKey struct {
+	userID uuid.UUID
+	// conversationID is uuid.Nil for presence announcements.
+	conversationID uuid.UUID
+}
+
+type bucket struct {
+	tokens   float64
+	lastFill time.Time
+}
+
+// throttle is an in-memory token bucket per key: bursts of up to burst events,
+// then one event per interval.
+type throttle struct {
+	interval time.Duration
+	burst    int
+
+	mu        sync.Mutex
+	buckets   map[throttleKey]*bucket
+	lastSweep time.Time
+}
+
+func newThrottle(interval time.Duration, burst int) *throttle {
+	return &throttle{
+		interval:  interval,
+		burst:     burst,
+		buckets:   map[throttleKey]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (me *throttle) allow(key throttleKey) bool {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(me.lastSweep) > throttleSweepInterval {
+		// a bucket idle for burst intervals is full again, the same as a
+		// missing one.
+		for key, b := range me.buckets {
+			if now.Sub(b.lastFill) > me.interval*time.Duration(me.burst) {
+				delete(me.buckets, key)
+			}
+		}
+		me.lastSweep = now
+	}
+
+	b, ok := me.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(me.burst), lastFill: now}
+		me.buckets[key] = b
+	}
+	b.tokens = min(float64(me.burst), b.tokens+float64(now.Sub(b.lastFill))/float64(me.interval))
+	b.lastFill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,51 @@
+package push
+
+import (
+	"chatapp/config"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+var errForbiddenAddress = errors.New("push endpoint resolves to a forbidden address")
+
+// newHTTPClient returns the client used to reach push endpoints. Since devices
+// pick their endpoints, it refuses to connect to loopback, private and
+// link-local addresses so endpoints can't be used to reach internal services.
+func newHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: config.PushRequestTimeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if config.PushAllowPrivateEndpoints {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+				ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+				return errForbiddenAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+	transport.Proxy = nil
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.PushRequestTimeout,
+		// a redirect could point anywhere, push services don't redirect.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package push
+
+import (
+	"chatapp/repo"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Provider delivers wake-ups through one push mechanism. Subscriptions are
+// routed to the provider registered under their provider name.
+type Provider interface {
+	// Validate checks that the subscription has what the provider needs to
+	// deliver to it.
+	Validate(subscription repo.PushSubscription) error
+	// Send makes a single delivery attempt of the wake-up payload. It returns
+	// ErrSubscriptionGone when the subscription no longer exists, and a
+	// *RetryableError when the attempt may succeed later.
+	Send(ctx context.Context, subscription repo.PushSubscription, payload []byte) error
+}
+
+// ErrSubscriptionGone is returned by providers for expired or unsubscribed
+// subscriptions, which are then removed.
+var ErrSubscriptionGone = errors.New("push subscription gone")
+
+type RetryableError struct {
+	// RetryAfter is the delay asked for by the push service, if any.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (me *RetryableError) Error() string {
+	return me.Err.Error()
+}
+
+func (me *RetryableError) Unwrap() error {
+	return me.Err
+}
+
+// checkResponse maps the response of an HTTP push endpoint to the errors
+// expected from providers.
+func checkResponse(resp *http.Response) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &RetryableError{
+			RetryAfter: time.Duration(retryAfter) * time.Second,
+			Err:        fmt.Errorf("unexpected push endpoint response: %s", resp.Status),
+		}
+	}
+	return fmt.Errorf("unexpected push endpoint response: %s", resp.Status)
+}
@@ -0,0 +1,122 @@
+package push
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"context"
+	"errors"
+	"time"
+)
+
+// endpointState tracks the deliveries to a single endpoint. Wake-ups are
+// idempotent, so every notification arriving while one is pending is folded
+// into it, and endpoints get at most one wake-up per PushMinInterval.
+type endpointState struct {
+	subscription repo.PushSubscription
+	pending      bool
+	// running is set while a goroutine is delivering to the endpoint.
+	running     bool
+	lastAttempt time.Time
+	// failures counts the failed attempts since the endpoint last succeeded,
+	// across wake-ups, so a failing endpoint stays backed off.
+	failures   int
+	retryAfter time.Duration
+}
+
+// schedule marks a wake-up as pending for the subscription's endpoint and
+// starts delivering to it if nothing is yet.
+func (me *PushService) schedule(subscription repo.PushSubscription) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	state, ok := me.endpoints[subscription.Endpoint]
+	if !ok {
+		state = &endpointState{}
+		me.endpoints[subscription.Endpoint] = state
+	}
+	state.subscription = subscription
+	state.pending = true
+	if state.running {
+		return
+	}
+	state.running = true
+	go me.deliver(subscription.Endpoint, state)
+}
+
+// nextAttemptDelay must be called with mu held.
+func (me *PushService) nextAttemptDelay(state *endpointState) time.Duration {
+	// waiting a little lets a burst of messages coalesce into one wake-up.
+	delay := config.PushCoalesceWindow
+	if !state.lastAttempt.IsZero() {
+		delay = max(delay, time.Until(state.lastAttempt.Add(config.PushMinInterval)))
+	}
+	if state.failures > 0 {
+		// failures isn't bounded, the shift is so it can't overflow.
+		backoff := min(config.PushRetryBackoff<<min(state.failures-1, 30), config.PushMaxBackoff)
+		delay = max(delay, time.Until(state.lastAttempt.Add(max(backoff, state.retryAfter))))
+	}
+	return delay
+}
+
+// deliver sends the pending wake-ups of an endpoint until there are none left.
+// It only exits once the endpoint's rate limit has elapsed, so the state of an
+// endpoint that isn't failing can be forgotten.
+func (me *PushService) deliver(endpoint string, state *endpointState) {
+	// attempts counts the tries of the current wake-up, a wake-up is given up
+	// after config.PushMaxAttempts.
+	attempts := 0
+	for {
+		me.mu.Lock()
+		delay := me.nextAttemptDelay(state)
+		me.mu.Unlock()
+
+		time.Sleep(delay)
+
+		me.mu.Lock()
+		if !state.pending {
+			state.running = false
+			if state.failures == 0 {
+				delete(me.endpoints, endpoint)
+			}
+			me.mu.Unlock()
+			return
+		}
+		state.pending = false
+		subscription := state.subscription
+		me.mu.Unlock()
+
+		err := me.providers[subscription.Provider].Send(context.Background(), subscription, wakeUpPayload)
+
+		me.mu.Lock()
+		attempts++
+		state.lastAttempt = time.Now()
+		state.retryAfter = 0
+		var retryable *RetryableError
+		isRetryable := errors.As(err, &retryable)
+		switch {
+		case err == nil:
+			attempts = 0
+			state.failures = 0
+		case errors.Is(err, ErrSubscriptionGone):
+			attempts = 0
+			state.failures = 0
+			go me.removeSubscription(subscription)
+		case isRetryable && attempts < config.PushMaxAttempts:
+			state.failures++
+			state.retryAfter = retryable.RetryAfter
+			// a newer wake-up may already be pending, which replaces the
+			// failed one.
+			state.pending = true
+			me.logger.Warn("retrying push notification", "deviceID", subscription.DeviceID, "failures", state.failures, "errors", err)
+		default:
+			attempts = 0
+			// the backoff still applies to the next wake-up.
+			state.failures++
+			if isRetryable {
+				state.retryAfter = retryable.RetryAfter
+			}
+			me.logger.Error("failed to send push notification", "deviceID", subscription.DeviceID, "errors", err)
+		}
+		me.mu.Unlock()
+	}
+}
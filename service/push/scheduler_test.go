@@ -0,0 +1,241 @@
+package push
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB records the statements executed by the queries, which for the
+// scheduler are only the removals of gone subscriptions.
+type fakeDB struct {
+	execs chan []any
+}
+
+func (me *fakeDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if !strings.Contains(query, "delete from push_subscriptions") {
+		return nil, errors.New("unexpected query")
+	}
+	me.execs <- args
+	return driver.RowsAffected(1), nil
+}
+
+func (me *fakeDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (me *fakeDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (me *fakeDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	panic("not supported")
+}
+
+// fakePushService answers each push request with the next scripted response,
+// repeating the last one.
+type fakePushService struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []fakeResponse
+	requests  chan time.Time
+}
+
+type fakeResponse struct {
+	status     int
+	retryAfter string
+}
+
+func newFakePushService(t *testing.T, responses ...fakeResponse) *fakePushService {
+	t.Helper()
+	fake := &fakePushService{responses: responses, requests: make(chan time.Time, 16)}
+	fake.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+
+		fake.mu.Lock()
+		response := fake.responses[0]
+		if len(fake.responses) > 1 {
+			fake.responses = fake.responses[1:]
+		}
+		fake.mu.Unlock()
+
+		if response.retryAfter != "" {
+			w.Header().Set("Retry-After", response.retryAfter)
+		}
+		w.WriteHeader(response.status)
+		fake.requests <- time.Now()
+	}))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func (me *fakePushService) respond(responses ...fakeResponse) {
+	me.mu.Lock()
+	me.responses = responses
+	me.mu.Unlock()
+}
+
+// waitRequest returns when the next push request was received.
+func (me *fakePushService) waitRequest(t *testing.T) time.Time {
+	t.Helper()
+	select {
+	case at := <-me.requests:
+		return at
+	case <-time.After(5 * time.Second):
+		t.Fatal("no push request received")
+		return time.Time{}
+	}
+}
+
+func (me *fakePushService) expectNoRequest(t *testing.T, wait time.Duration) {
+	t.Helper()
+	select {
+	case <-me.requests:
+		t.Fatal("unexpected push request")
+	case <-time.After(wait):
+	}
+}
+
+const testPushBackoff = 50 * time.Millisecond
+
+// TestMain shortens the scheduler's delays. They are only set once, delivery
+// goroutines can outlive the test that started them.
+func TestMain(m *testing.M) {
+	config.PushCoalesceWindow = time.Millisecond
+	config.PushMinInterval = time.Millisecond
+	config.PushRetryBackoff = testPushBackoff
+	config.PushMaxBackoff = time.Minute
+	config.PushMaxAttempts = 3
+	os.Exit(m.Run())
+}
+
+func newTestPushService(t *testing.T, fake *fakePushService, db *fakeDB) *PushService {
+	t.Helper()
+	vapidKey := newTestVAPIDKey(t)
+	return &PushService{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		queries:  repo.New(db),
+		vapidKey: vapidKey,
+		providers: map[string]Provider{
+			ProviderWebPush:     NewWebPushProvider(fake.Client(), vapidKey),
+			ProviderUnifiedPush: NewUnifiedPushProvider(fake.Client()),
+		},
+		endpoints: map[string]*endpointState{},
+	}
+}
+
+func (me *PushService) endpointFailures(endpoint string) (int, bool) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	state, ok := me.endpoints[endpoint]
+	if !ok {
+		return 0, false
+	}
+	return state.failures, true
+}
+
+// waitFailures waits for the outcome of the last attempt to be recorded.
+func (me *PushService) waitFailures(t *testing.T, endpoint string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		failures, ok := me.endpointFailures(endpoint)
+		if ok == (want > 0) && failures == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("failures = %d, %t, want %d", failures, ok, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduleRetriesWithRetryAfter(t *testing.T) {
+	fake := newFakePushService(t,
+		fakeResponse{status: http.StatusTooManyRequests, retryAfter: "1"},
+		fakeResponse{status: http.StatusServiceUnavailable},
+		fakeResponse{status: http.StatusCreated},
+	)
+	pushService := newTestPushService(t, fake, &fakeDB{})
+	subscription := newUserAgent(t).subscription(fake.URL)
+
+	pushService.schedule(subscription)
+
+	throttled := fake.waitRequest(t)
+	retried := fake.waitRequest(t)
+	if retried.Sub(throttled) < time.Second {
+		t.Errorf("retried after %s, want at least the Retry-After of 1s", retried.Sub(throttled))
+	}
+	fake.waitRequest(t)
+	fake.expectNoRequest(t, 100*time.Millisecond)
+
+	if _, ok := pushService.endpointFailures(subscription.Endpoint); ok {
+		t.Error("endpoint state kept after a successful delivery")
+	}
+}
+
+func TestScheduleRemovesGoneSubscription(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			fake := newFakePushService(t, fakeResponse{status: status})
+			db := &fakeDB{execs: make(chan []any, 1)}
+			pushService := newTestPushService(t, fake, db)
+			subscription := newUserAgent(t).subscription(fake.URL)
+
+			pushService.schedule(subscription)
+			fake.waitRequest(t)
+
+			select {
+			case args := <-db.execs:
+				if args[0] != subscription.DeviceID || args[1] != subscription.Endpoint {
+					t.Errorf("deleted subscription %v, want %s %s", args, subscription.DeviceID, subscription.Endpoint)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("subscription not removed")
+			}
+			fake.expectNoRequest(t, 100*time.Millisecond)
+		})
+	}
+}
+
+func TestScheduleKeepsBackoffAcrossWakeUps(t *testing.T) {
+	fake := newFakePushService(t, fakeResponse{status: http.StatusServiceUnavailable})
+	pushService := newTestPushService(t, fake, &fakeDB{})
+	subscription := newUserAgent(t).subscription(fake.URL)
+
+	pushService.schedule(subscription)
+	for range config.PushMaxAttempts {
+		fake.waitRequest(t)
+	}
+	// the wake-up is given up after config.PushMaxAttempts, the failures are
+	// kept.
+	pushService.waitFailures(t, subscription.Endpoint, 3)
+	last := time.Now()
+	fake.expectNoRequest(t, 2*testPushBackoff)
+
+	// the next wake-up waits for the backoff of the third failure in a row.
+	pushService.schedule(subscription)
+	next := fake.waitRequest(t)
+	if want := testPushBackoff << 2; next.Sub(last) < want-10*time.Millisecond {
+		t.Errorf("next wake-up sent %s after the last failure, want about %s", next.Sub(last), want)
+	}
+	pushService.waitFailures(t, subscription.Endpoint, 4)
+
+	// its retry succeeds, which resets the backoff.
+	fake.respond(fakeResponse{status: http.StatusCreated})
+	fake.waitRequest(t)
+	pushService.waitFailures(t, subscription.Endpoint, 0)
+}
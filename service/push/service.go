@@ -0,0 +1,145 @@
+package push
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/push/webpush"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// wakeUpPayload is the only payload ever pushed. It carries nothing about the
+// message, the device fetches its mailbox once woken up.
+var wakeUpPayload = []byte(`{"type":"new_message"}`)
+
+type PushService struct {
+	logger    *slog.Logger
+	queries   *repo.Queries
+	vapidKey  *webpush.VAPIDKey
+	providers map[string]Provider
+
+	mu sync.Mutex
+	// endpoints holds the delivery state of the endpoints with a wake-up
+	// pending or sent recently, and of those failing until they succeed.
+	endpoints map[string]*endpointState
+}
+
+func NewPushService(logger *slog.Logger, queries *repo.Queries) *PushService {
+	vapidKey, err := webpush.NewVAPIDKey(config.WebPushVAPIDPrivateKey)
+	if err != nil {
+		panic(err.Error())
+	}
+	client := newHTTPClient()
+
+	return &PushService{
+		logger:   logger,
+		queries:  queries,
+		vapidKey: vapidKey,
+		providers: map[string]Provider{
+			ProviderWebPush:     NewWebPushProvider(client, vapidKey),
+			ProviderUnifiedPush: NewUnifiedPushProvider(client),
+		},
+		endpoints: map[string]*endpointState{},
+	}
+}
+
+// VAPIDPublicKey is the applicationServerKey browsers subscribe with.
+func (me *PushService) VAPIDPublicKey() []byte {
+	return me.vapidKey.PublicKey()
+}
+
+type SubscribeParams struct {
+	DeviceID uuid.UUID
+	Provider string
+	Endpoint string
+	// P256dh and Auth are the keys of Web Push subscriptions.
+	P256dh []byte
+	Auth   []byte
+}
+
+func (me SubscribeParams) validate() error {
+	return validation.ValidateStruct(&me,
+		validation.Field(&me.Provider, validation.Required, validation.In(ProviderWebPush, ProviderUnifiedPush)),
+		validation.Field(&me.Endpoint, validation.Required, validation.Length(1, 2048), validation.By(func(value any) error {
+			endpoint, err := url.Parse(me.Endpoint)
+			if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+				return errors.New("must be an https url")
+			}
+			return nil
+		})),
+	)
+}
+
+// Subscribe registers the push subscription of a device, replacing the
+// previous one.
+func (me *PushService) Subscribe(params SubscribeParams) error {
+	if err := params.validate(); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	subscription := repo.PushSubscription{
+		DeviceID: params.DeviceID,
+		Provider: params.Provider,
+		Endpoint: params.Endpoint,
+	}
+	if params.Provider == ProviderWebPush {
+		subscription.P256dh = params.P256dh
+		subscription.Auth = params.Auth
+	}
+	if err := me.providers[params.Provider].Validate(subscription); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	if err := me.queries.UpsertPushSubscription(context.Background(), repo.UpsertPushSubscriptionParams{
+		DeviceID: subscription.DeviceID,
+		Provider: subscription.Provider,
+		Endpoint: subscription.Endpoint,
+		P256dh:   subscription.P256dh,
+		Auth:     subscription.Auth,
+	}); err != nil {
+		return fmt.Errorf("failed to upsert push subscription: %w", err)
+	}
+	return nil
+}
+
+func (me *PushService) Unsubscribe(deviceID uuid.UUID) error {
+	if err := me.queries.DeletePushSubscription(context.Background(), deviceID); err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	return nil
+}
+
+// NotifyDevice wakes the device up if it has a push subscription. Delivery
+// happens in the background, see schedule.
+func (me *PushService) NotifyDevice(deviceID uuid.UUID) {
+	go func() {
+		subscription, err := me.queries.GetPushSubscriptionByDeviceID(context.Background(), deviceID)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				me.logger.Error("failed to get push subscription", "deviceID", deviceID, "errors", err)
+			}
+			return
+		}
+		me.schedule(subscription)
+	}()
+}
+
+// removeSubscription removes a subscription the push service reported gone,
+// unless the device has replaced it in the meantime.
+func (me *PushService) removeSubscription(subscription repo.PushSubscription) {
+	if err := me.queries.DeletePushSubscriptionByEndpoint(context.Background(), repo.DeletePushSubscriptionByEndpointParams{
+		DeviceID: subscription.DeviceID,
+		Endpoint: subscription.Endpoint,
+	}); err != nil {
+		me.logger.Error("failed to delete push subscription", "deviceID", subscription.DeviceID, "errors", err)
+	}
+}
@@ -0,0 +1,48 @@
+package push
+
+import (
+	"bytes"
+	"chatapp/repo"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const ProviderUnifiedPush = "unifiedpush"
+
+// UnifiedPushProvider POSTs the wake-up to the HTTPS endpoint handed out by the
+// device's UnifiedPush distributor, which forwards it to the app as is. It
+// works for any plain webhook too.
+type UnifiedPushProvider struct {
+	client *http.Client
+}
+
+func NewUnifiedPushProvider(client *http.Client) *UnifiedPushProvider {
+	return &UnifiedPushProvider{
+		client: client,
+	}
+}
+
+func (me *UnifiedPushProvider) Validate(subscription repo.PushSubscription) error {
+	return nil
+}
+
+func (me *UnifiedPushProvider) Send(ctx context.Context, subscription repo.PushSubscription, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := me.client.Do(req)
+	if err != nil {
+		if errors.Is(err, errForbiddenAddress) {
+			return err
+		}
+		return &RetryableError{Err: fmt.Errorf("failed to send push request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
@@ -0,0 +1,80 @@
+package push
+
+import (
+	"bytes"
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service/push/webpush"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const ProviderWebPush = "webpush"
+
+// WebPushProvider delivers standard Web Push messages, encrypted with
+// aes128gcm and authenticated with VAPID.
+type WebPushProvider struct {
+	client   *http.Client
+	vapidKey *webpush.VAPIDKey
+}
+
+func NewWebPushProvider(client *http.Client, vapidKey *webpush.VAPIDKey) *WebPushProvider {
+	return &WebPushProvider{
+		client:   client,
+		vapidKey: vapidKey,
+	}
+}
+
+func (me *WebPushProvider) Validate(subscription repo.PushSubscription) error {
+	if err := validation.ValidateStruct(&subscription,
+		validation.Field(&subscription.P256dh, validation.Required, validation.Length(65, 65)),
+		validation.Field(&subscription.Auth, validation.Required, validation.Length(webpush.AuthSecretSize, webpush.AuthSecretSize)),
+	); err != nil {
+		return err
+	}
+	// catches keys that aren't a valid P-256 point.
+	if _, err := webpush.EncryptPayload(subscription.P256dh, subscription.Auth, nil); err != nil {
+		return validation.Errors{"p256dh": err}
+	}
+	return nil
+}
+
+func (me *WebPushProvider) Send(ctx context.Context, subscription repo.PushSubscription, payload []byte) error {
+	body, err := webpush.EncryptPayload(subscription.P256dh, subscription.Auth, payload)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt payload: %w", err)
+	}
+	authorization, err := me.vapidKey.Authorization(subscription.Endpoint, config.WebPushSubject, config.WebPushVAPIDExpiration)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(config.WebPushTTL/time.Second)))
+	req.Header.Set("Urgency", "high")
+	// a single pending wake-up per device is enough, later ones replace it.
+	req.Header.Set("Topic", "new-message")
+
+	resp, err := me.client.Do(req)
+	if err != nil {
+		if errors.Is(err, errForbiddenAddress) {
+			return err
+		}
+		return &RetryableError{Err: fmt.Errorf("failed to send push request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
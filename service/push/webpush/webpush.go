@@ -0,0 +1,194 @@
+// Package webpush implements the Web Push protocol primitives: payload
+// encryption (RFC 8291) and VAPID application server identification (RFC 8292).
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+)
+
+const (
+	// AuthSecretSize is the size of the subscription's auth secret.
+	AuthSecretSize = 16
+	saltSize       = 16
+	// recordSize is the aes128gcm record size advertised in the header. The
+	// payloads are small enough to always fit a single record.
+	recordSize = 4096
+	// lastRecordDelimiter ends the plaintext of the last record (RFC 8188).
+	lastRecordDelimiter = 0x02
+)
+
+var ErrInvalidSubscriptionKeys = errors.New("invalid push subscription keys")
+
+// EncryptPayload encrypts the payload for a subscription with the aes128gcm
+// content encoding, as specified by RFC 8291 (Message Encryption for Web Push).
+// The result is the complete request body, header included.
+func EncryptPayload(p256dh, authSecret, payload []byte) ([]byte, error) {
+	if len(authSecret) != AuthSecretSize {
+		return nil, ErrInvalidSubscriptionKeys
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionKeys
+	}
+	if len(payload)+1+aes.BlockSize > recordSize {
+		return nil, fmt.Errorf("payload too large: %d bytes", len(payload))
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	return encryptPayload(asPrivate, salt, uaPublic, authSecret, payload)
+}
+
+func encryptPayload(asPrivate *ecdh.PrivateKey, salt []byte, uaPublic *ecdh.PublicKey, authSecret, payload []byte) ([]byte, error) {
+	asPublic := asPrivate.PublicKey().Bytes()
+	p256dh := uaPublic.Bytes()
+
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute shared secret: %w", err)
+	}
+
+	// combines the shared secret with the auth secret, binding both public
+	// keys.
+	prkKey, err := hkdf.Extract(sha256.New, ecdhSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(p256dh) + string(asPublic)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// header: salt || rs || idlen || keyid, the key ID being the ephemeral
+	// public key.
+	body := make([]byte, 0, saltSize+4+1+len(asPublic)+len(payload)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(asPublic)))
+	body = append(body, asPublic...)
+
+	plaintext := append(append([]byte{}, payload...), lastRecordDelimiter)
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+// VAPIDKey identifies the application server to push services (RFC 8292).
+type VAPIDKey struct {
+	private *ecdsa.PrivateKey
+	// public is the uncompressed P-256 point, as passed to the browser's
+	// applicationServerKey.
+	public []byte
+}
+
+// NewVAPIDKey loads a VAPID key from its raw 32 byte P-256 private scalar.
+func NewVAPIDKey(privateKey []byte) (*VAPIDKey, error) {
+	ecdhKey, err := ecdh.P256().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	public := ecdhKey.PublicKey().Bytes()
+
+	return &VAPIDKey{
+		private: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(public[1:33]),
+				Y:     new(big.Int).SetBytes(public[33:]),
+			},
+			D: new(big.Int).SetBytes(privateKey),
+		},
+		public: public,
+	}, nil
+}
+
+// GenerateVAPIDKey returns a new raw P-256 private scalar.
+func GenerateVAPIDKey() ([]byte, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return key.Bytes(), nil
+}
+
+func (me *VAPIDKey) PublicKey() []byte {
+	return me.public
+}
+
+// Authorization returns the value of the Authorization header for a push to
+// the endpoint: a JWT signed with ES256 for the endpoint's origin, and the
+// public key.
+func (me *VAPIDKey) Authorization(endpoint, subject string, expiration time.Duration) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid push endpoint: %w", err)
+	}
+
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, err := json.Marshal(map[string]any{
+		"aud": endpointURL.Scheme + "://" + endpointURL.Host,
+		"exp": time.Now().Add(expiration).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, me.private, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign vapid token: %w", err)
+	}
+
+	// ES256 signatures are r || s, each left-padded to 32 bytes.
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return fmt.Sprintf("vapid t=%s, k=%s", token, base64.RawURLEncoding.EncodeToString(me.public)), nil
+}
@@ -0,0 +1,53 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"encoding/base64"
+	"testing"
+)
+
+func decodeBase64(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("failed to decode %q: %v", s, err)
+	}
+	return b
+}
+
+// TestEncryptPayloadRFC8291 checks the example of RFC 8291 Appendix A.
+func TestEncryptPayloadRFC8291(t *testing.T) {
+	plaintext := []byte("When I grow up, I want to be a watermelon")
+	asPrivate, err := ecdh.P256().NewPrivateKey(decodeBase64(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(decodeBase64(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := decodeBase64(t, "DGv6ra1nlYgDCS1FRnbzlw")
+	authSecret := decodeBase64(t, "BTBZMqHH6r4Tts7J_aSIgg")
+	want := decodeBase64(t, "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN")
+
+	got, err := encryptPayload(asPrivate, salt, uaPublic, authSecret, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("encryptPayload() = %s, want %s", base64.RawURLEncoding.EncodeToString(got), base64.RawURLEncoding.EncodeToString(want))
+	}
+}
+
+func TestEncryptPayloadInvalidKeys(t *testing.T) {
+	p256dh := decodeBase64(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4")
+	authSecret := decodeBase64(t, "BTBZMqHH6r4Tts7J_aSIgg")
+
+	if _, err := EncryptPayload(p256dh[:64], authSecret, nil); err != ErrInvalidSubscriptionKeys {
+		t.Errorf("truncated p256dh: err = %v, want %v", err, ErrInvalidSubscriptionKeys)
+	}
+	if _, err := EncryptPayload(p256dh, authSecret[:8], nil); err != ErrInvalidSubscriptionKeys {
+		t.Errorf("short auth secret: err = %v, want %v", err, ErrInvalidSubscriptionKeys)
+	}
+}
@@ -0,0 +1,235 @@
+package push
+
+import (
+	"bytes"
+	"chatapp/repo"
+	"chatapp/service/push/webpush"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// userAgent is the browser side of a Web Push subscription.
+type userAgent struct {
+	private    *ecdh.PrivateKey
+	authSecret []byte
+}
+
+func newUserAgent(t *testing.T) *userAgent {
+	t.Helper()
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authSecret := make([]byte, webpush.AuthSecretSize)
+	rand.Read(authSecret)
+	return &userAgent{private: private, authSecret: authSecret}
+}
+
+func (me *userAgent) subscription(endpoint string) repo.PushSubscription {
+	return repo.PushSubscription{
+		DeviceID: uuid.New(),
+		Provider: ProviderWebPush,
+		Endpoint: endpoint,
+		P256dh:   me.private.PublicKey().Bytes(),
+		Auth:     me.authSecret,
+	}
+}
+
+// decrypt reverses the aes128gcm encryption of RFC 8291, for a body holding a
+// single record.
+func (me *userAgent) decrypt(body []byte) ([]byte, error) {
+	if len(body) < 21 {
+		return nil, errors.New("body too short")
+	}
+	salt := body[:16]
+	recordSize := binary.BigEndian.Uint32(body[16:20])
+	idLen := int(body[20])
+	if len(body) < 21+idLen {
+		return nil, errors.New("body too short")
+	}
+	asPublicBytes := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+	if uint32(len(ciphertext)) > recordSize {
+		return nil, errors.New("more than one record")
+	}
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+	ecdhSecret, err := me.private.ECDH(asPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	prkKey, err := hkdf.Extract(sha256.New, ecdhSecret, me.authSecret)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(me.private.PublicKey().Bytes()) + string(asPublicBytes)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the last record ends with the delimiter, possibly followed by padding.
+	plaintext = bytes.TrimRight(plaintext, "\x00")
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		return nil, errors.New("missing last record delimiter")
+	}
+	return plaintext[:len(plaintext)-1], nil
+}
+
+// verifyVAPID checks the Authorization header of a push request the way a push
+// service does, and returns the token's claims.
+func verifyVAPID(authorization string, vapidPublic []byte) (map[string]any, error) {
+	params, ok := strings.CutPrefix(authorization, "vapid ")
+	if !ok {
+		return nil, errors.New("not a vapid authorization")
+	}
+	var token, key string
+	for _, param := range strings.Split(params, ", ") {
+		name, value, _ := strings.Cut(param, "=")
+		switch name {
+		case "t":
+			token = value
+		case "k":
+			key = value
+		}
+	}
+	if key != base64.RawURLEncoding.EncodeToString(vapidPublic) {
+		return nil, errors.New("unexpected vapid public key")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		return nil, errors.New("malformed signature")
+	}
+	x, y := elliptic.Unmarshal(elliptic.P256(), vapidPublic)
+	publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(publicKey, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		return nil, errors.New("invalid signature")
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func newTestVAPIDKey(t *testing.T) *webpush.VAPIDKey {
+	t.Helper()
+	private, err := webpush.GenerateVAPIDKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vapidKey, err := webpush.NewVAPIDKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vapidKey
+}
+
+func TestWebPushProviderSend(t *testing.T) {
+	vapidKey := newTestVAPIDKey(t)
+	ua := newUserAgent(t)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	provider := NewWebPushProvider(server.Client(), vapidKey)
+	subscription := ua.subscription(server.URL + "/push/abc")
+	if err := provider.Validate(subscription); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if err := provider.Send(context.Background(), subscription, wakeUpPayload); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+
+	request := <-requests
+	if got := request.header.Get("Content-Encoding"); got != "aes128gcm" {
+		t.Errorf("Content-Encoding = %q, want aes128gcm", got)
+	}
+	if request.header.Get("TTL") == "" {
+		t.Error("TTL header missing")
+	}
+
+	plaintext, err := ua.decrypt(request.body)
+	if err != nil {
+		t.Fatalf("failed to decrypt push message: %v", err)
+	}
+	if !bytes.Equal(plaintext, wakeUpPayload) {
+		t.Errorf("decrypted payload = %q, want %q", plaintext, wakeUpPayload)
+	}
+
+	claims, err := verifyVAPID(request.header.Get("Authorization"), vapidKey.PublicKey())
+	if err != nil {
+		t.Fatalf("invalid vapid authorization: %v", err)
+	}
+	if claims["aud"] != server.URL {
+		t.Errorf("aud = %v, want %s", claims["aud"], server.URL)
+	}
+}
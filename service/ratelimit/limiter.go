@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"chatapp/config"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Policy allows Limit hits per Window on each key.
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// route policies, the limits come from config.
+var (
+	LoginPolicyByIP = Policy{
+		Name:   "login-ip",
+		Limit:  config.LoginRateLimitPerIP,
+		Window: config.LoginRateLimitWindow,
+	}
+	LoginPolicyByEmail = Policy{
+		Name:   "login-email",
+		Limit:  config.LoginRateLimitPerEmail,
+		Window: config.LoginRateLimitWindow,
+	}
+	RegisterPolicyByIP = Policy{
+		Name:   "register-ip",
+		Limit:  config.RegisterRateLimitPerIP,
+		Window: config.RegisterRateLimitWindow,
+	}
+	VerifyEmailPolicyByIP = Policy{
+		Name:   "verify-email-ip",
+		Limit:  config.VerifyEmailRateLimitPerIP,
+		Window: config.VerifyEmailRateLimitWindow,
+	}
+	PasswordResetPolicyByIP = Policy{
+		Name:   "password-reset-ip",
+		Limit:  config.PasswordResetRateLimitPerIP,
+		Window: config.PasswordResetRateLimitWindow,
+	}
+	PasswordResetPolicyByEmail = Policy{
+		Name:   "password-reset-email",
+		Limit:  config.PasswordResetRateLimitPerEmail,
+		Window: config.PasswordResetRateLimitWindow,
+	}
+	LoginLinkPolicyByIP = Policy{
+		Name:   "login-link-ip",
+		Limit:  config.LoginLinkRateLimitPerIP,
+		Window: config.LoginLinkRateLimitWindow,
+	}
+	LoginLinkPolicyByEmail = Policy{
+		Name:   "login-link-email",
+		Limit:  config.LoginLinkRateLimitPerEmail,
+		Window: config.LoginLinkRateLimitWindow,
+	}
+	MFAPolicyByIP = Policy{
+		Name:   "mfa-ip",
+		Limit:  config.MFARateLimitPerIP,
+		Window: config.MFARateLimitWindow,
+	}
+	SendPolicyByCredentials = Policy{
+		Name:   "send-credentials",
+		Limit:  config.SendRateLimitPerAccount,
+		Window: config.SendRateLimitWindow,
+	}
+	UploadPolicyByCredentials = Policy{
+		Name:   "upload-credentials",
+		Limit:  config.UploadRateLimitPerAccount,
+		Window: config.UploadRateLimitWindow,
+	}
+	OpaqueUpgradePolicyByCredentials = Policy{
+		Name:   "opaque-upgrade-credentials",
+		Limit:  config.OpaqueUpgradeRateLimitPerAccount,
+		Window: config.OpaqueUpgradeRateLimitWindow,
+	}
+)
+
+type Limiter struct {
+	store Store
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{
+		store: store,
+	}
+}
+
+// Store gives access to the counters, for limits that don't fit policies.
+func (me *Limiter) Store() Store {
+	return me.store
+}
+
+// Allow counts a hit on the key under the policy. When the limit is exceeded,
+// it returns false and how long until the window ends.
+func (me *Limiter) Allow(ctx context.Context, policy Policy, key string) (bool, time.Duration, error) {
+	hits, resetAt, err := me.store.Hit(ctx, policy.Name+":"+key, policy.Window)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to hit %s: %w", policy.Name, err)
+	}
+	if hits > int64(policy.Limit) {
+		return false, time.Until(resetAt), nil
+	}
+	return true, 0, nil
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is how often expired counters are dropped.
+const memorySweepInterval = time.Minute
+
+type memoryCounter struct {
+	hits    int64
+	resetAt time.Time
+}
+
+// MemoryStore keeps the counters of a single instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*memoryCounter
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters:  map[string]*memoryCounter{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (me *MemoryStore) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(me.lastSweep) >= memorySweepInterval {
+		for key, counter := range me.counters {
+			if !now.Before(counter.resetAt) {
+				delete(me.counters, key)
+			}
+		}
+		me.lastSweep = now
+	}
+
+	counter, ok := me.counters[key]
+	if !ok || !now.Before(counter.resetAt) {
+		counter = &memoryCounter{resetAt: now.Add(window)}
+		me.counters[key] = counter
+	}
+	counter.hits++
+
+	return counter.hits, counter.resetAt, nil
+}
+
+func (me *MemoryStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	counter, ok := me.counters[key]
+	if !ok || !time.Now().Before(counter.resetAt) {
+		return 0, time.Time{}, nil
+	}
+	return counter.hits, counter.resetAt, nil
+}
+
+func (me *MemoryStore) Reset(ctx context.Context, key string) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	delete(me.counters, key)
+	return nil
+}
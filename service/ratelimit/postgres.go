@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PostgresStore shares the counters between instances without valkey.
+type PostgresStore struct {
+	logger  *slog.Logger
+	queries *repo.Queries
+}
+
+func NewPostgresStore(logger *slog.Logger, queries *repo.Queries) *PostgresStore {
+	return &PostgresStore{
+		logger:  logger,
+		queries: queries,
+	}
+}
+
+func (me *PostgresStore) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	now := time.Now()
+	counter, err := me.queries.HitRateLimitCounter(ctx, repo.HitRateLimitCounterParams{
+		Key:     key,
+		ResetAt: now.Add(window),
+		Now:     now,
+	})
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to hit rate limit counter: %w", err)
+	}
+	return counter.Hits, counter.ResetAt, nil
+}
+
+func (me *PostgresStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	counter, err := me.queries.GetRateLimitCounter(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, time.Time{}, nil
+		}
+		return 0, time.Time{}, fmt.Errorf("failed to get rate limit counter: %w", err)
+	}
+	return counter.Hits, counter.ResetAt, nil
+}
+
+func (me *PostgresStore) Reset(ctx context.Context, key string) error {
+	if err := me.queries.DeleteRateLimitCounter(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete rate limit counter: %w", err)
+	}
+	return nil
+}
+
+// StartCleanupWorker deletes the counters past their window.
+func (me *PostgresStore) StartCleanupWorker(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-time.After(config.RateLimitCleanupWorkerTick):
+				if _, err := me.queries.DeleteExpiredRateLimitCounters(ctx); err != nil {
+					me.logger.Error("failed to delete expired rate limit counters", "errors", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
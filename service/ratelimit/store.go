@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store keeps fixed window hit counters.
+type Store interface {
+	// Hit counts a hit on the key and returns the hits so far in the current
+	// window, and when that window ends. A window starts with the first hit.
+	Hit(ctx context.Context, key string, window time.Duration) (int64, time.Time, error)
+	// Get returns the hits in the current window without counting one, zero
+	// when there is no current window.
+	Get(ctx context.Context, key string) (int64, time.Time, error)
+	// Reset forgets the hits on the key.
+	Reset(ctx context.Context, key string) error
+}
+
+// NewStore returns the store selected by config.RateLimitStore, valkey may be
+// nil unless it's the one selected.
+func NewStore(logger *slog.Logger, queries *repo.Queries, valkey *redis.Client) (Store, error) {
+	switch config.RateLimitStore {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "postgres":
+		return NewPostgresStore(logger, queries), nil
+	case "valkey":
+		if valkey == nil {
+			return nil, errors.New("the valkey rate limit store requires VALKEY_URL")
+		}
+		return NewValkeyStore(valkey), nil
+	}
+	return nil, fmt.Errorf("unknown rate limit store %q", config.RateLimitStore)
+}
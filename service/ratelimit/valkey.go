@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ValkeyStore shares the counters between instances.
+type ValkeyStore struct {
+	valkey *redis.Client
+}
+
+func NewValkeyStore(valkey *redis.Client) *ValkeyStore {
+	return &ValkeyStore{
+		valkey: valkey,
+	}
+}
+
+func valkeyKey(key string) string {
+	return "ratelimit:" + key
+}
+
+func (me *ValkeyStore) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	var (
+		incr *redis.IntCmd
+		pttl *redis.DurationCmd
+	)
+	// NX only sets the expiration on the first hit of the window.
+	if _, err := me.valkey.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, valkeyKey(key))
+		pipe.Do(ctx, "pexpire", valkeyKey(key), window.Milliseconds(), "nx")
+		pttl = pipe.PTTL(ctx, valkeyKey(key))
+		return nil
+	}); err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to count hit: %w", err)
+	}
+
+	return incr.Val(), time.Now().Add(pttl.Val()), nil
+}
+
+func (me *ValkeyStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	var (
+		get  *redis.StringCmd
+		pttl *redis.DurationCmd
+	)
+	if _, err := me.valkey.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, valkeyKey(key))
+		pttl = pipe.PTTL(ctx, valkeyKey(key))
+		return nil
+	}); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, time.Time{}, fmt.Errorf("failed to get counter: %w", err)
+	}
+
+	hits, err := get.Int64()
+	if err != nil || pttl.Val() <= 0 {
+		return 0, time.Time{}, nil
+	}
+	return hits, time.Now().Add(pttl.Val()), nil
+}
+
+func (me *ValkeyStore) Reset(ctx context.Context, key string) error {
+	if err := me.valkey.Del(ctx, valkeyKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to reset counter: %w", err)
+	}
+	return nil
+}
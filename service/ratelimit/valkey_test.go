@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"chatapp/db/valkeyfake"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestValkeyStore(t *testing.T) *ValkeyStore {
+	t.Helper()
+	server, err := valkeyfake.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	options, err := redis.ParseURL(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(options)
+	t.Cleanup(func() { client.Close() })
+	return NewValkeyStore(client)
+}
+
+func TestValkeyStoreHitWindow(t *testing.T) {
+	store := newTestValkeyStore(t)
+	ctx := context.Background()
+	const window = 200 * time.Millisecond
+
+	start := time.Now()
+	hits, resetAt, err := store.Hit(ctx, "login:a", window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits != 1 {
+		t.Errorf("first hit = %d, want 1", hits)
+	}
+	if resetAt.Before(start) || resetAt.After(start.Add(window+50*time.Millisecond)) {
+		t.Errorf("resetAt = %s after start, want about %s", resetAt.Sub(start), window)
+	}
+
+	// later hits count in the same window, they don't extend it.
+	hits, secondResetAt, err := store.Hit(ctx, "login:a", window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits != 2 {
+		t.Errorf("second hit = %d, want 2", hits)
+	}
+	if secondResetAt.After(resetAt.Add(10 * time.Millisecond)) {
+		t.Errorf("second hit moved the window from %s to %s", resetAt, secondResetAt)
+	}
+
+	if hits, _, err := store.Get(ctx, "login:a"); err != nil || hits != 2 {
+		t.Errorf("Get() = %d, %v, want 2", hits, err)
+	}
+	if hits, _, err := store.Get(ctx, "login:b"); err != nil || hits != 0 {
+		t.Errorf("Get() of another key = %d, %v, want 0", hits, err)
+	}
+
+	time.Sleep(time.Until(resetAt) + 20*time.Millisecond)
+
+	if hits, _, err := store.Get(ctx, "login:a"); err != nil || hits != 0 {
+		t.Errorf("Get() after the window = %d, %v, want 0", hits, err)
+	}
+	hits, _, err = store.Hit(ctx, "login:a", window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits != 1 {
+		t.Errorf("hit after the window = %d, want 1", hits)
+	}
+}
+
+func TestValkeyStoreReset(t *testing.T) {
+	store := newTestValkeyStore(t)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, _, err := store.Hit(ctx, "login:a", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Reset(ctx, "login:a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if hits, _, err := store.Get(ctx, "login:a"); err != nil || hits != 0 {
+		t.Errorf("Get() after Reset() = %d, %v, want 0", hits, err)
+	}
+	hits, _, err := store.Hit(ctx, "login:a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits != 1 {
+		t.Errorf("hit after Reset() = %d, want 1", hits)
+	}
+}
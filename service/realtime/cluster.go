@@ -0,0 +1,175 @@
+package realtime
+
+import (
+	"chatapp/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// bus message kinds.
+const (
+	kindUserEvent    = "user-event"
+	kindDeviceEvent  = "device-event"
+	kindConnected    = "connected"
+	kindDisconnected = "disconnected"
+	// kindHeartbeat carries every device connected to the instance, which
+	// fixes whatever connected and disconnected messages got reordered.
+	kindHeartbeat = "heartbeat"
+	// kindHello asks the other instances for a heartbeat.
+	kindHello = "hello"
+)
+
+// busMessage is what dispatchers share on the cluster bus.
+type busMessage struct {
+	Kind       string    `json:"kind"`
+	InstanceID uuid.UUID `json:"instanceId"`
+	UserID     uuid.UUID `json:"userId,omitzero"`
+	DeviceID   uuid.UUID `json:"deviceId,omitzero"`
+	// EventType and Data are set for events.
+	EventType string          `json:"eventType,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	// Devices maps the connected devices to their users in heartbeats.
+	Devices map[uuid.UUID]uuid.UUID `json:"devices,omitempty"`
+}
+
+// remoteInstance is what a dispatcher knows of another instance.
+type remoteInstance struct {
+	// devices maps the devices connected to the instance to their users.
+	devices   map[uuid.UUID]uuid.UUID
+	lastHeard time.Time
+}
+
+// alive reports whether the instance sent a heartbeat recently enough for its
+// connections to count.
+func (me *remoteInstance) alive() bool {
+	return time.Since(me.lastHeard) < config.ClusterHeartbeatInterval*3
+}
+
+func (me *remoteInstance) hasUser(userID uuid.UUID) bool {
+	for _, deviceUserID := range me.devices {
+		if deviceUserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Start connects the dispatcher to the cluster bus, until the context is done.
+func (me *Dispatcher) Start(ctx context.Context) error {
+	if err := me.bus.Start(ctx, me.handle); err != nil {
+		return fmt.Errorf("failed to start cluster bus: %w", err)
+	}
+
+	me.send(busMessage{Kind: kindHello})
+	me.heartbeat()
+
+	go func() {
+		for {
+			select {
+			case <-time.After(config.ClusterHeartbeatInterval):
+				me.heartbeat()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// send publishes the message on the bus, as coming from this instance.
+func (me *Dispatcher) send(msg busMessage) {
+	msg.InstanceID = me.instanceID
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		me.logger.Error("failed to marshal bus message", "errors", err)
+		return
+	}
+	if err := me.bus.Publish(payload); err != nil {
+		me.logger.Error("failed to publish bus message", "errors", err)
+	}
+}
+
+func (me *Dispatcher) publishEvent(msg busMessage, event Event) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		me.logger.Error("failed to marshal event", "errors", err)
+		return
+	}
+	msg.EventType = event.Type
+	msg.Data = data
+	me.send(msg)
+}
+
+func (me *Dispatcher) announce(kind string, userID, deviceID uuid.UUID) {
+	me.send(busMessage{Kind: kind, UserID: userID, DeviceID: deviceID})
+}
+
+// heartbeat tells the other instances which devices are connected here, and
+// forgets the instances that went silent.
+func (me *Dispatcher) heartbeat() {
+	me.mu.Lock()
+	devices := make(map[uuid.UUID]uuid.UUID, len(me.byDevice))
+	for deviceID, subs := range me.byDevice {
+		for sub := range subs {
+			devices[deviceID] = sub.UserID
+			break
+		}
+	}
+	for instanceID, remote := range me.remotes {
+		if !remote.alive() {
+			delete(me.remotes, instanceID)
+		}
+	}
+	me.mu.Unlock()
+
+	me.send(busMessage{Kind: kindHeartbeat, Devices: devices})
+}
+
+// handle is the bus handler.
+func (me *Dispatcher) handle(seq uint64, payload []byte) {
+	var msg busMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		me.logger.Error("failed to unmarshal bus message", "errors", err)
+		return
+	}
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	me.lastID = seq
+
+	switch msg.Kind {
+	case kindUserEvent, kindDeviceEvent:
+		me.deliver(msg, Event{ID: seq, Type: msg.EventType, Data: msg.Data})
+		return
+	}
+
+	if msg.InstanceID == me.instanceID {
+		return
+	}
+	remote, ok := me.remotes[msg.InstanceID]
+	if !ok {
+		remote = &remoteInstance{devices: map[uuid.UUID]uuid.UUID{}}
+		me.remotes[msg.InstanceID] = remote
+	}
+	remote.lastHeard = time.Now()
+
+	switch msg.Kind {
+	case kindConnected:
+		remote.devices[msg.DeviceID] = msg.UserID
+	case kindDisconnected:
+		delete(remote.devices, msg.DeviceID)
+	case kindHeartbeat:
+		remote.devices = msg.Devices
+		if remote.devices == nil {
+			remote.devices = map[uuid.UUID]uuid.UUID{}
+		}
+	case kindHello:
+		// the handler can't publish, it runs within the bus.
+		go me.heartbeat()
+	}
+}
@@ -0,0 +1,287 @@
+package realtime
+
+import (
+	"chatapp/config"
+	"chatapp/service/cluster"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// subscriptionBufferSize is how many events a connection may fall behind
+// before it gets dropped.
+const subscriptionBufferSize = 64
+
+// EventTypeResync is sent first to a subscription that can't be resumed from
+// its last event ID, the client must then catch up through the mailbox and
+// sync APIs.
+const EventTypeResync = "resync"
+
+// Event is what gets pushed to connected devices. IDs increase with every
+// published event, so clients can resume from the last one they got.
+type Event struct {
+	ID   uint64 `json:"id"`
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// history keeps the latest events published to a device, for subscriptions
+// resuming after a reconnect. It has every event of the device with an ID
+// greater than since.
+type history struct {
+	events     []Event
+	since      uint64
+	lastActive time.Time
+}
+
+// Dispatcher fans events out to the devices that are currently connected, and
+// keeps a short history of them so devices can resume after a reconnect.
+// Events go through the cluster bus, so they reach devices connected to any
+// instance. Nothing is persisted: devices that were gone for longer catch up
+// through the mailbox and sync APIs.
+type Dispatcher struct {
+	logger     *slog.Logger
+	bus        cluster.Bus
+	instanceID uuid.UUID
+
+	mu sync.RWMutex
+	// lastID is the sequence number of the last bus message handled.
+	lastID    uint64
+	byUser    map[uuid.UUID]map[*Subscription]struct{}
+	byDevice  map[uuid.UUID]map[*Subscription]struct{}
+	histories map[uuid.UUID]*history
+	// userDevices are the devices of each user that have a history.
+	userDevices map[uuid.UUID]map[uuid.UUID]struct{}
+	lastSweep   time.Time
+	// remotes are the other instances, by ID.
+	remotes map[uuid.UUID]*remoteInstance
+}
+
+func NewDispatcher(logger *slog.Logger, bus cluster.Bus) *Dispatcher {
+	return &Dispatcher{
+		logger:      logger,
+		bus:         bus,
+		instanceID:  uuid.New(),
+		remotes:     map[uuid.UUID]*remoteInstance{},
+		byUser:      map[uuid.UUID]map[*Subscription]struct{}{},
+		byDevice:    map[uuid.UUID]map[*Subscription]struct{}{},
+		histories:   map[uuid.UUID]*history{},
+		userDevices: map[uuid.UUID]map[uuid.UUID]struct{}{},
+		lastSweep:   time.Now(),
+	}
+}
+
+// Subscription is a connected device. Events is closed when the subscription
+// is closed, either by the connection or by the dispatcher because the
+// connection couldn't keep up.
+type Subscription struct {
+	UserID   uuid.UUID
+	DeviceID uuid.UUID
+	Events   <-chan Event
+	// LastEventID is the ID of the last event published before the
+	// subscription, clients can resume from it when they got nothing newer.
+	LastEventID uint64
+
+	events     chan Event
+	dispatcher *Dispatcher
+	closed     bool
+}
+
+// Subscribe registers a connected device. With a non-zero lastEventID, the
+// events the device missed since are replayed first, or a resync event if
+// they aren't all known anymore.
+func (me *Dispatcher) Subscribe(userID, deviceID uuid.UUID, lastEventID uint64) *Subscription {
+	events := make(chan Event, subscriptionBufferSize+config.RealtimeHistorySize)
+	sub := &Subscription{
+		UserID:     userID,
+		DeviceID:   deviceID,
+		Events:     events,
+		events:     events,
+		dispatcher: me,
+	}
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	me.sweepHistories()
+
+	if len(me.byDevice[deviceID]) == 0 {
+		go me.announce(kindConnected, userID, deviceID)
+	}
+
+	h, ok := me.histories[deviceID]
+	if !ok {
+		h = &history{since: me.lastID}
+		me.histories[deviceID] = h
+		if me.userDevices[userID] == nil {
+			me.userDevices[userID] = map[uuid.UUID]struct{}{}
+		}
+		me.userDevices[userID][deviceID] = struct{}{}
+	}
+	h.lastActive = time.Now()
+	sub.LastEventID = me.lastID
+
+	if lastEventID > 0 {
+		// an ID from the future comes from before a server restart.
+		if lastEventID < h.since || lastEventID > me.lastID {
+			events <- Event{ID: me.lastID, Type: EventTypeResync}
+		} else {
+			for _, event := range h.events {
+				if event.ID > lastEventID {
+					events <- event
+				}
+			}
+		}
+	}
+
+	if me.byUser[userID] == nil {
+		me.byUser[userID] = map[*Subscription]struct{}{}
+	}
+	me.byUser[userID][sub] = struct{}{}
+	if me.byDevice[deviceID] == nil {
+		me.byDevice[deviceID] = map[*Subscription]struct{}{}
+	}
+	me.byDevice[deviceID][sub] = struct{}{}
+
+	return sub
+}
+
+func (me *Subscription) Close() {
+	me.dispatcher.mu.Lock()
+	defer me.dispatcher.mu.Unlock()
+	me.dispatcher.remove(me)
+}
+
+// remove must be called with mu held.
+func (me *Dispatcher) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.events)
+
+	delete(me.byUser[sub.UserID], sub)
+	if len(me.byUser[sub.UserID]) == 0 {
+		delete(me.byUser, sub.UserID)
+	}
+	delete(me.byDevice[sub.DeviceID], sub)
+	if len(me.byDevice[sub.DeviceID]) == 0 {
+		delete(me.byDevice, sub.DeviceID)
+		go me.announce(kindDisconnected, sub.UserID, sub.DeviceID)
+	}
+	if h, ok := me.histories[sub.DeviceID]; ok {
+		h.lastActive = time.Now()
+	}
+}
+
+// sweepHistories forgets the histories of devices that have been gone for
+// longer than config.RealtimeHistoryTTL. It must be called with mu held.
+func (me *Dispatcher) sweepHistories() {
+	if time.Since(me.lastSweep) < config.RealtimeHistoryTTL {
+		return
+	}
+	me.lastSweep = time.Now()
+
+	for deviceID, h := range me.histories {
+		if len(me.byDevice[deviceID]) > 0 || time.Since(h.lastActive) < config.RealtimeHistoryTTL {
+			continue
+		}
+		delete(me.histories, deviceID)
+		for userID, devices := range me.userDevices {
+			delete(devices, deviceID)
+			if len(devices) == 0 {
+				delete(me.userDevices, userID)
+			}
+		}
+	}
+}
+
+// record adds the event to the device's history, if it has one. It must be
+// called with mu held.
+func (me *Dispatcher) record(deviceID uuid.UUID, event Event) {
+	h, ok := me.histories[deviceID]
+	if !ok {
+		return
+	}
+	if len(h.events) == config.RealtimeHistorySize {
+		h.since = h.events[0].ID
+		h.events = h.events[1:]
+	}
+	h.events = append(h.events, event)
+	if len(me.byDevice[deviceID]) > 0 {
+		h.lastActive = time.Now()
+	}
+}
+
+// IsConnected reports whether any device of the user is connected, to any
+// instance.
+func (me *Dispatcher) IsConnected(userID uuid.UUID) bool {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	if len(me.byUser[userID]) > 0 {
+		return true
+	}
+	for _, remote := range me.remotes {
+		if remote.alive() && remote.hasUser(userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDeviceConnected reports whether the device is connected, to any instance.
+func (me *Dispatcher) IsDeviceConnected(deviceID uuid.UUID) bool {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	if len(me.byDevice[deviceID]) > 0 {
+		return true
+	}
+	for _, remote := range me.remotes {
+		if _, ok := remote.devices[deviceID]; ok && remote.alive() {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishToUser sends the event to every connected device of the user.
+func (me *Dispatcher) PublishToUser(userID uuid.UUID, event Event) {
+	me.publishEvent(busMessage{Kind: kindUserEvent, UserID: userID}, event)
+}
+
+// PublishToDevice sends the event to the device if it's connected.
+func (me *Dispatcher) PublishToDevice(deviceID uuid.UUID, event Event) {
+	me.publishEvent(busMessage{Kind: kindDeviceEvent, DeviceID: deviceID}, event)
+}
+
+// deliver hands an event received from the bus to the local subscriptions.
+// It must be called with mu held.
+func (me *Dispatcher) deliver(msg busMessage, event Event) {
+	switch msg.Kind {
+	case kindUserEvent:
+		for deviceID := range me.userDevices[msg.UserID] {
+			me.record(deviceID, event)
+		}
+		me.publish(me.byUser, msg.UserID, event)
+	case kindDeviceEvent:
+		me.record(msg.DeviceID, event)
+		me.publish(me.byDevice, msg.DeviceID, event)
+	}
+}
+
+// publish must be called with mu held.
+func (me *Dispatcher) publish(index map[uuid.UUID]map[*Subscription]struct{}, key uuid.UUID, event Event) {
+	for sub := range index[key] {
+		select {
+		case sub.events <- event:
+		default:
+			// dropping a single event would leave a silent gap, so the slow
+			// connection is closed instead and the client resyncs when it
+			// reconnects.
+			me.logger.Warn("dropping slow realtime subscription", "deviceID", sub.DeviceID)
+			me.remove(sub)
+		}
+	}
+}
@@ -4,14 +4,30 @@ import (
 	"errors"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/lib/pq"
 )
 
 var (
-	ErrValidation       = errors.New("Invalid Input")
-	ErrEmailConflict    = errors.New("Email Already Exists")
-	ErrUsernameConflict = errors.New("Username Already Exists")
-	ErrUnauthorized     = errors.New("Unauthorized")
-	ErrEmailNotVerified = errors.New("Email Not Verified")
+	ErrValidation           = errors.New("Invalid Input")
+	ErrEmailConflict        = errors.New("Email Already Exists")
+	ErrUsernameConflict     = errors.New("Username Already Exists")
+	ErrUnauthorized         = errors.New("Unauthorized")
+	ErrEmailNotVerified     = errors.New("Email Not Verified")
+	ErrNotFound             = errors.New("Not Found")
+	ErrForbidden            = errors.New("Forbidden")
+	ErrMismatchedDevices    = errors.New("Mismatched Devices")
+	ErrReadReceiptsDisabled = errors.New("Read Receipts Disabled")
+	ErrEditWindowExpired    = errors.New("Edit Window Expired")
+	ErrQuotaExceeded        = errors.New("Quota Exceeded")
+	ErrConflict             = errors.New("Conflict")
+	ErrExpired              = errors.New("Expired")
+	ErrOffsetMismatch       = errors.New("Offset Mismatch")
+	ErrThrottled            = errors.New("Throttled")
+	ErrLocked               = errors.New("Locked")
+	ErrPasswordResetNeeded  = errors.New("Password Reset Needed")
+	ErrMFARequired          = errors.New("MFA Required")
+	ErrWebAuthn             = errors.New("WebAuthn Ceremony Failed")
+	ErrOpaque               = errors.New("Invalid OPAQUE Message")
 )
 
 type ValidationErrorMap = validation.Errors
@@ -22,3 +38,10 @@ func ExtractValidationErrorsMap(err error) (ValidationErrorMap, bool) {
 	ok := errors.As(err, &vem)
 	return vem, ok
 }
+
+// IsUniqueViolation reports whether err is a postgres unique constraint
+// violation.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
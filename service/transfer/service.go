@@ -0,0 +1,521 @@
+package transfer
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/blob"
+	"chatapp/service/realtime"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// realtime event types.
+const (
+	EventTypeHistoryTransfer = "history_transfer"
+)
+
+// transfer statuses.
+const (
+	StatusUploading = "uploading"
+	StatusReady     = "ready"
+	StatusCompleted = "completed"
+	StatusCanceled  = "canceled"
+)
+
+// Transfer is a history transfer with its progress, as both devices see it.
+// The archive key in EncryptedKey is encrypted to the target device, the
+// server never has it.
+type Transfer struct {
+	ID               uuid.UUID `json:"id"`
+	SourceDeviceID   uuid.UUID `json:"sourceDeviceId"`
+	TargetDeviceID   uuid.UUID `json:"targetDeviceId"`
+	EncryptedKey     []byte    `json:"encryptedKey"`
+	ChunkCount       int       `json:"chunkCount"`
+	TotalSize        int64     `json:"totalSize"`
+	Status           string    `json:"status"`
+	UploadedChunks   int       `json:"uploadedChunks"`
+	DownloadedChunks int       `json:"downloadedChunks"`
+	UploadedSize     int64     `json:"uploadedSize"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+}
+
+// TransferService relays encrypted history archives between a user's devices,
+// chunk by chunk through the blob store.
+type TransferService struct {
+	logger     *slog.Logger
+	db         *sql.DB
+	queries    *repo.Queries
+	store      blob.Store
+	dispatcher *realtime.Dispatcher
+}
+
+func NewTransferService(logger *slog.Logger, db *sql.DB, queries *repo.Queries, store blob.Store, dispatcher *realtime.Dispatcher) *TransferService {
+	return &TransferService{
+		logger:     logger,
+		db:         db,
+		queries:    queries,
+		store:      store,
+		dispatcher: dispatcher,
+	}
+}
+
+func (me *TransferService) newTransfer(ctx context.Context, row repo.HistoryTransfer) (Transfer, error) {
+	var zero Transfer
+
+	progress, err := me.queries.GetHistoryTransferProgress(ctx, row.ID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get history transfer progress: %w", err)
+	}
+
+	return Transfer{
+		ID:               row.ID,
+		SourceDeviceID:   row.SourceDeviceID,
+		TargetDeviceID:   row.TargetDeviceID,
+		EncryptedKey:     row.EncryptedKey,
+		ChunkCount:       int(row.ChunkCount),
+		TotalSize:        row.TotalSize,
+		Status:           row.Status,
+		UploadedChunks:   int(progress.UploadedChunks),
+		DownloadedChunks: int(progress.DownloadedChunks),
+		UploadedSize:     progress.UploadedSize,
+		CreatedAt:        row.CreatedAt,
+		UpdatedAt:        row.UpdatedAt,
+		ExpiresAt:        row.ExpiresAt,
+	}, nil
+}
+
+// publish sends the transfer's progress to both devices. It is best effort,
+// devices that aren't connected get it from GetTransfer.
+func (me *TransferService) publish(ctx context.Context, transferID uuid.UUID) {
+	row, err := me.queries.GetHistoryTransfer(ctx, transferID)
+	if err != nil {
+		me.logger.Error("failed to get history transfer", "errors", err)
+		return
+	}
+	transfer, err := me.newTransfer(ctx, row)
+	if err != nil {
+		me.logger.Error("failed to get history transfer progress", "errors", err)
+		return
+	}
+
+	event := realtime.Event{Type: EventTypeHistoryTransfer, Data: transfer}
+	me.dispatcher.PublishToDevice(transfer.SourceDeviceID, event)
+	me.dispatcher.PublishToDevice(transfer.TargetDeviceID, event)
+}
+
+// CreateTransfer starts a transfer from the current device to another one of
+// the user's devices. ErrQuotaExceeded is returned when the user has
+// config.MaxOpenHistoryTransfers uploading or ready already.
+func (me *TransferService) CreateTransfer(params CreateTransferParams) (Transfer, error) {
+	var zero Transfer
+	if err := params.validate(); err != nil {
+		return zero, fmt.Errorf("%w: %w", service.ErrValidation, err)
+	}
+
+	ctx := context.Background()
+
+	target, err := me.queries.GetDeviceByID(ctx, params.TargetDeviceID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return zero, fmt.Errorf("failed to get device by id: %w", err)
+	}
+	if err != nil || target.UserID != params.UserID {
+		return zero, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"target-device-id": errors.New("unknown device"),
+		})
+	}
+
+	// the limit check needs a real transaction: the lock must hold until the
+	// transfer is committed.
+	tx, err := me.db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	queries := me.queries.WithTx(tx)
+
+	if err := queries.LockUser(ctx, params.UserID); err != nil {
+		return zero, fmt.Errorf("failed to lock user: %w", err)
+	}
+	open, err := queries.CountOpenHistoryTransfers(ctx, params.UserID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to count open history transfers: %w", err)
+	}
+	if open >= int64(config.MaxOpenHistoryTransfers) {
+		return zero, service.ErrQuotaExceeded
+	}
+
+	row, err := queries.InsertHistoryTransfer(ctx, repo.InsertHistoryTransferParams{
+		ID:             uuid.New(),
+		UserID:         params.UserID,
+		SourceDeviceID: params.SourceDeviceID,
+		TargetDeviceID: params.TargetDeviceID,
+		EncryptedKey:   params.EncryptedKey,
+		ChunkCount:     int32(params.ChunkCount),
+		TotalSize:      params.TotalSize,
+		ExpiresAt:      time.Now().Add(config.HistoryTransferExpiration),
+	})
+	if err != nil {
+		return zero, fmt.Errorf("failed to insert history transfer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return zero, fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	me.publish(ctx, row.ID)
+	return me.newTransfer(ctx, row)
+}
+
+type CreateTransferParams struct {
+	UserID         uuid.UUID
+	SourceDeviceID uuid.UUID
+	TargetDeviceID uuid.UUID
+	EncryptedKey   []byte
+	ChunkCount     int
+	TotalSize      int64
+}
+
+func (me *CreateTransferParams) validate() error {
+	return validation.ValidateStruct(me,
+		validation.Field(&me.TargetDeviceID, validation.Required, validation.NotIn(me.SourceDeviceID).Error("must be another device")),
+		validation.Field(&me.EncryptedKey, validation.Required, validation.Length(1, 1024)),
+		validation.Field(&me.ChunkCount, validation.Required, validation.Min(1), validation.Max(int(config.MaxHistoryTransferSize/config.MaxHistoryTransferChunkSize)+1)),
+		validation.Field(&me.TotalSize, validation.Required, validation.Min(int64(1)), validation.Max(config.MaxHistoryTransferSize)),
+	)
+}
+
+// getTransfer returns a transfer of the device, as its source, its target or
+// either. Expired transfers are gone.
+func (me *TransferService) getTransfer(ctx context.Context, transferID, deviceID uuid.UUID, asSource, asTarget bool) (repo.HistoryTransfer, error) {
+	var zero repo.HistoryTransfer
+
+	row, err := me.queries.GetHistoryTransfer(ctx, transferID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, service.ErrNotFound
+		}
+		return zero, fmt.Errorf("failed to get history transfer: %w", err)
+	}
+	if !(asSource && row.SourceDeviceID == deviceID) && !(asTarget && row.TargetDeviceID == deviceID) {
+		return zero, service.ErrNotFound
+	}
+	if time.Now().After(row.ExpiresAt) {
+		return zero, service.ErrNotFound
+	}
+
+	return row, nil
+}
+
+func (me *TransferService) GetTransfer(deviceID, transferID uuid.UUID) (Transfer, error) {
+	ctx := context.Background()
+	var zero Transfer
+
+	row, err := me.getTransfer(ctx, transferID, deviceID, true, true)
+	if err != nil {
+		return zero, err
+	}
+	return me.newTransfer(ctx, row)
+}
+
+// ListTransfers returns the device's transfers, incoming and outgoing, newest
+// first.
+func (me *TransferService) ListTransfers(deviceID uuid.UUID) ([]Transfer, error) {
+	ctx := context.Background()
+
+	rows, err := me.queries.ListDeviceHistoryTransfers(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list history transfers: %w", err)
+	}
+
+	transfers := make([]Transfer, 0, len(rows))
+	for _, row := range rows {
+		transfer, err := me.newTransfer(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
+
+// UploadChunk stores one chunk of the archive from the source device. Chunks
+// can't be replaced, ErrConflict is returned for one uploaded already. The
+// transfer is ready once every chunk is there.
+func (me *TransferService) UploadChunk(params UploadChunkParams) error {
+	ctx := context.Background()
+
+	row, err := me.getTransfer(ctx, params.TransferID, params.DeviceID, true, false)
+	if err != nil {
+		return err
+	}
+	if row.Status != StatusUploading {
+		return service.ErrConflict
+	}
+	if params.Index < 0 || params.Index >= int(row.ChunkCount) {
+		return fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"index": errors.New("out of bounds"),
+		})
+	}
+	if err := validation.Validate(params.Size, validation.Required, validation.Max(config.MaxHistoryTransferChunkSize)); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{"size": err})
+	}
+
+	progress, err := me.queries.GetHistoryTransferProgress(ctx, row.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get history transfer progress: %w", err)
+	}
+	if progress.UploadedSize+params.Size > row.TotalSize {
+		return service.ErrQuotaExceeded
+	}
+
+	blobID := uuid.New()
+	if err := me.store.Put(ctx, blobID, params.Body, params.Size); err != nil {
+		return fmt.Errorf("failed to put blob: %w", err)
+	}
+
+	rows, err := me.queries.InsertHistoryTransferChunk(ctx, repo.InsertHistoryTransferChunkParams{
+		TransferID: row.ID,
+		Index:      int32(params.Index),
+		BlobID:     blobID,
+		Size:       params.Size,
+	})
+	if err != nil || rows == 0 {
+		if err := me.store.Delete(ctx, blobID); err != nil {
+			me.logger.Error("failed to delete unused chunk blob", "errors", err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert history transfer chunk: %w", err)
+		}
+		return service.ErrConflict
+	}
+
+	if int(progress.UploadedChunks)+1 >= int(row.ChunkCount) {
+		if err := me.markReady(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	me.publish(ctx, row.ID)
+	return nil
+}
+
+type UploadChunkParams struct {
+	DeviceID   uuid.UUID
+	TransferID uuid.UUID
+	Index      int
+	Size       int64
+	Body       io.Reader
+}
+
+// markReady moves the transfer on once every chunk is uploaded, counted again
+// since chunks can be uploaded concurrently.
+func (me *TransferService) markReady(ctx context.Context, row repo.HistoryTransfer) error {
+	progress, err := me.queries.GetHistoryTransferProgress(ctx, row.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get history transfer progress: %w", err)
+	}
+	if int(progress.UploadedChunks) < int(row.ChunkCount) {
+		return nil
+	}
+
+	if _, err := me.queries.UpdateHistoryTransferStatus(ctx, repo.UpdateHistoryTransferStatusParams{
+		ID:         row.ID,
+		Status:     StatusReady,
+		FromStatus: StatusUploading,
+	}); err != nil {
+		return fmt.Errorf("failed to update history transfer status: %w", err)
+	}
+	return nil
+}
+
+// OpenChunk reads a chunk for the target device, which can start downloading
+// before the upload is complete. The caller must close it.
+func (me *TransferService) OpenChunk(deviceID, transferID uuid.UUID, index int) (io.ReadCloser, int64, error) {
+	ctx := context.Background()
+
+	row, err := me.getTransfer(ctx, transferID, deviceID, false, true)
+	if err != nil {
+		return nil, 0, err
+	}
+	if row.Status != StatusUploading && row.Status != StatusReady {
+		return nil, 0, service.ErrNotFound
+	}
+
+	chunk, err := me.queries.GetHistoryTransferChunk(ctx, repo.GetHistoryTransferChunkParams{
+		TransferID: row.ID,
+		Index:      int32(index),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, service.ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to get history transfer chunk: %w", err)
+	}
+
+	body, err := me.store.Get(ctx, chunk.BlobID, 0, chunk.Size)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, 0, service.ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to get blob: %w", err)
+	}
+
+	if chunk.DownloadedAt.Valid {
+		return body, chunk.Size, nil
+	}
+	if err := me.queries.MarkHistoryTransferChunkDownloaded(ctx, repo.MarkHistoryTransferChunkDownloadedParams{
+		TransferID: row.ID,
+		Index:      int32(index),
+	}); err != nil {
+		body.Close()
+		return nil, 0, fmt.Errorf("failed to mark history transfer chunk downloaded: %w", err)
+	}
+	me.publish(ctx, row.ID)
+
+	return body, chunk.Size, nil
+}
+
+// CompleteTransfer is called by the target device once it imported the
+// archive, its chunks are deleted.
+func (me *TransferService) CompleteTransfer(deviceID, transferID uuid.UUID) error {
+	ctx := context.Background()
+
+	row, err := me.getTransfer(ctx, transferID, deviceID, false, true)
+	if err != nil {
+		return err
+	}
+
+	rows, err := me.queries.UpdateHistoryTransferStatus(ctx, repo.UpdateHistoryTransferStatusParams{
+		ID:         row.ID,
+		Status:     StatusCompleted,
+		FromStatus: StatusReady,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update history transfer status: %w", err)
+	}
+	if rows == 0 {
+		return service.ErrConflict
+	}
+
+	if err := me.deleteChunks(ctx, row.ID); err != nil {
+		return err
+	}
+
+	me.publish(ctx, row.ID)
+	return nil
+}
+
+// CancelTransfer can be called by either device until the transfer is
+// completed, its chunks are deleted.
+func (me *TransferService) CancelTransfer(deviceID, transferID uuid.UUID) error {
+	ctx := context.Background()
+
+	row, err := me.getTransfer(ctx, transferID, deviceID, true, true)
+	if err != nil {
+		return err
+	}
+
+	rows, err := me.queries.CancelHistoryTransfer(ctx, row.ID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel history transfer: %w", err)
+	}
+	if rows == 0 {
+		return service.ErrConflict
+	}
+
+	if err := me.deleteChunks(ctx, row.ID); err != nil {
+		return err
+	}
+
+	me.publish(ctx, row.ID)
+	return nil
+}
+
+// DeleteUserTransfers removes every transfer of a user whose account is
+// purged, with the chunks' blobs.
+func (me *TransferService) DeleteUserTransfers(userID uuid.UUID) error {
+	ctx := context.Background()
+
+	transferIDs, err := me.queries.ListUserHistoryTransfers(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list user history transfers: %w", err)
+	}
+	for _, transferID := range transferIDs {
+		if err := me.deleteChunks(ctx, transferID); err != nil {
+			return err
+		}
+		if err := me.queries.DeleteHistoryTransfer(ctx, transferID); err != nil {
+			return fmt.Errorf("failed to delete history transfer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteChunks removes the blobs before the rows, so a failure never leaves a
+// blob nothing refers to.
+func (me *TransferService) deleteChunks(ctx context.Context, transferID uuid.UUID) error {
+	blobIDs, err := me.queries.ListHistoryTransferChunkBlobs(ctx, transferID)
+	if err != nil {
+		return fmt.Errorf("failed to list history transfer chunk blobs: %w", err)
+	}
+	for _, blobID := range blobIDs {
+		if err := me.store.Delete(ctx, blobID); err != nil {
+			return fmt.Errorf("failed to delete blob: %w", err)
+		}
+	}
+	if err := me.queries.DeleteHistoryTransferChunks(ctx, transferID); err != nil {
+		return fmt.Errorf("failed to delete history transfer chunks: %w", err)
+	}
+	return nil
+}
+
+// StartGarbageCollectionWorker periodically deletes expired transfers with
+// their chunks.
+func (me *TransferService) StartGarbageCollectionWorker(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-time.After(config.HistoryTransferGCWorkerTick):
+				if err := me.collectGarbage(ctx); err != nil {
+					me.logger.Error("failed to collect history transfer garbage", "errors", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (me *TransferService) collectGarbage(ctx context.Context) error {
+	for {
+		transferIDs, err := me.queries.ListExpiredHistoryTransfers(ctx, int32(config.HistoryTransferGCBatchSize))
+		if err != nil {
+			return fmt.Errorf("failed to list expired history transfers: %w", err)
+		}
+
+		for _, transferID := range transferIDs {
+			if err := me.deleteChunks(ctx, transferID); err != nil {
+				return err
+			}
+			if err := me.queries.DeleteHistoryTransfer(ctx, transferID); err != nil {
+				return fmt.Errorf("failed to delete history transfer: %w", err)
+			}
+		}
+
+		if len(transferIDs) < config.HistoryTransferGCBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package kt
+
+import (
+	"encoding/binary"
+
+	"github.com/google/uuid"
+)
+
+// Binding is a single log entry: the identity key published for a device of a
+// user at some point in time.
+type Binding struct {
+	Username    string    `json:"username"`
+	DeviceID    uuid.UUID `json:"deviceId"`
+	IdentityKey []byte    `json:"identityKey"`
+}
+
+// Encode returns the canonical encoding of the binding that is hashed into
+// the tree:
+//
+//	uint16 len(username) || username || device id (16 bytes) || uint16 len(key) || key
+func (me Binding) Encode() []byte {
+	buf := make([]byte, 0, 2+len(me.Username)+len(me.DeviceID)+2+len(me.IdentityKey))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(me.Username)))
+	buf = append(buf, me.Username...)
+	buf = append(buf, me.DeviceID[:]...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(me.IdentityKey)))
+	buf = append(buf, me.IdentityKey...)
+	return buf
+}
+
+func (me Binding) LeafHash() Hash {
+	return LeafHash(me.Encode())
+}
@@ -0,0 +1,223 @@
+// Package kt contains the key transparency primitives shared by the server
+// and the offline verifier. It implements the Merkle tree hashing, inclusion
+// proofs and consistency proofs described in RFC 9162 (Certificate
+// Transparency v2), and must not depend on any server configuration.
+package kt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+const HashSize = sha256.Size
+
+type Hash = [HashSize]byte
+
+var (
+	ErrInvalidProof = errors.New("invalid proof")
+	ErrInvalidRange = errors.New("invalid tree range")
+)
+
+// LeafHash returns MTH({d}) = SHA-256(0x00 || d).
+func LeafHash(data []byte) Hash {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+	var out Hash
+	h.Sum(out[:0])
+	return out
+}
+
+// NodeHash returns SHA-256(0x01 || left || right).
+func NodeHash(left, right Hash) Hash {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left[:])
+	h.Write(right[:])
+	var out Hash
+	h.Sum(out[:0])
+	return out
+}
+
+// EmptyRootHash is the root of a tree with no leaves, SHA-256("").
+func EmptyRootHash() Hash {
+	return sha256.Sum256(nil)
+}
+
+// RootHash computes the Merkle tree hash of the given leaf hashes.
+func RootHash(leaves []Hash) Hash {
+	if len(leaves) == 0 {
+		return EmptyRootHash()
+	}
+	return subtreeHash(leaves)
+}
+
+func subtreeHash(leaves []Hash) Hash {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := splitPoint(len(leaves))
+	return NodeHash(subtreeHash(leaves[:k]), subtreeHash(leaves[k:]))
+}
+
+// splitPoint returns the largest power of two strictly smaller than n.
+func splitPoint(n int) int {
+	return 1 << (bits.Len(uint(n-1)) - 1)
+}
+
+// InclusionPath returns the audit path for the leaf at index in the tree made
+// of the given leaf hashes.
+func InclusionPath(leaves []Hash, index int) ([]Hash, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, ErrInvalidRange
+	}
+	return inclusionPath(leaves, index), nil
+}
+
+func inclusionPath(leaves []Hash, index int) []Hash {
+	if len(leaves) <= 1 {
+		return []Hash{}
+	}
+	k := splitPoint(len(leaves))
+	if index < k {
+		return append(inclusionPath(leaves[:k], index), subtreeHash(leaves[k:]))
+	}
+	return append(inclusionPath(leaves[k:], index-k), subtreeHash(leaves[:k]))
+}
+
+// ConsistencyPath returns the proof that the tree made of the first
+// oldSize leaves is a prefix of the tree made of all the given leaves.
+func ConsistencyPath(leaves []Hash, oldSize int) ([]Hash, error) {
+	if oldSize < 0 || oldSize > len(leaves) {
+		return nil, ErrInvalidRange
+	}
+	if oldSize == 0 || oldSize == len(leaves) {
+		return []Hash{}, nil
+	}
+	return subproof(leaves, oldSize, true), nil
+}
+
+func subproof(leaves []Hash, m int, complete bool) []Hash {
+	n := len(leaves)
+	if m == n {
+		if complete {
+			return []Hash{}
+		}
+		return []Hash{subtreeHash(leaves)}
+	}
+	k := splitPoint(n)
+	if m <= k {
+		return append(subproof(leaves[:k], m, complete), subtreeHash(leaves[k:]))
+	}
+	return append(subproof(leaves[k:], m-k, false), subtreeHash(leaves[:k]))
+}
+
+// VerifyInclusion checks an audit path as described in RFC 9162 section
+// 2.1.3.2.
+func VerifyInclusion(leafHash Hash, index, treeSize uint64, proof []Hash, root Hash) error {
+	if index >= treeSize {
+		return fmt.Errorf("%w: leaf index %d is outside a tree of size %d", ErrInvalidRange, index, treeSize)
+	}
+
+	fn, sn := index, treeSize-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return fmt.Errorf("%w: audit path is too long", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			r = NodeHash(p, r)
+			if fn&1 == 0 {
+				for fn&1 == 0 && fn != 0 {
+					fn >>= 1
+					sn >>= 1
+				}
+			}
+		} else {
+			r = NodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 {
+		return fmt.Errorf("%w: audit path is too short", ErrInvalidProof)
+	}
+	if !bytes.Equal(r[:], root[:]) {
+		return fmt.Errorf("%w: root hash mismatch", ErrInvalidProof)
+	}
+	return nil
+}
+
+// VerifyConsistency checks a consistency proof as described in RFC 9162
+// section 2.1.4.2.
+func VerifyConsistency(oldSize, newSize uint64, oldRoot, newRoot Hash, proof []Hash) error {
+	switch {
+	case oldSize > newSize:
+		return fmt.Errorf("%w: old size %d is larger than new size %d", ErrInvalidRange, oldSize, newSize)
+	case oldSize == newSize:
+		if len(proof) != 0 {
+			return fmt.Errorf("%w: expected an empty proof for equal sizes", ErrInvalidProof)
+		}
+		if oldRoot != newRoot {
+			return fmt.Errorf("%w: root hash mismatch", ErrInvalidProof)
+		}
+		return nil
+	case oldSize == 0:
+		if len(proof) != 0 {
+			return fmt.Errorf("%w: expected an empty proof for an empty old tree", ErrInvalidProof)
+		}
+		return nil
+	}
+
+	if len(proof) == 0 {
+		return fmt.Errorf("%w: empty proof", ErrInvalidProof)
+	}
+
+	// if the old tree is a complete subtree, its root is the implicit first
+	// element of the proof.
+	if oldSize&(oldSize-1) == 0 {
+		proof = append([]Hash{oldRoot}, proof...)
+	}
+
+	fn, sn := oldSize-1, newSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return fmt.Errorf("%w: proof is too long", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = NodeHash(c, fr)
+			sr = NodeHash(c, sr)
+			if fn&1 == 0 {
+				for fn&1 == 0 && fn != 0 {
+					fn >>= 1
+					sn >>= 1
+				}
+			}
+		} else {
+			sr = NodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 {
+		return fmt.Errorf("%w: proof is too short", ErrInvalidProof)
+	}
+	if fr != oldRoot {
+		return fmt.Errorf("%w: old root hash mismatch", ErrInvalidProof)
+	}
+	if sr != newRoot {
+		return fmt.Errorf("%w: new root hash mismatch", ErrInvalidProof)
+	}
+	return nil
+}
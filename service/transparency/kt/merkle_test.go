@@ -0,0 +1,269 @@
+package kt
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// the reference vectors of the RFC 6962 reference implementation, whose tree
+// hashing and proofs RFC 9162 section 2.1 keeps unchanged.
+var referenceLeaves = []string{
+	"",
+	"00",
+	"10",
+	"2021",
+	"3031",
+	"40414243",
+	"5051525354555657",
+	"606162636465666768696a6b6c6d6e6f",
+}
+
+var referenceRoots = []string{
+	"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+	"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+	"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+	"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+	"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+	"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+	"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+}
+
+var referenceInclusionPaths = []struct {
+	index, treeSize int
+	path            []string
+}{
+	{0, 0, nil},
+	{0, 1, []string{}},
+	{0, 8, []string{
+		"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+		"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+		"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4",
+	}},
+	{5, 8, []string{
+		"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+		"ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	}},
+	{2, 3, []string{
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+	}},
+	{1, 5, []string{
+		"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+		"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+	}},
+}
+
+var referenceConsistencyProofs = []struct {
+	oldSize, newSize int
+	proof            []string
+}{
+	{1, 1, []string{}},
+	{1, 8, []string{
+		"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+		"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+		"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4",
+	}},
+	{6, 8, []string{
+		"0ebc5d3437fbe2db158b9f126a1d118e308181031d0a949f8dededebc558ef6a",
+		"ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	}},
+	{2, 5, []string{
+		"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+		"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+	}},
+}
+
+func decodeHash(t *testing.T, s string) Hash {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != HashSize {
+		t.Fatalf("invalid hash %q", s)
+	}
+	return Hash(b)
+}
+
+func decodeHashes(t *testing.T, ss []string) []Hash {
+	t.Helper()
+	hashes := make([]Hash, len(ss))
+	for i, s := range ss {
+		hashes[i] = decodeHash(t, s)
+	}
+	return hashes
+}
+
+func referenceLeafHashes(t *testing.T) []Hash {
+	t.Helper()
+	leaves := make([]Hash, len(referenceLeaves))
+	for i, s := range referenceLeaves {
+		data, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves[i] = LeafHash(data)
+	}
+	return leaves
+}
+
+// testLeafHashes returns n distinct leaf hashes.
+func testLeafHashes(n int) []Hash {
+	leaves := make([]Hash, n)
+	for i := range leaves {
+		leaves[i] = LeafHash([]byte(fmt.Sprintf("leaf %d", i)))
+	}
+	return leaves
+}
+
+func hashesEqual(a, b []Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRootHashReference(t *testing.T) {
+	if got, want := RootHash(nil), decodeHash(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"); got != want {
+		t.Errorf("RootHash() of an empty tree = %x, want %x", got, want)
+	}
+
+	leaves := referenceLeafHashes(t)
+	for size := 1; size <= len(leaves); size++ {
+		if got, want := RootHash(leaves[:size]), decodeHash(t, referenceRoots[size-1]); got != want {
+			t.Errorf("RootHash() of size %d = %x, want %x", size, got, want)
+		}
+	}
+}
+
+func TestInclusionPathReference(t *testing.T) {
+	leaves := referenceLeafHashes(t)
+	for _, test := range referenceInclusionPaths {
+		path, err := InclusionPath(leaves[:test.treeSize], test.index)
+		if test.path == nil {
+			if !errors.Is(err, ErrInvalidRange) {
+				t.Errorf("InclusionPath(%d) of size %d = %v, want %v", test.index, test.treeSize, err, ErrInvalidRange)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("InclusionPath(%d) of size %d = %v", test.index, test.treeSize, err)
+		}
+		want := decodeHashes(t, test.path)
+		if !hashesEqual(path, want) {
+			t.Errorf("InclusionPath(%d) of size %d = %x, want %x", test.index, test.treeSize, path, want)
+		}
+
+		root := decodeHash(t, referenceRoots[test.treeSize-1])
+		if err := VerifyInclusion(leaves[test.index], uint64(test.index), uint64(test.treeSize), want, root); err != nil {
+			t.Errorf("VerifyInclusion(%d) of size %d = %v", test.index, test.treeSize, err)
+		}
+	}
+}
+
+func TestConsistencyPathReference(t *testing.T) {
+	leaves := referenceLeafHashes(t)
+	for _, test := range referenceConsistencyProofs {
+		proof, err := ConsistencyPath(leaves[:test.newSize], test.oldSize)
+		if err != nil {
+			t.Fatalf("ConsistencyPath(%d, %d) = %v", test.oldSize, test.newSize, err)
+		}
+		want := decodeHashes(t, test.proof)
+		if !hashesEqual(proof, want) {
+			t.Errorf("ConsistencyPath(%d, %d) = %x, want %x", test.oldSize, test.newSize, proof, want)
+		}
+
+		oldRoot := decodeHash(t, referenceRoots[test.oldSize-1])
+		newRoot := decodeHash(t, referenceRoots[test.newSize-1])
+		if err := VerifyConsistency(uint64(test.oldSize), uint64(test.newSize), oldRoot, newRoot, want); err != nil {
+			t.Errorf("VerifyConsistency(%d, %d) = %v", test.oldSize, test.newSize, err)
+		}
+	}
+}
+
+func TestInclusionRoundTrip(t *testing.T) {
+	for size := 1; size <= 70; size++ {
+		leaves := testLeafHashes(size)
+		root := RootHash(leaves)
+		for index := range size {
+			path, err := InclusionPath(leaves, index)
+			if err != nil {
+				t.Fatalf("InclusionPath(%d) of size %d = %v", index, size, err)
+			}
+			if err := VerifyInclusion(leaves[index], uint64(index), uint64(size), path, root); err != nil {
+				t.Fatalf("VerifyInclusion(%d) of size %d = %v", index, size, err)
+			}
+
+			// the path proves nothing for another leaf or index, nor once cut or
+			// extended.
+			other := (index + 1) % size
+			if size > 1 && VerifyInclusion(leaves[other], uint64(index), uint64(size), path, root) == nil {
+				t.Errorf("VerifyInclusion(%d) of size %d accepted leaf %d", index, size, other)
+			}
+			if size > 1 && VerifyInclusion(leaves[index], uint64(other), uint64(size), path, root) == nil {
+				t.Errorf("VerifyInclusion(%d) of size %d accepted index %d", index, size, other)
+			}
+			if len(path) > 0 && VerifyInclusion(leaves[index], uint64(index), uint64(size), path[:len(path)-1], root) == nil {
+				t.Errorf("VerifyInclusion(%d) of size %d accepted a truncated path", index, size)
+			}
+			if VerifyInclusion(leaves[index], uint64(index), uint64(size), append(path, root), root) == nil {
+				t.Errorf("VerifyInclusion(%d) of size %d accepted an extended path", index, size)
+			}
+		}
+	}
+}
+
+func TestConsistencyRoundTrip(t *testing.T) {
+	for newSize := 1; newSize <= 70; newSize++ {
+		leaves := testLeafHashes(newSize)
+		newRoot := RootHash(leaves)
+		for oldSize := 0; oldSize <= newSize; oldSize++ {
+			oldRoot := RootHash(leaves[:oldSize])
+			proof, err := ConsistencyPath(leaves, oldSize)
+			if err != nil {
+				t.Fatalf("ConsistencyPath(%d, %d) = %v", oldSize, newSize, err)
+			}
+			if err := VerifyConsistency(uint64(oldSize), uint64(newSize), oldRoot, newRoot, proof); err != nil {
+				t.Fatalf("VerifyConsistency(%d, %d) = %v", oldSize, newSize, err)
+			}
+
+			if oldSize == 0 {
+				continue
+			}
+			// a rewritten history doesn't verify.
+			forged := LeafHash([]byte("forged"))
+			if VerifyConsistency(uint64(oldSize), uint64(newSize), forged, newRoot, proof) == nil {
+				t.Errorf("VerifyConsistency(%d, %d) accepted another old root", oldSize, newSize)
+			}
+			if VerifyConsistency(uint64(oldSize), uint64(newSize), oldRoot, forged, proof) == nil {
+				t.Errorf("VerifyConsistency(%d, %d) accepted another new root", oldSize, newSize)
+			}
+			if len(proof) > 0 && VerifyConsistency(uint64(oldSize), uint64(newSize), oldRoot, newRoot, proof[:len(proof)-1]) == nil {
+				t.Errorf("VerifyConsistency(%d, %d) accepted a truncated proof", oldSize, newSize)
+			}
+		}
+	}
+}
+
+func TestInvalidRanges(t *testing.T) {
+	leaves := testLeafHashes(4)
+	if _, err := InclusionPath(leaves, 4); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("InclusionPath() past the tree = %v, want %v", err, ErrInvalidRange)
+	}
+	if _, err := ConsistencyPath(leaves, 5); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("ConsistencyPath() past the tree = %v, want %v", err, ErrInvalidRange)
+	}
+	if err := VerifyInclusion(leaves[0], 4, 4, nil, RootHash(leaves)); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("VerifyInclusion() past the tree = %v, want %v", err, ErrInvalidRange)
+	}
+	if err := VerifyConsistency(5, 4, Hash{}, RootHash(leaves), nil); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("VerifyConsistency() of a shrinking tree = %v, want %v", err, ErrInvalidRange)
+	}
+}
@@ -0,0 +1,106 @@
+package kt
+
+import (
+	"crypto/ed25519"
+	"fmt"
+)
+
+// InclusionProof proves that Binding is the leaf at LeafIndex of the tree of
+// size TreeSize.
+type InclusionProof struct {
+	LeafIndex uint64   `json:"leafIndex"`
+	TreeSize  uint64   `json:"treeSize"`
+	Binding   Binding  `json:"binding"`
+	AuditPath [][]byte `json:"auditPath"`
+}
+
+// Verify checks the proof against a tree head of the same size. The tree
+// head's signature is not checked.
+func (me InclusionProof) Verify(head TreeHead) error {
+	if me.TreeSize != head.TreeSize {
+		return fmt.Errorf("%w: proof is for tree size %d, tree head has size %d", ErrInvalidRange, me.TreeSize, head.TreeSize)
+	}
+	root, err := head.root()
+	if err != nil {
+		return err
+	}
+	path, err := toHashes(me.AuditPath)
+	if err != nil {
+		return err
+	}
+	return VerifyInclusion(me.Binding.LeafHash(), me.LeafIndex, me.TreeSize, path, root)
+}
+
+// ConsistencyProof proves that the tree of size FirstSize is a prefix of the
+// tree of size SecondSize.
+type ConsistencyProof struct {
+	FirstSize  uint64   `json:"firstSize"`
+	SecondSize uint64   `json:"secondSize"`
+	Proof      [][]byte `json:"proof"`
+}
+
+// Verify checks the proof between two tree heads. The tree heads' signatures
+// are not checked.
+func (me ConsistencyProof) Verify(first, second TreeHead) error {
+	if me.FirstSize != first.TreeSize || me.SecondSize != second.TreeSize {
+		return fmt.Errorf("%w: proof sizes don't match the tree heads", ErrInvalidRange)
+	}
+	firstRoot, err := first.root()
+	if err != nil {
+		return err
+	}
+	secondRoot, err := second.root()
+	if err != nil {
+		return err
+	}
+	proof, err := toHashes(me.Proof)
+	if err != nil {
+		return err
+	}
+	return VerifyConsistency(me.FirstSize, me.SecondSize, firstRoot, secondRoot, proof)
+}
+
+// MonitorReport lists every binding ever published for an account, each with
+// an inclusion proof against the same signed tree head.
+type MonitorReport struct {
+	Username string           `json:"username"`
+	TreeHead SignedTreeHead   `json:"treeHead"`
+	Entries  []InclusionProof `json:"entries"`
+}
+
+// Verify checks the tree head signature, that every entry belongs to the
+// account, and every inclusion proof.
+func (me MonitorReport) Verify(publicKey ed25519.PublicKey) error {
+	if err := me.TreeHead.Verify(publicKey); err != nil {
+		return err
+	}
+	for _, entry := range me.Entries {
+		if entry.Binding.Username != me.Username {
+			return fmt.Errorf("%w: leaf %d belongs to %q, not %q", ErrInvalidProof, entry.LeafIndex, entry.Binding.Username, me.Username)
+		}
+		if err := entry.Verify(me.TreeHead.TreeHead); err != nil {
+			return fmt.Errorf("leaf %d: %w", entry.LeafIndex, err)
+		}
+	}
+	return nil
+}
+
+func toHashes(raw [][]byte) ([]Hash, error) {
+	hashes := make([]Hash, 0, len(raw))
+	for _, r := range raw {
+		if len(r) != HashSize {
+			return nil, fmt.Errorf("%w: hashes must be %d bytes", ErrInvalidProof, HashSize)
+		}
+		hashes = append(hashes, Hash(r))
+	}
+	return hashes, nil
+}
+
+// FromHashes converts hashes to their wire representation.
+func FromHashes(hashes []Hash) [][]byte {
+	raw := make([][]byte, 0, len(hashes))
+	for _, h := range hashes {
+		raw = append(raw, h[:])
+	}
+	return raw
+}
@@ -0,0 +1,56 @@
+package kt
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"fmt"
+)
+
+const treeHeadSignaturePrefix = "chatapp-kt-tree-head-v1"
+
+type TreeHead struct {
+	TreeSize uint64 `json:"treeSize"`
+	RootHash []byte `json:"rootHash"`
+	// Timestamp is in unix milliseconds.
+	Timestamp int64 `json:"timestamp"`
+}
+
+type SignedTreeHead struct {
+	TreeHead
+	Signature []byte `json:"signature"`
+}
+
+func (me TreeHead) signedData() []byte {
+	buf := make([]byte, 0, len(treeHeadSignaturePrefix)+8+8+len(me.RootHash))
+	buf = append(buf, treeHeadSignaturePrefix...)
+	buf = binary.BigEndian.AppendUint64(buf, me.TreeSize)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(me.Timestamp))
+	buf = append(buf, me.RootHash...)
+	return buf
+}
+
+func (me TreeHead) root() (Hash, error) {
+	var root Hash
+	if len(me.RootHash) != HashSize {
+		return root, fmt.Errorf("%w: root hash must be %d bytes", ErrInvalidProof, HashSize)
+	}
+	copy(root[:], me.RootHash)
+	return root, nil
+}
+
+func SignTreeHead(privateKey ed25519.PrivateKey, head TreeHead) SignedTreeHead {
+	return SignedTreeHead{
+		TreeHead:  head,
+		Signature: ed25519.Sign(privateKey, head.signedData()),
+	}
+}
+
+func (me SignedTreeHead) Verify(publicKey ed25519.PublicKey) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key size: %d", len(publicKey))
+	}
+	if !ed25519.Verify(publicKey, me.signedData(), me.Signature) {
+		return fmt.Errorf("%w: bad tree head signature", ErrInvalidProof)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package transparency
+
+import (
+	"chatapp/config"
+	"chatapp/repo"
+	"chatapp/service"
+	"chatapp/service/transparency/kt"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// TransparencyService serves the append-only key transparency log. Entries
+// are appended by the key service whenever an identity key is published.
+type TransparencyService struct {
+	queries    *repo.Queries
+	signingKey ed25519.PrivateKey
+
+	// leafHashes caches the log's leaf hashes. The log is append-only, so a
+	// cached prefix never goes stale.
+	leafHashesMu sync.Mutex
+	leafHashes   []kt.Hash
+}
+
+func NewTransparencyService(queries *repo.Queries) *TransparencyService {
+	if len(config.KeyTransparencySigningKey) != ed25519.SeedSize {
+		panic(fmt.Sprintf("key transparency signing key must be a %d byte ed25519 seed", ed25519.SeedSize))
+	}
+	return &TransparencyService{
+		queries:    queries,
+		signingKey: ed25519.NewKeyFromSeed(config.KeyTransparencySigningKey),
+	}
+}
+
+func (me *TransparencyService) PublicKey() ed25519.PublicKey {
+	return me.signingKey.Public().(ed25519.PublicKey)
+}
+
+// GetTreeHead returns the signed tree head for the given size, signing and
+// storing it the first time it is requested. A zero size means the current
+// size of the log.
+func (me *TransparencyService) GetTreeHead(treeSize uint64) (kt.SignedTreeHead, error) {
+	ctx := context.Background()
+
+	treeSize, err := me.resolveTreeSize(ctx, treeSize)
+	if err != nil {
+		return kt.SignedTreeHead{}, err
+	}
+
+	return me.getTreeHead(ctx, treeSize)
+}
+
+func (me *TransparencyService) getTreeHead(ctx context.Context, treeSize uint64) (kt.SignedTreeHead, error) {
+	var zero kt.SignedTreeHead
+
+	stored, err := me.queries.GetKeyTransparencyTreeHead(ctx, int64(treeSize))
+	if err == nil {
+		return treeHeadFromRow(stored), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return zero, fmt.Errorf("failed to get tree head: %w", err)
+	}
+
+	leaves, err := me.getLeafHashes(ctx, treeSize)
+	if err != nil {
+		return zero, err
+	}
+	root := kt.RootHash(leaves)
+
+	signed := kt.SignTreeHead(me.signingKey, kt.TreeHead{
+		TreeSize:  treeSize,
+		RootHash:  root[:],
+		Timestamp: time.Now().UnixMilli(),
+	})
+	if err := me.queries.InsertKeyTransparencyTreeHead(ctx, repo.InsertKeyTransparencyTreeHeadParams{
+		TreeSize:  int64(signed.TreeSize),
+		RootHash:  signed.RootHash,
+		Timestamp: signed.Timestamp,
+		Signature: signed.Signature,
+	}); err != nil {
+		return zero, fmt.Errorf("failed to insert tree head: %w", err)
+	}
+
+	// another request may have stored a tree head for this size first; always
+	// hand out the stored one so there's only ever one head per size.
+	stored, err = me.queries.GetKeyTransparencyTreeHead(ctx, int64(treeSize))
+	if err != nil {
+		return zero, fmt.Errorf("failed to get tree head: %w", err)
+	}
+	return treeHeadFromRow(stored), nil
+}
+
+func treeHeadFromRow(row repo.KtTreeHead) kt.SignedTreeHead {
+	return kt.SignedTreeHead{
+		TreeHead: kt.TreeHead{
+			TreeSize:  uint64(row.TreeSize),
+			RootHash:  row.RootHash,
+			Timestamp: row.Timestamp,
+		},
+		Signature: row.Signature,
+	}
+}
+
+// GetInclusionProof proves that the entry at leafIndex is part of the tree of
+// the given size. A zero size means the current size of the log.
+func (me *TransparencyService) GetInclusionProof(leafIndex, treeSize uint64) (kt.InclusionProof, error) {
+	ctx := context.Background()
+	var zero kt.InclusionProof
+
+	treeSize, err := me.resolveTreeSize(ctx, treeSize)
+	if err != nil {
+		return zero, err
+	}
+	if leafIndex >= treeSize {
+		return zero, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"leaf-index": errors.New("must be smaller than the tree size"),
+		})
+	}
+
+	entry, err := me.queries.GetKeyTransparencyEntry(ctx, int64(leafIndex))
+	if err != nil {
+		return zero, fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	leaves, err := me.getLeafHashes(ctx, treeSize)
+	if err != nil {
+		return zero, err
+	}
+
+	return inclusionProof(entry, leaves)
+}
+
+func inclusionProof(entry repo.KtEntry, leaves []kt.Hash) (kt.InclusionProof, error) {
+	path, err := kt.InclusionPath(leaves, int(entry.LeafIndex))
+	if err != nil {
+		return kt.InclusionProof{}, fmt.Errorf("failed to build inclusion path: %w", err)
+	}
+	return kt.InclusionProof{
+		LeafIndex: uint64(entry.LeafIndex),
+		TreeSize:  uint64(len(leaves)),
+		Binding: kt.Binding{
+			Username:    entry.Username,
+			DeviceID:    entry.DeviceID,
+			IdentityKey: entry.IdentityKey,
+		},
+		AuditPath: kt.FromHashes(path),
+	}, nil
+}
+
+// GetConsistencyProof proves that the tree of size first is a prefix of the
+// tree of size second. A zero second size means the current size of the log.
+func (me *TransparencyService) GetConsistencyProof(first, second uint64) (kt.ConsistencyProof, error) {
+	ctx := context.Background()
+	var zero kt.ConsistencyProof
+
+	second, err := me.resolveTreeSize(ctx, second)
+	if err != nil {
+		return zero, err
+	}
+	if first > second {
+		return zero, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"first": errors.New("must not be larger than second"),
+		})
+	}
+
+	leaves, err := me.getLeafHashes(ctx, second)
+	if err != nil {
+		return zero, err
+	}
+
+	proof, err := kt.ConsistencyPath(leaves, int(first))
+	if err != nil {
+		return zero, fmt.Errorf("failed to build consistency path: %w", err)
+	}
+
+	return kt.ConsistencyProof{
+		FirstSize:  first,
+		SecondSize: second,
+		Proof:      kt.FromHashes(proof),
+	}, nil
+}
+
+// GetMonitorReport returns every key ever published for the user's account,
+// each with an inclusion proof against the current signed tree head, so the
+// user can audit that the server never published a key they don't own.
+func (me *TransparencyService) GetMonitorReport(userID uuid.UUID) (kt.MonitorReport, error) {
+	ctx := context.Background()
+	var zero kt.MonitorReport
+
+	user, err := me.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return zero, fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	treeHead, err := me.GetTreeHead(0)
+	if err != nil {
+		return zero, err
+	}
+
+	leaves, err := me.getLeafHashes(ctx, treeHead.TreeSize)
+	if err != nil {
+		return zero, err
+	}
+
+	entries, err := me.queries.ListKeyTransparencyEntriesByUsername(ctx, repo.ListKeyTransparencyEntriesByUsernameParams{
+		Username: user.Username,
+		TreeSize: int64(treeHead.TreeSize),
+	})
+	if err != nil {
+		return zero, fmt.Errorf("failed to list entries: %w", err)
+	}
+
+	report := kt.MonitorReport{
+		Username: user.Username,
+		TreeHead: treeHead,
+		Entries:  make([]kt.InclusionProof, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		proof, err := inclusionProof(entry, leaves)
+		if err != nil {
+			return zero, err
+		}
+		report.Entries = append(report.Entries, proof)
+	}
+
+	return report, nil
+}
+
+func (me *TransparencyService) resolveTreeSize(ctx context.Context, treeSize uint64) (uint64, error) {
+	currentSize, err := me.queries.GetKeyTransparencyTreeSize(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get tree size: %w", err)
+	}
+	if treeSize == 0 {
+		return uint64(currentSize), nil
+	}
+	if treeSize > uint64(currentSize) {
+		return 0, fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"tree-size": errors.New("must not be larger than the current tree size"),
+		})
+	}
+	return treeSize, nil
+}
+
+// getLeafHashes returns the first treeSize leaf hashes of the log, loading
+// any that aren't cached yet.
+func (me *TransparencyService) getLeafHashes(ctx context.Context, treeSize uint64) ([]kt.Hash, error) {
+	me.leafHashesMu.Lock()
+	defer me.leafHashesMu.Unlock()
+
+	if cached := uint64(len(me.leafHashes)); cached < treeSize {
+		rows, err := me.queries.ListKeyTransparencyLeafHashes(ctx, repo.ListKeyTransparencyLeafHashesParams{
+			FromIndex: int64(cached),
+			ToIndex:   int64(treeSize),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list leaf hashes: %w", err)
+		}
+		if uint64(len(rows)) != treeSize-cached {
+			return nil, fmt.Errorf("log is missing leaves in range [%d, %d)", cached, treeSize)
+		}
+		for _, row := range rows {
+			me.leafHashes = append(me.leafHashes, kt.Hash(row))
+		}
+	}
+
+	return me.leafHashes[:treeSize:treeSize], nil
+}
@@ -3,7 +3,10 @@ package user
 import (
 	"chatapp/repo"
 	"chatapp/service"
+	"chatapp/service/presence"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"regexp"
 
@@ -12,6 +15,11 @@ import (
 	"github.com/google/uuid"
 )
 
+// DeliveryAccessKeySize is the size of the key a user hands to their contacts
+// (inside their encrypted profile) so they can send them sealed sender
+// messages.
+const DeliveryAccessKeySize = 16
+
 type UserService struct {
 	queries *repo.Queries
 }
@@ -72,3 +80,68 @@ func (me *CreateUserParams) validate() error {
 		validation.Field(&me.CredentialsID, validation.Required, is.UUID),
 	)
 }
+
+func (me *UserService) GetUserByCredentialsID(credentialsID uuid.UUID) (repo.User, error) {
+	user, err := me.queries.GetUserByCredentialsID(context.Background(), credentialsID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, service.ErrNotFound
+		}
+		return user, fmt.Errorf("failed to get user by credentials id: %w", err)
+	}
+	return user, nil
+}
+
+func (me *UserService) GetUserByUsername(username string) (repo.User, error) {
+	user, err := me.queries.GetUserByUsername(context.Background(), username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, service.ErrNotFound
+		}
+		return user, fmt.Errorf("failed to get user by username: %w", err)
+	}
+	return user, nil
+}
+
+// SetDeliveryAccessKey sets the key required to send sealed sender messages to
+// the user. A nil key disables sealed sender delivery.
+func (me *UserService) SetDeliveryAccessKey(userID uuid.UUID, accessKey []byte) error {
+	if accessKey != nil && len(accessKey) != DeliveryAccessKeySize {
+		return fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{
+			"access-key": fmt.Errorf("must be %d bytes", DeliveryAccessKeySize),
+		})
+	}
+
+	if err := me.queries.UpdateUserDeliveryAccessKey(context.Background(), repo.UpdateUserDeliveryAccessKeyParams{
+		ID:                userID,
+		DeliveryAccessKey: accessKey,
+	}); err != nil {
+		return fmt.Errorf("failed to update delivery access key: %w", err)
+	}
+
+	return nil
+}
+
+func (me *UserService) SetReadReceiptsEnabled(userID uuid.UUID, enabled bool) error {
+	if err := me.queries.UpdateUserReadReceiptsEnabled(context.Background(), repo.UpdateUserReadReceiptsEnabledParams{
+		ID:                  userID,
+		ReadReceiptsEnabled: enabled,
+	}); err != nil {
+		return fmt.Errorf("failed to update read receipts setting: %w", err)
+	}
+	return nil
+}
+
+func (me *UserService) SetPresenceVisibility(userID uuid.UUID, visibility string) error {
+	if err := validation.Validate(visibility, validation.Required, validation.In(presence.VisibilityEveryone, presence.VisibilityContacts, presence.VisibilityNobody)); err != nil {
+		return fmt.Errorf("%w: %w", service.ErrValidation, validation.Errors{"visibility": err})
+	}
+
+	if err := me.queries.UpdateUserPresenceVisibility(context.Background(), repo.UpdateUserPresenceVisibilityParams{
+		ID:                 userID,
+		PresenceVisibility: visibility,
+	}); err != nil {
+		return fmt.Errorf("failed to update presence visibility: %w", err)
+	}
+	return nil
+}